@@ -0,0 +1,750 @@
+package main
+
+import (
+    "bufio"
+    "context"
+    "flag"
+    "fmt"
+    "io"
+    "os"
+    "os/signal"
+    "strconv"
+    "strings"
+    "time"
+
+    "gotcha/internal/agent"
+    "gotcha/internal/app"
+    "gotcha/internal/diff"
+    "gotcha/internal/llm"
+    "gotcha/internal/platform"
+    "gotcha/internal/session"
+    "gotcha/internal/storage"
+)
+
+// runCommand dispatches `gotcha <command> [args]` and returns the exit code.
+func runCommand(ctx context.Context, cfg platform.Config, sessionManager *session.Manager, args []string) int {
+    switch args[0] {
+    case "research":
+        return runResearch(ctx, cfg, sessionManager, args[1:])
+    case "events":
+        return runEvents(ctx, cfg, sessionManager, args[1:])
+    case "weights":
+        return runWeights(ctx, cfg, sessionManager, args[1:])
+    case "watch":
+        return runWatch(ctx, cfg, sessionManager, args[1:])
+    case "revisions":
+        return runRevisions(ctx, cfg, sessionManager, args[1:])
+    case "templates":
+        return runTemplates(ctx, cfg)
+    case "sessions":
+        return runSessions(ctx, cfg, sessionManager, args[1:])
+    case "search":
+        return runSearch(sessionManager, args[1:])
+    default:
+        fmt.Fprintf(os.Stderr, "unknown command %q\n", args[0])
+        fmt.Fprintln(os.Stderr, "commands: research, events, weights, watch, revisions, templates, sessions, search")
+        return 2
+    }
+}
+
+// services bundles the background research stack used outside the TUI.
+type services struct {
+    bus        agent.EventBus
+    svc        *app.Service
+    tasks      *agent.TaskManager
+    researcher *agent.Researcher
+}
+
+func newServices(ctx context.Context, cfg platform.Config, sessionID string) (*services, error) {
+    db, err := storage.Open(cfg.Paths.DBPath())
+    if err != nil { return nil, err }
+    if err := storage.Migrate(db); err != nil { return nil, err }
+    svc := app.NewService(db, cfg.Paths)
+    if sessionID != "" {
+        if _, err := svc.CreateOrOpenSession(ctx, sessionID, "Session", ""); err != nil { return nil, err }
+    }
+    bus := agent.NewEventLog(cfg.Paths).Wrap(agent.NewMemoryBus(64))
+    tasks := agent.NewTaskManager(bus, cfg.Concurrency.Tasks)
+    return &services{
+        bus:        bus,
+        svc:        svc,
+        tasks:      tasks,
+        researcher: agent.NewResearcher(bus, llm.FromConfig(cfg.LLM, cfg.ProxyURL), svc, tasks, agent.NewResearchConfig(cfg)),
+    }, nil
+}
+
+// runResearch runs one research task in the foreground, printing its events.
+// Ctrl+C cancels the task.
+func runResearch(ctx context.Context, cfg platform.Config, sessionManager *session.Manager, args []string) int {
+    fs := flag.NewFlagSet("research", flag.ContinueOnError)
+    sessionFlag := fs.String("session", "", "Session to write the report into (default: new session)")
+    resumeFlag := fs.String("resume", "", "Resume an interrupted run from its checkpoint (requires -session)")
+    deepFlag := fs.Bool("deep", cfg.Research.Iterative, "Iterate search rounds with gap analysis")
+    depthFlag := fs.Int("depth", cfg.Research.MaxDepth, "Maximum search rounds in deep mode")
+    timeFlag := fs.Duration("time", cfg.Research.MaxDuration, "Maximum time spent searching in deep mode")
+    tokensFlag := fs.Int("max-tokens", 0, "Stop deep research after this many LLM tokens (0: no limit)")
+    verifyFlag := fs.Bool("verify", cfg.Research.Verify, "Check report claims against the fetched sources")
+    reviewFlag := fs.Int("review", cfg.Research.ReviewRounds, "Critique and revise the draft this many rounds (0: off)")
+    budgetTokens := fs.Int("budget-tokens", cfg.Budget.Run.Tokens, "Hard limit on LLM tokens for this run (0: none)")
+    budgetCost := fs.Float64("budget-cost", cfg.Budget.Run.Cost, "Hard limit on cost in USD for this run (0: none)")
+    budgetTime := fs.Duration("budget-time", cfg.Budget.Run.Duration, "Hard limit on wall-clock time for this run (0: none)")
+    budgetRequests := fs.Int("budget-requests", cfg.Budget.Run.Requests, "Hard limit on LLM, search and fetch requests for this run (0: none)")
+    yesFlag := fs.Bool("yes", cfg.Research.AutoApprove, "Approve the planner's outline without reviewing it (for batch runs)")
+    compareFlag := fs.Bool("compare", false, "Compare the options named in the prompt in a weighted decision matrix")
+    weightsFlag := fs.String("weights", "", "Criterion weights for -compare, e.g. \"cost=2,performance=3\"")
+    corpusFlag := fs.String("corpus", cfg.Search.CorpusDir, "Also search the Markdown, text, HTML and PDF files in this directory")
+    templateFlag := fs.String("template", cfg.Research.Template, "Report template (see `gotcha templates`)")
+    clarifyFlag := fs.Bool("clarify", cfg.Research.Clarify, "Ask clarifying questions on stdin when the prompt is ambiguous")
+    if err := fs.Parse(args); err != nil { return 2 }
+    weights, err := agent.ParseWeights(*weightsFlag)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "error: %v\n", err)
+        return 2
+    }
+    prompt := strings.TrimSpace(strings.Join(fs.Args(), " "))
+    if prompt == "" && (*resumeFlag == "" || *sessionFlag == "") {
+        fmt.Fprintln(os.Stderr, "usage: gotcha research [-session id] [-deep [-depth n] [-time d] [-max-tokens n]] [-compare [-weights c=n,...]] [-corpus dir] [-template name] <prompt>")
+        fmt.Fprintln(os.Stderr, "       gotcha research -session id -resume run-id")
+        return 2
+    }
+
+    sessionID := *sessionFlag
+    if sessionID == "" {
+        if sessionID, err = sessionManager.CreateNewSession(); err != nil {
+            fmt.Fprintf(os.Stderr, "error creating session: %v\n", err)
+            return 1
+        }
+    }
+    s, err := newServices(ctx, cfg, sessionID)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "error: %v\n", err)
+        return 1
+    }
+    if *templateFlag != "" && *resumeFlag == "" {
+        if _, err := s.researcher.Template(*templateFlag); err != nil {
+            fmt.Fprintf(os.Stderr, "error: %v\n", err)
+            return 2
+        }
+    }
+    if *corpusFlag != "" {
+        files, passages, err := s.researcher.SetCorpus(ctx, *corpusFlag)
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "error: %v\n", err)
+            return 1
+        }
+        fmt.Printf("corpus: %d files, %d passages in %s\n", files, passages, *corpusFlag)
+    }
+
+    runID := *resumeFlag
+    if runID == "" && *sessionFlag != "" {
+        runID = offerResume(s.researcher, sessionID)
+    }
+
+    events, unsubscribe := s.bus.Subscribe(ctx, sessionID)
+    defer unsubscribe()
+    var taskID string
+    if runID != "" {
+        if taskID, err = s.researcher.ResumeRun(sessionID, runID); err != nil {
+            fmt.Fprintf(os.Stderr, "error resuming run: %v\n", err)
+            return 1
+        }
+        fmt.Printf("%s resumed in %s\n", taskID, sessionID)
+    } else {
+        opts := s.researcher.DefaultOptions()
+        opts.Iterative, opts.MaxDepth, opts.MaxDuration, opts.MaxTokens = *deepFlag, *depthFlag, *timeFlag, *tokensFlag
+        opts.Verify, opts.ReviewRounds = *verifyFlag, *reviewFlag
+        opts.Budget = agent.Budget{Tokens: *budgetTokens, Cost: *budgetCost, Duration: *budgetTime, Requests: *budgetRequests}
+        opts.AutoApprove = *yesFlag
+        if *compareFlag { opts.Mode, opts.Weights = agent.ModeCompare, weights }
+        opts.Template, opts.Clarify = *templateFlag, *clarifyFlag
+        taskID = s.researcher.Start(sessionID, prompt, opts)
+        fmt.Printf("%s started in %s\n", taskID, sessionID)
+        titled := make(chan struct{})
+        go func() { defer close(titled); titleSession(ctx, cfg, sessionManager, sessionID, prompt) }()
+        defer func() { <-titled }()
+    }
+
+    interrupt := make(chan os.Signal, 1)
+    signal.Notify(interrupt, os.Interrupt)
+    defer signal.Stop(interrupt)
+    var (
+        lines     <-chan string // stdin, read once questions or an outline need the user
+        outline   *agent.Plan   // outline being edited
+        questions []string      // clarifying questions being answered
+        answers   []string
+    )
+    answer := func() {
+        if err := s.researcher.AnswerQuestions(taskID, answers); err != nil { fmt.Fprintf(os.Stderr, "error: %v\n", err) }
+        questions, answers = nil, nil
+    }
+    for {
+        select {
+        case <-interrupt:
+            _ = s.tasks.Cancel(taskID)
+        case line, ok := <-lines:
+            if !ok {
+                lines = nil
+                if questions != nil { answer() }
+                if outline != nil {
+                    fmt.Println("\nstdin closed before the outline was approved; cancelling (use -yes for batch runs)")
+                    _ = s.tasks.Cancel(taskID)
+                }
+                continue
+            }
+            if questions != nil {
+                answers = append(answers, strings.TrimSpace(line))
+                if len(answers) < len(questions) {
+                    fmt.Printf("%d. %s\n> ", len(answers)+1, questions[len(answers)])
+                    continue
+                }
+                answer()
+                continue
+            }
+            if outline != nil && editOutline(outline, line, s, taskID) { outline = nil }
+        case e := <-events:
+            if e.TaskID != taskID { continue }
+            fmt.Println(formatEvent(e))
+            if e.Phase == agent.PhaseClarify && e.Type == "questions" {
+                if qs, ok := s.researcher.PendingQuestions(taskID); ok {
+                    questions, answers = qs, nil
+                    fmt.Println("\nThe prompt is open to interpretation. Answer each question (empty line: no preference):")
+                    fmt.Printf("1. %s\n> ", qs[0])
+                    if lines == nil { lines = readLines(stdin) }
+                }
+                continue
+            }
+            if e.Phase == agent.PhaseOutline && e.Type == "approval" {
+                if p, ok := s.researcher.PendingOutline(taskID); ok {
+                    outline = &p
+                    printOutline(p)
+                    fmt.Println(outlineHelp)
+                    fmt.Print("outline> ")
+                    if lines == nil { lines = readLines(stdin) }
+                }
+                continue
+            }
+            if e.Phase != agent.PhaseTask || !agent.TaskState(e.Type).Terminal() { continue }
+            if e.Type == string(agent.TaskDone) {
+                fmt.Printf("report: %s\n", s.svc.ReportPath(sessionID))
+                if *compareFlag { fmt.Printf("matrix: %s\n", s.svc.ComparisonCSVPath(sessionID)) }
+                return 0
+            }
+            return 1
+        }
+    }
+}
+
+// runEvents prints a session's event log for post-mortems and lists phases
+// that started but never finished.
+func runEvents(ctx context.Context, cfg platform.Config, sessionManager *session.Manager, args []string) int {
+    fs := flag.NewFlagSet("events", flag.ContinueOnError)
+    sessionFlag := fs.String("session", "", "Session to inspect (default: last session)")
+    taskFlag := fs.String("task", "", "Only show events of this task")
+    phaseFlag := fs.String("phase", "", "Only show events of this phase")
+    if err := fs.Parse(args); err != nil { return 2 }
+
+    sessionID := *sessionFlag
+    if sessionID == "" {
+        var err error
+        if sessionID, err = sessionManager.GetLastSession(); err != nil || sessionID == "" {
+            fmt.Fprintln(os.Stderr, "no session to inspect")
+            return 1
+        }
+    }
+
+    log := agent.NewEventLog(cfg.Paths)
+    bus := agent.NewMemoryBus(1)
+    var opts []agent.SubscribeOption
+    if *phaseFlag != "" { opts = append(opts, agent.WithPhases(agent.Phase(*phaseFlag))) }
+    events, unsubscribe := bus.Subscribe(ctx, sessionID, append(opts, agent.WithBuffer(1<<20))...)
+    n, err := log.Replay(ctx, bus, sessionID)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "error: %v\n", err)
+        return 1
+    }
+    if n == 0 {
+        fmt.Printf("no events logged for %s\n", sessionID)
+        return 0
+    }
+
+    type key struct {
+        task  string
+        phase agent.Phase
+    }
+    open := map[key]agent.Event{}
+    var order []key
+    quiet := time.NewTimer(100 * time.Millisecond)
+    for done := false; !done; {
+        select {
+        case e := <-events:
+            quiet.Reset(100 * time.Millisecond)
+            if *taskFlag != "" && e.TaskID != *taskFlag { continue }
+            fmt.Println(formatEvent(e))
+            k := key{e.TaskID, e.Phase}
+            switch e.Type {
+            case "started":
+                if _, ok := open[k]; !ok { order = append(order, k) }
+                open[k] = e
+            case "done", "error":
+                delete(open, k)
+            }
+        case <-quiet.C:
+            done = true
+        }
+    }
+    unsubscribe()
+
+    for _, k := range order {
+        if e, ok := open[k]; ok {
+            fmt.Printf("unfinished: %s %s started %s and never completed\n", k.task, k.phase, e.At.Format(time.RFC3339))
+        }
+    }
+    return 0
+}
+
+// runWeights changes the criterion weights of a comparison run and rewrites
+// the report and matrix CSV with the new scores.
+func runWeights(ctx context.Context, cfg platform.Config, sessionManager *session.Manager, args []string) int {
+    fs := flag.NewFlagSet("weights", flag.ContinueOnError)
+    sessionFlag := fs.String("session", "", "Session of the comparison (default: last session)")
+    runFlag := fs.String("run", "", "Comparison run to reweight (default: the latest)")
+    if err := fs.Parse(args); err != nil { return 2 }
+
+    sessionID := *sessionFlag
+    if sessionID == "" {
+        var err error
+        if sessionID, err = sessionManager.GetLastSession(); err != nil || sessionID == "" {
+            fmt.Fprintln(os.Stderr, "no session to reweight")
+            return 1
+        }
+    }
+    s, err := newServices(ctx, cfg, sessionID)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "error: %v\n", err)
+        return 1
+    }
+    runID := *runFlag
+    if runID == "" {
+        j, ok := s.researcher.LatestComparison(sessionID)
+        if !ok {
+            fmt.Fprintf(os.Stderr, "no comparison runs in %s\n", sessionID)
+            return 1
+        }
+        runID = j.RunID
+    }
+    weights, err := agent.ParseWeights(fs.Args()...)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "error: %v\n", err)
+        return 2
+    }
+    j, err := s.researcher.Reweight(sessionID, runID, weights)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "error: %v\n", err)
+        return 1
+    }
+    totals := j.Comparison.Totals()
+    for _, c := range j.Comparison.Criteria { fmt.Printf("  %-20s weight %g\n", c.Name, c.Weight) }
+    for i, o := range j.Comparison.Options {
+        if totals[i] < 0 { fmt.Printf("%-22s –\n", o); continue }
+        fmt.Printf("%-22s %.2f\n", o, totals[i])
+    }
+    if j.Status == agent.RunComplete { fmt.Printf("report: %s\nmatrix: %s\n", s.svc.ReportPath(sessionID), s.svc.ComparisonCSVPath(sessionID)) }
+    return 0
+}
+
+// runTemplates lists the report templates, built-in and from .gotcha/templates.
+func runTemplates(ctx context.Context, cfg platform.Config) int {
+    s, err := newServices(ctx, cfg, "")
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "error: %v\n", err)
+        return 1
+    }
+    all, err := s.researcher.Templates()
+    for _, t := range all {
+        origin := "built-in"
+        if t.Path != "" { origin = t.Path }
+        fmt.Printf("%-22s %s (%s)\n", t.Name, t.Description, origin)
+        var heads []string
+        for _, sec := range t.Sections { heads = append(heads, sec.Heading) }
+        layout := "sections"
+        if !t.Fixed { layout = "core sections" }
+        fmt.Printf("%-22s %s: %s\n", "", layout, strings.Join(heads, " · "))
+    }
+    if err != nil { fmt.Fprintf(os.Stderr, "skipped templates that failed to load:\n%v\n", err) }
+    return 0
+}
+
+// runRevisions lists a session's report revisions, diffs two of them section
+// by section or restores one.
+func runRevisions(ctx context.Context, cfg platform.Config, sessionManager *session.Manager, args []string) int {
+    fs := flag.NewFlagSet("revisions", flag.ContinueOnError)
+    sessionFlag := fs.String("session", "", "Session whose report to inspect (default: last session)")
+    if err := fs.Parse(args); err != nil { return 2 }
+    const usage = "usage: gotcha revisions [-session id] [list | diff <a> <b> | show <n> | restore <n>]"
+
+    sessionID := *sessionFlag
+    if sessionID == "" {
+        var err error
+        if sessionID, err = sessionManager.GetLastSession(); err != nil || sessionID == "" {
+            fmt.Fprintln(os.Stderr, "no session to inspect")
+            return 1
+        }
+    }
+    s, err := newServices(ctx, cfg, sessionID)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "error: %v\n", err)
+        return 1
+    }
+    rest := fs.Args()
+    var nums []int
+    for _, a := range rest[min(1, len(rest)):] {
+        n, err := strconv.Atoi(strings.TrimPrefix(a, "r"))
+        if err != nil {
+            fmt.Fprintln(os.Stderr, usage)
+            return 2
+        }
+        nums = append(nums, n)
+    }
+    verb := "list"
+    if len(rest) > 0 { verb = rest[0] }
+    switch {
+    case verb == "list" && len(nums) == 0:
+        revs, err := s.researcher.Revisions(sessionID)
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "error: %v\n", err)
+            return 1
+        }
+        if len(revs) == 0 {
+            fmt.Printf("no report revisions in %s\n", sessionID)
+            return 0
+        }
+        for _, rev := range revs {
+            fmt.Printf("r%-3d %s  %3d sources  $%.4f  %-24s %s", rev.N, rev.At.Local().Format("2006-01-02 15:04"), rev.Sources, rev.Cost, rev.Model, rev.Title)
+            if rev.RestoredFrom > 0 { fmt.Printf("  (restored r%d)", rev.RestoredFrom) }
+            fmt.Println()
+        }
+        return 0
+    case verb == "show" && len(nums) == 1:
+        _, doc, err := s.researcher.Revision(sessionID, nums[0])
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "error: %v\n", err)
+            return 1
+        }
+        fmt.Print(doc)
+        return 0
+    case verb == "diff" && len(nums) == 2:
+        diffs, err := s.researcher.DiffRevisions(sessionID, nums[0], nums[1])
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "error: %v\n", err)
+            return 1
+        }
+        for _, d := range diffs {
+            heading := d.Heading
+            if heading == "" { heading = "(title)" }
+            fmt.Printf("%-9s %-40s +%d -%d\n", d.Status, heading, d.Added, d.Removed)
+            if d.Status == diff.SectionUnchanged { continue }
+            for _, l := range d.Lines {
+                switch l.Kind {
+                case diff.Delete:
+                    fmt.Println("  - " + l.Text)
+                case diff.Insert:
+                    fmt.Println("  + " + l.Text)
+                }
+            }
+        }
+        return 0
+    case verb == "restore" && len(nums) == 1:
+        rev, err := s.researcher.RestoreRevision(sessionID, nums[0])
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "error: %v\n", err)
+            return 1
+        }
+        fmt.Printf("restored r%d as r%d: %s\n", nums[0], rev.N, s.svc.ReportPath(sessionID))
+        return 0
+    }
+    fmt.Fprintln(os.Stderr, usage)
+    return 2
+}
+
+const watchUsage = `usage: gotcha watch add [-name id] [-schedule "cron"] [-deep] [-template name] <prompt>
+       gotcha watch list
+       gotcha watch run <id> | -due | -daemon
+       gotcha watch rm <id>`
+
+// runWatch manages watches: saved prompts re-researched on demand or on a
+// cron schedule, keeping every version of the report.
+func runWatch(ctx context.Context, cfg platform.Config, sessionManager *session.Manager, args []string) int {
+    if len(args) == 0 {
+        fmt.Fprintln(os.Stderr, watchUsage)
+        return 2
+    }
+    s, err := newServices(ctx, cfg, "")
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "error: %v\n", err)
+        return 1
+    }
+    watcher := agent.NewWatcher(s.researcher)
+    switch args[0] {
+    case "add":
+        fs := flag.NewFlagSet("watch add", flag.ContinueOnError)
+        nameFlag := fs.String("name", "", "Watch ID (default: derived from the prompt)")
+        scheduleFlag := fs.String("schedule", "", "Cron expression, e.g. \"0 9 * * mon\" or @daily (default: on demand only)")
+        deepFlag := fs.Bool("deep", cfg.Research.Iterative, "Iterate search rounds with gap analysis")
+        verifyFlag := fs.Bool("verify", cfg.Research.Verify, "Check report claims against the fetched sources")
+        templateFlag := fs.String("template", cfg.Research.Template, "Report template (see `gotcha templates`)")
+        if err := fs.Parse(args[1:]); err != nil { return 2 }
+        prompt := strings.TrimSpace(strings.Join(fs.Args(), " "))
+        if prompt == "" {
+            fmt.Fprintln(os.Stderr, watchUsage)
+            return 2
+        }
+        if *templateFlag != "" {
+            if _, err := s.researcher.Template(*templateFlag); err != nil {
+                fmt.Fprintf(os.Stderr, "error: %v\n", err)
+                return 2
+            }
+        }
+        sessionID, err := sessionManager.CreateNewSession()
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "error creating session: %v\n", err)
+            return 1
+        }
+        opts := s.researcher.DefaultOptions()
+        opts.Iterative, opts.Verify, opts.Template = *deepFlag, *verifyFlag, *templateFlag
+        wt, err := watcher.Add(*nameFlag, sessionID, prompt, *scheduleFlag, opts)
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "error: %v\n", err)
+            return 2
+        }
+        fmt.Printf("watch %s added (session %s)\n", wt.ID, wt.SessionID)
+        if next := wt.Next(); !next.IsZero() { fmt.Printf("next run: %s\n", next.Format(time.RFC1123)) }
+        return 0
+    case "list", "ls":
+        all, err := watcher.List()
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "error: %v\n", err)
+            return 1
+        }
+        if len(all) == 0 {
+            fmt.Println("no watches")
+            return 0
+        }
+        for _, wt := range all {
+            sched, next := "on demand", "-"
+            if wt.Schedule != "" { sched = wt.Schedule }
+            if n := wt.Next(); !n.IsZero() { next = n.Format("2006-01-02 15:04") }
+            fmt.Printf("%-24s %-16s next %-16s %d version(s)  %s\n", wt.ID, sched, next, len(wt.Versions), wt.Prompt)
+            if k := len(wt.Versions); k > 0 && wt.Versions[k-1].Summary != "" {
+                fmt.Printf("%-24s last change: %s\n", "", wt.Versions[k-1].Summary)
+            }
+            if wt.Failures > 0 { fmt.Printf("%-24s %d failed run(s) since the last success; retrying with backoff\n", "", wt.Failures) }
+        }
+        return 0
+    case "rm", "remove":
+        if len(args) != 2 {
+            fmt.Fprintln(os.Stderr, watchUsage)
+            return 2
+        }
+        if err := watcher.Remove(args[1]); err != nil {
+            fmt.Fprintf(os.Stderr, "error: %v\n", err)
+            return 1
+        }
+        fmt.Printf("watch %s removed\n", args[1])
+        return 0
+    case "run":
+        fs := flag.NewFlagSet("watch run", flag.ContinueOnError)
+        dueFlag := fs.Bool("due", false, "Run every scheduled watch that is due")
+        daemonFlag := fs.Bool("daemon", false, "Keep running scheduled watches until interrupted")
+        if err := fs.Parse(args[1:]); err != nil { return 2 }
+        ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+        defer stop()
+        if *daemonFlag {
+            fmt.Println("running scheduled watches; Ctrl+C to stop")
+            watcher.Start(ctx, func(wt agent.Watch, v agent.WatchVersion, err error) { printWatchRun(wt.ID, v, err) })
+            <-ctx.Done()
+            return 0
+        }
+        ids := fs.Args()
+        if *dueFlag {
+            due, err := watcher.Due(time.Now())
+            if err != nil {
+                fmt.Fprintf(os.Stderr, "error: %v\n", err)
+                return 1
+            }
+            for _, wt := range due { ids = append(ids, wt.ID) }
+            if len(ids) == 0 { fmt.Println("no watches due") }
+        } else if len(ids) == 0 {
+            fmt.Fprintln(os.Stderr, watchUsage)
+            return 2
+        }
+        code := 0
+        for _, id := range ids {
+            fmt.Printf("running watch %s\n", id)
+            v, err := watcher.Run(ctx, id)
+            printWatchRun(id, v, err)
+            if err != nil { code = 1 }
+        }
+        return code
+    default:
+        fmt.Fprintln(os.Stderr, watchUsage)
+        return 2
+    }
+}
+
+func printWatchRun(id string, v agent.WatchVersion, err error) {
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "watch %s: %v\n", id, err)
+        return
+    }
+    fmt.Printf("watch %s: v%d saved to %s\n", id, v.N, v.Report)
+    if v.Changes != "" { fmt.Printf("  %s\n  changes: %s\n", v.Summary, v.Changes) }
+}
+
+const outlineHelp = `Review the outline before composing:
+  y                     approve and compose
+  t <title>             set the report title
+  e <n> <heading>       rename section n
+  i <n> <instructions>  set section n's instructions
+  s <n> <q1; q2>        set section n's search queries
+  a <n> <heading>       add a section at position n
+  d <n>                 delete section n
+  m <n> <to>            move section n to position <to>
+  p                     print the outline
+  q                     cancel the run`
+
+func printOutline(p agent.Plan) {
+    fmt.Printf("\nOutline: %s\n", p.Title)
+    for i, sec := range p.Sections {
+        fmt.Printf("  %d. %s\n", i+1, sec.Heading)
+        if strings.TrimSpace(sec.Instructions) != "" { fmt.Printf("     %s\n", sec.Instructions) }
+        if len(sec.Queries) > 0 { fmt.Printf("     queries: %s\n", strings.Join(sec.Queries, "; ")) }
+        if prefs := sectionPrefs(sec); prefs != "" { fmt.Printf("     %s\n", prefs) }
+    }
+    if len(p.Queries) > 0 { fmt.Printf("  queries for all sections: %s\n", strings.Join(p.Queries, "; ")) }
+}
+
+// sectionPrefs describes a section's preferred sources and recency.
+func sectionPrefs(sec agent.Section) string {
+    var parts []string
+    if len(sec.SourceTypes) > 0 { parts = append(parts, "prefers "+strings.Join(sec.SourceTypes, ", ")) }
+    if sec.Recency != "" { parts = append(parts, "recency: "+sec.Recency) }
+    return strings.Join(parts, " · ")
+}
+
+// editOutline applies one outline command typed on stdin and reports whether
+// the review is over (approved or cancelled).
+func editOutline(p *agent.Plan, line string, s *services, taskID string) bool {
+    fields := strings.Fields(line)
+    if len(fields) == 0 { fmt.Print("outline> "); return false }
+    // index parses a 1-based section number
+    index := func(arg string, limit int) (int, bool) {
+        var n int
+        if _, err := fmt.Sscan(arg, &n); err != nil || n < 1 || n > limit {
+            fmt.Printf("no section %q\n", arg)
+            return 0, false
+        }
+        return n - 1, true
+    }
+    rest := func(n int) string {
+        // text after the first n fields
+        out := strings.TrimSpace(line)
+        for i := 0; i < n; i++ { out = strings.TrimSpace(strings.TrimPrefix(out, fields[i])) }
+        return out
+    }
+    switch fields[0] {
+    case "y", "yes":
+        if err := s.researcher.ApproveOutline(taskID, *p); err != nil {
+            fmt.Printf("cannot approve: %v\n", err)
+            return false
+        }
+        fmt.Println("outline approved")
+        return true
+    case "q":
+        _ = s.tasks.Cancel(taskID)
+        return true
+    }
+    defer fmt.Print("outline> ")
+    switch fields[0] {
+    case "p":
+        printOutline(*p)
+    case "t":
+        p.Title = rest(1)
+    case "e", "i":
+        if len(fields) < 3 { fmt.Println(outlineHelp); return false }
+        if n, ok := index(fields[1], len(p.Sections)); ok {
+            if fields[0] == "e" { p.Sections[n].Heading = rest(2) } else { p.Sections[n].Instructions = rest(2) }
+        }
+    case "s":
+        if len(fields) < 2 { fmt.Println(outlineHelp); return false }
+        if n, ok := index(fields[1], len(p.Sections)); ok { p.Sections[n].Queries = strings.Split(rest(2), ";") }
+    case "a":
+        if len(fields) < 3 { fmt.Println(outlineHelp); return false }
+        if n, ok := index(fields[1], len(p.Sections)+1); ok { p.InsertSection(n, agent.Section{Heading: rest(2)}) }
+    case "d":
+        if len(fields) < 2 { fmt.Println(outlineHelp); return false }
+        if n, ok := index(fields[1], len(p.Sections)); ok { p.DeleteSection(n) }
+    case "m":
+        if len(fields) < 3 { fmt.Println(outlineHelp); return false }
+        from, ok1 := index(fields[1], len(p.Sections))
+        to, ok2 := index(fields[2], len(p.Sections))
+        if ok1 && ok2 { p.MoveSection(from, to) }
+    default:
+        fmt.Println(outlineHelp)
+        return false
+    }
+    if fields[0] != "p" { printOutline(*p) }
+    return false
+}
+
+// stdin is the one buffered reader over os.Stdin. Prompts must share it: a
+// reader of their own could buffer input meant for the next prompt.
+var stdin = bufio.NewReader(os.Stdin)
+
+// readLines streams lines from r until EOF.
+func readLines(r io.Reader) <-chan string {
+    ch := make(chan string)
+    go func() {
+        defer close(ch)
+        sc := bufio.NewScanner(r)
+        for sc.Scan() { ch <- sc.Text() }
+    }()
+    return ch
+}
+
+// offerResume asks on stdin whether to continue the newest interrupted run in
+// the session instead of starting over. It returns the run ID to resume, if any.
+func offerResume(researcher *agent.Researcher, sessionID string) string {
+    runs, err := researcher.IncompleteRuns(sessionID)
+    if err != nil || len(runs) == 0 { return "" }
+    j := runs[len(runs)-1]
+    done, total := j.Progress()
+    fmt.Printf("Run %s (%q) stopped at %d/%d sections. Resume it from its checkpoint? [y/N] ", j.RunID, j.Title(), done, total)
+    answer, _ := stdin.ReadString('\n')
+    switch strings.ToLower(strings.TrimSpace(answer)) {
+    case "y", "yes":
+        return j.RunID
+    }
+    return ""
+}
+
+func toFloat(v any) float64 {
+    f, _ := v.(float64)
+    return f
+}
+
+func formatEvent(e agent.Event) string {
+    line := fmt.Sprintf("%s  %-8s %-9s", e.At.Format("15:04:05"), e.Phase, e.Type)
+    if e.Progress.Total > 0 { line += fmt.Sprintf(" %d/%d", e.Progress.Done, e.Progress.Total) }
+    if title, ok := e.Meta["title"].(string); ok && title != "" { line += "  " + title }
+    if section, ok := e.Meta["section"].(string); ok && section != "" { line += "  " + section }
+    if reason, ok := e.Meta["stop_reason"].(string); ok && reason != "" { line += "  stopped: " + reason }
+    if e.Phase == agent.PhaseExtract {
+        if dups, ok := e.Meta["duplicates"]; ok { line += fmt.Sprintf("  %v duplicate(s), dedup ratio %.0f%%", dups, toFloat(e.Meta["dedup_ratio"])*100) }
+    }
+    if e.Phase == agent.PhaseBudget && e.Type == "warning" {
+        line += fmt.Sprintf("  %v %v at %.0f%% of %v", e.Meta["scope"], e.Meta["limit"], toFloat(e.Meta["used"])*100, e.Meta["of"])
+    }
+    if b, ok := e.Meta["budget"].(map[string]any); ok && e.Type != "progress" { line += "  [" + agent.FormatBudget(b) + "]" }
+    if e.Err != "" { line += "  error: " + e.Err }
+    return line
+}
@@ -26,6 +26,12 @@ func main() {
 
     sessionManager := session.NewManager()
 
+    if flag.NArg() > 0 {
+        code := runCommand(ctx, cfg, sessionManager, flag.Args())
+        reportRepairs(sessionManager)
+        os.Exit(code)
+    }
+
     var sessionID string
     var err error
 
@@ -86,20 +92,30 @@ func main() {
     time.Sleep(50 * time.Millisecond)
 }
 
+// reportRepairs tells the user about damaged session files that were
+// recovered or rebuilt.
+func reportRepairs(sessionManager *session.Manager) {
+    for _, r := range sessionManager.Repairs() { fmt.Fprintf(os.Stderr, "repaired %s\n", r) }
+}
+
 func runSessionSelector(sessionManager *session.Manager) (string, error) {
-    sessions, err := sessionManager.ListSessions()
+    all, err := sessionManager.AllSessions()
     if err != nil {
         return "", err
     }
 
-    if len(sessions) == 0 {
+    if len(all) == 0 {
         fmt.Println("Nothing to resume from - no existing sessions found.")
         fmt.Println("Use 'gotcha' to start a new session.")
         return "", nil // Return empty string to indicate no session selected
     }
 
     // Create session selector UI
-    selector := tui.NewSessionSelector(sessions)
+    sessions, err := sessionManager.ListSessions()
+    if err != nil {
+        return "", err
+    }
+    selector := tui.NewSessionSelector(sessionManager, sessions)
     p := tea.NewProgram(selector, tea.WithAltScreen(), tea.WithoutSignalHandler())
 
     result, err := p.Run()
@@ -0,0 +1,40 @@
+package main
+
+import (
+    "flag"
+    "fmt"
+    "os"
+    "strings"
+
+    "gotcha/internal/session"
+)
+
+// runSearch searches the conversations, notes and reports of every session
+// and prints the best matches with a snippet each.
+func runSearch(sessionManager *session.Manager, args []string) int {
+    fs := flag.NewFlagSet("search", flag.ContinueOnError)
+    limit := fs.Int("n", 10, "maximum number of results")
+    if err := fs.Parse(args); err != nil { return 2 }
+    query := strings.Join(fs.Args(), " ")
+    if strings.TrimSpace(query) == "" {
+        fmt.Fprintln(os.Stderr, "usage: gotcha search [-n 10] <terms>")
+        return 2
+    }
+    hits, err := sessionManager.Search(query, *limit)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "error: %v\n", err)
+        return 1
+    }
+    if len(hits) == 0 {
+        fmt.Println("no matches")
+        return 1
+    }
+    for _, h := range hits {
+        title := h.Title
+        if title == "" { title = "(untitled)" }
+        if h.Archived { title += " [archived]" }
+        fmt.Printf("%-12s %-28s %s\n", h.SessionID, h.Location(), title)
+        fmt.Printf("%-12s %s\n\n", "", h.Snippet)
+    }
+    return 0
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+    "context"
+    "flag"
+    "fmt"
+    "os"
+    "strings"
+    "time"
+
+    "gotcha/internal/llm"
+    "gotcha/internal/platform"
+    "gotcha/internal/session"
+)
+
+const sessionsUsage = `usage: gotcha sessions [list [-all|-archived]]
+       gotcha sessions rename <id> <title>
+       gotcha sessions describe <id> [description]
+       gotcha sessions archive|unarchive <id>...
+       gotcha sessions delete [-yes] <id>...
+       gotcha sessions gc [-dry-run] [-min-age 1h]`
+
+// runSessions lists sessions with their titles and descriptions, renames,
+// describes, archives or deletes them, or garbage-collects the store.
+func runSessions(ctx context.Context, cfg platform.Config, sessionManager *session.Manager, args []string) int {
+    fs := flag.NewFlagSet("sessions", flag.ContinueOnError)
+    if err := fs.Parse(args); err != nil { return 2 }
+    rest := fs.Args()
+    action := "list"
+    if len(rest) > 0 { action, rest = rest[0], rest[1:] }
+    switch action {
+    case "list", "ls":
+        lf := flag.NewFlagSet("sessions list", flag.ContinueOnError)
+        all := lf.Bool("all", false, "include archived sessions")
+        archived := lf.Bool("archived", false, "list only archived sessions")
+        if err := lf.Parse(rest); err != nil { return 2 }
+        sessions, err := sessionManager.AllSessions()
+        if err == nil && !*all {
+            var shown []session.SessionInfo
+            for _, s := range sessions {
+                if s.Archived == *archived { shown = append(shown, s) }
+            }
+            sessions = shown
+        }
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "error: %v\n", err)
+            return 1
+        }
+        if len(sessions) == 0 { fmt.Println("no sessions") }
+        for _, s := range sessions { printSession(s) }
+        return 0
+    case "rename":
+        if len(rest) < 2 { fmt.Fprintln(os.Stderr, sessionsUsage); return 2 }
+        if err := sessionManager.Rename(rest[0], strings.Join(rest[1:], " ")); err != nil {
+            fmt.Fprintf(os.Stderr, "error: %v\n", err)
+            return 1
+        }
+        fmt.Printf("%s renamed\n", rest[0])
+        return 0
+    case "describe":
+        if len(rest) < 1 { fmt.Fprintln(os.Stderr, sessionsUsage); return 2 }
+        if err := sessionManager.Describe(rest[0], strings.Join(rest[1:], " ")); err != nil {
+            fmt.Fprintf(os.Stderr, "error: %v\n", err)
+            return 1
+        }
+        fmt.Printf("%s described\n", rest[0])
+        return 0
+    case "archive", "unarchive":
+        if len(rest) < 1 { fmt.Fprintln(os.Stderr, sessionsUsage); return 2 }
+        code := 0
+        for _, id := range rest {
+            var err error
+            if action == "archive" { err = sessionManager.Archive(id) } else { err = sessionManager.Unarchive(id) }
+            if err != nil {
+                fmt.Fprintf(os.Stderr, "error: %v\n", err)
+                code = 1
+                continue
+            }
+            fmt.Printf("%s %sd\n", id, action)
+        }
+        return code
+    case "delete", "rm":
+        df := flag.NewFlagSet("sessions delete", flag.ContinueOnError)
+        yes := df.Bool("yes", false, "delete without asking")
+        if err := df.Parse(rest); err != nil { return 2 }
+        ids := df.Args()
+        if len(ids) < 1 { fmt.Fprintln(os.Stderr, sessionsUsage); return 2 }
+        if !*yes && !confirm(fmt.Sprintf("Delete %s and everything in it (conversation, notes, reports)? [y/N] ", strings.Join(ids, ", "))) {
+            fmt.Println("nothing deleted")
+            return 1
+        }
+        code := 0
+        for _, id := range ids {
+            if err := sessionManager.Delete(id); err != nil {
+                fmt.Fprintf(os.Stderr, "error: %v\n", err)
+                code = 1
+                continue
+            }
+            fmt.Printf("%s deleted\n", id)
+        }
+        return code
+    case "gc":
+        gf := flag.NewFlagSet("sessions gc", flag.ContinueOnError)
+        dryRun := gf.Bool("dry-run", false, "show what would be removed without removing it")
+        minAge := gf.Duration("min-age", session.DefaultGCMinAge, "leave anything modified more recently than this")
+        if err := gf.Parse(rest); err != nil { return 2 }
+        report, err := sessionManager.GC(session.GCOptions{MinAge: *minAge, DryRun: *dryRun})
+        if err != nil {
+            fmt.Fprintf(os.Stderr, "error: %v\n", err)
+            return 1
+        }
+        verb := "removed"
+        if *dryRun { verb = "would remove" }
+        for _, id := range report.Sessions { fmt.Printf("%s empty session %s\n", verb, id) }
+        for _, f := range report.Files { fmt.Printf("%s %s\n", verb, f) }
+        fmt.Printf("%s %d empty sessions and %d stale files (%s)\n", verb, len(report.Sessions), len(report.Files), formatBytes(report.Bytes))
+        return 0
+    default:
+        fmt.Fprintln(os.Stderr, sessionsUsage)
+        return 2
+    }
+}
+
+// printSession prints one session of a listing: ID, last update and title,
+// with the description indented below.
+func printSession(s session.SessionInfo) {
+    title := s.Title
+    if title == "" { title = "(untitled)" }
+    if s.Archived { title += " [archived]" }
+    fmt.Printf("%-12s %s  %s\n", s.ID, s.UpdatedAt.Local().Format("2006-01-02 15:04"), title)
+    if s.Description != "" { fmt.Printf("%-12s %s\n", "", s.Description) }
+}
+
+// titleSession titles a new session after the research prompt it was
+// created for, with the model when one is configured.
+func titleSession(ctx context.Context, cfg platform.Config, sessionManager *session.Manager, sessionID, prompt string) {
+    ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+    defer cancel()
+    title, description := session.SuggestTitle(ctx, llm.FromConfig(cfg.LLM, cfg.ProxyURL), []session.ChatMsg{{Role: "user", Text: prompt}})
+    _, _ = sessionManager.SetAutoTitle(sessionID, title, description)
+}
+
+// confirm asks a yes/no question on stdin; anything but y or yes is no.
+func confirm(question string) bool {
+    fmt.Print(question)
+    answer, _ := stdin.ReadString('\n')
+    answer = strings.ToLower(strings.TrimSpace(answer))
+    return answer == "y" || answer == "yes"
+}
+
+func formatBytes(n int64) string {
+    switch {
+    case n >= 1<<20:
+        return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+    case n >= 1<<10:
+        return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+    }
+    return fmt.Sprintf("%d B", n)
+}
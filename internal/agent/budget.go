@@ -0,0 +1,213 @@
+package agent
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "math"
+    "sort"
+    "time"
+
+    "gotcha/internal/platform"
+)
+
+// ErrBudgetExhausted is returned by run phases once a budget limit is hit.
+// The run then stops and writes a partial report from what it has.
+var ErrBudgetExhausted = errors.New("budget exhausted")
+
+// BudgetError says which limit was reached; it matches ErrBudgetExhausted.
+type BudgetError struct {
+    Scope string // run or session
+    Limit string // tokens, cost, time or requests
+    Of    string // the limit, formatted
+}
+
+func (e *BudgetError) Error() string {
+    return fmt.Sprintf("%s: %s %s limit of %s reached", ErrBudgetExhausted, e.Scope, e.Limit, e.Of)
+}
+
+func (e *BudgetError) Unwrap() error { return ErrBudgetExhausted }
+
+// Reason describes the limit for report notes, e.g. "run budget of $0.50".
+func (e *BudgetError) Reason() string { return fmt.Sprintf("%s budget of %s", e.Scope, e.Of) }
+
+// Budget caps what a run (or all runs of a session) may consume. Zero fields
+// are unlimited.
+type Budget struct {
+    Tokens   int           `json:"tokens,omitempty"`
+    Cost     float64       `json:"cost,omitempty"` // USD
+    Duration time.Duration `json:"duration,omitempty"`
+    Requests int           `json:"requests,omitempty"` // LLM calls, searches and fetches
+}
+
+func budgetFromConfig(l platform.BudgetLimits) Budget {
+    return Budget{Tokens: l.Tokens, Cost: l.Cost, Duration: l.Duration, Requests: l.Requests}
+}
+
+// IsZero reports whether no limit is set.
+func (b Budget) IsZero() bool { return b == Budget{} }
+
+// Prices turn usage into cost.
+type Prices struct {
+    InputPerMTok  float64
+    OutputPerMTok float64
+    PerSearch     float64
+}
+
+func (p Prices) llmCost(prompt, completion int) float64 {
+    return (float64(prompt)*p.InputPerMTok + float64(completion)*p.OutputPerMTok) / 1e6
+}
+
+// usedFractions returns, per limited dimension, the fraction of b consumed by u.
+func (b Budget) usedFractions(u Usage) map[string]float64 {
+    out := map[string]float64{}
+    if b.Tokens > 0 { out["tokens"] = float64(u.Tokens()) / float64(b.Tokens) }
+    if b.Cost > 0 { out["cost"] = u.Cost / b.Cost }
+    if b.Duration > 0 { out["time"] = float64(u.Elapsed) / float64(b.Duration) }
+    if b.Requests > 0 { out["requests"] = float64(u.Requests()) / float64(b.Requests) }
+    return out
+}
+
+func (b Budget) describe(dim string) string {
+    switch dim {
+    case "tokens":
+        return fmt.Sprintf("%d tokens", b.Tokens)
+    case "cost":
+        return fmt.Sprintf("$%.2f", b.Cost)
+    case "time":
+        return b.Duration.String()
+    case "requests":
+        return fmt.Sprintf("%d requests", b.Requests)
+    }
+    return dim
+}
+
+// budgetState tracks consumption against the run and session budgets.
+// Fields are guarded by runState.mu.
+type budgetState struct {
+    run, session Budget
+    prior        Usage         // other runs of the session
+    base         time.Duration // elapsed before this attempt (resumed runs)
+    started      time.Time
+    inactive     func() time.Duration // time the task spent paused or waiting on the user
+    inactiveAt   time.Duration        // inactive() when started was set
+    warnAt       []float64
+    warned       map[string]bool
+}
+
+// newBudgetState starts the budget clock of a run attempt executing as t.
+// Only active time counts: time t spends paused, or idle waiting for the
+// user's answers or outline approval, is left out.
+func (r *Researcher) newBudgetState(t *Task, j *Journal) *budgetState {
+    b := &budgetState{
+        run:     j.Options.Budget,
+        session: r.cfg.SessionBudget,
+        base:    j.Usage.Elapsed,
+        started: time.Now(),
+        warnAt:  append([]float64(nil), r.cfg.WarnAt...),
+        warned:  map[string]bool{},
+    }
+    if t != nil {
+        b.inactive = t.Inactive
+        b.inactiveAt = t.Inactive()
+    }
+    sort.Float64s(b.warnAt)
+    if !b.session.IsZero() {
+        if js, err := r.Journals(j.SessionID); err == nil {
+            for _, o := range js {
+                if o.RunID != j.RunID { b.prior = b.prior.Add(o.Usage) }
+            }
+        }
+    }
+    return b
+}
+
+// tick refreshes the elapsed active time of u.
+func (b *budgetState) tick(u *Usage) {
+    active := time.Since(b.started)
+    if b.inactive != nil { active -= b.inactive() - b.inactiveAt }
+    u.Elapsed = b.base + max(0, active)
+}
+
+// check compares consumption with both budgets. It returns the warnings newly
+// crossed and, once any limit is reached, an ErrBudgetExhausted error.
+func (b *budgetState) check(u Usage) (warnings []map[string]any, err error) {
+    scopes := []struct {
+        name  string
+        limit Budget
+        used  Usage
+    }{{"run", b.run, u}, {"session", b.session, b.prior.Add(u)}}
+    for _, s := range scopes {
+        fr := s.limit.usedFractions(s.used)
+        dims := make([]string, 0, len(fr))
+        for d := range fr { dims = append(dims, d) }
+        sort.Strings(dims)
+        for _, d := range dims {
+            f := fr[d]
+            if f >= 1 && err == nil {
+                err = &BudgetError{Scope: s.name, Limit: d, Of: s.limit.describe(d)}
+                continue
+            }
+            for _, t := range b.warnAt {
+                key := fmt.Sprintf("%s/%s/%g", s.name, d, t)
+                if f < t || b.warned[key] { continue }
+                b.warned[key] = true
+                warnings = append(warnings, map[string]any{"scope": s.name, "limit": d, "threshold": t, "used": roundFraction(f), "of": s.limit.describe(d)})
+            }
+        }
+    }
+    return warnings, err
+}
+
+// meta summarises consumption for Event.Meta["budget"]. "used" is the largest
+// fraction of any run or session limit, absent when no limit is set.
+func (b *budgetState) meta(u Usage) map[string]any {
+    m := map[string]any{
+        "tokens":   u.Tokens(),
+        "cost":     math.Round(u.Cost*1e4) / 1e4,
+        "requests": u.Requests(),
+        "elapsed":  u.Elapsed.Round(time.Second).String(),
+    }
+    used, limited := 0.0, false
+    for _, fr := range []map[string]float64{b.run.usedFractions(u), b.session.usedFractions(b.prior.Add(u))} {
+        for _, f := range fr {
+            limited = true
+            used = math.Max(used, f)
+        }
+    }
+    if limited { m["used"] = roundFraction(used) }
+    return m
+}
+
+func roundFraction(f float64) float64 { return math.Round(f*100) / 100 }
+
+// spend is called before every LLM call, search and fetch. It publishes
+// threshold warnings and refuses the request once a budget is exhausted.
+func (st *runState) spend(ctx context.Context) error {
+    st.mu.Lock()
+    st.budget.tick(&st.j.Usage)
+    warnings, err := st.budget.check(st.j.Usage)
+    st.mu.Unlock()
+    for _, w := range warnings { st.publish(ctx, Event{Phase: PhaseBudget, Type: "warning", Meta: w}) }
+    return err
+}
+
+// FormatBudget renders an Event.Meta["budget"] value for status lines, e.g.
+// "12.3k tok · $0.04 · 45%". It accepts live and replayed (JSON-decoded) values.
+func FormatBudget(m map[string]any) string {
+    num := func(k string) float64 {
+        switch v := m[k].(type) {
+        case int:
+            return float64(v)
+        case float64:
+            return v
+        }
+        return 0
+    }
+    tok := num("tokens")
+    s := fmt.Sprintf("%.0f tok", tok)
+    if tok >= 1000 { s = fmt.Sprintf("%.1fk tok", tok/1000) }
+    s += fmt.Sprintf(" · $%.2f", num("cost"))
+    if _, ok := m["used"]; ok { s += fmt.Sprintf(" · %.0f%%", num("used")*100) }
+    return s
+}
@@ -0,0 +1,117 @@
+package agent
+
+import (
+    "context"
+    "errors"
+    "testing"
+    "time"
+)
+
+func TestBudgetCheck(t *testing.T) {
+    b := &budgetState{run: Budget{Tokens: 100, Requests: 10}, session: Budget{Cost: 1}, prior: Usage{Cost: 0.3}, warnAt: []float64{0.5, 0.8}, warned: map[string]bool{}}
+    cases := []struct {
+        name     string
+        used     Usage
+        warnings []string // scope/limit/threshold of the warnings newly crossed
+        limit    string   // scope/limit of the budget error, if any
+    }{
+        {"below every threshold", Usage{PromptTokens: 10, LLMCalls: 1, Cost: 0.05}, nil, ""},
+        {"half the tokens", Usage{PromptTokens: 50, LLMCalls: 1, Cost: 0.05}, []string{"run/tokens/0.5"}, ""},
+        {"warnings are not repeated", Usage{PromptTokens: 55, LLMCalls: 2, Cost: 0.05}, nil, ""},
+        {"session cost counts other runs", Usage{PromptTokens: 55, LLMCalls: 2, Cost: 0.55}, []string{"session/cost/0.5", "session/cost/0.8"}, ""},
+        {"run limit reached", Usage{PromptTokens: 60, CompletionTokens: 40, LLMCalls: 3, Cost: 0.55}, nil, "run/tokens"},
+        {"session limit reached", Usage{PromptTokens: 60, LLMCalls: 3, Cost: 0.75}, nil, "session/cost"},
+    }
+    for _, c := range cases {
+        warnings, err := b.check(c.used)
+        var got []string
+        for _, w := range warnings { got = append(got, w["scope"].(string)+"/"+w["limit"].(string)+"/"+formatWeight(w["threshold"].(float64))) }
+        if len(got) != len(c.warnings) {
+            t.Errorf("%s: warnings %v, want %v", c.name, got, c.warnings)
+        } else {
+            for i := range got {
+                if got[i] != c.warnings[i] { t.Errorf("%s: warnings %v, want %v", c.name, got, c.warnings) }
+            }
+        }
+        var be *BudgetError
+        switch {
+        case c.limit == "" && err != nil:
+            t.Errorf("%s: unexpected error %v", c.name, err)
+        case c.limit != "" && !errors.As(err, &be):
+            t.Errorf("%s: got %v, want a budget error", c.name, err)
+        case c.limit != "" && be.Scope+"/"+be.Limit != c.limit:
+            t.Errorf("%s: %s/%s limit reached, want %s", c.name, be.Scope, be.Limit, c.limit)
+        }
+    }
+}
+
+func TestBudgetError(t *testing.T) {
+    err := error(&BudgetError{Scope: "run", Limit: "cost", Of: "$0.50"})
+    if !errors.Is(err, ErrBudgetExhausted) { t.Error("BudgetError does not match ErrBudgetExhausted") }
+    if got, want := err.Error(), "budget exhausted: run cost limit of $0.50 reached"; got != want { t.Errorf("Error() = %q, want %q", got, want) }
+    if got, want := err.(*BudgetError).Reason(), "run budget of $0.50"; got != want { t.Errorf("Reason() = %q, want %q", got, want) }
+}
+
+func TestSpend(t *testing.T) {
+    r := testResearcher(t)
+    r.cfg.WarnAt = []float64{0.5}
+    events, cancel := r.tasks.bus.Subscribe(context.Background(), "s1", WithPhases(PhaseBudget))
+    defer cancel()
+    j := &Journal{RunID: "r1", SessionID: "s1", Options: RunOptions{Budget: Budget{Requests: 2}}}
+    var errs []error
+    runStep(t, r, j, func(ctx context.Context, st *runState) error {
+        for i := 0; i < 3; i++ {
+            errs = append(errs, st.spend(ctx))
+            j.Usage.Searches++
+        }
+        return nil
+    })
+    if errs[0] != nil || errs[1] != nil { t.Errorf("spending within the budget failed: %v", errs[:2]) }
+    if !errors.Is(errs[2], ErrBudgetExhausted) { t.Errorf("third request on a budget of two: got %v", errs[2]) }
+    var warnings int
+    for _, e := range drain(events) {
+        if e.Type == "warning" { warnings++ }
+    }
+    if warnings != 1 { t.Errorf("published %d warnings, want 1", warnings) }
+}
+
+func TestBudgetClockSkipsPausesAndWaits(t *testing.T) {
+    r := testResearcher(t)
+    j := &Journal{RunID: "r1", SessionID: "s1", Usage: Usage{Elapsed: time.Minute}}
+    const wait = 150 * time.Millisecond
+    paused, resumed := make(chan struct{}), make(chan struct{})
+    go func() {
+        <-paused
+        if err := r.tasks.Pause("r1"); err != nil { t.Error(err) }
+        time.Sleep(wait)
+        if err := r.tasks.Resume("r1"); err != nil { t.Error(err) }
+        close(resumed)
+    }()
+    runStep(t, r, j, func(ctx context.Context, st *runState) error {
+        // Waiting on the user, then paused by them.
+        if err := st.t.Idle(ctx, func() error { time.Sleep(wait); return nil }); err != nil { return err }
+        close(paused)
+        <-resumed
+        if err := st.t.Checkpoint(ctx); err != nil { return err }
+        st.budget.tick(&j.Usage)
+        return nil
+    })
+    if active := j.Usage.Elapsed - time.Minute; active < 0 || active >= wait {
+        t.Errorf("counted %v of active time besides the earlier minute, want well under %v", active, wait)
+    }
+}
+
+func TestFormatBudget(t *testing.T) {
+    cases := []struct {
+        meta map[string]any
+        want string
+    }{
+        {map[string]any{"tokens": 950, "cost": 0.004}, "950 tok · $0.00"},
+        {map[string]any{"tokens": 12345, "cost": 0.0412, "used": 0.45}, "12.3k tok · $0.04 · 45%"},
+        {map[string]any{"tokens": float64(2000), "cost": float64(1.5), "used": float64(1)}, "2.0k tok · $1.50 · 100%"}, // replayed from JSON
+        {map[string]any{}, "0 tok · $0.00"},
+    }
+    for _, c := range cases {
+        if got := FormatBudget(c.meta); got != c.want { t.Errorf("FormatBudget(%v) = %q, want %q", c.meta, got, c.want) }
+    }
+}
@@ -0,0 +1,208 @@
+package agent
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+    "time"
+
+    "gotcha/internal/llm"
+    "gotcha/internal/platform"
+)
+
+// ErrNoPendingQuestions is returned when a run is not waiting for answers.
+var ErrNoPendingQuestions = errors.New("no clarifying questions awaiting answers")
+
+// Clarification is a clarifying question about a research prompt and the
+// user's answer; an empty answer means no preference.
+type Clarification struct {
+    Question string `json:"question"`
+    Answer   string `json:"answer"`
+}
+
+// clarificationSet is the Q&A for one prompt, kept in the session so a
+// re-run of the same prompt reuses it.
+type clarificationSet struct {
+    Prompt string          `json:"prompt"`
+    At     time.Time       `json:"at"`
+    QA     []Clarification `json:"qa"`
+}
+
+// pendingQuestions is a run blocked on clarifying questions.
+type pendingQuestions struct {
+    questions []string
+    answers   chan []string
+}
+
+// PendingQuestions returns the clarifying questions a run is waiting on.
+func (r *Researcher) PendingQuestions(runID string) ([]string, bool) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    p, ok := r.asking[runID]
+    if !ok { return nil, false }
+    return append([]string(nil), p.questions...), true
+}
+
+// PendingClarifications lists the runs of a session waiting for answers.
+func (r *Researcher) PendingClarifications(sessionID string) []string {
+    var ids []string
+    for _, t := range r.tasks.List(sessionID) {
+        if _, ok := r.PendingQuestions(t.ID); ok { ids = append(ids, t.ID) }
+    }
+    return ids
+}
+
+// AnswerQuestions lets a waiting run plan with the given answers, one per
+// question; missing or empty answers mean no preference.
+func (r *Researcher) AnswerQuestions(runID string, answers []string) error {
+    r.mu.Lock()
+    p, ok := r.asking[runID]
+    if ok { delete(r.asking, runID) }
+    r.mu.Unlock()
+    if !ok { return fmt.Errorf("%w: %s", ErrNoPendingQuestions, runID) }
+    p.answers <- answers
+    return nil
+}
+
+// Clarifications returns the Q&A saved for prompt in a session, if any.
+func (r *Researcher) Clarifications(sessionID, prompt string) ([]Clarification, bool) {
+    sets, _ := r.loadClarifications(sessionID)
+    key := clarificationKey(prompt)
+    for i := len(sets) - 1; i >= 0; i-- {
+        if clarificationKey(sets[i].Prompt) == key { return sets[i].QA, true }
+    }
+    return nil, false
+}
+
+// clarify runs before planning. When the run allows it, the model judges
+// whether the prompt is ambiguous and, if so, the run publishes two to four
+// questions and waits for the answers. Answers given earlier in the session
+// for the same prompt are reused without asking again.
+func (r *Researcher) clarify(ctx context.Context, st *runState) error {
+    j := st.j
+    if j.Clarified { return nil }
+    // Clarified is set only once the step has finished, so a run stopped
+    // while waiting for answers asks again when resumed.
+    if qa, ok := r.Clarifications(j.SessionID, j.Prompt); ok {
+        j.Clarifications, j.Clarified = qa, true
+        st.publish(ctx, Event{Phase: PhaseClarify, Type: "done", Meta: map[string]any{"questions": len(qa), "reused": true}})
+        return st.save()
+    }
+    // Unattended runs cannot be asked.
+    if !j.Options.Clarify || j.Options.AutoApprove || r.llm == nil {
+        j.Clarified = true
+        return nil
+    }
+
+    st.publish(ctx, Event{Phase: PhaseClarify, Type: "started"})
+    questions, err := r.clarifyingQuestions(ctx, st)
+    if err != nil {
+        if ctx.Err() != nil { return ctx.Err() }
+        // Planning can go ahead without clarification.
+        st.publish(ctx, Event{Phase: PhaseClarify, Type: "error", Err: err.Error()})
+        j.Clarified = true
+        return nil
+    }
+    if len(questions) == 0 {
+        j.Clarified = true
+        st.publish(ctx, Event{Phase: PhaseClarify, Type: "done", Meta: map[string]any{"questions": 0}})
+        return nil
+    }
+
+    pend := &pendingQuestions{questions: questions, answers: make(chan []string, 1)}
+    r.mu.Lock()
+    r.asking[j.RunID] = pend
+    r.mu.Unlock()
+    defer func() {
+        r.mu.Lock()
+        delete(r.asking, j.RunID)
+        r.mu.Unlock()
+    }()
+    st.publish(ctx, Event{Phase: PhaseClarify, Type: "questions", Meta: map[string]any{"questions": questions}})
+    var answers []string
+    err = st.t.Idle(ctx, func() error {
+        select {
+        case answers = <-pend.answers:
+            return nil
+        case <-ctx.Done():
+            return ctx.Err()
+        }
+    })
+    if err != nil { return err }
+
+    j.Clarifications = make([]Clarification, len(questions))
+    for i, q := range questions {
+        j.Clarifications[i].Question = q
+        if i < len(answers) { j.Clarifications[i].Answer = strings.TrimSpace(answers[i]) }
+    }
+    if err := r.saveClarifications(j.SessionID, j.Prompt, j.Clarifications); err != nil { return err }
+    j.Clarified = true
+    if err := st.save(); err != nil { return err }
+    st.publish(ctx, Event{Phase: PhaseClarify, Type: "done", Meta: map[string]any{"questions": len(questions)}})
+    return nil
+}
+
+// clarifyingQuestions asks the model whether the prompt is ambiguous enough
+// to warrant questions, returning none when it is not.
+func (r *Researcher) clarifyingQuestions(ctx context.Context, st *runState) ([]string, error) {
+    sys := "You help scope research requests. Decide whether the prompt is ambiguous: unclear audience, purpose, scope, time frame, region or definitions that would change what a good report covers. Return strict JSON: {\"ambiguous\": bool, \"questions\": [2-4 short questions, each answerable in a few words]}. Return no questions when a competent researcher could proceed. No extra text."
+    res, err := st.complete(ctx, llm.Request{System: sys, Prompt: "Research prompt: " + strings.TrimSpace(st.j.Prompt) + "\n\nReturn JSON only.", MaxTokens: 300, Temperature: 0.2})
+    if err != nil { return nil, err }
+    var out struct {
+        Ambiguous bool     `json:"ambiguous"`
+        Questions []string `json:"questions"`
+    }
+    if err := json.Unmarshal([]byte(trimFences(res.Text)), &out); err != nil { return nil, nil }
+    var qs []string
+    for _, q := range out.Questions {
+        if q = strings.TrimSpace(q); q != "" { qs = append(qs, q) }
+    }
+    if !out.Ambiguous || len(qs) < 2 { return nil, nil }
+    if len(qs) > 4 { qs = qs[:4] }
+    return qs, nil
+}
+
+// clarificationBrief renders the run's answered questions for the planner.
+func (j *Journal) clarificationBrief() string {
+    var b strings.Builder
+    for _, c := range j.Clarifications {
+        if c.Answer == "" { continue }
+        fmt.Fprintf(&b, "- %s %s\n", c.Question, c.Answer)
+    }
+    if b.Len() == 0 { return "" }
+    return "The user clarified the request:\n" + b.String() + "\n"
+}
+
+func clarificationKey(prompt string) string { return strings.ToLower(strings.Join(strings.Fields(prompt), " ")) }
+
+func (r *Researcher) loadClarifications(sessionID string) ([]clarificationSet, error) {
+    data, err := os.ReadFile(r.svc.ClarificationsPath(sessionID))
+    if err != nil {
+        if os.IsNotExist(err) { return nil, nil }
+        return nil, err
+    }
+    var sets []clarificationSet
+    if err := json.Unmarshal(data, &sets); err != nil { return nil, fmt.Errorf("parse clarifications: %w", err) }
+    return sets, nil
+}
+
+func (r *Researcher) saveClarifications(sessionID, prompt string, qa []Clarification) error {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    sets, err := r.loadClarifications(sessionID)
+    if err != nil { return err }
+    key := clarificationKey(prompt)
+    kept := sets[:0]
+    for _, s := range sets {
+        if clarificationKey(s.Prompt) != key { kept = append(kept, s) }
+    }
+    kept = append(kept, clarificationSet{Prompt: strings.TrimSpace(prompt), At: time.Now(), QA: qa})
+    data, err := json.MarshalIndent(kept, "", "  ")
+    if err != nil { return err }
+    if err := os.MkdirAll(filepath.Dir(r.svc.ClarificationsPath(sessionID)), 0o755); err != nil { return err }
+    return platform.WriteFileAtomic(r.svc.ClarificationsPath(sessionID), data)
+}
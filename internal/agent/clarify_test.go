@@ -0,0 +1,71 @@
+package agent
+
+import (
+    "context"
+    "errors"
+    "testing"
+    "time"
+
+    "gotcha/internal/llm"
+)
+
+// askingLLM answers every request with the same clarifying questions.
+type askingLLM struct{}
+
+func (askingLLM) Name() string { return "asking" }
+
+func (askingLLM) Complete(ctx context.Context, req llm.Request, onToken llm.StreamHandler) (llm.Response, error) {
+    return llm.Response{Text: `{"ambiguous": true, "questions": ["Which region?", "Which year?"]}`}, nil
+}
+
+// clarifyRun runs the clarify step of a fresh run of prompt as a task and
+// returns the journal once the task ends, with the step's error.
+func clarifyRun(t *testing.T, r *Researcher, runID, prompt string, opts RunOptions, during func(runID string)) (*Journal, error) {
+    t.Helper()
+    j := &Journal{RunID: runID, SessionID: "s1", Prompt: prompt, Options: opts}
+    var err error
+    r.tasks.Submit(context.Background(), runID, "s1", prompt, func(ctx context.Context, task *Task) error {
+        err = r.clarify(ctx, &runState{r: r, t: task, j: j, budget: r.newBudgetState(task, j)})
+        return err
+    })
+    if during != nil { during(runID) }
+    r.tasks.Wait(context.Background(), runID)
+    return j, err
+}
+
+// waitAsking polls until runID is waiting for answers.
+func waitAsking(t *testing.T, r *Researcher, runID string) {
+    t.Helper()
+    for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+        if _, ok := r.PendingQuestions(runID); ok { return }
+    }
+    t.Fatalf("run %s never asked its questions", runID)
+}
+
+func TestClarifyMarksOnlyFinishedSteps(t *testing.T) {
+    r := testResearcher(t)
+    r.llm = askingLLM{}
+    opts := RunOptions{Clarify: true}
+
+    j, err := clarifyRun(t, r, "cancelled", "EV sales", opts, func(id string) {
+        waitAsking(t, r, id)
+        r.tasks.Cancel(id)
+    })
+    if !errors.Is(err, context.Canceled) { t.Errorf("cancelled step returned %v", err) }
+    if j.Clarified { t.Error("a run cancelled while waiting for answers was marked clarified") }
+
+    j, err = clarifyRun(t, r, "answered", "EV sales", opts, func(id string) {
+        waitAsking(t, r, id)
+        if err := r.AnswerQuestions(id, []string{" Europe "}); err != nil { t.Error(err) }
+    })
+    if err != nil || !j.Clarified { t.Fatalf("answered step: clarified %v, err %v", j.Clarified, err) }
+    if len(j.Clarifications) != 2 || j.Clarifications[0].Answer != "Europe" || j.Clarifications[1].Answer != "" {
+        t.Errorf("clarifications %+v", j.Clarifications)
+    }
+
+    j, err = clarifyRun(t, r, "reused", "ev sales", opts, nil)
+    if err != nil || !j.Clarified || len(j.Clarifications) != 2 { t.Errorf("reused step: clarified %v, %d answers, err %v", j.Clarified, len(j.Clarifications), err) }
+
+    j, err = clarifyRun(t, r, "unattended", "Battery prices", RunOptions{Clarify: true, AutoApprove: true}, nil)
+    if err != nil || !j.Clarified { t.Errorf("skipped step: clarified %v, err %v", j.Clarified, err) }
+}
@@ -0,0 +1,503 @@
+package agent
+
+import (
+    "bytes"
+    "context"
+    "encoding/csv"
+    "encoding/json"
+    "fmt"
+    "math"
+    "os"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+
+    "gotcha/internal/llm"
+    "gotcha/internal/search"
+)
+
+// Research modes.
+const (
+    ModeReport  = "report"  // free-form sections (default)
+    ModeCompare = "compare" // options scored against criteria in a decision matrix
+)
+
+// Comparison is the decision matrix of a comparison run: every option rated
+// against every criterion, with the evidence and sources behind each rating.
+type Comparison struct {
+    Options  []string    `json:"options"`
+    Criteria []Criterion `json:"criteria"`
+    Cells    []Cell      `json:"cells,omitempty"`
+    // Filled marks options whose cells are complete, by option index.
+    Filled []bool `json:"filled,omitempty"`
+    // Composed holds the weights by criterion name when the sections were
+    // written, so a later reweight can tell which prose is out of date.
+    Composed map[string]float64 `json:"composed_weights,omitempty"`
+}
+
+// Criterion is one row of the matrix; Weight scales its score in the total.
+type Criterion struct {
+    Name        string  `json:"name"`
+    Description string  `json:"description,omitempty"`
+    Weight      float64 `json:"weight"`
+}
+
+// Cell is the finding for one option under one criterion. Score runs from
+// 1 (poor) to 5 (excellent); zero means the sources gave no basis to score.
+type Cell struct {
+    Option    string `json:"option"`
+    Criterion string `json:"criterion"`
+    Finding   string `json:"finding"`
+    Score     int    `json:"score"`
+    Sources   []int  `json:"sources,omitempty"` // 1-based source numbers
+}
+
+var defaultCriteria = []string{"Cost", "Performance", "Ease of use", "Ecosystem", "Maturity"}
+
+// Cell returns the cell for option and criterion, if filled.
+func (c *Comparison) Cell(option, criterion string) (Cell, bool) {
+    for _, x := range c.Cells {
+        if x.Option == option && x.Criterion == criterion { return x, true }
+    }
+    return Cell{}, false
+}
+
+// criterion returns the index of the criterion named name, ignoring case.
+func (c *Comparison) criterion(name string) int {
+    for i, cr := range c.Criteria {
+        if strings.EqualFold(cr.Name, strings.TrimSpace(name)) { return i }
+    }
+    return -1
+}
+
+// SetWeights applies user weights by criterion name. Unknown names are an
+// error unless add is set, in which case they become new criteria.
+func (c *Comparison) SetWeights(weights map[string]float64, add bool) error {
+    names := make([]string, 0, len(weights))
+    for n := range weights { names = append(names, n) }
+    sort.Strings(names)
+    for _, n := range names {
+        w := weights[n]
+        if w < 0 || math.IsNaN(w) || math.IsInf(w, 0) { return fmt.Errorf("weight for %q must be a non-negative number", n) }
+        if i := c.criterion(n); i >= 0 {
+            c.Criteria[i].Weight = w
+            continue
+        }
+        if !add {
+            known := make([]string, len(c.Criteria))
+            for i, cr := range c.Criteria { known[i] = cr.Name }
+            return fmt.Errorf("unknown criterion %q (have: %s)", n, strings.Join(known, ", "))
+        }
+        c.Criteria = append(c.Criteria, Criterion{Name: strings.TrimSpace(n), Weight: w})
+    }
+    return nil
+}
+
+// weights returns the current weight of every criterion by name.
+func (c *Comparison) weights() map[string]float64 {
+    out := make(map[string]float64, len(c.Criteria))
+    for _, cr := range c.Criteria { out[cr.Name] = cr.Weight }
+    return out
+}
+
+// Reweighted reports whether the weights changed after the sections were
+// written.
+func (c *Comparison) Reweighted() bool {
+    if c.Composed == nil { return false }
+    for _, cr := range c.Criteria {
+        if w, ok := c.Composed[cr.Name]; !ok || w != cr.Weight { return true }
+    }
+    return false
+}
+
+// composedWeights lists the weights the sections were written with, e.g.
+// "Cost 2, Performance 1".
+func (c *Comparison) composedWeights() string {
+    var parts []string
+    for _, cr := range c.Criteria {
+        if w, ok := c.Composed[cr.Name]; ok { parts = append(parts, cr.Name+" "+formatWeight(w)) }
+    }
+    return strings.Join(parts, ", ")
+}
+
+// reTotalsTalk finds prose about scores and rankings, which a reweight can
+// make wrong.
+var reTotalsTalk = regexp.MustCompile(`(?i)\b(weight(s|ed|ing)?|scor(e|es|ed|ing)|totals?|overall|rank(s|ed|ing)?|winners?|wins)\b|\b[0-5]\.\d\d?\b`)
+
+// Totals returns each option's weighted score on the 1-5 scale, by option
+// index. Unscored cells and zero-weight criteria do not count; an option
+// with nothing scored gets -1.
+func (c *Comparison) Totals() []float64 {
+    out := make([]float64, len(c.Options))
+    for i, o := range c.Options {
+        var sum, weight float64
+        for _, cr := range c.Criteria {
+            if cell, ok := c.Cell(o, cr.Name); ok && cell.Score > 0 && cr.Weight > 0 {
+                sum += cr.Weight * float64(cell.Score)
+                weight += cr.Weight
+            }
+        }
+        out[i] = -1
+        if weight > 0 { out[i] = sum / weight }
+    }
+    return out
+}
+
+// Markdown renders the matrix as a table with criteria as rows and options
+// as columns, followed by the weighted total of each option.
+func (c *Comparison) Markdown() string {
+    var b strings.Builder
+    b.WriteString("| Criterion | Weight |")
+    for _, o := range c.Options { b.WriteString(" " + escapeCell(o) + " |") }
+    b.WriteString("\n|---|---|")
+    for range c.Options { b.WriteString("---|") }
+    b.WriteString("\n")
+    for _, cr := range c.Criteria {
+        fmt.Fprintf(&b, "| %s | %s |", escapeCell(cr.Name), formatWeight(cr.Weight))
+        for _, o := range c.Options {
+            cell, ok := c.Cell(o, cr.Name)
+            switch {
+            case !ok:
+                b.WriteString(" – |")
+            default:
+                txt := escapeCell(cell.Finding) + citeList(cell.Sources)
+                if cell.Score > 0 { txt += fmt.Sprintf(" (%d/5)", cell.Score) }
+                b.WriteString(" " + txt + " |")
+            }
+        }
+        b.WriteString("\n")
+    }
+    b.WriteString("| **Weighted score** | |")
+    for _, t := range c.Totals() {
+        if t < 0 {
+            b.WriteString(" – |")
+        } else {
+            fmt.Fprintf(&b, " **%.2f** |", t)
+        }
+    }
+    b.WriteString("\n")
+    return b.String()
+}
+
+// CSV exports the matrix in long form, one row per option and criterion,
+// with the URLs of the cited sources.
+func (c *Comparison) CSV(sources []search.Document) []byte {
+    var buf bytes.Buffer
+    w := csv.NewWriter(&buf)
+    _ = w.Write([]string{"option", "criterion", "weight", "score", "finding", "sources", "source_urls"})
+    for _, o := range c.Options {
+        for _, cr := range c.Criteria {
+            cell, _ := c.Cell(o, cr.Name)
+            var nums, urls []string
+            for _, n := range cell.Sources {
+                nums = append(nums, strconv.Itoa(n))
+                if n >= 1 && n <= len(sources) { urls = append(urls, sources[n-1].URL) }
+            }
+            score := ""
+            if cell.Score > 0 { score = strconv.Itoa(cell.Score) }
+            _ = w.Write([]string{o, cr.Name, formatWeight(cr.Weight), score, cell.Finding, strings.Join(nums, " "), strings.Join(urls, " ")})
+        }
+    }
+    w.Flush()
+    return buf.Bytes()
+}
+
+// summary renders the matrix compactly for the section writer's prompt.
+func (c *Comparison) summary() string {
+    var b strings.Builder
+    totals := c.Totals()
+    for i, o := range c.Options {
+        fmt.Fprintf(&b, "%s", o)
+        if totals[i] >= 0 { fmt.Fprintf(&b, " (weighted score %.2f/5)", totals[i]) }
+        b.WriteString(":\n")
+        for _, cr := range c.Criteria {
+            cell, ok := c.Cell(o, cr.Name)
+            if !ok { continue }
+            fmt.Fprintf(&b, "- %s (weight %s): %s%s", cr.Name, formatWeight(cr.Weight), cell.Finding, citeList(cell.Sources))
+            if cell.Score > 0 { fmt.Fprintf(&b, " — %d/5", cell.Score) }
+            b.WriteString("\n")
+        }
+    }
+    return strings.TrimSpace(b.String())
+}
+
+// ParseWeights reads criterion weights written as "cost=2, ease of use=0.5",
+// separated by commas or given as separate args.
+func ParseWeights(args ...string) (map[string]float64, error) {
+    out := map[string]float64{}
+    for _, a := range args {
+        for _, kv := range strings.Split(a, ",") {
+            if kv = strings.TrimSpace(kv); kv == "" { continue }
+            name, val, ok := strings.Cut(kv, "=")
+            name = strings.TrimSpace(name)
+            if !ok || name == "" { return nil, fmt.Errorf("weight %q: want criterion=number", kv) }
+            w, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+            if err != nil || w < 0 || math.IsNaN(w) || math.IsInf(w, 0) { return nil, fmt.Errorf("weight %q: want a non-negative number", kv) }
+            out[name] = w
+        }
+    }
+    return out, nil
+}
+
+func formatWeight(w float64) string { return strconv.FormatFloat(w, 'f', -1, 64) }
+
+// planComparison asks the model for the options and criteria to compare as
+// well as a short outline of prose sections around the matrix. Criteria the
+// user weighted up front are always included.
+func (r *Researcher) planComparison(ctx context.Context, st *runState, userPrompt string) (Plan, *Comparison, error) {
+    weights := st.j.Options.Weights
+    var raw struct {
+        Plan
+        Options  []string    `json:"options"`
+        Criteria []Criterion `json:"criteria"`
+    }
+    if r.llm != nil {
+        sys := "You are a meticulous research planner for product and technology comparisons. Return strict JSON with fields: title (5-9 words), options (the 2-6 alternatives being compared, short names), criteria (array of {name, description}, 4-7 decision criteria), sections (array of {heading, instructions} for prose around the comparison table, e.g. overview, trade-offs, recommendation), queries (3-5 web search queries). No extra text."
+        u := st.j.clarificationBrief() + fmt.Sprintf("Comparison prompt: %s\n", strings.TrimSpace(userPrompt))
+        if len(weights) > 0 {
+            names := make([]string, 0, len(weights))
+            for n := range weights { names = append(names, n) }
+            sort.Strings(names)
+            u += fmt.Sprintf("The user wants these criteria included: %s\n", strings.Join(names, ", "))
+        }
+        res, err := st.complete(ctx, llm.Request{System: sys, Prompt: u + "\nReturn JSON only.", MaxTokens: 900, Temperature: 0.2})
+        if err != nil { return Plan{}, nil, err }
+        if err := json.Unmarshal([]byte(trimFences(res.Text)), &raw); err != nil { raw.Options = nil }
+    }
+    if len(raw.Options) < 2 { raw.Options = splitOptions(userPrompt) }
+    if len(raw.Criteria) == 0 {
+        for _, n := range defaultCriteria { raw.Criteria = append(raw.Criteria, Criterion{Name: n}) }
+    }
+    cmp := &Comparison{}
+    for _, o := range raw.Options {
+        if o = strings.TrimSpace(o); o != "" { cmp.Options = append(cmp.Options, o) }
+    }
+    for _, cr := range raw.Criteria {
+        if cr.Name = strings.TrimSpace(cr.Name); cr.Name == "" || cmp.criterion(cr.Name) >= 0 { continue }
+        cr.Weight = 1
+        cmp.Criteria = append(cmp.Criteria, cr)
+    }
+    if err := cmp.SetWeights(weights, true); err != nil { return Plan{}, nil, err }
+    if len(cmp.Options) < 2 { return Plan{}, nil, fmt.Errorf("comparison needs at least two options; name them in the prompt, e.g. \"A vs B\"") }
+
+    p := raw.Plan
+    if len(p.Sections) == 0 { p.Sections = fallbackComparisonSections() }
+    if strings.TrimSpace(p.Title) == "" { p.Title = fallbackTitle(userPrompt) }
+    // Make sure every option gets searched for, not just the prompt as a whole.
+    for _, o := range cmp.Options {
+        q := o + " " + strings.ToLower(strings.Join(criterionNames(cmp.Criteria, 3), " "))
+        p.Queries = append(p.Queries, q)
+    }
+    return p, cmp, nil
+}
+
+func fallbackComparisonSections() []Section {
+    return []Section{
+        {Heading: "Overview", Instructions: "Introduce the options being compared and what each is for."},
+        {Heading: "Trade-offs", Instructions: "Discuss where the options differ most, drawing on the comparison matrix."},
+        {Heading: "Recommendation", Instructions: "Recommend an option for typical needs, given the weighted scores, and say when another would be better."},
+    }
+}
+
+func criterionNames(cs []Criterion, n int) []string {
+    var out []string
+    for _, c := range cs {
+        if len(out) == n { break }
+        out = append(out, c.Name)
+    }
+    return out
+}
+
+var (
+    // optionLead matches the phrasing a prompt puts before its options.
+    optionLead = regexp.MustCompile(`(?i)^(?:compare|comparison of|comparing|(?:should|would|could|can|do|does) (?:i|we|you|one) (?:use|choose|pick|prefer|buy|go with|learn)|(?:which|what)(?: is|'s| one is| should (?:i|we)) (?:better|best|use|choose|pick)|(?:choosing|choose|deciding|decide|pick|picking) between|between)\b[\s:,]*`)
+    // optionSep separates options named with "vs" or listed with commas; a
+    // trailing "and"/"or" after the comma belongs to the list, not the name.
+    optionSep = regexp.MustCompile(`(?i)\s+(?:vs\.?|versus)\s+|\s*,\s*(?:(?:or|and)\s+)?`)
+    orSep     = regexp.MustCompile(`(?i)\s+or\s+`)
+    andSep    = regexp.MustCompile(`(?i)\s+and\s+`)
+)
+
+// splitOptions guesses the options from a prompt like "Compare Postgres vs
+// MySQL vs SQLite for analytics" or "Should I use Postgres or MySQL?". Plain
+// "or"/"and" only separate options when the prompt has no "vs" or comma, so
+// names like "Rock and Roll" survive a proper list.
+func splitOptions(prompt string) []string {
+    s := strings.TrimSpace(prompt)
+    if i := strings.Index(strings.ToLower(s), " for "); i > 0 { s = s[:i] }
+    for {
+        lead := optionLead.FindString(s)
+        if lead == "" { break }
+        s = s[len(lead):]
+    }
+    s = strings.TrimRight(strings.TrimSpace(s), "?.!")
+    var parts []string
+    switch {
+    case optionSep.MatchString(s):
+        parts = optionSep.Split(s, -1)
+        // "A, B or C" leaves the last two together.
+        if last := parts[len(parts)-1]; len(parts) > 1 && orSep.MatchString(last) {
+            parts = append(parts[:len(parts)-1], orSep.Split(last, -1)...)
+        }
+    case orSep.MatchString(s):
+        parts = orSep.Split(s, -1)
+    default:
+        parts = andSep.Split(s, -1)
+    }
+    var out []string
+    for _, p := range parts {
+        if p = strings.TrimSpace(p); p != "" { out = append(out, p) }
+    }
+    return out
+}
+
+// fillMatrix rates every option against every criterion from the fetched
+// sources, one model call per option, bounded by the compose concurrency.
+// Each finished option is checkpointed so a resumed run only redoes the rest.
+func (r *Researcher) fillMatrix(ctx context.Context, st *runState) error {
+    j := st.j
+    cmp := j.Comparison
+    if cmp == nil { return nil }
+    if len(cmp.Filled) != len(cmp.Options) { cmp.Filled = make([]bool, len(cmp.Options)) }
+    total, done := len(cmp.Options), 0
+    for _, f := range cmp.Filled { if f { done++ } }
+    if done == total { return nil }
+    st.publish(ctx, Event{Phase: PhaseCompare, Type: "started", Progress: Progress{Done: done, Total: total}, Meta: map[string]any{"criteria": len(cmp.Criteria)}})
+
+    var (
+        wg       sync.WaitGroup
+        errMu    sync.Mutex
+        firstErr error
+    )
+    sem := make(chan struct{}, r.cfg.ComposeConcurrency)
+    for i, o := range cmp.Options {
+        if cmp.Filled[i] { continue }
+        wg.Add(1)
+        go func(i int, o string) {
+            defer wg.Done()
+            select {
+            case sem <- struct{}{}:
+            case <-ctx.Done():
+                return
+            }
+            defer func() { <-sem }()
+            cells, err := r.rateOption(ctx, st, o)
+            if err == nil {
+                st.mu.Lock()
+                cmp.Cells = append(cmp.Cells, cells...)
+                cmp.Filled[i] = true
+                done++
+                n := done
+                st.mu.Unlock()
+                if err = st.save(); err == nil {
+                    st.publish(ctx, Event{Phase: PhaseCompare, Type: "progress", Progress: Progress{Done: n, Total: total}, Meta: map[string]any{"option": o}})
+                }
+            }
+            if err != nil {
+                errMu.Lock()
+                if firstErr == nil { firstErr = err }
+                errMu.Unlock()
+            }
+        }(i, o)
+    }
+    wg.Wait()
+    if err := ctx.Err(); err != nil { return err }
+    if firstErr != nil {
+        st.publish(ctx, Event{Phase: PhaseCompare, Type: "error", Progress: Progress{Done: done, Total: total}, Err: firstErr.Error()})
+        return firstErr
+    }
+    // Stable order for rendering and export regardless of completion order.
+    sort.SliceStable(cmp.Cells, func(a, b int) bool {
+        return indexOf(cmp.Options, cmp.Cells[a].Option) < indexOf(cmp.Options, cmp.Cells[b].Option)
+    })
+    if err := st.save(); err != nil { return err }
+    st.publish(ctx, Event{Phase: PhaseCompare, Type: "done", Progress: Progress{Done: done, Total: total}})
+    return nil
+}
+
+// rateOption fills one option's column of the matrix.
+func (r *Researcher) rateOption(ctx context.Context, st *runState, option string) ([]Cell, error) {
+    if err := st.t.Checkpoint(ctx); err != nil { return nil, err }
+    cmp := st.j.Comparison
+    if r.llm == nil {
+        cells := make([]Cell, len(cmp.Criteria))
+        for i, cr := range cmp.Criteria {
+            cells[i] = Cell{Option: option, Criterion: cr.Name, Finding: "Not assessed (LLM not configured)."}
+        }
+        return cells, nil
+    }
+    var crit strings.Builder
+    for _, cr := range cmp.Criteria {
+        crit.WriteString("- " + cr.Name)
+        if cr.Description != "" { crit.WriteString(": " + cr.Description) }
+        crit.WriteString("\n")
+    }
+    names := strings.Join(criterionNames(cmp.Criteria, len(cmp.Criteria)), " ")
+    evidence := formatPassages(selectPassages(st.j.Sources, st.j.sourceWeights(), option+" "+names+" "+st.j.Prompt, maxSectionPassages))
+    if evidence == "" { evidence = "(no sources)" }
+    sys := "You are an analyst filling one column of a comparison matrix. Return strict JSON: {\"cells\": [{\"criterion\", \"finding\", \"score\", \"sources\"}]} with one cell per criterion. finding is one or two sentences of evidence about the option; score is 1 (poor) to 5 (excellent), or 0 if the sources give no basis; sources lists the numbers of the sources that support the finding. Use only the given sources. No extra text."
+    prompt := fmt.Sprintf("Comparison: %s\nOption: %s\n\nCriteria:\n%s\nSources:\n%s", strings.TrimSpace(st.j.Prompt), option, crit.String(), evidence)
+    res, err := st.complete(ctx, llm.Request{System: sys, Prompt: prompt, MaxTokens: 1000, Temperature: 0})
+    if err != nil { return nil, err }
+    var out struct{ Cells []Cell `json:"cells"` }
+    if err := json.Unmarshal([]byte(trimFences(res.Text)), &out); err != nil { return nil, fmt.Errorf("rate %s: parse response: %w", option, err) }
+    var cells []Cell
+    for _, c := range out.Cells {
+        i := cmp.criterion(c.Criterion)
+        if i < 0 { continue }
+        c.Option, c.Criterion = option, cmp.Criteria[i].Name
+        c.Finding = strings.TrimSpace(c.Finding)
+        c.Score = max(0, min(5, c.Score))
+        var srcs []int
+        for _, n := range c.Sources {
+            if n >= 1 && n <= len(st.j.Sources) { srcs = append(srcs, n) }
+        }
+        c.Sources = srcs
+        cells = append(cells, c)
+    }
+    return cells, nil
+}
+
+func indexOf(xs []string, x string) int {
+    for i, s := range xs { if s == x { return i } }
+    return len(xs)
+}
+
+// Reweight changes the criterion weights of a finished comparison run and
+// rewrites the session report and CSV with the new totals. Nothing is
+// re-researched; only the weighted scores change, and sections discussing
+// scores are marked as written with the earlier weights. A finished run can
+// be reweighted only while its report is the session's current one.
+func (r *Researcher) Reweight(sessionID, runID string, weights map[string]float64) (Journal, error) {
+    if info, ok := r.tasks.Get(runID); ok && !info.State.Terminal() { return Journal{}, fmt.Errorf("run %s is still %s", runID, info.State) }
+    j, err := r.LoadJournal(sessionID, runID)
+    if err != nil { return Journal{}, err }
+    if j.Comparison == nil { return Journal{}, fmt.Errorf("run %s is not a comparison", runID) }
+    if j.Status == RunComplete {
+        data, err := os.ReadFile(r.svc.ReportPath(sessionID))
+        if err != nil && !os.IsNotExist(err) { return Journal{}, err }
+        if err == nil && frontMatter(string(data))["run"] != runID {
+            return Journal{}, fmt.Errorf("run %s did not write the current report; reweighting it would overwrite a newer one", runID)
+        }
+    }
+    if err := j.Comparison.SetWeights(weights, false); err != nil { return Journal{}, err }
+    if j.Options.Weights == nil { j.Options.Weights = map[string]float64{} }
+    for n, w := range weights { j.Options.Weights[j.Comparison.Criteria[j.Comparison.criterion(n)].Name] = w }
+    if err := r.saveJournal(&j); err != nil { return Journal{}, err }
+    if j.Status == RunComplete {
+        if _, err := r.writeReport(&j); err != nil { return Journal{}, err }
+    }
+    return j, nil
+}
+
+// LatestComparison returns the most recent comparison run of the session.
+func (r *Researcher) LatestComparison(sessionID string) (Journal, bool) {
+    all, err := r.Journals(sessionID)
+    if err != nil { return Journal{}, false }
+    for i := len(all) - 1; i >= 0; i-- {
+        if all[i].Comparison != nil { return all[i], true }
+    }
+    return Journal{}, false
+}
@@ -0,0 +1,168 @@
+package agent
+
+import (
+    "encoding/csv"
+    "math"
+    "os"
+    "reflect"
+    "strings"
+    "testing"
+
+    "gotcha/internal/search"
+)
+
+func TestSplitOptions(t *testing.T) {
+    cases := []struct {
+        prompt string
+        want   []string
+    }{
+        {"Compare Postgres vs MySQL vs SQLite for analytics", []string{"Postgres", "MySQL", "SQLite"}},
+        {"Postgres versus MySQL", []string{"Postgres", "MySQL"}},
+        {"Should I use Postgres or MySQL?", []string{"Postgres", "MySQL"}},
+        {"Which is better: Rust or Go?", []string{"Rust", "Go"}},
+        {"What's better, Vim or Emacs", []string{"Vim", "Emacs"}},
+        {"Choosing between React and Vue for a dashboard", []string{"React", "Vue"}},
+        {"Compare Rock and Roll, Jazz, Blues", []string{"Rock and Roll", "Jazz", "Blues"}},
+        {"Rock and Roll vs Jazz", []string{"Rock and Roll", "Jazz"}},
+        {"Kafka, RabbitMQ, or NATS", []string{"Kafka", "RabbitMQ", "NATS"}},
+        {"Kafka, RabbitMQ or NATS", []string{"Kafka", "RabbitMQ", "NATS"}},
+        {"Compare Postgres", []string{"Postgres"}},
+    }
+    for _, c := range cases {
+        if got := splitOptions(c.prompt); !reflect.DeepEqual(got, c.want) { t.Errorf("splitOptions(%q) = %q, want %q", c.prompt, got, c.want) }
+    }
+}
+
+func testComparison() *Comparison {
+    return &Comparison{
+        Options:  []string{"A", "B", "C"},
+        Criteria: []Criterion{{Name: "Cost", Weight: 2}, {Name: "Speed", Weight: 1}, {Name: "Docs", Weight: 0}},
+        Cells: []Cell{
+            {Option: "A", Criterion: "Cost", Finding: "Free", Score: 5, Sources: []int{1}},
+            {Option: "A", Criterion: "Speed", Finding: "Slow", Score: 2},
+            {Option: "A", Criterion: "Docs", Finding: "Great", Score: 5},
+            {Option: "B", Criterion: "Cost", Finding: "Unknown"},
+            {Option: "B", Criterion: "Speed", Finding: "Fast | low latency", Score: 4, Sources: []int{2, 3}},
+        },
+    }
+}
+
+func TestComparisonTotals(t *testing.T) {
+    got := testComparison().Totals()
+    // A: (2*5 + 1*2) / 3; B: only Speed is scored; C: nothing scored. Docs
+    // has zero weight and an unscored cell does not count.
+    want := []float64{4, 4, -1}
+    for i := range want {
+        if math.Abs(got[i]-want[i]) > 1e-9 { t.Errorf("total of %s = %v, want %v", testComparison().Options[i], got[i], want[i]) }
+    }
+}
+
+func TestComparisonSetWeights(t *testing.T) {
+    c := testComparison()
+    if err := c.SetWeights(map[string]float64{"cost": 1, " speed ": 3}, false); err != nil { t.Fatal(err) }
+    if c.Criteria[0].Weight != 1 || c.Criteria[1].Weight != 3 { t.Errorf("weights %+v, want Cost=1 Speed=3", c.Criteria) }
+
+    if err := c.SetWeights(map[string]float64{"Support": 1}, false); err == nil || !strings.Contains(err.Error(), "Cost, Speed, Docs") {
+        t.Errorf("unknown criterion: err = %v, want one listing the known criteria", err)
+    }
+    if err := c.SetWeights(map[string]float64{"Support": 1.5}, true); err != nil { t.Fatal(err) }
+    if i := c.criterion("support"); i < 0 || c.Criteria[i].Weight != 1.5 { t.Errorf("added criterion missing: %+v", c.Criteria) }
+
+    for _, w := range []float64{-1, math.NaN(), math.Inf(1)} {
+        if err := c.SetWeights(map[string]float64{"Cost": w}, false); err == nil { t.Errorf("weight %v accepted", w) }
+    }
+}
+
+func TestParseWeights(t *testing.T) {
+    got, err := ParseWeights("cost=2, ease of use=0.5", " speed = 1 ", "")
+    if err != nil { t.Fatal(err) }
+    want := map[string]float64{"cost": 2, "ease of use": 0.5, "speed": 1}
+    if !reflect.DeepEqual(got, want) { t.Errorf("ParseWeights = %v, want %v", got, want) }
+
+    for _, bad := range []string{"cost", "=2", "cost=cheap", "cost=-1", "cost=NaN", "cost=Inf"} {
+        if _, err := ParseWeights(bad); err == nil { t.Errorf("ParseWeights(%q) succeeded", bad) }
+    }
+}
+
+func TestComparisonMarkdown(t *testing.T) {
+    want := `| Criterion | Weight | A | B | C |
+|---|---|---|---|---|
+| Cost | 2 | Free [1] (5/5) | Unknown | – |
+| Speed | 1 | Slow (2/5) | Fast \| low latency [2], [3] (4/5) | – |
+| Docs | 0 | Great (5/5) | – | – |
+| **Weighted score** | | **4.00** | **4.00** | – |
+`
+    if got := testComparison().Markdown(); got != want { t.Errorf("Markdown:\n%s\nwant:\n%s", got, want) }
+}
+
+func TestComparisonCSV(t *testing.T) {
+    sources := []search.Document{{URL: "https://a.example"}, {URL: "https://b.example"}}
+    rows, err := csv.NewReader(strings.NewReader(string(testComparison().CSV(sources)))).ReadAll()
+    if err != nil { t.Fatal(err) }
+    if len(rows) != 1+3*3 { t.Fatalf("got %d rows, want a header and one per option and criterion", len(rows)) }
+    if want := []string{"option", "criterion", "weight", "score", "finding", "sources", "source_urls"}; !reflect.DeepEqual(rows[0], want) {
+        t.Errorf("header %q, want %q", rows[0], want)
+    }
+    // Source 3 is out of range and gets no URL.
+    if want := []string{"B", "Speed", "1", "4", "Fast | low latency", "2 3", "https://b.example"}; !reflect.DeepEqual(rows[5], want) {
+        t.Errorf("row %q, want %q", rows[5], want)
+    }
+    if want := []string{"C", "Cost", "2", "", "", "", ""}; !reflect.DeepEqual(rows[7], want) { t.Errorf("row %q, want %q", rows[7], want) }
+}
+
+// finishedComparison saves a complete comparison run of session s1 whose
+// sections were written with the test comparison's weights, and writes its
+// report.
+func finishedComparison(t *testing.T, r *Researcher, runID string) Journal {
+    t.Helper()
+    cmp := testComparison()
+    cmp.Composed = cmp.weights()
+    j := Journal{
+        RunID: runID, SessionID: "s1", Prompt: "A vs B vs C", Status: RunComplete, Comparison: cmp,
+        Plan:     &Plan{Title: "A vs B vs C", Sections: []Section{{Heading: "Verdict"}, {Heading: "Background"}}},
+        Sections: []string{"## Verdict\n\nA wins overall with a weighted score of 4.00.", "## Background\n\nAll three are open source."},
+    }
+    if err := r.saveJournal(&j); err != nil { t.Fatal(err) }
+    if _, err := r.writeReport(&j); err != nil { t.Fatal(err) }
+    return j
+}
+
+func TestReweightMarksProseAboutScores(t *testing.T) {
+    r := testResearcher(t)
+    finishedComparison(t, r, "run-1")
+    report := func() string {
+        data, err := os.ReadFile(r.svc.ReportPath("s1"))
+        if err != nil { t.Fatal(err) }
+        return string(data)
+    }
+    if strings.Contains(report(), "earlier weights") { t.Error("a report that was never reweighted is marked") }
+
+    j, err := r.Reweight("s1", "run-1", map[string]float64{"speed": 3})
+    if err != nil { t.Fatal(err) }
+    if !j.Comparison.Reweighted() { t.Error("Reweighted is false after a weight changed") }
+    doc := report()
+    if !strings.Contains(doc, "the sections below were written with the earlier weights (Cost 2, Speed 1, Docs 0)") { t.Errorf("no reweight note under the table:\n%s", doc) }
+    verdict, background, _ := strings.Cut(doc[strings.Index(doc, "## Verdict"):], "## Background")
+    if !strings.Contains(verdict, "*Written with the earlier weights") { t.Errorf("the verdict discussing scores is not marked:\n%s", verdict) }
+    if strings.Contains(background, "*Written with the earlier weights") { t.Errorf("the background section is marked:\n%s", background) }
+
+    // Back to the weights the sections were written with: nothing is stale.
+    if _, err := r.Reweight("s1", "run-1", map[string]float64{"speed": 1}); err != nil { t.Fatal(err) }
+    if strings.Contains(report(), "earlier weights") { t.Error("marks remain after restoring the original weights") }
+}
+
+func TestReweightRefusesSupersededRun(t *testing.T) {
+    r := testResearcher(t)
+    finishedComparison(t, r, "run-1")
+    finishedComparison(t, r, "run-2")
+    before, _ := os.ReadFile(r.svc.ReportPath("s1"))
+    revs, _ := r.Revisions("s1")
+
+    if _, err := r.Reweight("s1", "run-1", map[string]float64{"cost": 1}); err == nil { t.Fatal("reweighting a run whose report was superseded succeeded") }
+    after, _ := os.ReadFile(r.svc.ReportPath("s1"))
+    if string(after) != string(before) { t.Error("report.md changed") }
+    if now, _ := r.Revisions("s1"); len(now) != len(revs) { t.Errorf("%d revisions, want %d", len(now), len(revs)) }
+    if j, _ := r.LoadJournal("s1", "run-1"); j.Comparison.Criteria[0].Weight != 2 { t.Error("the refused weights were saved") }
+
+    if _, err := r.Reweight("s1", "run-2", map[string]float64{"cost": 1}); err != nil { t.Errorf("reweighting the current run: %v", err) }
+}
@@ -0,0 +1,44 @@
+package agent
+
+import (
+    "context"
+
+    "gotcha/internal/search"
+)
+
+// SetCorpus makes later runs search the local documents in dir alongside any
+// web providers; an empty dir stops searching the corpus. Runs already
+// searching keep the providers they started a round with.
+func (r *Researcher) SetCorpus(ctx context.Context, dir string) (files, passages int, err error) {
+    if dir == "" {
+        r.mu.Lock()
+        r.corpus = nil
+        r.mu.Unlock()
+        return 0, 0, nil
+    }
+    c, err := search.NewCorpus(dir)
+    if err != nil { return 0, 0, err }
+    // Index now so problems show up before a run depends on it.
+    if files, passages, err = c.Stats(ctx); err != nil { return 0, 0, err }
+    r.mu.Lock()
+    r.corpus = c
+    r.mu.Unlock()
+    return files, passages, nil
+}
+
+// Corpus returns the local corpus searched by runs, if any.
+func (r *Researcher) Corpus() (*search.Corpus, bool) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    return r.corpus, r.corpus != nil
+}
+
+// providers returns the search backends for a round: the configured web
+// providers plus the local corpus.
+func (r *Researcher) providers() []search.Provider {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    out := append([]search.Provider(nil), r.cfg.Providers...)
+    if r.corpus != nil { out = append(out, r.corpus) }
+    return out
+}
@@ -0,0 +1,259 @@
+package agent
+
+import (
+    "context"
+    "fmt"
+    "math"
+    "net/url"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+
+    "gotcha/internal/search"
+)
+
+// SourcePolicy lists domains by reputation. A domain entry matches the host
+// itself and its subdomains; entries starting with a dot match a suffix
+// such as ".gov".
+type SourcePolicy struct {
+    Allow []string // trusted: scored as reputable
+    Deny  []string // never fetched
+    Boost []string // ranked above otherwise equal sources
+}
+
+// SourceScore rates one source; every component and the total run from 0 to 1.
+type SourceScore struct {
+    Reputation float64 `json:"reputation"` // domain lists
+    Freshness  float64 `json:"freshness"`  // publication date against the prompt's recency needs
+    Primary    float64 `json:"primary"`    // original research, documentation or data rather than coverage of it
+    Agreement  float64 `json:"agreement"`  // share of sources from other domains covering the same ground
+    Total      float64 `json:"total"`
+}
+
+// Weights of the score components in the total.
+const (
+    weightReputation = 0.35
+    weightFreshness  = 0.20
+    weightPrimary    = 0.20
+    weightAgreement  = 0.25
+)
+
+// Domains treated as reputable without configuration.
+var reputableDomains = []string{".gov", ".edu", ".int", "wikipedia.org", "arxiv.org", "doi.org", "nature.com", "science.org", "acm.org", "ieee.org", "who.int", "europa.eu"}
+
+// Hosts and path fragments that usually publish original material, and ones
+// that usually repackage it.
+var (
+    primaryHosts     = []string{".gov", ".edu", "arxiv.org", "doi.org", "github.com", "ietf.org", "w3.org", "sec.gov"}
+    primaryPaths     = []string{"/docs/", "/documentation/", "/paper", "/papers/", "/research/", "/publications/", "/spec", "/rfc", "/press", "/release", "/changelog", "/reference/", "/api/", ".pdf"}
+    primaryPhrases   = []string{"we propose", "we present", "our study", "our results", "in this paper", "this specification", "release notes", "methodology", "we measured", "dataset"}
+    secondaryHosts   = []string{"medium.com", "reddit.com", "quora.com", "pinterest.com", "linkedin.com", "substack.com", "news.ycombinator.com"}
+    secondaryPaths   = []string{"/blog/", "/news/", "/opinion/", "/top-", "/best-", "/listicle"}
+    secondaryPhrases = []string{"according to", "reported by", "told reporters", "sponsored", "affiliate", "roundup"}
+)
+
+// Words in a prompt that call for recent sources. Naming the current or the
+// previous year does too; see recencyHalfLife.
+var recencyWords = []string{"latest", "current", "currently", "recent", "recently", "today", "now", "new", "newest", "this year", "state of", "trend", "trends", "upcoming"}
+
+// scoreSources rates every source and publishes a summary. Scores are cheap
+// to recompute, so they are refreshed whenever the sources may have changed.
+func (r *Researcher) scoreSources(ctx context.Context, st *runState) error {
+    j := st.j
+    if len(j.Sources) == 0 { return nil }
+    st.publish(ctx, Event{Phase: PhaseExtract, Type: "started", Progress: Progress{Total: len(j.Sources)}})
+    now := time.Now()
+    j.Scores = scoreDocuments(j.Sources, r.cfg.Sources, recencyHalfLife(j.Prompt, now), now)
+    if err := st.save(); err != nil { return err }
+    var sum float64
+    for _, s := range j.Scores { sum += s.Total }
+    st.publish(ctx, Event{Phase: PhaseExtract, Type: "done", Progress: Progress{Done: len(j.Sources), Total: len(j.Sources)}, Meta: map[string]any{
+        "scored":      len(j.Scores),
+        "mean_score":  roundFraction(sum / float64(len(j.Scores))),
+        "duplicates":  j.Duplicates,
+        "dedup_ratio": dedupRatio(j),
+    }})
+    return nil
+}
+
+// scoreDocuments rates docs, index for index.
+func scoreDocuments(docs []search.Document, policy SourcePolicy, halfLife time.Duration, now time.Time) []SourceScore {
+    agreement := agreementScores(docs)
+    out := make([]SourceScore, len(docs))
+    for i, d := range docs {
+        host := hostOf(d.URL)
+        s := SourceScore{
+            Reputation: reputation(host, policy),
+            Freshness:  freshness(d.Published, halfLife, now),
+            Primary:    primaryScore(host, d),
+            Agreement:  agreement[i],
+        }
+        if isLocal(d.URL) {
+            // The user chose the corpus; its files are internal originals.
+            s.Reputation, s.Primary = localReputation, math.Max(s.Primary, localReputation)
+        }
+        s.Total = weightReputation*s.Reputation + weightFreshness*s.Freshness + weightPrimary*s.Primary + weightAgreement*s.Agreement
+        s.Reputation, s.Freshness, s.Primary, s.Agreement, s.Total = roundFraction(s.Reputation), roundFraction(s.Freshness), roundFraction(s.Primary), roundFraction(s.Agreement), roundFraction(s.Total)
+        out[i] = s
+    }
+    return out
+}
+
+// localReputation scores files from the local corpus.
+const localReputation = 0.8
+
+func isLocal(raw string) bool { return strings.HasPrefix(raw, "file://") }
+
+func hostOf(raw string) string {
+    u, err := url.Parse(raw)
+    if err != nil { return "" }
+    return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// matchDomain reports whether host falls under one of domains.
+func matchDomain(host string, domains []string) bool {
+    if host == "" { return false }
+    for _, d := range domains {
+        d = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(d), "*"))
+        switch {
+        case d == "":
+        case strings.HasPrefix(d, "."):
+            if strings.HasSuffix(host, d) { return true }
+        case host == d || strings.HasSuffix(host, "."+d):
+            return true
+        }
+    }
+    return false
+}
+
+// denied reports whether the policy excludes url.
+func (p SourcePolicy) denied(raw string) bool { return matchDomain(hostOf(raw), p.Deny) }
+
+func reputation(host string, p SourcePolicy) float64 {
+    score := 0.5
+    switch {
+    case matchDomain(host, p.Deny):
+        return 0
+    case matchDomain(host, p.Allow):
+        score = 0.9
+    case matchDomain(host, reputableDomains):
+        score = 0.8
+    case matchDomain(host, secondaryHosts):
+        score = 0.3
+    }
+    if matchDomain(host, p.Boost) { score += 0.3 }
+    return math.Min(score, 1)
+}
+
+// recencyHalfLife is how quickly sources lose freshness for a prompt: a year
+// when it asks about the current state of things, five otherwise.
+func recencyHalfLife(prompt string, now time.Time) time.Duration {
+    p := " " + strings.ToLower(prompt) + " "
+    words := append(recencyWords[:len(recencyWords):len(recencyWords)], strconv.Itoa(now.Year()), strconv.Itoa(now.Year()-1))
+    for _, w := range words {
+        if strings.Contains(p, " "+w+" ") || strings.Contains(p, " "+w+"?") || strings.Contains(p, " "+w+",") { return 365 * 24 * time.Hour }
+    }
+    return 5 * 365 * 24 * time.Hour
+}
+
+// freshness halves every halfLife since publication; undated sources score
+// in the middle.
+func freshness(published time.Time, halfLife time.Duration, now time.Time) float64 {
+    if published.IsZero() || halfLife <= 0 { return 0.5 }
+    age := now.Sub(published)
+    if age <= 0 { return 1 }
+    return math.Pow(0.5, float64(age)/float64(halfLife))
+}
+
+// primaryScore guesses whether a source is original material (papers,
+// specifications, official documentation and data) or coverage of it.
+func primaryScore(host string, d search.Document) float64 {
+    path := ""
+    if u, err := url.Parse(d.URL); err == nil { path = strings.ToLower(u.Path) }
+    text := strings.ToLower(excerpt(d.Text, 4000))
+    score := 0.5
+    if matchDomain(host, primaryHosts) { score += 0.25 }
+    if matchDomain(host, secondaryHosts) { score -= 0.25 }
+    for _, s := range primaryPaths {
+        if strings.Contains(path, s) { score += 0.15; break }
+    }
+    for _, s := range secondaryPaths {
+        if strings.Contains(path, s) { score -= 0.15; break }
+    }
+    score += 0.05 * float64(min(countAny(text, primaryPhrases), 3))
+    score -= 0.05 * float64(min(countAny(text, secondaryPhrases), 3))
+    return math.Max(0, math.Min(score, 1))
+}
+
+func countAny(s string, phrases []string) int {
+    n := 0
+    for _, p := range phrases { if strings.Contains(s, p) { n++ } }
+    return n
+}
+
+// agreementTerms is how many of a source's most frequent terms are compared.
+const agreementTerms = 30
+
+// agreementScores rates each source by how many sources on other domains
+// share a good part of its key terms, i.e. corroborate what it covers.
+func agreementScores(docs []search.Document) []float64 {
+    keys := make([]map[string]bool, len(docs))
+    hosts := make([]string, len(docs))
+    for i, d := range docs {
+        keys[i] = topTerms(d.Text, agreementTerms)
+        hosts[i] = hostOf(d.URL)
+        // Each local file counts as its own publisher.
+        if hosts[i] == "" { hosts[i] = d.URL }
+    }
+    out := make([]float64, len(docs))
+    for i := range docs {
+        var others, agree int
+        for k := range docs {
+            if k == i || hosts[k] == hosts[i] { continue }
+            others++
+            if len(keys[i]) == 0 { continue }
+            shared := 0
+            for t := range keys[i] { if keys[k][t] { shared++ } }
+            if float64(shared)/float64(len(keys[i])) >= 0.3 { agree++ }
+        }
+        out[i] = 0.5
+        if others > 0 { out[i] = float64(agree) / float64(others) }
+    }
+    return out
+}
+
+// topTerms returns the n most frequent terms of s.
+func topTerms(s string, n int) map[string]bool {
+    tf := terms(s)
+    words := make([]string, 0, len(tf))
+    for w := range tf { words = append(words, w) }
+    sort.Slice(words, func(a, b int) bool {
+        if tf[words[a]] != tf[words[b]] { return tf[words[a]] > tf[words[b]] }
+        return words[a] < words[b]
+    })
+    if len(words) > n { words = words[:n] }
+    out := make(map[string]bool, len(words))
+    for _, w := range words { out[w] = true }
+    return out
+}
+
+// sourceWeights turns scores into passage-ranking multipliers by source
+// index: 0.5 for the least credible source up to 1.5 for the most. Sources
+// without a score weigh 1.
+func (j *Journal) sourceWeights() []float64 {
+    if len(j.Scores) == 0 { return nil }
+    out := make([]float64, len(j.Sources))
+    for i := range out {
+        out[i] = 1
+        if i < len(j.Scores) { out[i] = 0.5 + j.Scores[i].Total }
+    }
+    return out
+}
+
+// formatScore renders a source's score for the Sources section.
+func formatScore(s SourceScore, published time.Time) string {
+    out := fmt.Sprintf("score %.2f (reputation %.2f, freshness %.2f, primary %.2f, agreement %.2f)", s.Total, s.Reputation, s.Freshness, s.Primary, s.Agreement)
+    if !published.IsZero() { out += ", published " + published.Format("2006-01-02") }
+    return out
+}
@@ -0,0 +1,70 @@
+package agent
+
+import (
+    "math"
+    "testing"
+    "time"
+)
+
+func TestRecencyHalfLife(t *testing.T) {
+    now := time.Date(2031, 3, 1, 0, 0, 0, 0, time.UTC)
+    year, fiveYears := 365*24*time.Hour, 5*365*24*time.Hour
+    cases := []struct {
+        prompt string
+        want   time.Duration
+    }{
+        {"What are the latest GPU benchmarks?", year},
+        {"State of WebAssembly", year},
+        {"Is Rust used in the kernel now?", year},
+        {"Best laptops of 2031", year},
+        {"EV sales in 2030, by country", year},
+        {"EV sales in 2029", fiveYears},
+        {"History of the printing press", fiveYears},
+        {"Renewable energy", fiveYears}, // "new" inside a word does not count
+    }
+    for _, c := range cases {
+        if got := recencyHalfLife(c.prompt, now); got != c.want { t.Errorf("recencyHalfLife(%q) = %v, want %v", c.prompt, got, c.want) }
+    }
+}
+
+func TestFreshness(t *testing.T) {
+    now := time.Date(2031, 3, 1, 0, 0, 0, 0, time.UTC)
+    year := 365 * 24 * time.Hour
+    cases := []struct {
+        name      string
+        published time.Time
+        halfLife  time.Duration
+        want      float64
+    }{
+        {"undated", time.Time{}, year, 0.5},
+        {"no half-life", now.Add(-year), 0, 0.5},
+        {"published now", now, year, 1},
+        {"dated in the future", now.Add(time.Hour), year, 1},
+        {"one half-life old", now.Add(-year), year, 0.5},
+        {"two half-lives old", now.Add(-2 * year), year, 0.25},
+    }
+    for _, c := range cases {
+        if got := freshness(c.published, c.halfLife, now); math.Abs(got-c.want) > 1e-9 { t.Errorf("%s: freshness = %v, want %v", c.name, got, c.want) }
+    }
+}
+
+func TestMatchDomain(t *testing.T) {
+    domains := []string{"example.com", ".gov", "*.edu", " Wiki.ORG ", ""}
+    cases := []struct {
+        host string
+        want bool
+    }{
+        {"example.com", true},
+        {"docs.example.com", true},
+        {"badexample.com", false},
+        {"example.com.evil.net", false},
+        {"nasa.gov", true},
+        {"gov", false},
+        {"mit.edu", true},
+        {"en.wiki.org", true},
+        {"", false},
+    }
+    for _, c := range cases {
+        if got := matchDomain(c.host, domains); got != c.want { t.Errorf("matchDomain(%q) = %v, want %v", c.host, got, c.want) }
+    }
+}
@@ -0,0 +1,137 @@
+package agent
+
+import (
+    "context"
+    "hash/fnv"
+    "math/bits"
+    "net/url"
+    "sort"
+    "strings"
+    "unicode"
+
+    "gotcha/internal/search"
+)
+
+const (
+    shingleWords   = 3  // words per shingle
+    minDedupeWords = 40 // texts shorter than this (snippets) are never collapsed
+    maxSimhashDist = 4  // differing fingerprint bits still counted as the same text
+)
+
+// canonicalURL normalizes a URL for exact-duplicate checks: scheme, "www.",
+// fragments, tracking parameters and trailing slashes are ignored.
+func canonicalURL(raw string) string {
+    u, err := url.Parse(strings.TrimSpace(raw))
+    if err != nil || u.Host == "" { return strings.TrimSpace(raw) }
+    q := u.Query()
+    for k := range q {
+        lk := strings.ToLower(k)
+        if strings.HasPrefix(lk, "utm_") || lk == "ref" || lk == "fbclid" || lk == "gclid" { q.Del(k) }
+    }
+    out := strings.TrimPrefix(strings.ToLower(u.Host), "www.") + strings.TrimSuffix(u.EscapedPath(), "/")
+    if enc := q.Encode(); enc != "" { out += "?" + enc }
+    return out
+}
+
+// simhash fingerprints text from its word shingles; near-identical texts get
+// fingerprints a few bits apart. ok is false when the text is too short to
+// fingerprint reliably.
+func simhash(text string) (fp uint64, ok bool) {
+    words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+    if len(words) < minDedupeWords { return 0, false }
+    var v [64]int
+    for i := 0; i+shingleWords <= len(words); i++ {
+        h := fnv.New64a()
+        h.Write([]byte(strings.Join(words[i:i+shingleWords], " ")))
+        sum := h.Sum64()
+        for b := 0; b < 64; b++ {
+            if sum&(1<<b) != 0 { v[b]++ } else { v[b]-- }
+        }
+    }
+    for b := 0; b < 64; b++ {
+        if v[b] > 0 { fp |= 1 << b }
+    }
+    return fp, true
+}
+
+// canonicalRank orders near-duplicates: the copy from the more reputable,
+// more primary source wins, then the longer text.
+func canonicalRank(d search.Document, policy SourcePolicy) float64 {
+    host := hostOf(d.URL)
+    return reputation(host, policy) + primaryScore(host, d) + float64(min(len(d.Text), 20000))/1e6
+}
+
+// mergeSources appends fetched docs to sources, collapsing each one that
+// duplicates a source already held (or an earlier doc of the batch) into that
+// source's alternate URLs. The better copy takes the existing index, so
+// source numbers already handed out keep pointing at the same content. It
+// returns the merged sources and how many docs were collapsed.
+func mergeSources(sources, docs []search.Document, policy SourcePolicy) ([]search.Document, int) {
+    type print struct {
+        fp uint64
+        ok bool
+    }
+    prints := make([]print, len(sources), len(sources)+len(docs))
+    urls := map[string]int{}
+    for i, d := range sources {
+        prints[i].fp, prints[i].ok = simhash(d.Text)
+        urls[canonicalURL(d.URL)] = i
+        for _, a := range d.Alternates { urls[canonicalURL(a)] = i }
+    }
+    dups := 0
+    for _, d := range docs {
+        fp, ok := simhash(d.Text)
+        match, found := urls[canonicalURL(d.URL)]
+        for i := 0; !found && ok && i < len(prints); i++ {
+            if prints[i].ok && bits.OnesCount64(prints[i].fp^fp) <= maxSimhashDist { match, found = i, true }
+        }
+        if !found {
+            urls[canonicalURL(d.URL)] = len(sources)
+            sources = append(sources, d)
+            prints = append(prints, print{fp, ok})
+            continue
+        }
+        dups++
+        keep, alt := sources[match], d
+        if canonicalRank(d, policy) > canonicalRank(keep, policy) {
+            keep, alt = d, keep
+            prints[match] = print{fp, ok}
+        }
+        keep.Alternates = appendAlternates(keep.Alternates, keep.URL, append([]string{alt.URL}, alt.Alternates...)...)
+        sources[match] = keep
+        urls[canonicalURL(d.URL)] = match
+    }
+    return sources, dups
+}
+
+// appendAlternates adds urls to alts, skipping the canonical URL and repeats.
+func appendAlternates(alts []string, canonical string, urls ...string) []string {
+    seen := map[string]bool{canonicalURL(canonical): true}
+    var out []string
+    for _, u := range append(alts, urls...) {
+        if k := canonicalURL(u); !seen[k] {
+            seen[k] = true
+            out = append(out, u)
+        }
+    }
+    sort.Strings(out)
+    return out
+}
+
+// dedupRatio is the share of fetched documents collapsed into another source.
+func dedupRatio(j *Journal) float64 {
+    total := len(j.Sources) + j.Duplicates
+    if total == 0 { return 0 }
+    return roundFraction(float64(j.Duplicates) / float64(total))
+}
+
+// publishDedup reports one fetch round's deduplication in the extract phase.
+func (st *runState) publishDedup(ctx context.Context, iteration, fetched, dups int) {
+    st.publish(ctx, Event{Phase: PhaseExtract, Type: "progress", Meta: map[string]any{
+        "iteration":   iteration,
+        "fetched":     fetched,
+        "duplicates":  dups,
+        "sources":     len(st.j.Sources),
+        "dedup_ratio": dedupRatio(st.j),
+    }})
+}
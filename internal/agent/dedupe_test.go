@@ -0,0 +1,80 @@
+package agent
+
+import (
+    "math/bits"
+    "reflect"
+    "strings"
+    "testing"
+
+    "gotcha/internal/search"
+)
+
+const (
+    articleA = `The city council approved a new transit plan on Tuesday that will add three bus rapid transit lines over the next five years. The plan, which passed by a vote of seven to two, calls for dedicated lanes on the busiest corridors, signal priority at major intersections and level boarding platforms at every station. Officials estimate that the lines will carry forty thousand riders a day once complete and cut average commute times by a quarter. Funding will come from a mix of federal grants, a regional sales tax approved by voters last year and fares. Critics argued that the dedicated lanes would worsen congestion for drivers and hurt businesses that rely on street parking, while supporters pointed to similar projects in other cities that increased ridership without lasting harm to local shops.`
+    articleB = `Researchers at the university have developed a battery chemistry that stores nearly twice the energy of conventional lithium ion cells by weight. The design replaces the graphite anode with a thin layer of lithium metal protected by a ceramic coating that prevents the growth of dendrites, the needle like structures that can short circuit a cell. In laboratory tests the prototype retained ninety percent of its capacity after eight hundred charge cycles. The team cautions that manufacturing the coating at scale remains difficult and expensive, and that the cells have so far only been tested at room temperature. A startup founded by two of the authors plans to produce sample cells for electric aircraft makers within two years.`
+)
+
+func TestSimhash(t *testing.T) {
+    a, ok := simhash(articleA)
+    if !ok { t.Fatal("article not fingerprinted") }
+    dist := func(text string) int {
+        fp, ok := simhash(text)
+        if !ok { t.Fatalf("text not fingerprinted: %q", text) }
+        return bits.OnesCount64(a ^ fp)
+    }
+    near := map[string]string{
+        "same text":          articleA,
+        "case and spacing":   strings.ToUpper(strings.Join(strings.Fields(articleA), "   ")),
+        "one word changed":   strings.Replace(articleA, "Tuesday", "Wednesday", 1),
+        "syndication footer": articleA + " This story originally appeared in the Daily Courier.",
+    }
+    for name, text := range near {
+        if d := dist(text); d > maxSimhashDist { t.Errorf("%s: %d bits apart, want at most %d", name, d, maxSimhashDist) }
+    }
+    if d := dist(articleB); d <= maxSimhashDist { t.Errorf("distinct articles only %d bits apart", d) }
+    if _, ok := simhash("A short search snippet about transit."); ok { t.Error("snippet was fingerprinted") }
+}
+
+func TestCanonicalURL(t *testing.T) {
+    same := []string{
+        "https://www.example.com/news/story/",
+        "http://example.com/news/story?utm_source=feed&ref=home",
+        "https://EXAMPLE.com/news/story#comments",
+    }
+    for _, u := range same[1:] {
+        if canonicalURL(u) != canonicalURL(same[0]) { t.Errorf("canonicalURL(%q) = %q, want %q", u, canonicalURL(u), canonicalURL(same[0])) }
+    }
+    if canonicalURL("https://example.com/news/story?id=2") == canonicalURL(same[0]) { t.Error("query parameters that select content were dropped") }
+}
+
+func TestMergeSources(t *testing.T) {
+    sources := []search.Document{
+        {URL: "https://medium.com/@writer/transit-plan", Text: articleA, Alternates: []string{"https://mirror.example/transit"}},
+        {URL: "https://example.com/snippet", Text: "Council approves transit plan."},
+    }
+    docs := []search.Document{
+        // The same story from the more reputable original replaces the copy.
+        {URL: "https://www.citynews.gov/transit-plan", Text: strings.Replace(articleA, "Tuesday", "Wednesday", 1)},
+        // A tracking variant of a URL already held.
+        {URL: "https://example.com/snippet?utm_campaign=x", Text: "Council approves transit plan."},
+        // A short snippet elsewhere is never treated as a duplicate.
+        {URL: "https://other.example/snippet", Text: "Council approves transit plan."},
+        {URL: "https://lab.edu/battery", Text: articleB},
+    }
+    got, dups := mergeSources(sources, docs, SourcePolicy{})
+    if dups != 2 { t.Errorf("collapsed %d docs, want 2", dups) }
+    urls := make([]string, len(got))
+    for i, d := range got { urls[i] = d.URL }
+    want := []string{"https://www.citynews.gov/transit-plan", "https://example.com/snippet", "https://other.example/snippet", "https://lab.edu/battery"}
+    if !reflect.DeepEqual(urls, want) { t.Fatalf("sources %q, want %q", urls, want) }
+
+    if alts, want := got[0].Alternates, []string{"https://medium.com/@writer/transit-plan", "https://mirror.example/transit"}; !reflect.DeepEqual(alts, want) {
+        t.Errorf("alternates of the kept copy %q, want %q", alts, want)
+    }
+    // A tracking variant is the same URL, not an alternate.
+    if alts := got[1].Alternates; len(alts) != 0 { t.Errorf("alternates of the snippet %q, want none", alts) }
+
+    // The displaced copy's URL still finds the merged source in a later batch.
+    again, dups := mergeSources(got, []search.Document{{URL: "https://medium.com/@writer/transit-plan/", Text: "Paywalled."}}, SourcePolicy{})
+    if dups != 1 || len(again) != len(got) { t.Errorf("refetched alternate was not collapsed: %d dups, %d sources", dups, len(again)) }
+}
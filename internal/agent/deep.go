@@ -0,0 +1,313 @@
+package agent
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "slices"
+    "strings"
+    "sync"
+    "time"
+
+    "gotcha/internal/llm"
+    "gotcha/internal/search"
+)
+
+// Stop reasons recorded when the research loop ends.
+const (
+    stopNoSearch   = "no search provider configured"
+    stopSinglePass = "single pass"
+    stopSufficient = "coverage judged sufficient"
+    stopNoQueries  = "no follow-up queries"
+    stopDepth      = "depth limit reached"
+    stopTime       = "time limit reached"
+    stopTokens     = "token budget exhausted"
+)
+
+// research runs search and fetch rounds until the loop is done. In iterative
+// mode each round ends with a gap analysis that either judges coverage
+// sufficient or proposes follow-up queries. Every round is checkpointed.
+func (r *Researcher) research(ctx context.Context, st *runState) error {
+    j := st.j
+    opts := j.Options
+    for !j.ResearchDone {
+        if err := st.t.Checkpoint(ctx); err != nil { return err }
+        if len(r.providers()) == 0 {
+            r.finishResearch(ctx, st, stopNoSearch)
+            return st.save()
+        }
+        if len(j.Pending) == 0 {
+            r.finishResearch(ctx, st, stopNoQueries)
+            return st.save()
+        }
+        started := time.Now()
+        iteration := len(j.Rounds) + 1
+
+        round, err := r.searchRound(ctx, st, iteration, j.Pending)
+        if err != nil { return err }
+        j.Rounds = append(j.Rounds, round)
+        j.Pending = nil
+        if err := st.save(); err != nil { return err }
+
+        if err := r.fetchSources(ctx, st, round.Results); err != nil { return err }
+        j.attachSectionSources()
+        j.ResearchTime += time.Since(started)
+        if err := st.save(); err != nil { return err }
+
+        // A time or token stop skips this round's analysis and keeps the
+        // previous one's open questions; the report marks them unresolved.
+        switch {
+        case !opts.Iterative || r.llm == nil:
+            r.finishResearch(ctx, st, stopSinglePass)
+        case opts.MaxDuration > 0 && j.ResearchTime >= opts.MaxDuration:
+            r.finishResearch(ctx, st, stopTime)
+        case opts.MaxTokens > 0 && j.Usage.Tokens() >= opts.MaxTokens:
+            r.finishResearch(ctx, st, stopTokens)
+        default:
+            // At the depth limit the budget still allows one more analysis,
+            // run only to learn which questions the last round left open.
+            analyzed := time.Now()
+            gaps, err := r.analyzeGaps(ctx, st, iteration)
+            if err != nil { return err }
+            j.OpenQuestions = gaps.OpenQuestions
+            j.ResearchTime += time.Since(analyzed)
+            switch {
+            case gaps.Sufficient:
+                j.OpenQuestions = nil
+                r.finishResearch(ctx, st, stopSufficient)
+            case opts.MaxDepth > 0 && iteration >= opts.MaxDepth:
+                r.finishResearch(ctx, st, stopDepth)
+            case len(gaps.Queries) == 0:
+                r.finishResearch(ctx, st, stopNoQueries)
+            default:
+                j.Pending = gaps.Queries
+            }
+        }
+        if err := st.save(); err != nil { return err }
+    }
+    return nil
+}
+
+// budgetStop reports whether the loop stopped on its time or token limit,
+// before analyzing its last round.
+func (j Journal) budgetStop() bool { return j.StopReason == stopTime || j.StopReason == stopTokens }
+
+func (r *Researcher) finishResearch(ctx context.Context, st *runState, reason string) {
+    st.j.ResearchDone = true
+    st.j.StopReason = reason
+    st.publish(ctx, Event{Phase: PhaseAnalyze, Type: "done", Meta: map[string]any{
+        "stop_reason":    reason,
+        "rounds":         len(st.j.Rounds),
+        "sources":        len(st.j.Sources),
+        "open_questions": st.j.OpenQuestions,
+    }})
+}
+
+// searchRound runs queries across all providers concurrently and returns the
+// de-duplicated results, interleaved by rank across queries, with the
+// queries that found each one.
+func (r *Researcher) searchRound(ctx context.Context, st *runState, iteration int, queries []string) (SearchRound, error) {
+    providers := r.providers()
+    total := len(queries) * len(providers)
+    st.publish(ctx, Event{Phase: PhaseSearch, Type: "started", Progress: Progress{Total: total}, Meta: map[string]any{"iteration": iteration, "queries": queries}})
+    results := make([][]search.Result, total)
+    var (
+        wg        sync.WaitGroup
+        done      int
+        errOnce   sync.Once
+        budgetErr error
+    )
+    sem := make(chan struct{}, r.cfg.SearchConcurrency)
+    for qi, q := range queries {
+        for pi, p := range providers {
+            wg.Add(1)
+            go func(slot int, q string, p search.Provider) {
+                defer wg.Done()
+                sem <- struct{}{}
+                defer func() { <-sem }()
+                // Local corpus searches are free and do not count as requests.
+                _, local := p.(*search.Corpus)
+                if !local {
+                    if err := st.spend(ctx); err != nil {
+                        errOnce.Do(func() { budgetErr = err })
+                        return
+                    }
+                }
+                res, err := p.Search(ctx, q, r.cfg.PerQuery)
+                st.mu.Lock()
+                done++
+                n := done
+                if !local {
+                    st.j.Usage.Searches++
+                    st.j.Usage.Cost += r.cfg.Prices.PerSearch
+                }
+                st.mu.Unlock()
+                e := Event{Phase: PhaseSearch, Type: "progress", Progress: Progress{Done: n, Total: total}, Meta: map[string]any{"query": q, "provider": p.Name(), "results": len(res)}}
+                if err != nil { e.Err = err.Error() }
+                st.publish(ctx, e)
+                results[slot] = res
+            }(qi*len(providers)+pi, q, p)
+        }
+    }
+    wg.Wait()
+    if err := ctx.Err(); err != nil { return SearchRound{}, err }
+    if budgetErr != nil { return SearchRound{}, budgetErr }
+
+    // Take results rank by rank across queries so the source limit spreads
+    // over every query, and with them every section.
+    seen := map[string]bool{}
+    round := SearchRound{Iteration: iteration, Queries: queries, Found: map[string][]string{}, At: time.Now()}
+    for slot, rs := range results {
+        q := queries[slot/len(providers)]
+        for _, res := range rs {
+            if res.URL == "" || slices.Contains(round.Found[res.URL], q) { continue }
+            round.Found[res.URL] = append(round.Found[res.URL], q)
+        }
+    }
+    for _, res := range interleave(results) {
+        if res.URL == "" || seen[res.URL] { continue }
+        seen[res.URL] = true
+        round.Results = append(round.Results, res)
+    }
+    st.publish(ctx, Event{Phase: PhaseSearch, Type: "done", Progress: Progress{Done: total, Total: total}, Meta: map[string]any{"iteration": iteration, "results": len(round.Results)}})
+    return round, nil
+}
+
+// fetchSources retrieves results not already among the run's sources, up to
+// the per-run source limit. Provider-supplied content is used as-is; failed
+// fetches fall back to the search snippet.
+func (r *Researcher) fetchSources(ctx context.Context, st *runState, results []search.Result) error {
+    have := map[string]bool{}
+    for _, d := range st.j.Sources {
+        have[canonicalURL(d.URL)] = true
+        for _, a := range d.Alternates { have[canonicalURL(a)] = true }
+    }
+    var todo []search.Result
+    for _, res := range results {
+        key := canonicalURL(res.URL)
+        if have[key] || r.cfg.Sources.denied(res.URL) { continue }
+        if r.cfg.MaxSources > 0 && len(st.j.Sources)+len(todo) >= r.cfg.MaxSources { break }
+        have[key] = true
+        todo = append(todo, res)
+    }
+    if len(todo) == 0 { return nil }
+
+    st.publish(ctx, Event{Phase: PhaseFetch, Type: "started", Progress: Progress{Total: len(todo)}})
+    docs := make([]search.Document, len(todo))
+    var (
+        wg        sync.WaitGroup
+        done      int
+        errOnce   sync.Once
+        budgetErr error
+    )
+    sem := make(chan struct{}, r.cfg.FetchConcurrency)
+    for i, res := range todo {
+        wg.Add(1)
+        go func(i int, res search.Result) {
+            defer wg.Done()
+            sem <- struct{}{}
+            defer func() { <-sem }()
+            // Corpus files are read locally, like their searches.
+            local := res.Provider == search.CorpusName
+            if !local {
+                if err := st.spend(ctx); err != nil {
+                    errOnce.Do(func() { budgetErr = err })
+                    return
+                }
+            }
+            start := time.Now()
+            doc, err := r.fetchOne(ctx, res)
+            docs[i] = doc
+            st.mu.Lock()
+            done++
+            n := done
+            if !local { st.j.Usage.Fetches++ }
+            st.mu.Unlock()
+            e := Event{Phase: PhaseFetch, Type: "progress", Progress: Progress{Done: n, Total: len(todo), Elapsed: time.Since(start)}, Meta: map[string]any{"url": res.URL, "title": doc.Title}}
+            if err != nil { e.Err = err.Error() }
+            st.publish(ctx, e)
+        }(i, res)
+    }
+    wg.Wait()
+    if err := ctx.Err(); err != nil { return err }
+    // Keep whatever was fetched before the budget ran out, collapsing
+    // near-duplicates into one source.
+    var fetched []search.Document
+    for _, d := range docs {
+        if strings.TrimSpace(d.Text) != "" { fetched = append(fetched, d) }
+    }
+    var dups int
+    st.j.Sources, dups = mergeSources(st.j.Sources, fetched, r.cfg.Sources)
+    st.j.Duplicates += dups
+    st.publishDedup(ctx, len(st.j.Rounds), len(fetched), dups)
+    if budgetErr != nil { return budgetErr }
+    st.publish(ctx, Event{Phase: PhaseFetch, Type: "done", Progress: Progress{Done: len(todo), Total: len(todo)}, Meta: map[string]any{"sources": len(st.j.Sources)}})
+    return nil
+}
+
+func (r *Researcher) fetchOne(ctx context.Context, res search.Result) (search.Document, error) {
+    doc := search.Document{URL: res.URL, Title: res.Title, Text: res.Content, Published: res.Published, FetchedAt: time.Now()}
+    if strings.TrimSpace(doc.Text) != "" || r.cfg.Fetcher == nil {
+        if doc.Text == "" { doc.Text = res.Snippet }
+        return doc, nil
+    }
+    fetched, err := r.cfg.Fetcher.Fetch(ctx, res.URL)
+    if err != nil {
+        doc.Text = res.Snippet
+        return doc, err
+    }
+    if fetched.Title == "" || fetched.Title == res.URL { fetched.Title = res.Title }
+    if fetched.Published.IsZero() { fetched.Published = res.Published }
+    return fetched, nil
+}
+
+type gapAnalysis struct {
+    Sufficient    bool     `json:"sufficient"`
+    OpenQuestions []string `json:"open_questions"`
+    Queries       []string `json:"queries"`
+}
+
+// analyzeGaps asks the model which parts of the prompt the gathered sources
+// leave unanswered and which queries would close those gaps.
+func (r *Researcher) analyzeGaps(ctx context.Context, st *runState, iteration int) (gapAnalysis, error) {
+    j := st.j
+    st.publish(ctx, Event{Phase: PhaseAnalyze, Type: "started", Meta: map[string]any{"iteration": iteration}})
+    var b strings.Builder
+    fmt.Fprintf(&b, "Research prompt: %s\n\nPlanned sections:\n", strings.TrimSpace(j.Prompt))
+    for _, s := range j.Plan.Sections { fmt.Fprintf(&b, "- %s: %s\n", safeHead(s.Heading), strings.TrimSpace(s.Instructions)) }
+    b.WriteString("\nQueries already run:\n")
+    for _, rd := range j.Rounds {
+        for _, q := range rd.Queries { b.WriteString("- " + q + "\n") }
+    }
+    b.WriteString("\nSources gathered so far:\n")
+    for i, d := range j.Sources { fmt.Fprintf(&b, "[%d] %s: %s\n", i+1, d.Title, excerpt(d.Text, 300)) }
+    b.WriteString("\nReturn JSON only.")
+
+    sys := "You are a research lead reviewing coverage. Return strict JSON: {\"sufficient\": bool, \"open_questions\": [questions the sources do not yet answer], \"queries\": [at most 4 new web search queries targeting those questions]}. Mark sufficient when every planned section can be written with cited evidence. No extra text."
+    res, err := st.complete(ctx, llm.Request{System: sys, Prompt: b.String(), MaxTokens: 600, Temperature: 0.2})
+    if err != nil {
+        st.publish(ctx, Event{Phase: PhaseAnalyze, Type: "error", Err: err.Error()})
+        return gapAnalysis{}, err
+    }
+    var g gapAnalysis
+    if err := json.Unmarshal([]byte(trimFences(res.Text)), &g); err != nil {
+        // An unreadable verdict ends the loop rather than burning more budget.
+        g = gapAnalysis{}
+    }
+    if len(g.Queries) > 4 { g.Queries = g.Queries[:4] }
+    st.publish(ctx, Event{Phase: PhaseAnalyze, Type: "progress", Meta: map[string]any{
+        "iteration":      iteration,
+        "sufficient":     g.Sufficient,
+        "open_questions": g.OpenQuestions,
+        "queries":        g.Queries,
+    }})
+    return g, nil
+}
+
+func excerpt(s string, n int) string {
+    s = strings.Join(strings.Fields(s), " ")
+    if len(s) <= n { return s }
+    cut := strings.LastIndexByte(s[:n], ' ')
+    if cut <= 0 { cut = n }
+    return s[:cut] + "…"
+}
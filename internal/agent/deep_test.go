@@ -0,0 +1,113 @@
+package agent
+
+import (
+    "context"
+    "fmt"
+    "reflect"
+    "strings"
+    "testing"
+    "time"
+
+    "gotcha/internal/llm"
+    "gotcha/internal/search"
+)
+
+// runStep runs fn on a run state for j as a task of r and returns fn's error
+// once the task ends.
+func runStep(t *testing.T, r *Researcher, j *Journal, fn func(ctx context.Context, st *runState) error) error {
+    t.Helper()
+    var err error
+    id := r.tasks.Submit(context.Background(), j.RunID, j.SessionID, "test", func(ctx context.Context, task *Task) error {
+        err = fn(ctx, &runState{r: r, t: task, j: j, budget: r.newBudgetState(task, j)})
+        return err
+    })
+    if _, werr := r.tasks.Wait(context.Background(), id); werr != nil { t.Fatal(werr) }
+    return err
+}
+
+func TestFetchSourcesCorpusIsFree(t *testing.T) {
+    r := testResearcher(t)
+    j := &Journal{RunID: "r1", SessionID: "s1", Options: RunOptions{Budget: Budget{Requests: 1}}}
+    results := []search.Result{
+        {URL: "file:///notes/a.md", Title: "A", Content: "alpha text", Provider: search.CorpusName},
+        {URL: "file:///notes/b.md", Title: "B", Content: "beta text", Provider: search.CorpusName},
+        {URL: "https://example.com/c", Title: "C", Content: "gamma text", Provider: "tavily"},
+    }
+    err := runStep(t, r, j, func(ctx context.Context, st *runState) error { return r.fetchSources(ctx, st, results) })
+    if err != nil { t.Fatalf("fetching two corpus files and one page on a one-request budget: %v", err) }
+    if len(j.Sources) != 3 { t.Errorf("got %d sources, want 3", len(j.Sources)) }
+    if j.Usage.Fetches != 1 { t.Errorf("counted %d fetches, want only the web page", j.Usage.Fetches) }
+}
+
+// stubProvider returns one result per query, with its content inline.
+type stubProvider struct{}
+
+func (stubProvider) Name() string { return "stub" }
+
+func (stubProvider) Search(ctx context.Context, query string, max int) ([]search.Result, error) {
+    url := "https://example.com/" + strings.ReplaceAll(query, " ", "-")
+    return []search.Result{{URL: url, Title: query, Content: "Findings on " + query + ".", Provider: "stub"}}, nil
+}
+
+// gapLLM answers gap analyses with verdicts in turn, the last one repeating,
+// each costing 600 tokens.
+func gapLLM(verdicts ...string) *funcLLM {
+    return &funcLLM{fn: func(req llm.Request, call int) (llm.Response, error) {
+        return llm.Response{Text: verdicts[min(call, len(verdicts))-1], PromptTokens: 500, CompletionTokens: 100}, nil
+    }}
+}
+
+func TestResearchLoopStops(t *testing.T) {
+    const more = `{"sufficient": false, "open_questions": ["Q%d?"], "queries": ["follow up %d"]}`
+    moreN := func(n int) string { return fmt.Sprintf(more, n, n) }
+    cases := []struct {
+        name     string
+        opts     RunOptions
+        verdicts []string
+        resumed  bool // from a checkpoint with an hour of research and open questions
+        reason   string
+        rounds   int
+        analyses int
+        open     []string
+    }{
+        {"single pass", RunOptions{}, nil, false, stopSinglePass, 1, 0, nil},
+        {"sufficient", RunOptions{Iterative: true}, []string{moreN(1), `{"sufficient": true, "open_questions": ["Q2?"]}`}, false, stopSufficient, 2, 2, nil},
+        {"no follow-up queries", RunOptions{Iterative: true}, []string{`{"sufficient": false, "open_questions": ["Q1?"]}`}, false, stopNoQueries, 1, 1, []string{"Q1?"}},
+        {"depth", RunOptions{Iterative: true, MaxDepth: 3}, []string{moreN(1), moreN(2), moreN(3)}, false, stopDepth, 3, 3, []string{"Q3?"}},
+        // The third round starts over 1000 tokens, after two analyses.
+        {"tokens", RunOptions{Iterative: true, MaxTokens: 1000}, []string{moreN(1), moreN(2), moreN(3)}, false, stopTokens, 3, 2, []string{"Q2?"}},
+        {"time", RunOptions{Iterative: true, MaxDuration: time.Hour}, []string{moreN(2)}, true, stopTime, 2, 0, []string{"Q1?"}},
+    }
+    for _, c := range cases {
+        t.Run(c.name, func(t *testing.T) {
+            r := testResearcher(t)
+            r.cfg.Providers = []search.Provider{stubProvider{}}
+            fake := gapLLM(c.verdicts...)
+            if c.verdicts != nil { r.llm = fake }
+            j := &Journal{RunID: "r1", SessionID: "s1", Prompt: "Battery prices", Options: c.opts, Plan: &Plan{Sections: []Section{{Heading: "Prices"}}}, Pending: []string{"battery prices"}}
+            if c.resumed {
+                j.Rounds = []SearchRound{{Iteration: 1, Queries: []string{"battery"}}}
+                j.ResearchTime, j.OpenQuestions = time.Hour, []string{"Q1?"}
+            }
+            if err := runStep(t, r, j, func(ctx context.Context, st *runState) error { return r.research(ctx, st) }); err != nil { t.Fatal(err) }
+            if !j.ResearchDone || j.StopReason != c.reason { t.Errorf("stopped (done %v) with %q, want %q", j.ResearchDone, j.StopReason, c.reason) }
+            if len(j.Rounds) != c.rounds { t.Errorf("ran %d rounds, want %d", len(j.Rounds), c.rounds) }
+            if fake.Calls() != c.analyses { t.Errorf("ran %d gap analyses, want %d", fake.Calls(), c.analyses) }
+            if !reflect.DeepEqual(j.OpenQuestions, c.open) { t.Errorf("open questions %q, want %q", j.OpenQuestions, c.open) }
+        })
+    }
+}
+
+func TestReportLabelsQuestionsLeftByBudget(t *testing.T) {
+    r := testResearcher(t)
+    j := &Journal{Prompt: "Battery prices", Plan: &Plan{Title: "Battery prices"}, Rounds: make([]SearchRound, 3), OpenQuestions: []string{"Which chemistries?"}}
+    for _, c := range []struct {
+        reason     string
+        unresolved bool
+    }{{stopTokens, true}, {stopTime, true}, {stopDepth, false}, {stopNoQueries, false}} {
+        j.StopReason = c.reason
+        doc := r.assembleMarkdown(j, nil, nil, time.Now())
+        if !strings.Contains(doc, "- Which chemistries?") { t.Errorf("%s: report lacks the open question", c.reason) }
+        if got := strings.Contains(doc, "unresolved. The budget ran out"); got != c.unresolved { t.Errorf("%s: labelled unresolved by the budget = %v, want %v", c.reason, got, c.unresolved) }
+    }
+}
@@ -0,0 +1,117 @@
+package agent
+
+import (
+    "bufio"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io/fs"
+    "os"
+    "sync"
+    "time"
+
+    "gotcha/internal/platform"
+)
+
+// EventLog persists events to events.jsonl in each session directory so runs
+// can be inspected after the fact and progress views rebuilt on resume.
+type EventLog struct {
+    paths platform.Paths
+
+    mu     sync.Mutex
+    exists map[string]bool // session directories known to exist
+}
+
+func NewEventLog(paths platform.Paths) *EventLog { return &EventLog{paths: paths, exists: map[string]bool{}} }
+
+// Wrap returns a bus that appends every published event to the log before
+// fanning it out, so nothing is lost to slow subscribers or early exits.
+// Replayed events are not recorded again. When appending to a session's log
+// starts failing, one PhaseLog error event is published for it, and another
+// only after appends have recovered and failed again.
+func (l *EventLog) Wrap(bus EventBus) EventBus { return &loggedBus{EventBus: bus, log: l, failing: map[string]bool{}} }
+
+type loggedBus struct {
+    EventBus
+    log *EventLog
+
+    mu      sync.Mutex
+    failing map[string]bool // sessions whose last append failed
+}
+
+func (b *loggedBus) Publish(ctx context.Context, e Event) {
+    if !e.Replayed && e.SessionID != "" {
+        err := b.log.Append(e)
+        b.mu.Lock()
+        first := err != nil && !b.failing[e.SessionID]
+        if err != nil { b.failing[e.SessionID] = true } else { delete(b.failing, e.SessionID) }
+        b.mu.Unlock()
+        if first {
+            // Published past the log: recording the failure would fail too.
+            b.EventBus.Publish(ctx, Event{SessionID: e.SessionID, TaskID: e.TaskID, Phase: PhaseLog, Type: "error", Err: err.Error(), At: time.Now()})
+        }
+    }
+    b.EventBus.Publish(ctx, e)
+}
+
+// Append writes one event to its session's log. Events for a session whose
+// directory no longer exists (it was deleted, possibly by another process,
+// while a task was still reporting) are dropped rather than bringing the
+// session back as a stray directory.
+func (l *EventLog) Append(e Event) error {
+    line, err := json.Marshal(e)
+    if err != nil { return fmt.Errorf("marshal event: %w", err) }
+    line = append(line, '\n')
+    l.mu.Lock()
+    defer l.mu.Unlock()
+    if !l.exists[e.SessionID] {
+        if _, err := os.Stat(l.paths.SessionDir(e.SessionID)); err != nil {
+            if errors.Is(err, fs.ErrNotExist) { return nil }
+            return fmt.Errorf("stat session: %w", err)
+        }
+        l.exists[e.SessionID] = true
+    }
+    err = platform.AppendFile(l.paths.SessionEventsPath(e.SessionID), line)
+    if errors.Is(err, fs.ErrNotExist) {
+        // Deleted since it was cached.
+        delete(l.exists, e.SessionID)
+        if _, serr := os.Stat(l.paths.SessionDir(e.SessionID)); errors.Is(serr, fs.ErrNotExist) { return nil }
+    }
+    return err
+}
+
+// Read returns the logged events of a session in order. Lines that fail to
+// parse (e.g. a write torn by a crash) are skipped.
+func (l *EventLog) Read(sessionID string) ([]Event, error) {
+    f, err := os.Open(l.paths.SessionEventsPath(sessionID))
+    if err != nil {
+        if os.IsNotExist(err) { return nil, nil }
+        return nil, fmt.Errorf("open event log: %w", err)
+    }
+    defer f.Close()
+    var out []Event
+    s := bufio.NewScanner(f)
+    s.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+    for s.Scan() {
+        var e Event
+        if err := json.Unmarshal(s.Bytes(), &e); err != nil { continue }
+        out = append(out, e)
+    }
+    if err := s.Err(); err != nil { return out, fmt.Errorf("read event log: %w", err) }
+    return out, nil
+}
+
+// Replay re-publishes a session's logged events on bus, marked Replayed, and
+// returns how many were sent. Subscribers should size their buffers to hold
+// the whole log if they need every event.
+func (l *EventLog) Replay(ctx context.Context, bus EventBus, sessionID string) (int, error) {
+    events, err := l.Read(sessionID)
+    if err != nil { return 0, err }
+    for i, e := range events {
+        if err := ctx.Err(); err != nil { return i, err }
+        e.Replayed = true
+        bus.Publish(ctx, e)
+    }
+    return len(events), nil
+}
@@ -2,6 +2,7 @@ package agent
 
 import (
     "context"
+    "sync"
     "time"
 )
 
@@ -11,45 +12,219 @@ const (
     PhaseSearch  Phase = "search"
     PhaseFetch   Phase = "fetch"
     PhaseExtract Phase = "extract"
+    PhaseClarify Phase = "clarify" // clarifying questions before planning
     PhaseOutline Phase = "outline"
+    PhaseAnalyze Phase = "analyze" // gap analysis between iterative search rounds
     PhaseSection Phase = "section"
     PhaseCompose Phase = "compose"
+    PhaseReview  Phase = "review" // self-critique and revision of the draft
+    PhaseVerify  Phase = "verify" // claim checking against fetched sources
+    PhaseBudget  Phase = "budget" // threshold warnings and exhaustion
+    PhaseCompare Phase = "compare" // filling the comparison matrix
+    PhaseLog     Phase = "log"     // failures writing the session's event log
+    // PhaseTask events carry task lifecycle changes; Type is the new TaskState.
+    PhaseTask    Phase = "task"
 )
 
 type Progress struct {
-    Done, Total int
-    Elapsed      time.Duration
+    Done    int           `json:"done"`
+    Total   int           `json:"total"`
+    Elapsed time.Duration `json:"elapsed,omitempty"`
 }
 
 // Event is emitted by background workers and consumed by the TUI.
 type Event struct {
-    SessionID string
-    TaskID    string
-    Phase     Phase
-    Type      string            // queued|started|progress|done|error
-    Progress  Progress          // optional
-    Meta      map[string]any    // url, title, model, cost tokens, etc.
-    Err       string            // user-safe error
-    At        time.Time
+    SessionID string         `json:"session_id"`
+    TaskID    string         `json:"task_id,omitempty"`
+    Phase     Phase          `json:"phase"`
+    Type      string         `json:"type"`               // queued|started|progress|done|error, or a TaskState for PhaseTask
+    Progress  Progress       `json:"progress"`           // optional
+    Meta      map[string]any `json:"meta,omitempty"`     // url, title, model, cost tokens, etc.
+    Err       string         `json:"error,omitempty"`    // user-safe error
+    At        time.Time      `json:"at"`
+    Replayed  bool           `json:"replayed,omitempty"` // re-published from the event log
 }
 
-// EventBus is a minimal pub/sub for UI updates.
+// EventBus is a pub/sub hub for UI updates. Every subscriber gets its own
+// queue, so a slow pane or background consumer never blocks publishers or
+// other subscribers.
 type EventBus interface {
     Publish(ctx context.Context, e Event)
-    Subscribe(ctx context.Context, sessionID string) (<-chan Event, func())
+    // Subscribe delivers events for sessionID ("" for all sessions) until the
+    // returned cancel func is called or ctx is done; the channel is then closed.
+    Subscribe(ctx context.Context, sessionID string, opts ...SubscribeOption) (<-chan Event, func())
 }
 
-// memoryBus is a minimal in-memory implementation suitable for MVP/testing.
-type memoryBus struct{
-    ch chan Event
+// OverflowPolicy decides what a full subscriber queue gives up.
+type OverflowPolicy int
+
+const (
+    // DropOldest discards the oldest queued event to make room.
+    DropOldest OverflowPolicy = iota
+    // CoalesceProgress first collapses queued progress events for the same
+    // task and phase to the newest one, then drops the oldest if still full.
+    CoalesceProgress
+)
+
+type subscribeConfig struct {
+    buffer   int
+    phases   map[Phase]bool
+    overflow OverflowPolicy
 }
 
+// SubscribeOption customises a single subscription.
+type SubscribeOption func(*subscribeConfig)
+
+// WithBuffer sets how many undelivered events the subscription queues.
+func WithBuffer(n int) SubscribeOption { return func(c *subscribeConfig) { c.buffer = n } }
+
+// WithPhases limits delivery to the given phases.
+func WithPhases(phases ...Phase) SubscribeOption {
+    return func(c *subscribeConfig) {
+        c.phases = map[Phase]bool{}
+        for _, p := range phases { c.phases[p] = true }
+    }
+}
+
+// WithOverflow selects the policy applied when the queue is full.
+func WithOverflow(p OverflowPolicy) SubscribeOption { return func(c *subscribeConfig) { c.overflow = p } }
+
+// memoryBus is an in-process EventBus with per-subscriber fan-out.
+type memoryBus struct {
+    buffer int
+
+    mu   sync.RWMutex
+    subs map[*subscriber]struct{}
+}
+
+// NewMemoryBus returns a bus whose subscriptions queue buffer events by default.
 func NewMemoryBus(buffer int) EventBus {
-    return &memoryBus{ch: make(chan Event, buffer)}
+    if buffer < 1 { buffer = 1 }
+    return &memoryBus{buffer: buffer, subs: map[*subscriber]struct{}{}}
+}
+
+func (b *memoryBus) Publish(_ context.Context, e Event) {
+    b.mu.RLock()
+    defer b.mu.RUnlock()
+    for s := range b.subs {
+        if s.matches(e) { s.offer(e) }
+    }
 }
 
-func (b *memoryBus) Publish(_ context.Context, e Event) { b.ch <- e }
-func (b *memoryBus) Subscribe(_ context.Context, _ string) (<-chan Event, func()) {
-    return b.ch, func() {}
+func (b *memoryBus) Subscribe(ctx context.Context, sessionID string, opts ...SubscribeOption) (<-chan Event, func()) {
+    cfg := subscribeConfig{buffer: b.buffer}
+    for _, opt := range opts { opt(&cfg) }
+    if cfg.buffer < 1 { cfg.buffer = 1 }
+    s := &subscriber{
+        sessionID: sessionID,
+        phases:    cfg.phases,
+        overflow:  cfg.overflow,
+        limit:     cfg.buffer,
+        out:       make(chan Event),
+        notify:    make(chan struct{}, 1),
+        done:      make(chan struct{}),
+    }
+    b.mu.Lock()
+    b.subs[s] = struct{}{}
+    b.mu.Unlock()
+
+    var once sync.Once
+    cancel := func() {
+        once.Do(func() {
+            b.mu.Lock()
+            delete(b.subs, s)
+            b.mu.Unlock()
+            close(s.done)
+        })
+    }
+    go s.pump()
+    if ctx.Done() != nil {
+        go func() {
+            select {
+            case <-ctx.Done():
+                cancel()
+            case <-s.done:
+            }
+        }()
+    }
+    return s.out, cancel
 }
 
+type subscriber struct {
+    sessionID string
+    phases    map[Phase]bool
+    overflow  OverflowPolicy
+    limit     int
+
+    out    chan Event
+    notify chan struct{}
+    done   chan struct{}
+
+    mu    sync.Mutex
+    queue []Event
+}
+
+func (s *subscriber) matches(e Event) bool {
+    if s.sessionID != "" && e.SessionID != s.sessionID { return false }
+    return len(s.phases) == 0 || s.phases[e.Phase]
+}
+
+// offer queues e without blocking, applying the overflow policy when full.
+func (s *subscriber) offer(e Event) {
+    s.mu.Lock()
+    s.queue = append(s.queue, e)
+    if len(s.queue) > s.limit && s.overflow == CoalesceProgress { s.queue = coalesceProgress(s.queue) }
+    if over := len(s.queue) - s.limit; over > 0 { s.queue = append(s.queue[:0], s.queue[over:]...) }
+    s.mu.Unlock()
+    select {
+    case s.notify <- struct{}{}:
+    default:
+    }
+}
+
+// pump moves queued events to the out channel until unsubscribed.
+func (s *subscriber) pump() {
+    defer close(s.out)
+    for {
+        s.mu.Lock()
+        if len(s.queue) == 0 {
+            s.mu.Unlock()
+            select {
+            case <-s.notify:
+                continue
+            case <-s.done:
+                return
+            }
+        }
+        e := s.queue[0]
+        s.queue = s.queue[1:]
+        s.mu.Unlock()
+        select {
+        case s.out <- e:
+        case <-s.done:
+            return
+        }
+    }
+}
+
+// coalesceProgress keeps only the newest progress event per task and phase,
+// preserving the order of everything else.
+func coalesceProgress(q []Event) []Event {
+    type key struct {
+        task  string
+        phase Phase
+    }
+    seen := map[key]bool{}
+    keep := make([]bool, len(q))
+    for i := len(q) - 1; i >= 0; i-- {
+        if q[i].Type != "progress" { keep[i] = true; continue }
+        k := key{q[i].TaskID, q[i].Phase}
+        keep[i] = !seen[k]
+        seen[k] = true
+    }
+    out := q[:0]
+    for i, e := range q {
+        if keep[i] { out = append(out, e) }
+    }
+    return out
+}
@@ -0,0 +1,225 @@
+package agent
+
+import (
+    "context"
+    "fmt"
+    "os"
+    "sync"
+    "testing"
+    "time"
+
+    "gotcha/internal/platform"
+)
+
+// drain collects events until none arrive for a short quiet period.
+func drain(ch <-chan Event) []Event {
+    var out []Event
+    for {
+        select {
+        case e, ok := <-ch:
+            if !ok { return out }
+            out = append(out, e)
+        case <-time.After(50 * time.Millisecond):
+            return out
+        }
+    }
+}
+
+func TestBusFanOut(t *testing.T) {
+    bus := NewMemoryBus(16)
+    a, cancelA := bus.Subscribe(context.Background(), "")
+    defer cancelA()
+    b, cancelB := bus.Subscribe(context.Background(), "")
+    defer cancelB()
+
+    for i := 0; i < 3; i++ {
+        bus.Publish(context.Background(), Event{SessionID: "s1", Type: fmt.Sprint(i)})
+    }
+    for name, ch := range map[string]<-chan Event{"a": a, "b": b} {
+        got := drain(ch)
+        if len(got) != 3 { t.Fatalf("subscriber %s got %d events, want 3", name, len(got)) }
+        for i, e := range got {
+            if e.Type != fmt.Sprint(i) { t.Errorf("subscriber %s event %d = %q, want %q", name, i, e.Type, fmt.Sprint(i)) }
+        }
+    }
+}
+
+func TestBusFilters(t *testing.T) {
+    bus := NewMemoryBus(16)
+    s1, cancel1 := bus.Subscribe(context.Background(), "s1")
+    defer cancel1()
+    tasks, cancel2 := bus.Subscribe(context.Background(), "", WithPhases(PhaseTask))
+    defer cancel2()
+
+    bus.Publish(context.Background(), Event{SessionID: "s1", Phase: PhaseCompose})
+    bus.Publish(context.Background(), Event{SessionID: "s2", Phase: PhaseCompose})
+    bus.Publish(context.Background(), Event{SessionID: "s2", Phase: PhaseTask})
+
+    if got := drain(s1); len(got) != 1 || got[0].SessionID != "s1" {
+        t.Errorf("session filter delivered %+v", got)
+    }
+    if got := drain(tasks); len(got) != 1 || got[0].Phase != PhaseTask {
+        t.Errorf("phase filter delivered %+v", got)
+    }
+}
+
+func TestBusDropOldestDoesNotBlockPublisher(t *testing.T) {
+    bus := NewMemoryBus(2)
+    ch, cancel := bus.Subscribe(context.Background(), "")
+    defer cancel()
+
+    done := make(chan struct{})
+    go func() {
+        for i := 0; i < 100; i++ { bus.Publish(context.Background(), Event{Type: fmt.Sprint(i)}) }
+        close(done)
+    }()
+    select {
+    case <-done:
+    case <-time.After(time.Second):
+        t.Fatal("Publish blocked on a full subscriber")
+    }
+
+    got := drain(ch)
+    if len(got) == 0 || len(got) > 3 { t.Fatalf("got %d events, want 1..3", len(got)) }
+    if last := got[len(got)-1].Type; last != "99" { t.Errorf("last event = %q, want newest (99)", last) }
+}
+
+func TestBusCoalesceProgress(t *testing.T) {
+    q := []Event{
+        {TaskID: "t1", Phase: PhaseCompose, Type: "started"},
+        {TaskID: "t1", Phase: PhaseCompose, Type: "progress", Progress: Progress{Done: 1}},
+        {TaskID: "t2", Phase: PhaseCompose, Type: "progress", Progress: Progress{Done: 1}},
+        {TaskID: "t1", Phase: PhaseCompose, Type: "progress", Progress: Progress{Done: 2}},
+        {TaskID: "t1", Phase: PhaseCompose, Type: "done"},
+    }
+    got := coalesceProgress(q)
+    want := []string{"started", "t2:1", "t1:2", "done"}
+    if len(got) != len(want) { t.Fatalf("got %d events, want %d: %+v", len(got), len(want), got) }
+    for i, e := range got {
+        label := e.Type
+        if e.Type == "progress" { label = fmt.Sprintf("%s:%d", e.TaskID, e.Progress.Done) }
+        if label != want[i] { t.Errorf("event %d = %s, want %s", i, label, want[i]) }
+    }
+}
+
+func TestBusCoalesceKeepsLifecycleEvents(t *testing.T) {
+    bus := NewMemoryBus(4)
+    ch, cancel := bus.Subscribe(context.Background(), "", WithOverflow(CoalesceProgress))
+    defer cancel()
+
+    bus.Publish(context.Background(), Event{TaskID: "t1", Phase: PhaseTask, Type: "running"})
+    for i := 1; i <= 50; i++ {
+        bus.Publish(context.Background(), Event{TaskID: "t1", Phase: PhaseCompose, Type: "progress", Progress: Progress{Done: i}})
+    }
+    bus.Publish(context.Background(), Event{TaskID: "t1", Phase: PhaseTask, Type: "done"})
+
+    got := drain(ch)
+    var sawRunning, sawDone bool
+    last := 0
+    for _, e := range got {
+        switch e.Type {
+        case "running":
+            sawRunning = true
+        case "done":
+            sawDone = true
+        case "progress":
+            if e.Progress.Done <= last { t.Errorf("progress went backwards: %d after %d", e.Progress.Done, last) }
+            last = e.Progress.Done
+        }
+    }
+    if !sawRunning || !sawDone { t.Errorf("lifecycle events lost: %+v", got) }
+    if last != 50 { t.Errorf("latest progress = %d, want 50", last) }
+}
+
+func TestBusUnsubscribe(t *testing.T) {
+    bus := NewMemoryBus(4)
+    ch, cancel := bus.Subscribe(context.Background(), "")
+    cancel()
+    cancel() // idempotent
+    bus.Publish(context.Background(), Event{Type: "after"})
+    select {
+    case _, ok := <-ch:
+        if ok { t.Fatal("received an event after unsubscribe") }
+    case <-time.After(time.Second):
+        t.Fatal("channel not closed after unsubscribe")
+    }
+
+    ctx, stop := context.WithCancel(context.Background())
+    ch, _ = bus.Subscribe(ctx, "")
+    stop()
+    select {
+    case _, ok := <-ch:
+        if ok { t.Fatal("received an event after context cancel") }
+    case <-time.After(time.Second):
+        t.Fatal("channel not closed after context cancel")
+    }
+}
+
+// TestBusConcurrent exercises publish, subscribe and unsubscribe together;
+// run with -race.
+func TestBusConcurrent(t *testing.T) {
+    bus := NewMemoryBus(8)
+    var wg sync.WaitGroup
+    for p := 0; p < 4; p++ {
+        wg.Add(1)
+        go func(p int) {
+            defer wg.Done()
+            for i := 0; i < 200; i++ {
+                bus.Publish(context.Background(), Event{SessionID: fmt.Sprintf("s%d", p%2), TaskID: "t", Phase: PhaseCompose, Type: "progress"})
+            }
+        }(p)
+    }
+    for c := 0; c < 8; c++ {
+        wg.Add(1)
+        go func(c int) {
+            defer wg.Done()
+            for i := 0; i < 20; i++ {
+                opts := []SubscribeOption{WithBuffer(c + 1)}
+                if c%2 == 0 { opts = append(opts, WithOverflow(CoalesceProgress)) }
+                ch, cancel := bus.Subscribe(context.Background(), fmt.Sprintf("s%d", c%3), opts...)
+                select {
+                case <-ch:
+                case <-time.After(time.Millisecond):
+                }
+                cancel()
+            }
+        }(c)
+    }
+    wg.Wait()
+}
+
+func TestLoggedBusReportsAppendFailureOnce(t *testing.T) {
+    paths := platform.Paths{Base: t.TempDir()}
+    if err := os.MkdirAll(paths.SessionsDir(), 0o755); err != nil { t.Fatal(err) }
+    // A file where the session directory should be makes every append fail.
+    if err := os.WriteFile(paths.SessionDir("broken"), nil, 0o644); err != nil { t.Fatal(err) }
+    log := NewEventLog(paths)
+    bus := log.Wrap(NewMemoryBus(16))
+    ch, cancel := bus.Subscribe(context.Background(), "")
+    defer cancel()
+
+    for i := 0; i < 3; i++ {
+        bus.Publish(context.Background(), Event{SessionID: "broken", Phase: PhaseSearch, Type: "progress"})
+    }
+    var errs int
+    for _, e := range drain(ch) {
+        if e.Phase == PhaseLog && e.Type == "error" && e.Err != "" { errs++ }
+    }
+    if errs != 1 { t.Errorf("got %d log error events, want 1", errs) }
+}
+
+func TestEventLogDropsEventsForDeletedSession(t *testing.T) {
+    paths := platform.Paths{Base: t.TempDir()}
+    if _, err := paths.EnsureSession("s1"); err != nil { t.Fatal(err) }
+    log := NewEventLog(paths)
+    if err := log.Append(Event{SessionID: "s1", Phase: PhaseSearch, Type: "started"}); err != nil { t.Fatal(err) }
+    if events, _ := log.Read("s1"); len(events) != 1 { t.Fatalf("log holds %d events, want 1", len(events)) }
+
+    // A task still reporting after its session was deleted, or one for a
+    // session that never had a directory.
+    if err := os.RemoveAll(paths.SessionDir("s1")); err != nil { t.Fatal(err) }
+    for _, id := range []string{"s1", "s2"} {
+        if err := log.Append(Event{SessionID: id, Phase: PhaseSearch, Type: "done"}); err != nil { t.Errorf("%s: %v", id, err) }
+        if _, err := os.Stat(paths.SessionDir(id)); !os.IsNotExist(err) { t.Errorf("session %s directory exists after an append: %v", id, err) }
+    }
+}
@@ -0,0 +1,71 @@
+package agent
+
+import (
+    "fmt"
+    "math"
+    "sort"
+    "strings"
+    "unicode"
+
+    "gotcha/internal/search"
+)
+
+const (
+    passageChars       = 900 // target passage length when chunking sources
+    maxSectionPassages = 8   // passages handed to the writer per section
+)
+
+// passage is an excerpt of a source; Source is the index into Journal.Sources.
+type passage struct {
+    Source int
+    Text   string
+    Score  float64
+}
+
+// selectPassages splits sources into paragraph-sized chunks and returns the
+// max chunks sharing the most terms with query, best first. weights, when
+// given, scale each source's passages by its credibility.
+func selectPassages(docs []search.Document, weights []float64, query string, max int) []passage {
+    q := terms(query)
+    if len(q) == 0 || len(docs) == 0 { return nil }
+    var out []passage
+    for i, d := range docs {
+        for _, c := range search.ChunkText(d.Text, passageChars) {
+            tf := terms(c)
+            score := 0.0
+            for t := range q {
+                if n := tf[t]; n > 0 { score += 1 + math.Log(float64(n)) }
+            }
+            if i < len(weights) { score *= weights[i] }
+            if score > 0 { out = append(out, passage{Source: i, Text: c, Score: score}) }
+        }
+    }
+    sort.SliceStable(out, func(a, b int) bool { return out[a].Score > out[b].Score })
+    if len(out) > max { out = out[:max] }
+    return out
+}
+
+// formatPassages renders passages for a prompt, labelled with 1-based source numbers.
+func formatPassages(ps []passage) string {
+    var b strings.Builder
+    for _, p := range ps { fmt.Fprintf(&b, "[%d] %s\n\n", p.Source+1, p.Text) }
+    return strings.TrimSpace(b.String())
+}
+
+var stopwords = map[string]bool{
+    "the": true, "and": true, "for": true, "with": true, "that": true, "this": true, "are": true, "was": true,
+    "from": true, "what": true, "which": true, "how": true, "why": true, "who": true, "when": true, "into": true,
+    "their": true, "they": true, "them": true, "its": true, "has": true, "have": true, "had": true, "not": true,
+    "but": true, "all": true, "any": true, "can": true, "will": true, "would": true, "should": true, "about": true,
+    "explain": true, "list": true, "key": true, "main": true, "summarize": true, "section": true,
+}
+
+// terms returns lower-cased word counts, ignoring short words and stopwords.
+func terms(s string) map[string]int {
+    out := map[string]int{}
+    for _, w := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+        if len(w) < 3 || stopwords[w] { continue }
+        out[w]++
+    }
+    return out
+}
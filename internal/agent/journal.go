@@ -0,0 +1,200 @@
+package agent
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "time"
+
+    "gotcha/internal/platform"
+    "gotcha/internal/search"
+)
+
+// RunStatus records how far a research run got.
+type RunStatus string
+
+const (
+    RunInProgress RunStatus = "running"
+    RunComplete   RunStatus = "complete"
+    RunFailed     RunStatus = "failed"
+    RunCancelled  RunStatus = "cancelled"
+)
+
+// ErrRunLive is returned when resuming a run that is still executing, here or
+// in another process.
+var ErrRunLive = errors.New("run is still running")
+
+// Journal is the checkpoint of a research run, rewritten after every phase and
+// section so a crashed or failed run can resume without redoing paid work.
+type Journal struct {
+    RunID     string     `json:"run_id"`
+    SessionID string     `json:"session_id"`
+    Prompt    string     `json:"prompt"`
+    Options   RunOptions `json:"options"`
+    Status    RunStatus  `json:"status"`
+    Err       string     `json:"error,omitempty"`
+    Plan      *Plan      `json:"plan,omitempty"`
+    // PlanApproved is set once the user (or auto-approve) accepted the outline.
+    PlanApproved bool `json:"plan_approved,omitempty"`
+    // Clarified is set once clarifying questions were asked and answered,
+    // reused from an earlier run, or skipped.
+    Clarified      bool            `json:"clarified,omitempty"`
+    Clarifications []Clarification `json:"clarifications,omitempty"`
+
+    // Research loop state
+    Pending       []string          `json:"pending_queries,omitempty"` // queries for the next round
+    Rounds        []SearchRound     `json:"rounds,omitempty"`
+    Sources       []search.Document `json:"sources,omitempty"`
+    // Duplicates counts fetched documents collapsed into another source.
+    Duplicates    int               `json:"duplicates,omitempty"`
+    // Scores rates Sources for credibility and freshness, index for index.
+    Scores        []SourceScore     `json:"source_scores,omitempty"`
+    ResearchDone  bool              `json:"research_done,omitempty"`
+    ResearchTime  time.Duration     `json:"research_time,omitempty"`
+    StopReason    string            `json:"stop_reason,omitempty"`
+    // Exhausted names the budget limit that cut the run short, if any.
+    Exhausted     string            `json:"budget_exhausted,omitempty"`
+    OpenQuestions []string          `json:"open_questions,omitempty"`
+    // SectionSources lists, by plan index, the sources a section's own
+    // queries found that pass its filters; empty means the shared pool.
+    SectionSources [][]int `json:"section_sources,omitempty"`
+    // Comparison holds the decision matrix in comparison mode.
+    Comparison *Comparison `json:"comparison,omitempty"`
+
+    // Sections holds composed Markdown by plan index; empty means pending.
+    Sections []string `json:"sections,omitempty"`
+    // Draft holds the sections as first composed when a review pass ran.
+    Draft      []string      `json:"draft,omitempty"`
+    Reviews    []ReviewRound `json:"reviews,omitempty"`
+    ReviewDone bool          `json:"review_done,omitempty"`
+    // Checks holds claim verification results by plan index.
+    Checks    []SectionCheck `json:"checks,omitempty"`
+    Usage     Usage          `json:"usage"`
+    CreatedAt time.Time      `json:"created_at"`
+    UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// SearchRound records one round of queries and what they returned.
+type SearchRound struct {
+    Iteration int             `json:"iteration"`
+    Queries   []string        `json:"queries"`
+    Results   []search.Result `json:"results"`
+    // Found maps each result URL to the queries that returned it.
+    Found map[string][]string `json:"found,omitempty"`
+    At    time.Time           `json:"at"`
+}
+
+// Usage counts what a run has consumed so far.
+type Usage struct {
+    LLMCalls         int           `json:"llm_calls"`
+    PromptTokens     int           `json:"prompt_tokens"`
+    CompletionTokens int           `json:"completion_tokens"`
+    Searches         int           `json:"searches"`
+    Fetches          int           `json:"fetches"`
+    Cost             float64       `json:"cost"` // USD, at the prices in effect when spent
+    Elapsed          time.Duration `json:"elapsed"`
+}
+
+func (u Usage) Tokens() int { return u.PromptTokens + u.CompletionTokens }
+
+// Requests counts billable calls: LLM completions, searches and fetches.
+func (u Usage) Requests() int { return u.LLMCalls + u.Searches + u.Fetches }
+
+// Add returns the sum of u and o.
+func (u Usage) Add(o Usage) Usage {
+    return Usage{
+        LLMCalls:         u.LLMCalls + o.LLMCalls,
+        PromptTokens:     u.PromptTokens + o.PromptTokens,
+        CompletionTokens: u.CompletionTokens + o.CompletionTokens,
+        Searches:         u.Searches + o.Searches,
+        Fetches:          u.Fetches + o.Fetches,
+        Cost:             u.Cost + o.Cost,
+        Elapsed:          u.Elapsed + o.Elapsed,
+    }
+}
+
+// Resumable reports whether the run stopped before completing on its own.
+func (j Journal) Resumable() bool { return j.Status == RunInProgress || j.Status == RunFailed }
+
+// Progress returns composed and planned section counts.
+func (j Journal) Progress() (done, total int) {
+    for _, s := range j.Sections { if s != "" { done++ } }
+    if j.Plan != nil { total = len(j.Plan.Sections) }
+    return done, total
+}
+
+func (j Journal) Title() string {
+    if j.Plan != nil && strings.TrimSpace(j.Plan.Title) != "" { return j.Plan.Title }
+    return fallbackTitle(j.Prompt)
+}
+
+func (r *Researcher) saveJournal(j *Journal) error {
+    j.UpdatedAt = time.Now()
+    path := r.svc.RunJournalPath(j.SessionID, j.RunID)
+    if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil { return fmt.Errorf("mkdir runs: %w", err) }
+    data, err := json.MarshalIndent(j, "", "  ")
+    if err != nil { return fmt.Errorf("marshal journal: %w", err) }
+    return platform.WriteFileAtomic(path, data)
+}
+
+// LoadJournal reads the checkpoint of a run.
+func (r *Researcher) LoadJournal(sessionID, runID string) (Journal, error) {
+    data, err := os.ReadFile(r.svc.RunJournalPath(sessionID, runID))
+    if err != nil { return Journal{}, fmt.Errorf("read journal: %w", err) }
+    var j Journal
+    if err := json.Unmarshal(data, &j); err != nil { return Journal{}, fmt.Errorf("parse journal %s: %w", runID, err) }
+    return j, nil
+}
+
+// Journals lists every run recorded for the session, oldest first.
+func (r *Researcher) Journals(sessionID string) ([]Journal, error) {
+    entries, err := os.ReadDir(r.svc.RunsDir(sessionID))
+    if err != nil {
+        if os.IsNotExist(err) { return nil, nil }
+        return nil, err
+    }
+    var out []Journal
+    for _, e := range entries {
+        if e.IsDir() || filepath.Ext(e.Name()) != ".json" { continue }
+        j, err := r.LoadJournal(sessionID, strings.TrimSuffix(e.Name(), ".json"))
+        if err != nil { continue }
+        out = append(out, j)
+    }
+    sort.Slice(out, func(i, k int) bool { return out[i].CreatedAt.Before(out[k].CreatedAt) })
+    return out, nil
+}
+
+// IncompleteRuns returns runs that stopped before finishing and are not
+// live in this or another process, i.e. candidates for ResumeRun.
+func (r *Researcher) IncompleteRuns(sessionID string) ([]Journal, error) {
+    all, err := r.Journals(sessionID)
+    if err != nil { return nil, err }
+    var out []Journal
+    for _, j := range all {
+        if !j.Resumable() || r.runLive(j.SessionID, j.RunID) { continue }
+        out = append(out, j)
+    }
+    return out, nil
+}
+
+// lockRun takes the run's lock file, held for as long as the run executes so
+// other processes (the TUI next to a CLI run) can tell it is live.
+func (r *Researcher) lockRun(sessionID, runID string) (release func(), err error) {
+    release, err = platform.TryLock(r.svc.RunLockPath(sessionID, runID))
+    if errors.Is(err, platform.ErrLocked) { return nil, fmt.Errorf("%w: %s", ErrRunLive, runID) }
+    return release, err
+}
+
+// runLive reports whether the run is queued or running here, or its lock is
+// held by another process.
+func (r *Researcher) runLive(sessionID, runID string) bool {
+    if info, ok := r.tasks.Get(runID); ok && !info.State.Terminal() { return true }
+    release, err := r.lockRun(sessionID, runID)
+    if err != nil { return errors.Is(err, ErrRunLive) }
+    release()
+    return false
+}
@@ -0,0 +1,117 @@
+package agent
+
+import (
+    "context"
+    "errors"
+    "os"
+    "reflect"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+
+    "gotcha/internal/llm"
+    "gotcha/internal/platform"
+)
+
+// funcLLM answers each request with the function's result and counts calls.
+type funcLLM struct {
+    mu    sync.Mutex
+    calls int
+    fn    func(req llm.Request, call int) (llm.Response, error)
+}
+
+func (f *funcLLM) Name() string { return "func" }
+
+func (f *funcLLM) Complete(ctx context.Context, req llm.Request, onToken llm.StreamHandler) (llm.Response, error) {
+    f.mu.Lock()
+    f.calls++
+    n := f.calls
+    f.mu.Unlock()
+    return f.fn(req, n)
+}
+
+func (f *funcLLM) Calls() int {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    return f.calls
+}
+
+// savedRun checkpoints a run of two sections with status and the first
+// section composed.
+func savedRun(t *testing.T, r *Researcher, runID string, status RunStatus, created time.Time) Journal {
+    t.Helper()
+    j := Journal{
+        RunID: runID, SessionID: "s1", Prompt: "Battery prices", Status: status, CreatedAt: created,
+        Plan:         &Plan{Title: "Battery prices", Sections: []Section{{Heading: "Past"}, {Heading: "Future"}}},
+        PlanApproved: true, Clarified: true, ResearchDone: true,
+        Sections:     []string{"Prices fell.", ""},
+        Usage:        Usage{LLMCalls: 4, PromptTokens: 1200, Cost: 0.01},
+    }
+    if err := r.saveJournal(&j); err != nil { t.Fatal(err) }
+    return j
+}
+
+func TestJournalRoundTrip(t *testing.T) {
+    r := testResearcher(t)
+    base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+    newer := savedRun(t, r, "r2", RunFailed, base.Add(time.Hour))
+    older := savedRun(t, r, "r1", RunComplete, base)
+    if err := os.WriteFile(r.svc.RunJournalPath("s1", "broken"), []byte("{"), 0o644); err != nil { t.Fatal(err) }
+
+    got, err := r.LoadJournal("s1", "r2")
+    if err != nil { t.Fatal(err) }
+    got.UpdatedAt, newer.UpdatedAt = time.Time{}, time.Time{}
+    if !reflect.DeepEqual(got, newer) { t.Errorf("loaded %+v\nsaved %+v", got, newer) }
+    if _, err := r.LoadJournal("s1", "broken"); err == nil { t.Error("loaded a truncated journal") }
+
+    all, err := r.Journals("s1")
+    if err != nil { t.Fatal(err) }
+    if len(all) != 2 || all[0].RunID != older.RunID || all[1].RunID != newer.RunID { t.Errorf("Journals listed %d runs, want r1 then r2 without the broken one", len(all)) }
+    if all, err := r.Journals("nope"); err != nil || all != nil { t.Errorf("Journals of a session without runs = %v, %v", all, err) }
+}
+
+func TestIncompleteRunsSkipsLiveRuns(t *testing.T) {
+    r := testResearcher(t)
+    base := time.Now()
+    savedRun(t, r, "done", RunComplete, base)
+    savedRun(t, r, "failed", RunFailed, base.Add(time.Second))
+    savedRun(t, r, "crashed", RunInProgress, base.Add(2*time.Second))
+    savedRun(t, r, "elsewhere", RunInProgress, base.Add(3*time.Second))
+    // Another process is still running this one.
+    release, err := platform.TryLock(r.svc.RunLockPath("s1", "elsewhere"))
+    if err != nil { t.Fatal(err) }
+    defer release()
+
+    runs, err := r.IncompleteRuns("s1")
+    if err != nil { t.Fatal(err) }
+    var ids []string
+    for _, j := range runs { ids = append(ids, j.RunID) }
+    if want := []string{"failed", "crashed"}; !reflect.DeepEqual(ids, want) { t.Errorf("incomplete runs %v, want %v", ids, want) }
+
+    if _, err := r.ResumeRun("s1", "elsewhere"); !errors.Is(err, ErrRunLive) { t.Errorf("resuming a run live elsewhere: %v", err) }
+    if _, err := r.ResumeRun("s1", "done"); err == nil { t.Error("resumed a complete run") }
+    if j, _ := r.LoadJournal("s1", "elsewhere"); j.Status != RunInProgress { t.Errorf("the live run's journal became %s", j.Status) }
+}
+
+func TestResumeRunComposesOnlyWhatIsMissing(t *testing.T) {
+    r := testResearcher(t)
+    fake := &funcLLM{fn: func(req llm.Request, call int) (llm.Response, error) { return llm.Response{Text: "Prices will keep falling.", PromptTokens: 100}, nil }}
+    r.llm = fake
+    savedRun(t, r, "r1", RunFailed, time.Now())
+
+    id, err := r.ResumeRun("s1", "r1")
+    if err != nil { t.Fatal(err) }
+    if id != "r1" { t.Errorf("resumed under ID %q", id) }
+    if _, err := r.tasks.Wait(context.Background(), id); err != nil { t.Fatal(err) }
+
+    j, err := r.LoadJournal("s1", "r1")
+    if err != nil { t.Fatal(err) }
+    if j.Status != RunComplete || j.Err != "" { t.Errorf("resumed run ended %s (%s)", j.Status, j.Err) }
+    if fake.Calls() != 1 { t.Errorf("made %d LLM calls, want one for the missing section", fake.Calls()) }
+    if j.Sections[0] != "Prices fell." || !strings.HasSuffix(j.Sections[1], "Prices will keep falling.") { t.Errorf("sections %q", j.Sections) }
+    if j.Usage.LLMCalls != 5 || j.Usage.PromptTokens != 1300 { t.Errorf("usage %+v does not carry on from the checkpoint", j.Usage) }
+    report, err := os.ReadFile(r.svc.ReportPath("s1"))
+    if err != nil { t.Fatal(err) }
+    if !strings.Contains(string(report), "Prices will keep falling.") { t.Error("the report lacks the resumed section") }
+}
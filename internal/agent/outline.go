@@ -0,0 +1,136 @@
+package agent
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "strings"
+)
+
+// ErrNoPendingOutline is returned when a run is not waiting for approval.
+var ErrNoPendingOutline = errors.New("no outline awaiting approval")
+
+// Clone returns a deep copy of p that can be edited freely.
+func (p Plan) Clone() Plan {
+    p.Sections = append([]Section(nil), p.Sections...)
+    for i := range p.Sections {
+        s := &p.Sections[i]
+        s.Queries = append([]string(nil), s.Queries...)
+        s.SourceTypes = append([]string(nil), s.SourceTypes...)
+    }
+    p.Queries = append([]string(nil), p.Queries...)
+    return p
+}
+
+// Validate reports why p cannot be composed, if it cannot.
+func (p Plan) Validate() error {
+    if strings.TrimSpace(p.Title) == "" { return errors.New("outline needs a title") }
+    if len(p.Sections) == 0 { return errors.New("outline needs at least one section") }
+    for i, s := range p.Sections {
+        if strings.TrimSpace(s.Heading) == "" { return fmt.Errorf("section %d needs a heading", i+1) }
+    }
+    return nil
+}
+
+// InsertSection adds s at index i (clamped to the section range).
+func (p *Plan) InsertSection(i int, s Section) {
+    i = max(0, min(i, len(p.Sections)))
+    p.Sections = append(p.Sections[:i], append([]Section{s}, p.Sections[i:]...)...)
+}
+
+// DeleteSection removes the section at index i.
+func (p *Plan) DeleteSection(i int) {
+    if i < 0 || i >= len(p.Sections) { return }
+    p.Sections = append(p.Sections[:i], p.Sections[i+1:]...)
+}
+
+// MoveSection moves the section at index from to index to.
+func (p *Plan) MoveSection(from, to int) {
+    if from < 0 || from >= len(p.Sections) || to < 0 || to >= len(p.Sections) || from == to { return }
+    s := p.Sections[from]
+    p.DeleteSection(from)
+    p.InsertSection(to, s)
+}
+
+// pendingOutline is a run blocked on outline approval.
+type pendingOutline struct {
+    plan     Plan
+    decision chan Plan
+}
+
+// PendingOutline returns the proposed outline of a run waiting for approval.
+func (r *Researcher) PendingOutline(runID string) (Plan, bool) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    p, ok := r.pending[runID]
+    if !ok { return Plan{}, false }
+    return p.plan.Clone(), true
+}
+
+// PendingOutlines lists the runs of a session waiting for outline approval.
+func (r *Researcher) PendingOutlines(sessionID string) []string {
+    var ids []string
+    for _, t := range r.tasks.List(sessionID) {
+        if _, ok := r.PendingOutline(t.ID); ok { ids = append(ids, t.ID) }
+    }
+    return ids
+}
+
+// ApproveOutline lets a waiting run compose p, which may be an edited copy
+// of the proposed outline.
+func (r *Researcher) ApproveOutline(runID string, p Plan) error {
+    if err := p.Validate(); err != nil { return err }
+    r.mu.Lock()
+    pend, ok := r.pending[runID]
+    if ok { delete(r.pending, runID) }
+    r.mu.Unlock()
+    if !ok { return fmt.Errorf("%w: %s", ErrNoPendingOutline, runID) }
+    pend.decision <- p
+    return nil
+}
+
+// awaitApproval blocks a run after planning until its outline is approved,
+// unless the run auto-approves. Rejecting an outline is done by cancelling
+// the task.
+func (r *Researcher) awaitApproval(ctx context.Context, st *runState) error {
+    j := st.j
+    if j.Options.AutoApprove || j.PlanApproved { return nil }
+    // Runs checkpointed before approval existed may already have sections.
+    if done, _ := j.Progress(); done > 0 { return nil }
+    pend := &pendingOutline{plan: j.Plan.Clone(), decision: make(chan Plan, 1)}
+    r.mu.Lock()
+    r.pending[j.RunID] = pend
+    r.mu.Unlock()
+    defer func() {
+        r.mu.Lock()
+        delete(r.pending, j.RunID)
+        r.mu.Unlock()
+    }()
+
+    headings := make([]string, len(j.Plan.Sections))
+    for i, s := range j.Plan.Sections { headings[i] = safeHead(s.Heading) }
+    st.publish(ctx, Event{Phase: PhaseOutline, Type: "approval", Meta: map[string]any{"title": j.Plan.Title, "sections": headings}})
+    // Waiting on the user does not hold a concurrency slot.
+    var p Plan
+    err := st.t.Idle(ctx, func() error {
+        select {
+        case p = <-pend.decision:
+            return nil
+        case <-ctx.Done():
+            return ctx.Err()
+        }
+    })
+    if err != nil { return err }
+    p.normalizeSections()
+    j.Plan = &p
+    j.Sections = make([]string, len(p.Sections))
+    // Search for the approved outline's queries, which the user may have edited.
+    if len(j.Rounds) == 0 {
+        j.Pending = p.searchQueries()
+        if len(j.Pending) == 0 { j.Pending = []string{strings.TrimSpace(j.Prompt)} }
+    }
+    j.PlanApproved = true
+    if err := st.save(); err != nil { return err }
+    st.publish(ctx, Event{Phase: PhaseOutline, Type: "approved", Meta: map[string]any{"title": p.Title, "sections": len(p.Sections)}})
+    return nil
+}
@@ -0,0 +1,111 @@
+package agent
+
+import (
+    "context"
+    "errors"
+    "os"
+    "reflect"
+    "testing"
+    "time"
+)
+
+// approvalRun runs the approval step of a planned run as a task and returns
+// the journal once the task ends, with the step's error.
+func approvalRun(t *testing.T, r *Researcher, runID string, during func(runID string)) (*Journal, error) {
+    t.Helper()
+    j := &Journal{RunID: runID, SessionID: "s1", Prompt: "Battery prices", Plan: &Plan{
+        Title:   "Battery prices",
+        Queries: []string{"battery prices"},
+        Sections: []Section{
+            {Heading: "Past", Queries: []string{"battery prices 2015"}},
+            {Heading: "Future", Queries: []string{"battery price forecast"}},
+        },
+    }}
+    j.Sections = make([]string, len(j.Plan.Sections))
+    var err error
+    r.tasks.Submit(context.Background(), runID, "s1", "Battery prices", func(ctx context.Context, task *Task) error {
+        err = r.awaitApproval(ctx, &runState{r: r, t: task, j: j, budget: r.newBudgetState(task, j)})
+        return err
+    })
+    if during != nil { during(runID) }
+    r.tasks.Wait(context.Background(), runID)
+    return j, err
+}
+
+// waitOutline polls until runID is waiting for its outline to be approved
+// and returns the proposed outline.
+func waitOutline(t *testing.T, r *Researcher, runID string) Plan {
+    t.Helper()
+    for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+        if p, ok := r.PendingOutline(runID); ok { return p }
+    }
+    t.Fatalf("run %s never proposed its outline", runID)
+    return Plan{}
+}
+
+func TestApproveOutline(t *testing.T) {
+    r := testResearcher(t)
+    var proposed Plan
+    j, err := approvalRun(t, r, "r1", func(id string) {
+        proposed = waitOutline(t, r, id)
+        if ids := r.PendingOutlines("s1"); !reflect.DeepEqual(ids, []string{id}) { t.Errorf("pending outlines %v", ids) }
+        if err := r.ApproveOutline(id, proposed); err != nil { t.Error(err) }
+    })
+    if err != nil { t.Fatal(err) }
+    if !j.PlanApproved || !reflect.DeepEqual(*j.Plan, proposed) { t.Errorf("approved %v, plan %+v", j.PlanApproved, j.Plan) }
+    if want := []string{"battery prices", "battery prices 2015", "battery price forecast"}; !reflect.DeepEqual(j.Pending, want) { t.Errorf("pending queries %q, want %q", j.Pending, want) }
+    if saved, err := r.LoadJournal("s1", "r1"); err != nil || !saved.PlanApproved { t.Errorf("checkpoint approved %v, err %v", saved.PlanApproved, err) }
+    if _, ok := r.PendingOutline("r1"); ok { t.Error("outline still pending after approval") }
+    if err := r.ApproveOutline("r1", proposed); !errors.Is(err, ErrNoPendingOutline) { t.Errorf("approving twice: %v", err) }
+
+    // Auto-approved and already approved runs do not wait.
+    j = &Journal{RunID: "r2", SessionID: "s1", Options: RunOptions{AutoApprove: true}, Plan: &proposed}
+    if err := runStep(t, r, j, func(ctx context.Context, st *runState) error { return r.awaitApproval(ctx, st) }); err != nil { t.Errorf("auto-approved run: %v", err) }
+}
+
+func TestApproveEditedOutline(t *testing.T) {
+    r := testResearcher(t)
+    j, err := approvalRun(t, r, "r1", func(id string) {
+        p := waitOutline(t, r, id)
+        // The copy is the caller's to edit.
+        p.Sections[0].Queries[0] = "lithium prices"
+        p.InsertSection(5, Section{Heading: "Risks", Queries: []string{"cobalt supply"}, Recency: " any "})
+        p.MoveSection(2, 0)
+        p.DeleteSection(2)
+        if again, _ := r.PendingOutline(id); again.Sections[0].Queries[0] != "battery prices 2015" { t.Error("editing the returned outline changed the pending one") }
+
+        bad := p.Clone()
+        bad.Sections[1].Heading = " "
+        if err := r.ApproveOutline(id, bad); err == nil { t.Error("approved a section without a heading") }
+        if _, ok := r.PendingOutline(id); !ok { t.Error("a rejected edit ended the wait") }
+        if err := r.ApproveOutline(id, p); err != nil { t.Error(err) }
+    })
+    if err != nil { t.Fatal(err) }
+    var headings []string
+    for _, s := range j.Plan.Sections { headings = append(headings, s.Heading) }
+    if want := []string{"Risks", "Past"}; !reflect.DeepEqual(headings, want) { t.Errorf("sections %q, want %q", headings, want) }
+    if j.Plan.Sections[0].Recency != "" { t.Errorf("recency %q was not normalized", j.Plan.Sections[0].Recency) }
+    if len(j.Sections) != 2 { t.Errorf("%d section slots for 2 sections", len(j.Sections)) }
+    if want := []string{"battery prices", "cobalt supply", "lithium prices"}; !reflect.DeepEqual(j.Pending, want) { t.Errorf("pending queries %q, want the edited outline's %q", j.Pending, want) }
+}
+
+func TestRejectOutline(t *testing.T) {
+    r := testResearcher(t)
+    j, err := approvalRun(t, r, "r1", func(id string) {
+        waitOutline(t, r, id)
+        r.tasks.Cancel(id)
+    })
+    if !errors.Is(err, context.Canceled) { t.Errorf("rejected step returned %v", err) }
+    if j.PlanApproved { t.Error("a rejected outline was marked approved") }
+    if _, ok := r.PendingOutline("r1"); ok { t.Error("rejected outline still pending") }
+
+    // A whole run rejected at its outline ends cancelled without a report.
+    id := r.Start("s1", "Battery prices", RunOptions{})
+    waitOutline(t, r, id)
+    r.tasks.Cancel(id)
+    r.tasks.Wait(context.Background(), id)
+    saved, err := r.LoadJournal("s1", id)
+    if err != nil { t.Fatal(err) }
+    if saved.Status != RunCancelled || saved.PlanApproved { t.Errorf("rejected run ended %s, approved %v", saved.Status, saved.PlanApproved) }
+    if _, err := os.Stat(r.svc.ReportPath("s1")); !os.IsNotExist(err) { t.Errorf("rejected run wrote a report: %v", err) }
+}
@@ -3,114 +3,496 @@ package agent
 import (
     "context"
     "encoding/json"
+    "errors"
     "fmt"
+    "path/filepath"
     "strings"
+    "sync"
     "time"
 
     "gotcha/internal/app"
+    "gotcha/internal/diff"
     "gotcha/internal/llm"
     "gotcha/internal/platform"
+    "gotcha/internal/search"
 )
 
-// Researcher coordinates a minimal research pipeline using an LLM planner and writer.
-// This MVP does not yet perform live web search; it focuses on plan+compose and
-// persists a Markdown draft to the session report path.
+// Researcher coordinates the research pipeline: an LLM planner, web search and
+// fetch rounds (optionally iterated with gap analysis), and an LLM writer that
+// persists a cited Markdown report to the session report path. Runs execute as
+// tasks on the shared TaskManager so they can be listed, paused and cancelled.
 type Researcher struct {
-    bus EventBus
-    llm llm.Client
-    svc *app.Service
+    bus   EventBus
+    llm   llm.Client
+    svc   *app.Service
+    tasks *TaskManager
+    cfg   ResearchConfig
+
+    mu      sync.Mutex
+    pending map[string]*pendingOutline   // runs waiting for outline approval
+    asking  map[string]*pendingQuestions // runs waiting for clarifying answers
+    corpus  *search.Corpus             // set with SetCorpus; replaces any configured corpus
+}
+
+// ResearchConfig wires search backends and limits into a Researcher.
+type ResearchConfig struct {
+    Providers          []search.Provider
+    Fetcher            search.Fetcher
+    PerQuery           int // results requested per query
+    MaxSources         int // documents fetched per run
+    SearchConcurrency  int
+    FetchConcurrency   int
+    ComposeConcurrency int
+    Defaults           RunOptions
+    SessionBudget      Budget
+    WarnAt             []float64 // budget fractions that trigger warnings
+    Prices             Prices
+    Sources            SourcePolicy // domain reputation lists
+    Model              string       // LLM model, recorded in report front matter
+}
+
+// NewResearchConfig builds the pipeline configuration from runtime config.
+func NewResearchConfig(cfg platform.Config) ResearchConfig {
+    return ResearchConfig{
+        Providers:          search.ProvidersFromConfig(cfg.Search, cfg.ProxyURL),
+        Fetcher:            search.NewHTTPFetcher(cfg.ProxyURL),
+        PerQuery:           cfg.Search.PerQuery,
+        MaxSources:         cfg.Search.MaxResults,
+        SearchConcurrency:  cfg.Concurrency.Search,
+        FetchConcurrency:   cfg.Concurrency.Fetch,
+        ComposeConcurrency: cfg.Concurrency.Compose,
+        Defaults: RunOptions{
+            Iterative:    cfg.Research.Iterative,
+            MaxDepth:     cfg.Research.MaxDepth,
+            MaxDuration:  cfg.Research.MaxDuration,
+            Verify:       cfg.Research.Verify,
+            ReviewRounds: cfg.Research.ReviewRounds,
+            Budget:       budgetFromConfig(cfg.Budget.Run),
+            AutoApprove:  cfg.Research.AutoApprove,
+            Template:     cfg.Research.Template,
+            Clarify:      cfg.Research.Clarify,
+        },
+        SessionBudget: budgetFromConfig(cfg.Budget.Session),
+        WarnAt:        cfg.Budget.WarnAt,
+        Prices: Prices{
+            InputPerMTok:  cfg.Budget.InputPerMTok,
+            OutputPerMTok: cfg.Budget.OutputPerMTok,
+            PerSearch:     cfg.Budget.PerSearch,
+        },
+        Sources: SourcePolicy{Allow: cfg.Search.AllowDomains, Deny: cfg.Search.DenyDomains, Boost: cfg.Search.BoostDomains},
+        Model:   cfg.LLM.Model,
+    }
 }
 
-func NewResearcher(bus EventBus, llmClient llm.Client, svc *app.Service) *Researcher {
-    return &Researcher{bus: bus, llm: llmClient, svc: svc}
+// RunOptions tune a single research run.
+type RunOptions struct {
+    // Iterative repeats search rounds, asking the model which questions remain
+    // open, until coverage is sufficient or a limit below is hit.
+    Iterative   bool          `json:"iterative,omitempty"`
+    MaxDepth    int           `json:"max_depth,omitempty"`    // search rounds
+    MaxDuration time.Duration `json:"max_duration,omitempty"` // time spent in the search loop
+    MaxTokens   int           `json:"max_tokens,omitempty"`   // LLM tokens spent before the loop stops
+    // Verify checks each section's claims against the sources before the
+    // report is written.
+    Verify bool `json:"verify,omitempty"`
+    // ReviewRounds is the number of critique and revision rounds run on the
+    // draft; zero skips the review.
+    ReviewRounds int `json:"review_rounds,omitempty"`
+    // Budget is the hard limit for this run; the session budget applies too.
+    Budget Budget `json:"budget,omitempty"`
+    // AutoApprove composes the planner's outline without waiting for the
+    // user to review it.
+    AutoApprove bool `json:"auto_approve,omitempty"`
+    // Mode is ModeReport (the default) or ModeCompare, which scores the
+    // options in the prompt against weighted criteria.
+    Mode string `json:"mode,omitempty"`
+    // Weights sets criterion weights by name in comparison mode; named
+    // criteria the planner missed are added.
+    Weights map[string]float64 `json:"weights,omitempty"`
+    // Template names the report template that shapes the outline, the
+    // writing and the layout; empty uses none.
+    Template string `json:"template,omitempty"`
+    // Clarify lets the run ask clarifying questions about an ambiguous
+    // prompt before planning. Auto-approved runs never ask.
+    Clarify bool `json:"clarify,omitempty"`
 }
 
-// Start kicks off a background planning and composition run.
-func (r *Researcher) Start(sessionID, prompt string) {
-    go r.run(sessionID, prompt)
+func NewResearcher(bus EventBus, llmClient llm.Client, svc *app.Service, tasks *TaskManager, cfg ResearchConfig) *Researcher {
+    if cfg.PerQuery < 1 { cfg.PerQuery = 5 }
+    if cfg.SearchConcurrency < 1 { cfg.SearchConcurrency = 1 }
+    if cfg.FetchConcurrency < 1 { cfg.FetchConcurrency = 1 }
+    if cfg.ComposeConcurrency < 1 { cfg.ComposeConcurrency = 1 }
+    r := &Researcher{bus: bus, llm: llmClient, svc: svc, tasks: tasks, pending: map[string]*pendingOutline{}, asking: map[string]*pendingQuestions{}}
+    // A configured corpus is held apart so SetCorpus can replace it.
+    var web []search.Provider
+    for _, p := range cfg.Providers {
+        if c, ok := p.(*search.Corpus); ok { r.corpus = c } else { web = append(web, p) }
+    }
+    cfg.Providers = web
+    r.cfg = cfg
+    return r
 }
 
-type plan struct {
+// Tasks returns the manager that runs this researcher's tasks.
+func (r *Researcher) Tasks() *TaskManager { return r.tasks }
+
+// DefaultOptions returns the configured run options.
+func (r *Researcher) DefaultOptions() RunOptions { return r.cfg.Defaults }
+
+// Start queues a background research run and returns its task ID.
+// The task ID doubles as the run ID of the journal checkpointing the run.
+func (r *Researcher) Start(sessionID, prompt string, opts RunOptions) string {
+    return r.tasks.Submit(context.Background(), "", sessionID, fallbackTitle(prompt), func(ctx context.Context, t *Task) error {
+        j := &Journal{RunID: t.ID(), SessionID: sessionID, Prompt: prompt, Options: opts, Status: RunInProgress, CreatedAt: time.Now()}
+        return r.run(ctx, t, j)
+    })
+}
+
+// ResumeRun restarts an incomplete run from its last checkpoint under the
+// same ID, reusing its plan, sources and any sections already composed.
+func (r *Researcher) ResumeRun(sessionID, runID string) (string, error) {
+    j, err := r.LoadJournal(sessionID, runID)
+    if err != nil { return "", err }
+    if !j.Resumable() { return "", fmt.Errorf("run %s is %s", runID, j.Status) }
+    if info, ok := r.tasks.Get(runID); ok && !info.State.Terminal() { return "", fmt.Errorf("run %s is already %s", runID, info.State) }
+    if r.runLive(sessionID, runID) { return "", fmt.Errorf("%w: %s", ErrRunLive, runID) }
+    j.Status, j.Err = RunInProgress, ""
+    return r.tasks.Submit(context.Background(), runID, sessionID, j.Title(), func(ctx context.Context, t *Task) error {
+        return r.run(ctx, t, &j)
+    }), nil
+}
+
+// runState is the in-memory side of a run: the task handle plus the journal
+// it checkpoints. mu guards j while phases work concurrently.
+type runState struct {
+    r      *Researcher
+    t      *Task
+    j      *Journal
+    budget *budgetState
+    tpl    *Template // report template, if the run uses one
+    mu     sync.Mutex
+}
+
+// publish emits e with the run's budget consumption in Meta["budget"].
+func (st *runState) publish(ctx context.Context, e Event) {
+    meta := make(map[string]any, len(e.Meta)+1)
+    for k, v := range e.Meta { meta[k] = v }
+    st.mu.Lock()
+    st.budget.tick(&st.j.Usage)
+    meta["budget"] = st.budget.meta(st.j.Usage)
+    st.mu.Unlock()
+    e.Meta = meta
+    st.t.Publish(ctx, e)
+}
+
+// save checkpoints the journal.
+func (st *runState) save() error {
+    st.mu.Lock()
+    defer st.mu.Unlock()
+    st.budget.tick(&st.j.Usage)
+    return st.r.saveJournal(st.j)
+}
+
+// complete calls the LLM and records token usage and cost against the run.
+func (st *runState) complete(ctx context.Context, req llm.Request) (llm.Response, error) {
+    if err := st.spend(ctx); err != nil { return llm.Response{}, err }
+    res, err := st.r.llm.Complete(ctx, req, nil)
+    st.mu.Lock()
+    st.j.Usage.LLMCalls++
+    st.j.Usage.PromptTokens += res.PromptTokens
+    st.j.Usage.CompletionTokens += res.CompletionTokens
+    st.j.Usage.Cost += st.r.cfg.Prices.llmCost(res.PromptTokens, res.CompletionTokens)
+    st.mu.Unlock()
+    return res, err
+}
+
+// Plan is the planner's outline for a report.
+type Plan struct {
     Title    string    `json:"title"`
-    Sections []section `json:"sections"`
+    Sections []Section `json:"sections"`
+    Queries  []string  `json:"queries,omitempty"` // initial search queries
 }
-type section struct {
-    Heading      string `json:"heading"`
-    Instructions string `json:"instructions"`
+
+// Section is one planned report section.
+type Section struct {
+    Heading      string   `json:"heading"`
+    Instructions string   `json:"instructions"`
+    Queries      []string `json:"queries,omitempty"`      // searches gathering this section's evidence
+    SourceTypes  []string `json:"source_types,omitempty"` // preferred kinds of source, from SourceTypes
+    Recency      string   `json:"recency,omitempty"`      // how recent evidence must be, e.g. "past year"
 }
 
-func (r *Researcher) run(sessionID, prompt string) {
-    ctx := context.Background()
-    // Outline phase
-    r.bus.Publish(ctx, Event{SessionID: sessionID, Phase: PhaseOutline, Type: "started", At: time.Now()})
-    pl, err := r.plan(ctx, prompt)
+func (r *Researcher) run(ctx context.Context, t *Task, j *Journal) (err error) {
+    // Taken before the deferred save so a run live elsewhere keeps its journal.
+    release, err := r.lockRun(j.SessionID, j.RunID)
+    if err != nil { return err }
+    defer release()
+    st := &runState{r: r, t: t, j: j, budget: r.newBudgetState(t, j)}
+    defer func() {
+        switch {
+        case err == nil:
+            j.Status = RunComplete
+        case ctx.Err() != nil:
+            j.Status = RunCancelled
+        default:
+            j.Status, j.Err = RunFailed, err.Error()
+        }
+        if serr := st.save(); serr != nil && err == nil { err = serr }
+    }()
+    prompt := j.Prompt
+    if st.tpl, err = r.runTemplate(j); err != nil { return err }
+
+    // Outline phase, after any clarifying questions
+    if j.Plan == nil {
+        if err := r.clarify(ctx, st); err != nil { return err }
+        st.publish(ctx, Event{Phase: PhaseOutline, Type: "started"})
+        var (
+            pl  Plan
+            cmp *Comparison
+        )
+        if j.Options.Mode == ModeCompare {
+            pl, cmp, err = r.planComparison(ctx, st, prompt)
+        } else {
+            pl, err = r.plan(ctx, st, prompt)
+        }
+        if err == nil && st.tpl != nil { pl = st.tpl.apply(pl) }
+        if err != nil {
+            st.publish(ctx, Event{Phase: PhaseOutline, Type: "error", Err: err.Error()})
+            return err
+        }
+        j.Plan, j.Comparison = &pl, cmp
+        j.Sections = make([]string, len(pl.Sections))
+        j.Pending = pl.searchQueries()
+        if len(j.Pending) == 0 { j.Pending = []string{strings.TrimSpace(prompt)} }
+        if err := st.save(); err != nil { return err }
+        st.publish(ctx, Event{Phase: PhaseOutline, Type: "done", Meta: map[string]any{"title": pl.Title, "sections": len(pl.Sections)}})
+    } else {
+        st.publish(ctx, Event{Phase: PhaseOutline, Type: "done", Meta: map[string]any{"title": j.Plan.Title, "sections": len(j.Plan.Sections), "resumed": true}})
+    }
+    if err := r.awaitApproval(ctx, st); err != nil { return err }
+    if len(j.Sections) != len(j.Plan.Sections) { j.Sections = make([]string, len(j.Plan.Sections)) }
+
+    if err := r.phases(ctx, st); err != nil {
+        var be *BudgetError
+        if !errors.As(err, &be) || ctx.Err() != nil { return err }
+        // Out of budget: write what we have as a partial report.
+        j.Exhausted = be.Reason()
+        st.publish(ctx, Event{Phase: PhaseBudget, Type: "exhausted", Err: be.Error(), Meta: map[string]any{"scope": be.Scope, "limit": be.Limit, "of": be.Of}})
+    }
+    meta, err := r.writeReport(j)
     if err != nil {
-        r.bus.Publish(ctx, Event{SessionID: sessionID, Phase: PhaseOutline, Type: "error", Err: err.Error(), At: time.Now()})
-        return
+        st.publish(ctx, Event{Phase: PhaseCompose, Type: "error", Err: err.Error()})
+        return err
     }
-    r.bus.Publish(ctx, Event{SessionID: sessionID, Phase: PhaseOutline, Type: "done", At: time.Now(), Meta: map[string]any{"title": pl.Title, "sections": len(pl.Sections)}})
+    st.publish(ctx, Event{Phase: PhaseCompose, Type: "done", Meta: meta})
+    return nil
+}
+
+// writeReport renders the journal to the session's report.md, kept as a new
+// numbered revision, plus the pre-review draft and its diff when a review ran
+// and the matrix CSV in comparison mode. It returns the written paths for
+// event metadata.
+func (r *Researcher) writeReport(j *Journal) (map[string]any, error) {
+    at := time.Now()
+    doc := r.assembleMarkdown(j, j.Sections, j.Checks, at)
+    rev, err := r.saveRevision(j.SessionID, doc)
+    if err != nil { return nil, err }
+    path := r.svc.ReportPath(j.SessionID)
+    meta := map[string]any{"path": path, "revision": rev.N, "revision_path": rev.Path}
+    if j.Draft != nil {
+        // Keep the pre-review draft and what the review changed next to the report.
+        draft := r.assembleMarkdown(j, j.Draft, nil, at)
+        draftPath, diffPath := r.svc.DraftPath(j.SessionID), r.svc.ReportDiffPath(j.SessionID)
+        if err := platform.WriteFileAtomic(draftPath, []byte(draft)); err != nil { return nil, err }
+        // Diff without verification flags so it shows only the review's edits.
+        patch := diff.Unified(filepath.Base(draftPath), filepath.Base(path), draft, r.assembleMarkdown(j, j.Sections, nil, at), 3)
+        if err := platform.WriteFileAtomic(diffPath, []byte(patch)); err != nil { return nil, err }
+        meta["draft"], meta["diff"] = draftPath, diffPath
+    }
+    if j.Comparison != nil {
+        csvPath := r.svc.ComparisonCSVPath(j.SessionID)
+        if err := platform.WriteFileAtomic(csvPath, j.Comparison.CSV(j.Sources)); err != nil { return nil, err }
+        meta["csv"] = csvPath
+    }
+    return meta, nil
+}
+
+// phases runs everything between the outline and the written report.
+func (r *Researcher) phases(ctx context.Context, st *runState) error {
+    // Search, fetch and (in iterative mode) gap analysis rounds
+    if err := r.research(ctx, st); err != nil { return err }
+
+    // Credibility and freshness scores, used to rank evidence from here on
+    if err := r.scoreSources(ctx, st); err != nil { return err }
+
+    // Comparison matrix, which the sections then discuss
+    if err := r.fillMatrix(ctx, st); err != nil { return err }
 
     // Compose phase
-    total := len(pl.Sections)
-    if total == 0 { total = 1 }
-    r.bus.Publish(ctx, Event{SessionID: sessionID, Phase: PhaseCompose, Type: "started", At: time.Now(), Progress: Progress{Done: 0, Total: total}})
-    var parts []string
-    for i, s := range pl.Sections {
-        txt, werr := r.writeSection(ctx, prompt, pl.Title, s)
-        if werr != nil { txt = fmt.Sprintf("## %s\n\n(Unable to compose section: %v)\n", safeHead(s.Heading), werr) }
-        parts = append(parts, txt)
-        r.bus.Publish(ctx, Event{SessionID: sessionID, Phase: PhaseCompose, Type: "progress", At: time.Now(), Progress: Progress{Done: i+1, Total: total}})
-    }
-    doc := r.assembleMarkdown(pl.Title, parts)
-    // Persist
-    path := r.svc.ReportPath(sessionID)
-    if err := platform.WriteFileAtomic(path, []byte(doc)); err != nil {
-        r.bus.Publish(ctx, Event{SessionID: sessionID, Phase: PhaseCompose, Type: "error", Err: err.Error(), At: time.Now()})
-        return
-    }
-    r.bus.Publish(ctx, Event{SessionID: sessionID, Phase: PhaseCompose, Type: "done", At: time.Now(), Meta: map[string]any{"path": path}})
-}
-
-func (r *Researcher) plan(ctx context.Context, userPrompt string) (plan, error) {
+    if err := r.compose(ctx, st); err != nil { return err }
+
+    // Review phase
+    if err := r.review(ctx, st); err != nil { return err }
+
+    // Verify phase
+    if st.j.Options.Verify {
+        if err := r.verify(ctx, st); err != nil { return err }
+    }
+    return nil
+}
+
+// Attempts per section before the run fails; the journal keeps every section
+// already written so a resumed run only redoes the failures.
+const composeAttempts = 3
+
+// composeBackoff is the pause after a first failed attempt, growing with each
+// further one. Tests shorten it.
+var composeBackoff = 2 * time.Second
+
+// compose writes the pending sections concurrently, bounded by the compose
+// concurrency. Sections land in plan order in the journal regardless of the
+// order they finish in.
+func (r *Researcher) compose(ctx context.Context, st *runState) error {
+    j := st.j
+    total := len(j.Plan.Sections)
+    done, _ := j.Progress()
+    if cmp := j.Comparison; cmp != nil && cmp.Composed == nil { cmp.Composed = cmp.weights() }
+    j.attachSectionSources()
+    st.publish(ctx, Event{Phase: PhaseCompose, Type: "started", Progress: Progress{Done: done, Total: total}})
+
+    var (
+        wg       sync.WaitGroup
+        errMu    sync.Mutex
+        firstErr error
+    )
+    sem := make(chan struct{}, r.cfg.ComposeConcurrency)
+    for i, s := range j.Plan.Sections {
+        if j.Sections[i] != "" { continue }
+        wg.Add(1)
+        go func(i int, s Section) {
+            defer wg.Done()
+            select {
+            case sem <- struct{}{}:
+            case <-ctx.Done():
+                return
+            }
+            defer func() { <-sem }()
+            txt, err := r.composeSection(ctx, st, i, s)
+            if err != nil {
+                errMu.Lock()
+                if firstErr == nil { firstErr = err }
+                errMu.Unlock()
+                return
+            }
+            st.mu.Lock()
+            j.Sections[i] = txt
+            done++
+            n := done
+            st.mu.Unlock()
+            if err := st.save(); err != nil {
+                errMu.Lock()
+                if firstErr == nil { firstErr = err }
+                errMu.Unlock()
+            }
+            st.publish(ctx, Event{Phase: PhaseCompose, Type: "progress", Progress: Progress{Done: n, Total: total}, Meta: map[string]any{"section": safeHead(s.Heading)}})
+        }(i, s)
+    }
+    wg.Wait()
+    if err := ctx.Err(); err != nil { return err }
+    if firstErr != nil {
+        st.publish(ctx, Event{Phase: PhaseCompose, Type: "error", Progress: Progress{Done: done, Total: total}, Err: firstErr.Error()})
+        return firstErr
+    }
+    return nil
+}
+
+// composeSection writes one section, retrying transient failures with a
+// growing pause between attempts.
+func (r *Researcher) composeSection(ctx context.Context, st *runState, index int, s Section) (string, error) {
+    meta := func(attempt int) map[string]any {
+        return map[string]any{"section": safeHead(s.Heading), "index": index, "attempt": attempt}
+    }
+    var err error
+    for attempt := 1; attempt <= composeAttempts; attempt++ {
+        if err := st.t.Checkpoint(ctx); err != nil { return "", err }
+        start := time.Now()
+        st.publish(ctx, Event{Phase: PhaseSection, Type: "started", Meta: meta(attempt)})
+        var txt string
+        txt, err = r.writeSection(ctx, st, index, s)
+        if err == nil {
+            st.publish(ctx, Event{Phase: PhaseSection, Type: "done", Progress: Progress{Elapsed: time.Since(start)}, Meta: meta(attempt)})
+            return txt, nil
+        }
+        if ctx.Err() != nil { return "", ctx.Err() }
+        st.publish(ctx, Event{Phase: PhaseSection, Type: "error", Err: err.Error(), Meta: meta(attempt)})
+        if errors.Is(err, ErrBudgetExhausted) { return "", err }
+        if attempt == composeAttempts { break }
+        select {
+        case <-time.After(time.Duration(attempt) * composeBackoff):
+        case <-ctx.Done():
+            return "", ctx.Err()
+        }
+    }
+    return "", fmt.Errorf("section %q failed after %d attempts: %w", safeHead(s.Heading), composeAttempts, err)
+}
+
+func (r *Researcher) plan(ctx context.Context, st *runState, userPrompt string) (Plan, error) {
     // If no LLM configured, return a deterministic fallback plan.
     if r.llm == nil {
-        return plan{
-            Title:   fallbackTitle(userPrompt),
-            Sections: []section{
-                {Heading: "Overview", Instructions: "Explain key concepts, context, and relevance."},
-                {Heading: "Key Points", Instructions: "List and explain main findings or considerations."},
-                {Heading: "Conclusion", Instructions: "Summarize takeaways and next steps."},
-            },
-        }, nil
+        if st.tpl != nil { return Plan{Title: fallbackTitle(userPrompt), Sections: append([]Section(nil), st.tpl.Sections...)}, nil }
+        return fallbackPlan(userPrompt), nil
     }
     // Strict JSON planner prompt
-    sys := "You are a meticulous research planner. Return strict JSON with fields: title (5-9 words), sections (array of {heading, instructions}). No extra text."
+    sys := "You are a meticulous research planner. Return strict JSON with fields: title (5-9 words), sections (array of {heading, instructions, queries, source_types, recency}), queries (up to 3 web search queries for background that serves every section). For each section give queries (1-3 web search queries that would gather its evidence), source_types (preferred kinds of source from: " + strings.Join(SourceTypes, ", ") + ") and recency (how recent its evidence must be, e.g. \"past year\", \"5 years\" or \"any\"). No extra text."
     u := fmt.Sprintf("Research prompt: %s\n\nReturn JSON only.", strings.TrimSpace(userPrompt))
-    res, err := r.llm.Complete(ctx, llm.Request{System: sys, Prompt: u, MaxTokens: 600, Temperature: 0.2}, nil)
-    if err != nil { return plan{}, err }
+    if qa := st.j.clarificationBrief(); qa != "" { u = qa + u }
+    if st.tpl != nil { u = st.tpl.plannerBrief() + "\n" + u }
+    res, err := st.complete(ctx, llm.Request{System: sys, Prompt: u, MaxTokens: 1400, Temperature: 0.2})
+    if err != nil { return Plan{}, err }
     // Extract JSON from possible code fences
     raw := strings.TrimSpace(res.Text)
     raw = trimFences(raw)
-    var p plan
-    if err := json.Unmarshal([]byte(raw), &p); err != nil {
-        // Fallback simple plan
-        p = plan{Title: fallbackTitle(userPrompt), Sections: []section{{Heading: "Overview", Instructions: "Explain key concepts, context, and relevance."}, {Heading: "Key Points", Instructions: "List and explain main findings or considerations."}, {Heading: "Conclusion", Instructions: "Summarize takeaways and next steps."}}}
+    var p Plan
+    if err := json.Unmarshal([]byte(raw), &p); err != nil || len(p.Sections) == 0 {
+        p = fallbackPlan(userPrompt)
     }
+    p.normalizeSections()
     if strings.TrimSpace(p.Title) == "" { p.Title = fallbackTitle(userPrompt) }
     return p, nil
 }
 
-func (r *Researcher) writeSection(ctx context.Context, userPrompt, title string, s section) (string, error) {
+func fallbackPlan(userPrompt string) Plan {
+    return Plan{
+        Title: fallbackTitle(userPrompt),
+        Sections: []Section{
+            {Heading: "Overview", Instructions: "Explain key concepts, context, and relevance."},
+            {Heading: "Key Points", Instructions: "List and explain main findings or considerations."},
+            {Heading: "Conclusion", Instructions: "Summarize takeaways and next steps."},
+        },
+    }
+}
+
+// writeSection writes the section at index i of the plan from the evidence
+// ranked for it.
+func (r *Researcher) writeSection(ctx context.Context, st *runState, i int, s Section) (string, error) {
+    userPrompt, title := st.j.Prompt, st.j.Plan.Title
     if r.llm == nil {
         // Deterministic offline content so the app remains usable without API keys.
         body := fmt.Sprintf("## %s\n\n%s\n\n- Prompt: %s\n- Note: LLM not configured; this is a placeholder.\n",
             safeHead(s.Heading), strings.TrimSpace(s.Instructions), strings.TrimSpace(userPrompt))
         return body, nil
     }
-    sys := "You write concise, well-structured Markdown sections. No preamble, no chatty tone. Use headings provided. Cite with footnotes [^1] style only if sources are given; otherwise omit citations."
+    evidence := formatPassages(st.j.sectionPassages(i, s.Heading+" "+s.Instructions+" "+userPrompt))
+    sys := "You write concise, well-structured Markdown sections. No preamble, no chatty tone. Use headings provided. Cite sources inline as [n] using the numbers given, only for claims they support; if no sources are given, omit citations."
     prompt := fmt.Sprintf("Title: %s\nUser Prompt: %s\n\nWrite the section below as Markdown.\nHeading: %s\nInstructions: %s\n",
         strings.TrimSpace(title), strings.TrimSpace(userPrompt), safeHead(s.Heading), strings.TrimSpace(s.Instructions))
-    res, err := r.llm.Complete(ctx, llm.Request{System: sys, Prompt: prompt, MaxTokens: 800, Temperature: 0.4}, nil)
+    if cmp := st.j.Comparison; cmp != nil {
+        prompt += "\nThe report includes a comparison table; discuss it rather than repeating it. Matrix (scores 1-5):\n" + cmp.summary() + "\n"
+    }
+    if st.tpl != nil { prompt += st.tpl.writerBrief() }
+    if brief := s.sectionBrief(); brief != "" { prompt += "\n" + brief }
+    if evidence != "" { prompt += "\nSources:\n" + evidence }
+    res, err := st.complete(ctx, llm.Request{System: sys, Prompt: prompt, MaxTokens: 800, Temperature: 0.4})
     if err != nil { return "", err }
     out := strings.TrimSpace(res.Text)
     if !strings.HasPrefix(out, "#") && !strings.HasPrefix(strings.ToLower(out), fmt.Sprintf("## %s", strings.ToLower(s.Heading))) {
@@ -119,20 +501,80 @@ func (r *Researcher) writeSection(ctx context.Context, userPrompt, title string,
     return out, nil
 }
 
-func (r *Researcher) assembleMarkdown(title string, sections []string) string {
+// assembleMarkdown renders the report from sections in plan order, flagging
+// claims and adding a verification summary when checks are given.
+func (r *Researcher) assembleMarkdown(j *Journal, sections []string, checks []SectionCheck, at time.Time) string {
+    title := j.Plan.Title
     var b strings.Builder
     // front matter
     b.WriteString("---\n")
     b.WriteString("title: \""+escapeYAML(title)+"\"\n")
-    b.WriteString("generated_at: \""+time.Now().Format(time.RFC3339)+"\"\n")
+    b.WriteString("generated_at: \""+at.Format(time.RFC3339)+"\"\n")
     b.WriteString("tool: gotcha\n")
+    b.WriteString("run: "+j.RunID+"\n")
+    b.WriteString("prompt: \""+escapeYAML(strings.Join(strings.Fields(j.Prompt), " "))+"\"\n")
+    if r.llm != nil && r.cfg.Model != "" { b.WriteString("model: \""+escapeYAML(r.cfg.Model)+"\"\n") }
+    fmt.Fprintf(&b, "sources: %d\n", len(j.Sources))
+    fmt.Fprintf(&b, "tokens: %d\n", j.Usage.Tokens())
+    fmt.Fprintf(&b, "cost: %.4f\n", j.Usage.Cost)
+    tpl, _ := r.runTemplate(j)
+    if tpl != nil { b.WriteString("template: "+tpl.Name+"\n") }
     b.WriteString("---\n\n")
     b.WriteString("# "+title+"\n\n")
-    for _, s := range sections { b.WriteString(s); if !strings.HasSuffix(s, "\n") { b.WriteString("\n") }; b.WriteString("\n") }
-    // Placeholder sources block (to be populated once web research lands)
+    if tpl != nil && tpl.Contents {
+        b.WriteString("## Contents\n\n")
+        for i, s := range sections {
+            if s != "" && i < len(j.Plan.Sections) { fmt.Fprintf(&b, "- %s\n", safeHead(j.Plan.Sections[i].Heading)) }
+        }
+        b.WriteString("\n")
+    }
+    if j.Exhausted != "" {
+        var missing []string
+        for i, s := range sections {
+            if s == "" && i < len(j.Plan.Sections) { missing = append(missing, safeHead(j.Plan.Sections[i].Heading)) }
+        }
+        fmt.Fprintf(&b, "> **Partial report:** the run stopped when its %s was exhausted.", j.Exhausted)
+        if len(missing) > 0 { fmt.Fprintf(&b, " Sections not written: %s.", strings.Join(missing, ", ")) }
+        b.WriteString("\n\n")
+    }
+    reweighted := j.Comparison != nil && j.Comparison.Reweighted()
+    if j.Comparison != nil && len(j.Comparison.Cells) > 0 {
+        b.WriteString("## Comparison\n\n")
+        b.WriteString(j.Comparison.Markdown())
+        b.WriteString("\nScores run from 1 (poor) to 5 (excellent); the weighted score averages them by criterion weight.\n\n")
+        if reweighted {
+            fmt.Fprintf(&b, "> **Reweighted:** the sections below were written with the earlier weights (%s). Where they discuss scores or rankings, this table is current.\n\n", j.Comparison.composedWeights())
+        }
+    }
+    for i, s := range sections {
+        if s == "" { continue }
+        if i < len(checks) { s = flagClaims(s, checks[i].Claims) }
+        b.WriteString(s); if !strings.HasSuffix(s, "\n") { b.WriteString("\n") }; b.WriteString("\n")
+        if reweighted && reTotalsTalk.MatchString(s) { b.WriteString("> *Written with the earlier weights; see the Comparison table for the current scores.*\n\n") }
+    }
+    if len(j.OpenQuestions) > 0 {
+        b.WriteString("## Open Questions\n\n")
+        if j.budgetStop() {
+            fmt.Fprintf(&b, "Research stopped after %d round(s) (%s) with these questions from the last gap analysis unresolved. The budget ran out before the final round was analyzed, so it may have answered some of them:\n\n", len(j.Rounds), j.StopReason)
+        } else {
+            fmt.Fprintf(&b, "Research stopped after %d round(s) (%s) with these questions unanswered:\n\n", len(j.Rounds), j.StopReason)
+        }
+        for _, q := range j.OpenQuestions { b.WriteString("- "+q+"\n") }
+        b.WriteString("\n")
+    }
+    writeVerificationSummary(&b, j, sections, checks)
+    writeQueryMap(&b, j)
     b.WriteString("\n---\n\n")
     b.WriteString("## Sources\n\n")
-    b.WriteString("(Sources to be added by web research pipeline.)\n")
+    if len(j.Sources) == 0 {
+        b.WriteString("(No sources were retrieved for this report.)\n")
+    }
+    for i, d := range j.Sources {
+        fmt.Fprintf(&b, "%d. [%s](%s)", i+1, escapeLink(d.Title), d.URL)
+        if i < len(j.Scores) { b.WriteString(" — " + formatScore(j.Scores[i], d.Published)) }
+        b.WriteString("\n")
+        if len(d.Alternates) > 0 { fmt.Fprintf(&b, "   Also published at: %s\n", strings.Join(d.Alternates, ", ")) }
+    }
     return b.String()
 }
 
@@ -155,6 +597,8 @@ func safeHead(h string) string { return strings.TrimSpace(strings.Trim(h, "# "))
 
 func escapeYAML(s string) string { return strings.ReplaceAll(s, "\"", "\\\"") }
 
+func escapeLink(s string) string { return strings.NewReplacer("[", "(", "]", ")").Replace(strings.TrimSpace(s)) }
+
 func fallbackTitle(s string) string {
     s = strings.TrimSpace(s)
     if s == "" { return "Untitled Research" }
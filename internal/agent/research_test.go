@@ -0,0 +1,84 @@
+package agent
+
+import (
+    "context"
+    "errors"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+
+    "gotcha/internal/llm"
+)
+
+var errOverloaded = errors.New("model overloaded")
+
+// flakyWriter writes each section as "## <heading>\n\nOn <heading>.", failing
+// the first fails[heading] attempts at it. Earlier sections take longer so
+// they finish after later ones.
+type flakyWriter struct {
+    mu       sync.Mutex
+    fails    map[string]int
+    attempts map[string]int
+}
+
+func (w *flakyWriter) Name() string { return "flaky" }
+
+func (w *flakyWriter) Complete(ctx context.Context, req llm.Request, onToken llm.StreamHandler) (llm.Response, error) {
+    _, heading, _ := strings.Cut(req.Prompt, "Heading: ")
+    heading, _, _ = strings.Cut(heading, "\n")
+    w.mu.Lock()
+    w.attempts[heading]++
+    n := w.attempts[heading]
+    w.mu.Unlock()
+    time.Sleep(time.Duration('E'-heading[0]) * 5 * time.Millisecond)
+    if n <= w.fails[heading] { return llm.Response{}, errOverloaded }
+    return llm.Response{Text: "## " + heading + "\n\nOn " + heading + "."}, nil
+}
+
+func TestComposeRetriesAndKeepsPlanOrder(t *testing.T) {
+    defer func(d time.Duration) { composeBackoff = d }(composeBackoff)
+    composeBackoff = time.Millisecond
+    r := testResearcher(t)
+    r.cfg.ComposeConcurrency = 4
+    w := &flakyWriter{fails: map[string]int{"B": 2, "D": composeAttempts}, attempts: map[string]int{}}
+    r.llm = w
+    j := &Journal{RunID: "r1", SessionID: "s1", Prompt: "Battery prices", CreatedAt: time.Now(),
+        Plan:     &Plan{Title: "Battery prices", Sections: []Section{{Heading: "A"}, {Heading: "B"}, {Heading: "C"}, {Heading: "D"}}},
+        Sections: make([]string, 4),
+    }
+    events, cancel := r.tasks.bus.Subscribe(context.Background(), "s1", WithPhases(PhaseSection, PhaseCompose))
+    defer cancel()
+
+    err := runStep(t, r, j, func(ctx context.Context, st *runState) error { return r.compose(ctx, st) })
+    if !errors.Is(err, errOverloaded) || !strings.Contains(err.Error(), `section "D" failed after 3 attempts`) { t.Fatalf("compose returned %v, want D failing after 3 attempts", err) }
+    for h, want := range map[string]int{"A": 1, "B": 3, "C": 1, "D": composeAttempts} {
+        if got := w.attempts[h]; got != want { t.Errorf("section %s: %d attempts, want %d", h, got, want) }
+    }
+    want := []string{"## A\n\nOn A.", "## B\n\nOn B.", "## C\n\nOn C.", ""}
+    for i := range want {
+        if j.Sections[i] != want[i] { t.Errorf("section %d = %q, want %q", i, j.Sections[i], want[i]) }
+    }
+    saved, err := r.LoadJournal("s1", "r1")
+    if err != nil { t.Fatal(err) }
+    if done, _ := saved.Progress(); done != 3 { t.Errorf("checkpoint holds %d sections, want the 3 written", done) }
+
+    var retries, failed int
+    for _, e := range drain(events) {
+        switch {
+        case e.Phase == PhaseSection && e.Type == "error":
+            retries++
+        case e.Phase == PhaseCompose && e.Type == "error":
+            failed++
+            if !strings.Contains(e.Err, `"D"`) || e.Progress.Done != 3 { t.Errorf("compose error event %q at %d done", e.Err, e.Progress.Done) }
+        }
+    }
+    if retries != 5 || failed != 1 { t.Errorf("published %d attempt errors and %d compose errors, want 5 and 1", retries, failed) }
+
+    // Resuming writes only the failed section.
+    w.fails["D"] = 0
+    w.attempts = map[string]int{}
+    if err := runStep(t, r, j, func(ctx context.Context, st *runState) error { return r.compose(ctx, st) }); err != nil { t.Fatal(err) }
+    if len(w.attempts) != 1 || w.attempts["D"] != 1 { t.Errorf("resumed compose attempted %v, want only D", w.attempts) }
+    if j.Sections[3] != "## D\n\nOn D." { t.Errorf("section D = %q", j.Sections[3]) }
+}
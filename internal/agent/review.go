@@ -0,0 +1,172 @@
+package agent
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "sort"
+    "strings"
+    "sync"
+
+    "gotcha/internal/llm"
+)
+
+// Issue is one problem the reviewer found in a draft.
+type Issue struct {
+    Section int    `json:"section"` // plan index
+    Kind    string `json:"kind"`    // gap|redundancy|contradiction|clarity
+    Detail  string `json:"detail"`
+}
+
+// ReviewRound records one critique and the sections revised in response.
+type ReviewRound struct {
+    Round   int     `json:"round"`
+    Issues  []Issue `json:"issues,omitempty"`
+    Revised []int   `json:"revised,omitempty"` // plan indexes
+}
+
+// review critiques the composed report for gaps, redundancy, contradictions
+// between sections and unclear wording, then rewrites the affected sections.
+// It repeats for up to Options.ReviewRounds rounds or until the reviewer finds
+// nothing to fix. The pre-review sections are kept in Journal.Draft.
+func (r *Researcher) review(ctx context.Context, st *runState) error {
+    j := st.j
+    rounds := j.Options.ReviewRounds
+    if rounds <= 0 || j.ReviewDone { return nil }
+    if r.llm == nil {
+        st.publish(ctx, Event{Phase: PhaseReview, Type: "done", Meta: map[string]any{"skipped": "LLM not configured"}})
+        return nil
+    }
+    if j.Draft == nil {
+        j.Draft = append([]string(nil), j.Sections...)
+        if err := st.save(); err != nil { return err }
+    }
+    st.publish(ctx, Event{Phase: PhaseReview, Type: "started", Progress: Progress{Done: len(j.Reviews), Total: rounds}})
+    for len(j.Reviews) < rounds {
+        if err := st.t.Checkpoint(ctx); err != nil { return err }
+        round := ReviewRound{Round: len(j.Reviews) + 1}
+        issues, err := r.critique(ctx, st)
+        if err != nil {
+            if ctx.Err() != nil { return ctx.Err() }
+            if errors.Is(err, ErrBudgetExhausted) { return err }
+            // The draft stands on its own; a failed critique ends the review.
+            st.publish(ctx, Event{Phase: PhaseReview, Type: "error", Err: err.Error(), Meta: map[string]any{"round": round.Round}})
+            break
+        }
+        round.Issues = issues
+        if len(issues) > 0 {
+            if round.Revised, err = r.revise(ctx, st, issues); err != nil {
+                // Keep the sections revised before the budget ran out on record.
+                if len(round.Revised) > 0 { j.Reviews = append(j.Reviews, round) }
+                return err
+            }
+        }
+        j.Reviews = append(j.Reviews, round)
+        if err := st.save(); err != nil { return err }
+        st.publish(ctx, Event{Phase: PhaseReview, Type: "progress", Progress: Progress{Done: round.Round, Total: rounds}, Meta: map[string]any{
+            "round":   round.Round,
+            "issues":  len(issues),
+            "revised": len(round.Revised),
+        }})
+        if len(issues) == 0 { break }
+    }
+    j.ReviewDone = true
+    if err := st.save(); err != nil { return err }
+    st.publish(ctx, Event{Phase: PhaseReview, Type: "done", Meta: map[string]any{"rounds": len(j.Reviews)}})
+    return nil
+}
+
+// critique asks the model for concrete problems in the current sections.
+func (r *Researcher) critique(ctx context.Context, st *runState) ([]Issue, error) {
+    j := st.j
+    var b strings.Builder
+    fmt.Fprintf(&b, "Research prompt: %s\n\n", strings.TrimSpace(j.Prompt))
+    for i, s := range j.Sections { fmt.Fprintf(&b, "=== Section %d ===\n%s\n\n", i+1, strings.TrimSpace(s)) }
+    b.WriteString("Return JSON only.")
+    sys := "You are a demanding editor reviewing a research report draft. Find concrete problems: gaps (the prompt or a section's purpose is not fully answered), redundancy (the same point made in several sections), contradictions between sections, and unclear wording. Return strict JSON: {\"issues\": [{\"section\": section number, \"kind\": \"gap\"|\"redundancy\"|\"contradiction\"|\"clarity\", \"detail\": what is wrong and how to fix it}]}. Return an empty list when the draft needs no changes. No extra text."
+    res, err := st.complete(ctx, llm.Request{System: sys, Prompt: b.String(), MaxTokens: 1000, Temperature: 0.2})
+    if err != nil { return nil, err }
+    var out struct {
+        Issues []Issue `json:"issues"`
+    }
+    if err := json.Unmarshal([]byte(trimFences(res.Text)), &out); err != nil { return nil, fmt.Errorf("unreadable critique: %w", err) }
+    var issues []Issue
+    for _, is := range out.Issues {
+        // The model numbers sections from 1.
+        is.Section--
+        is.Detail = strings.TrimSpace(is.Detail)
+        if is.Section < 0 || is.Section >= len(j.Sections) || is.Detail == "" { continue }
+        issues = append(issues, is)
+    }
+    return issues, nil
+}
+
+// revise rewrites every section named in issues, bounded by the compose
+// concurrency, and returns the plan indexes it changed. A section whose
+// rewrite fails keeps its current text; running out of budget fails the
+// round, returning what was revised before.
+func (r *Researcher) revise(ctx context.Context, st *runState, issues []Issue) ([]int, error) {
+    bySection := map[int][]Issue{}
+    for _, is := range issues { bySection[is.Section] = append(bySection[is.Section], is) }
+
+    var (
+        wg        sync.WaitGroup
+        revised   []int
+        budgetErr error
+    )
+    sem := make(chan struct{}, r.cfg.ComposeConcurrency)
+    for idx, list := range bySection {
+        wg.Add(1)
+        go func(idx int, list []Issue) {
+            defer wg.Done()
+            sem <- struct{}{}
+            defer func() { <-sem }()
+            txt, err := r.reviseSection(ctx, st, idx, list)
+            var be *BudgetError
+            if errors.As(err, &be) {
+                st.mu.Lock()
+                if budgetErr == nil { budgetErr = err }
+                st.mu.Unlock()
+                return
+            }
+            if err != nil {
+                if ctx.Err() == nil { st.publish(ctx, Event{Phase: PhaseReview, Type: "error", Err: err.Error(), Meta: map[string]any{"section": sectionHeading(st.j.Sections[idx])}}) }
+                return
+            }
+            st.mu.Lock()
+            st.j.Sections[idx] = txt
+            revised = append(revised, idx)
+            st.mu.Unlock()
+        }(idx, list)
+    }
+    wg.Wait()
+    if err := ctx.Err(); err != nil { return nil, err }
+    sort.Ints(revised)
+    return revised, budgetErr
+}
+
+func (r *Researcher) reviseSection(ctx context.Context, st *runState, idx int, issues []Issue) (string, error) {
+    j := st.j
+    st.mu.Lock()
+    current := j.Sections[idx]
+    others := make([]string, 0, len(j.Sections))
+    for i, s := range j.Sections {
+        if i != idx { others = append(others, sectionHeading(s)) }
+    }
+    st.mu.Unlock()
+
+    var b strings.Builder
+    fmt.Fprintf(&b, "Research prompt: %s\nOther sections: %s\n\nSection to revise:\n%s\n\nReviewer notes:\n", strings.TrimSpace(j.Prompt), strings.Join(others, "; "), strings.TrimSpace(current))
+    for _, is := range issues { fmt.Fprintf(&b, "- (%s) %s\n", is.Kind, is.Detail) }
+    if evidence := formatPassages(j.sectionPassages(idx, current)); evidence != "" {
+        b.WriteString("\nSources:\n" + evidence + "\n")
+    }
+    sys := "You revise one section of a research report to address the reviewer notes. Keep the heading, keep what is correct, and only cite sources inline as [n] using the numbers given. Return the complete revised section in Markdown. No preamble."
+    res, err := st.complete(ctx, llm.Request{System: sys, Prompt: b.String(), MaxTokens: 900, Temperature: 0.3})
+    if err != nil { return "", err }
+    out := strings.TrimSpace(res.Text)
+    if out == "" { return "", fmt.Errorf("empty revision for section %q", sectionHeading(current)) }
+    if !strings.HasPrefix(out, "#") { out = "## " + sectionHeading(current) + "\n\n" + out }
+    return out, nil
+}
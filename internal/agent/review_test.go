@@ -0,0 +1,105 @@
+package agent
+
+import (
+    "context"
+    "errors"
+    "reflect"
+    "strings"
+    "testing"
+    "time"
+
+    "gotcha/internal/llm"
+)
+
+// reviewLLM returns critiques in turn, the last one repeating, and revises a
+// section by appending " (revised)", failing for sections in failing.
+func reviewLLM(critiques []string, failing ...string) *funcLLM {
+    n := 0
+    return &funcLLM{fn: func(req llm.Request, call int) (llm.Response, error) {
+        if strings.HasPrefix(req.System, "You revise") {
+            for _, h := range failing {
+                if strings.Contains(req.Prompt, "## "+h+"\n") { return llm.Response{}, errors.New("model overloaded") }
+            }
+            _, section, _ := strings.Cut(req.Prompt, "Section to revise:\n")
+            section, _, _ = strings.Cut(section, "\n\nReviewer notes:")
+            return llm.Response{Text: section + " (revised)"}, nil
+        }
+        c := critiques[min(n, len(critiques)-1)]
+        n++
+        return llm.Response{Text: c}, nil
+    }}
+}
+
+func reviewJournal(runID string, rounds int) *Journal {
+    return &Journal{
+        RunID: runID, SessionID: "s1", Prompt: "Battery prices", Status: RunInProgress, CreatedAt: time.Now(),
+        Options:      RunOptions{ReviewRounds: rounds},
+        Plan:         &Plan{Title: "Battery prices", Sections: []Section{{Heading: "Past"}, {Heading: "Future"}, {Heading: "Risks"}}},
+        PlanApproved: true, Clarified: true, ResearchDone: true,
+        Sections:     []string{"## Past\n\nPrices fell.", "## Future\n\nPrices will fall.", "## Risks\n\nSupply."},
+    }
+}
+
+const critiqueAll = `{"issues": [
+    {"section": 1, "kind": "gap", "detail": "Give figures."},
+    {"section": 2, "kind": "clarity", "detail": "By when?"},
+    {"section": 3, "kind": "gap", "detail": "Name the metals."},
+    {"section": 9, "kind": "gap", "detail": "No such section."},
+    {"section": 1, "kind": "clarity", "detail": " "}
+]}`
+
+func TestReviewRounds(t *testing.T) {
+    r := testResearcher(t)
+    fake := reviewLLM([]string{critiqueAll, `{"issues": [{"section": 1, "kind": "clarity", "detail": "Shorter."}]}`, `{"issues": []}`}, "Future")
+    r.llm = fake
+    j := reviewJournal("r1", 5)
+    draft := append([]string(nil), j.Sections...)
+    events, cancel := r.tasks.bus.Subscribe(context.Background(), "s1", WithPhases(PhaseReview))
+    defer cancel()
+
+    if err := runStep(t, r, j, func(ctx context.Context, st *runState) error { return r.review(ctx, st) }); err != nil { t.Fatal(err) }
+    if !j.ReviewDone { t.Error("review not marked done") }
+    if !reflect.DeepEqual(j.Draft, draft) { t.Errorf("draft %q, want the sections as composed", j.Draft) }
+    if len(j.Reviews) != 3 { t.Fatalf("ran %d rounds, want 3 ending with the clean critique", len(j.Reviews)) }
+    if got := len(j.Reviews[0].Issues); got != 3 { t.Errorf("first round kept %d issues, want 3 without the bad section and empty detail", got) }
+    if got, want := j.Reviews[0].Revised, []int{0, 2}; !reflect.DeepEqual(got, want) { t.Errorf("first round revised %v, want %v", got, want) }
+    if got, want := j.Reviews[1].Revised, []int{0}; !reflect.DeepEqual(got, want) { t.Errorf("second round revised %v, want %v", got, want) }
+    want := []string{"## Past\n\nPrices fell. (revised) (revised)", "## Future\n\nPrices will fall.", "## Risks\n\nSupply. (revised)"}
+    if !reflect.DeepEqual(j.Sections, want) { t.Errorf("sections %q, want %q", j.Sections, want) }
+    var failures int
+    for _, e := range drain(events) {
+        if e.Type == "error" { failures++ }
+    }
+    if failures != 1 { t.Errorf("published %d errors, want one for the section the model failed on", failures) }
+    if fake.Calls() != 7 { t.Errorf("made %d LLM calls, want 3 critiques and 4 revisions", fake.Calls()) }
+}
+
+func TestReviewStopsAtRoundLimit(t *testing.T) {
+    r := testResearcher(t)
+    r.llm = reviewLLM([]string{critiqueAll})
+    j := reviewJournal("r1", 2)
+    if err := runStep(t, r, j, func(ctx context.Context, st *runState) error { return r.review(ctx, st) }); err != nil { t.Fatal(err) }
+    if len(j.Reviews) != 2 || !j.ReviewDone { t.Errorf("ran %d rounds (done %v), want the limit of 2", len(j.Reviews), j.ReviewDone) }
+}
+
+func TestReviewStopsRunOnBudget(t *testing.T) {
+    r := testResearcher(t)
+    r.llm = reviewLLM([]string{critiqueAll})
+    // A single round: nothing after the revisions would notice the budget.
+    j := reviewJournal("r1", 1)
+    // The critique and one revision fit; the second revision does not.
+    j.Options.Budget = Budget{Requests: 2}
+    err := runStep(t, r, j, func(ctx context.Context, st *runState) error { return r.review(ctx, st) })
+    var be *BudgetError
+    if !errors.As(err, &be) { t.Fatalf("review on a two-request budget returned %v, want a budget error", err) }
+    if j.ReviewDone { t.Error("review marked done after running out of budget") }
+    if len(j.Reviews) != 1 || len(j.Reviews[0].Revised) != 1 { t.Errorf("recorded rounds %+v, want one round with the single revision that fit", j.Reviews) }
+
+    // Through the whole run the budget stops it with a partial report.
+    r = testResearcher(t)
+    r.llm = reviewLLM([]string{critiqueAll})
+    j = reviewJournal("r2", 1)
+    j.Options.Budget = Budget{Requests: 2}
+    if err := runStep(t, r, j, func(ctx context.Context, st *runState) error { return r.run(ctx, st.t, j) }); err != nil { t.Fatal(err) }
+    if j.Status != RunComplete || j.Exhausted != "run budget of 2 requests" { t.Errorf("run ended %s with budget stop %q", j.Status, j.Exhausted) }
+}
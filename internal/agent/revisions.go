@@ -0,0 +1,191 @@
+package agent
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+
+    "gotcha/internal/diff"
+    "gotcha/internal/platform"
+)
+
+// Revision is one saved version of a session's report. Every report written
+// becomes a new revision; report.md is always a copy of the latest one.
+type Revision struct {
+    N            int
+    At           time.Time
+    Title        string
+    RunID        string
+    Prompt       string
+    Model        string
+    Sources      int
+    Tokens       int
+    Cost         float64
+    RestoredFrom int    // revision this one restored, if any
+    Path         string // rNNN.md in the session's revisions directory
+}
+
+// revisionLockTimeout bounds how long saving a revision waits for another
+// process numbering one in the same session.
+const revisionLockTimeout = 10 * time.Second
+
+var reRevisionFile = regexp.MustCompile(`^r(\d+)\.md$`)
+
+// Revisions lists a session's report revisions, oldest first.
+func (r *Researcher) Revisions(sessionID string) ([]Revision, error) {
+    entries, err := os.ReadDir(r.svc.RevisionsDir(sessionID))
+    if err != nil {
+        if os.IsNotExist(err) { return nil, nil }
+        return nil, err
+    }
+    var out []Revision
+    for _, e := range entries {
+        m := reRevisionFile.FindStringSubmatch(e.Name())
+        if m == nil { continue }
+        path := filepath.Join(r.svc.RevisionsDir(sessionID), e.Name())
+        data, err := os.ReadFile(path)
+        if err != nil { return nil, err }
+        rev := parseRevision(string(data))
+        rev.N, _ = strconv.Atoi(m[1])
+        rev.Path = path
+        out = append(out, rev)
+    }
+    sort.Slice(out, func(i, k int) bool { return out[i].N < out[k].N })
+    return out, nil
+}
+
+// Revision returns one revision and its Markdown.
+func (r *Researcher) Revision(sessionID string, n int) (Revision, string, error) {
+    path := revisionPath(r.svc.RevisionsDir(sessionID), n)
+    data, err := os.ReadFile(path)
+    if err != nil {
+        if os.IsNotExist(err) { return Revision{}, "", fmt.Errorf("%s has no report revision %d", sessionID, n) }
+        return Revision{}, "", err
+    }
+    rev := parseRevision(string(data))
+    rev.N, rev.Path = n, path
+    return rev, string(data), nil
+}
+
+// DiffRevisions compares revisions a and b section by section, ignoring
+// their front matter.
+func (r *Researcher) DiffRevisions(sessionID string, a, b int) ([]diff.SectionDiff, error) {
+    _, old, err := r.Revision(sessionID, a)
+    if err != nil { return nil, err }
+    _, cur, err := r.Revision(sessionID, b)
+    if err != nil { return nil, err }
+    return diff.Sections(stripFrontMatter(old), stripFrontMatter(cur)), nil
+}
+
+// RestoreRevision makes revision n the session's report again. History is
+// kept: the restored text is saved as a new revision that records where it
+// came from.
+func (r *Researcher) RestoreRevision(sessionID string, n int) (Revision, error) {
+    _, doc, err := r.Revision(sessionID, n)
+    if err != nil { return Revision{}, err }
+    doc = setFrontMatter(doc, "restored_from", strconv.Itoa(n))
+    doc = setFrontMatter(doc, "restored_at", `"`+time.Now().Format(time.RFC3339)+`"`)
+    return r.saveRevision(sessionID, doc)
+}
+
+// saveRevision numbers doc as the session's next revision, stores it and
+// writes it to report.md. A report written before revisions existed is
+// kept as the first revision. Numbering happens under a file lock, so a CLI
+// run and the TUI saving at once neither share a number nor write report.md
+// out of order; each number is also claimed with an exclusive create.
+func (r *Researcher) saveRevision(sessionID, doc string) (Revision, error) {
+    dir := r.svc.RevisionsDir(sessionID)
+    if err := os.MkdirAll(dir, 0o755); err != nil { return Revision{}, fmt.Errorf("mkdir revisions: %w", err) }
+    release, err := platform.Lock(filepath.Join(dir, ".lock"), revisionLockTimeout)
+    if err != nil { return Revision{}, fmt.Errorf("lock revisions: %w", err) }
+    defer release()
+    last, err := lastRevision(dir)
+    if err != nil { return Revision{}, err }
+    if last == 0 {
+        if old, err := os.ReadFile(r.svc.ReportPath(sessionID)); err == nil {
+            if last, err = claimRevision(dir, 1); err != nil { return Revision{}, err }
+            if err := platform.WriteFileAtomic(revisionPath(dir, last), []byte(setFrontMatter(string(old), "revision", strconv.Itoa(last)))); err != nil { return Revision{}, err }
+        }
+    }
+    n, err := claimRevision(dir, last+1)
+    if err != nil { return Revision{}, err }
+    doc = setFrontMatter(doc, "revision", strconv.Itoa(n))
+    path := revisionPath(dir, n)
+    if err := platform.WriteFileAtomic(path, []byte(doc)); err != nil { return Revision{}, err }
+    if err := platform.WriteFileAtomic(r.svc.ReportPath(sessionID), []byte(doc)); err != nil { return Revision{}, err }
+    rev := parseRevision(doc)
+    rev.N, rev.Path = n, path
+    return rev, nil
+}
+
+// lastRevision returns the highest revision number in dir, from the file
+// names alone, or 0 if there is none.
+func lastRevision(dir string) (int, error) {
+    entries, err := os.ReadDir(dir)
+    if err != nil && !os.IsNotExist(err) { return 0, err }
+    last := 0
+    for _, e := range entries {
+        if m := reRevisionFile.FindStringSubmatch(e.Name()); m != nil {
+            if n, _ := strconv.Atoi(m[1]); n > last { last = n }
+        }
+    }
+    return last, nil
+}
+
+// claimRevision reserves the first free revision number from n on by
+// creating its file exclusively, and returns it.
+func claimRevision(dir string, n int) (int, error) {
+    for ; ; n++ {
+        f, err := os.OpenFile(revisionPath(dir, n), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+        if err == nil { return n, f.Close() }
+        if !os.IsExist(err) { return 0, fmt.Errorf("claim revision %d: %w", n, err) }
+    }
+}
+
+func revisionPath(dir string, n int) string { return filepath.Join(dir, fmt.Sprintf("r%03d.md", n)) }
+
+// frontMatter returns the key/value lines of doc's front matter.
+func frontMatter(doc string) map[string]string {
+    out := map[string]string{}
+    m := reFrontMatter.FindString(doc)
+    if m == "" { return out }
+    for _, line := range strings.Split(m, "\n") {
+        k, v, ok := strings.Cut(line, ":")
+        if !ok || strings.TrimSpace(k) == "" || strings.HasPrefix(line, " ") { continue }
+        v = strings.TrimSpace(v)
+        if len(v) >= 2 && v[0] == '"' && v[len(v)-1] == '"' { v = strings.ReplaceAll(v[1:len(v)-1], `\"`, `"`) }
+        out[strings.TrimSpace(k)] = v
+    }
+    return out
+}
+
+// setFrontMatter sets key to the literal YAML value in doc's front matter,
+// replacing an existing entry or appending one. A doc without front matter
+// gets one.
+func setFrontMatter(doc, key, value string) string {
+    line := key + ": " + value
+    m := reFrontMatter.FindString(doc)
+    if m == "" { return "---\n" + line + "\n---\n\n" + doc }
+    re := regexp.MustCompile(`(?m)^` + regexp.QuoteMeta(key) + `:.*$`)
+    if re.MatchString(m) { return re.ReplaceAllLiteralString(m, line) + doc[len(m):] }
+    end := strings.LastIndex(m, "\n---") + 1
+    return doc[:end] + line + "\n" + doc[end:]
+}
+
+func parseRevision(doc string) Revision {
+    fm := frontMatter(doc)
+    rev := Revision{Title: fm["title"], RunID: fm["run"], Prompt: fm["prompt"], Model: fm["model"]}
+    rev.Sources, _ = strconv.Atoi(fm["sources"])
+    rev.Tokens, _ = strconv.Atoi(fm["tokens"])
+    rev.Cost, _ = strconv.ParseFloat(fm["cost"], 64)
+    rev.RestoredFrom, _ = strconv.Atoi(fm["restored_from"])
+    at := fm["generated_at"]
+    if fm["restored_at"] != "" { at = fm["restored_at"] }
+    rev.At, _ = time.Parse(time.RFC3339, at)
+    return rev
+}
@@ -0,0 +1,72 @@
+package agent
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "sort"
+    "sync"
+    "testing"
+
+    "gotcha/internal/app"
+    "gotcha/internal/platform"
+)
+
+func testResearcher(t *testing.T) *Researcher {
+    t.Helper()
+    svc := app.NewService(nil, platform.Paths{Base: t.TempDir()})
+    return NewResearcher(NewMemoryBus(16), nil, svc, NewTaskManager(NewMemoryBus(16), 1), ResearchConfig{})
+}
+
+func TestSaveRevisionKeepsEarlierReport(t *testing.T) {
+    r := testResearcher(t)
+    report := r.svc.ReportPath("s1")
+    if err := os.MkdirAll(filepath.Dir(report), 0o755); err != nil { t.Fatal(err) }
+    if err := os.WriteFile(report, []byte("# Old\n"), 0o644); err != nil { t.Fatal(err) }
+
+    rev, err := r.saveRevision("s1", "---\ntitle: New\n---\n\n# New\n")
+    if err != nil { t.Fatal(err) }
+    if rev.N != 2 || rev.Title != "New" { t.Errorf("saved revision %d %q, want 2 \"New\"", rev.N, rev.Title) }
+    _, old, err := r.Revision("s1", 1)
+    if err != nil { t.Fatal(err) }
+    if frontMatter(old)["revision"] != "1" || stripFrontMatter(old) != "# Old\n" { t.Errorf("revision 1 = %q, want the earlier report", old) }
+    data, _ := os.ReadFile(report)
+    if frontMatter(string(data))["revision"] != "2" { t.Errorf("report.md = %q, want revision 2", data) }
+}
+
+func TestSaveRevisionConcurrent(t *testing.T) {
+    r := testResearcher(t)
+    const n = 8
+    var wg sync.WaitGroup
+    nums := make([]int, n)
+    for i := 0; i < n; i++ {
+        wg.Add(1)
+        go func(i int) {
+            defer wg.Done()
+            rev, err := r.saveRevision("s1", fmt.Sprintf("# Report %d\n", i))
+            if err != nil { t.Error(err) }
+            nums[i] = rev.N
+        }(i)
+    }
+    wg.Wait()
+    sort.Ints(nums)
+    for i, got := range nums {
+        if got != i+1 { t.Fatalf("revision numbers %v, want 1 to %d once each", nums, n) }
+    }
+    revs, err := r.Revisions("s1")
+    if err != nil { t.Fatal(err) }
+    if len(revs) != n { t.Errorf("listed %d revisions, want %d", len(revs), n) }
+    // report.md holds the newest revision.
+    data, _ := os.ReadFile(r.svc.ReportPath("s1"))
+    if frontMatter(string(data))["revision"] != fmt.Sprint(n) { t.Errorf("report.md = %q, want revision %d", data, n) }
+}
+
+func TestClaimRevisionSkipsTaken(t *testing.T) {
+    dir := t.TempDir()
+    for _, k := range []int{1, 2, 4} {
+        if err := os.WriteFile(revisionPath(dir, k), nil, 0o644); err != nil { t.Fatal(err) }
+    }
+    if last, _ := lastRevision(dir); last != 4 { t.Errorf("last revision %d, want 4", last) }
+    if n, err := claimRevision(dir, 2); err != nil || n != 3 { t.Errorf("claimed %d, %v; want 3", n, err) }
+    if n, err := claimRevision(dir, 3); err != nil || n != 5 { t.Errorf("claimed %d, %v; want 5", n, err) }
+}
@@ -0,0 +1,316 @@
+package agent
+
+import (
+    "fmt"
+    "net/url"
+    "regexp"
+    "strconv"
+    "strings"
+    "time"
+
+    "gotcha/internal/search"
+)
+
+// Per-section query planning: each planned section may carry its own search
+// queries, preferred source types and a recency requirement. Its queries run
+// alongside the plan's general ones, and the sources they find that pass its
+// filters are attached to the section as its own evidence. A section whose
+// queries and filters leave it nothing draws on the shared pool instead. The
+// report lists which queries fed which section.
+
+// Section evidence weighting.
+const (
+    maxSectionQueries = 3
+    ownSourceBoost    = 1.5  // found by the section's own queries
+    otherSourceWeight = 0.6  // found only by other sections' queries
+    typeMatchBoost    = 1.25 // matches a preferred source type
+    staleWeight       = 0.5  // older than the section's recency requirement
+)
+
+// SourceTypes are the source types a section can prefer.
+var SourceTypes = []string{"academic", "official", "documentation", "news", "data", "industry", "forum"}
+
+// sourceTypeHints recognize source types by host; entries starting with "/"
+// match the URL path.
+var sourceTypeHints = map[string][]string{
+    "academic":      {"arxiv.org", "doi.org", "nature.com", "science.org", "sciencedirect.com", "springer.com", "wiley.com", "ieee.org", "acm.org", "ncbi.nlm.nih.gov", "jstor.org", "ssrn.com", "plos.org", "semanticscholar.org", ".edu", ".ac.uk", "/abs/", "/doi/", "/paper"},
+    "official":      {".gov", ".gov.uk", ".gc.ca", "europa.eu", "who.int", "un.org", "oecd.org", "imf.org", "worldbank.org", "/legislation/", "/regulation"},
+    "documentation": {"readthedocs.io", "developer.mozilla.org", "learn.microsoft.com", "docs.", "developer.", "/docs/", "/documentation/", "/manual/", "/reference/", "/spec"},
+    "news":          {"reuters.com", "apnews.com", "bbc.co.uk", "bbc.com", "nytimes.com", "theguardian.com", "bloomberg.com", "ft.com", "wsj.com", "cnbc.com", "theverge.com", "techcrunch.com", "arstechnica.com", "/news/"},
+    "data":          {"ourworldindata.org", "statista.com", "kaggle.com", "data.gov", "data.", "/data/", "/statistics/", "/dataset"},
+    "industry":      {"gartner.com", "mckinsey.com", "deloitte.com", "pwc.com", "idc.com", "forrester.com", "bcg.com", "/whitepaper", "/white-paper", "/report"},
+    "forum":         {"reddit.com", "stackoverflow.com", "stackexchange.com", "news.ycombinator.com", "discourse.", "/forum", "/community/"},
+}
+
+// matchesSourceType reports whether d looks like one of the given types.
+func matchesSourceType(d search.Document, types []string) bool {
+    if len(types) == 0 { return false }
+    host := hostOf(d.URL)
+    path := ""
+    if u, err := url.Parse(d.URL); err == nil { path = strings.ToLower(u.Path) }
+    for _, t := range types {
+        for _, h := range sourceTypeHints[strings.ToLower(strings.TrimSpace(t))] {
+            switch {
+            case strings.HasPrefix(h, "/"):
+                if strings.Contains(path, h) { return true }
+            case strings.HasSuffix(h, "."):
+                if strings.HasPrefix(host, h) { return true }
+            case matchDomain(host, []string{h}):
+                return true
+            }
+        }
+    }
+    return false
+}
+
+// reRecency matches a whole-word age such as "year", "6 months", "6-month"
+// or "2y"; units must not be the tail of another word ("today", "new").
+var reRecency = regexp.MustCompile(`\b(\d+)?[\s-]*(days?|d|weeks?|w|months?|mo|years?|yrs?|y)\b`)
+
+// parseRecency reads a recency requirement such as "past year", "6 months",
+// "2y" or "any" as a maximum source age; zero means any age, and so does
+// anything it does not recognize.
+func parseRecency(s string) time.Duration {
+    m := reRecency.FindStringSubmatch(strings.ToLower(s))
+    if m == nil { return 0 }
+    n := 1
+    if m[1] != "" { n, _ = strconv.Atoi(m[1]) }
+    day := 24 * time.Hour
+    unit := map[string]time.Duration{"day": day, "d": day, "week": 7 * day, "w": 7 * day, "month": 30 * day, "mo": 30 * day, "year": 365 * day, "yr": 365 * day, "y": 365 * day}[strings.TrimSuffix(m[2], "s")]
+    return time.Duration(n) * unit
+}
+
+// normalizeSections trims the planner's per-section fields: at most three
+// queries, known source types only.
+func (p *Plan) normalizeSections() {
+    known := map[string]bool{}
+    for _, t := range SourceTypes { known[t] = true }
+    for i := range p.Sections {
+        s := &p.Sections[i]
+        s.Queries = uniqueQueries(s.Queries)
+        if len(s.Queries) > maxSectionQueries { s.Queries = s.Queries[:maxSectionQueries] }
+        var types []string
+        for _, t := range s.SourceTypes {
+            if t = strings.ToLower(strings.TrimSpace(t)); known[t] { types = append(types, t) }
+        }
+        s.SourceTypes = types
+        s.Recency = strings.TrimSpace(s.Recency)
+        if strings.EqualFold(s.Recency, "any") { s.Recency = "" }
+    }
+}
+
+// searchQueries returns the plan's general queries followed by every
+// section's own, without duplicates.
+func (p Plan) searchQueries() []string {
+    qs := append([]string(nil), p.Queries...)
+    for _, s := range p.Sections { qs = append(qs, s.Queries...) }
+    return uniqueQueries(qs)
+}
+
+func uniqueQueries(qs []string) []string {
+    seen := map[string]bool{}
+    var out []string
+    for _, q := range qs {
+        q = strings.TrimSpace(q)
+        k := strings.ToLower(q)
+        if q == "" || seen[k] { continue }
+        seen[k] = true
+        out = append(out, q)
+    }
+    return out
+}
+
+// queryHits maps the canonical URL of every search result to the queries
+// that returned it.
+func (j *Journal) queryHits() map[string][]string {
+    hits := map[string][]string{}
+    for _, rd := range j.Rounds {
+        for u, qs := range rd.Found { hits[canonicalURL(u)] = append(hits[canonicalURL(u)], qs...) }
+    }
+    return hits
+}
+
+// sourceQueries returns, per source index, the lower-cased queries that
+// found it (through its URL or an alternate).
+func (j *Journal) sourceQueries() []map[string]bool {
+    hits := j.queryHits()
+    out := make([]map[string]bool, len(j.Sources))
+    for i, d := range j.Sources {
+        out[i] = map[string]bool{}
+        for _, u := range append([]string{d.URL}, d.Alternates...) {
+            for _, q := range hits[canonicalURL(u)] { out[i][strings.ToLower(q)] = true }
+        }
+    }
+    return out
+}
+
+// sectionSources returns the indexes of the sources found by section i's
+// own queries.
+func (j *Journal) sectionSources(i int) []int {
+    if j.Plan == nil || i >= len(j.Plan.Sections) { return nil }
+    var out []int
+    for k, qs := range j.sourceQueries() {
+        for _, q := range j.Plan.Sections[i].Queries {
+            if qs[strings.ToLower(q)] { out = append(out, k); break }
+        }
+    }
+    return out
+}
+
+// accepts reports whether d passes the section's filters: one of its
+// preferred source types, if it names any, and with a recency requirement a
+// publication date known to fall within it.
+func (s Section) accepts(d search.Document) bool {
+    if len(s.SourceTypes) > 0 && !matchesSourceType(d, s.SourceTypes) { return false }
+    if maxAge := parseRecency(s.Recency); maxAge > 0 && (d.Published.IsZero() || time.Since(d.Published) > maxAge) { return false }
+    return true
+}
+
+// attachSectionSources gives each section the sources its own queries found
+// that pass its filters, by plan index.
+func (j *Journal) attachSectionSources() {
+    if j.Plan == nil { return }
+    j.SectionSources = make([][]int, len(j.Plan.Sections))
+    for i, s := range j.Plan.Sections {
+        for _, k := range j.sectionSources(i) {
+            if s.accepts(j.Sources[k]) { j.SectionSources[i] = append(j.SectionSources[i], k) }
+        }
+    }
+}
+
+// sectionPassages selects the evidence for section i about query: passages
+// from the sources attached to the section, or, when those yield none, from
+// the shared pool ranked by sectionWeights.
+func (j *Journal) sectionPassages(i int, query string) []passage {
+    if i < len(j.SectionSources) && len(j.SectionSources[i]) > 0 {
+        base := j.sourceWeights()
+        own := make([]float64, len(j.Sources))
+        for _, k := range j.SectionSources[i] {
+            if k >= len(own) { continue }
+            own[k] = 1
+            if k < len(base) { own[k] = base[k] }
+        }
+        if ps := selectPassages(j.Sources, own, query, maxSectionPassages); len(ps) > 0 { return ps }
+    }
+    return selectPassages(j.Sources, j.sectionWeights(i), query, maxSectionPassages)
+}
+
+// sectionWeights ranks evidence for section i: on top of the credibility
+// weights, sources its own queries found count more, sources only other
+// sections' queries found count less, and preferred source types and the
+// recency requirement apply.
+func (j *Journal) sectionWeights(i int) []float64 {
+    base := j.sourceWeights()
+    if j.Plan == nil || i >= len(j.Plan.Sections) { return base }
+    s := j.Plan.Sections[i]
+    if len(s.Queries) == 0 && len(s.SourceTypes) == 0 && s.Recency == "" { return base }
+    own := map[string]bool{}
+    for _, q := range s.Queries { own[strings.ToLower(q)] = true }
+    others := map[string]bool{}
+    for k, o := range j.Plan.Sections {
+        if k == i { continue }
+        for _, q := range o.Queries {
+            if !own[strings.ToLower(q)] { others[strings.ToLower(q)] = true }
+        }
+    }
+    maxAge := parseRecency(s.Recency)
+    out := make([]float64, len(j.Sources))
+    for k, qs := range j.sourceQueries() {
+        out[k] = 1
+        if k < len(base) { out[k] = base[k] }
+        mine, theirs, general := false, false, len(qs) == 0
+        for q := range qs {
+            switch {
+            case own[q]:
+                mine = true
+            case others[q]:
+                theirs = true
+            default:
+                general = true
+            }
+        }
+        switch {
+        case mine:
+            out[k] *= ownSourceBoost
+        case theirs && !general:
+            out[k] *= otherSourceWeight
+        }
+        d := j.Sources[k]
+        if matchesSourceType(d, s.SourceTypes) { out[k] *= typeMatchBoost }
+        if maxAge > 0 && !d.Published.IsZero() && time.Since(d.Published) > maxAge { out[k] *= staleWeight }
+    }
+    return out
+}
+
+// sectionBrief tells the writer about the section's source preferences.
+func (s Section) sectionBrief() string {
+    var b strings.Builder
+    if len(s.SourceTypes) > 0 { fmt.Fprintf(&b, "Preferred sources: %s.\n", strings.Join(s.SourceTypes, ", ")) }
+    if s.Recency != "" { fmt.Fprintf(&b, "Recency required: %s; call out older figures as possibly dated.\n", s.Recency) }
+    return b.String()
+}
+
+// writeQueryMap adds the table of which queries fed which section, with
+// the sources each section's queries found.
+func writeQueryMap(b *strings.Builder, j *Journal) {
+    if j.Plan == nil || len(j.Rounds) == 0 { return }
+    sectioned := map[string]bool{}
+    hasOwn := false
+    for _, s := range j.Plan.Sections {
+        for _, q := range s.Queries { sectioned[strings.ToLower(q)] = true; hasOwn = true }
+    }
+    if !hasOwn { return }
+    b.WriteString("## Research Queries\n\n")
+    b.WriteString("| Section | Queries | Sources used |\n|---|---|---|\n")
+    sq := j.sourceQueries()
+    found := func(queries []string) string {
+        var refs []string
+        for k, qs := range sq {
+            for _, q := range queries {
+                if qs[strings.ToLower(q)] { refs = append(refs, fmt.Sprintf("[%d]", k+1)); break }
+            }
+        }
+        if len(refs) == 0 { return "–" }
+        return strings.Join(refs, " ")
+    }
+    quote := func(queries []string) string {
+        out := make([]string, len(queries))
+        for i, q := range queries { out[i] = "“" + escapeCell(q) + "”" }
+        return strings.Join(out, "<br>")
+    }
+    for i, s := range j.Plan.Sections {
+        if len(s.Queries) == 0 { continue }
+        used := "shared sources (none of its own passed its filters)"
+        if i < len(j.SectionSources) && len(j.SectionSources[i]) > 0 {
+            refs := make([]string, len(j.SectionSources[i]))
+            for n, k := range j.SectionSources[i] { refs[n] = fmt.Sprintf("[%d]", k+1) }
+            used = strings.Join(refs, " ")
+        }
+        fmt.Fprintf(b, "| %s | %s | %s |\n", escapeCell(safeHead(s.Heading)), quote(s.Queries), used)
+    }
+    // General and follow-up queries serve every section.
+    var general []string
+    for _, rd := range j.Rounds {
+        for _, q := range rd.Queries {
+            if !sectioned[strings.ToLower(q)] { general = append(general, q) }
+        }
+    }
+    if general = uniqueQueries(general); len(general) > 0 {
+        fmt.Fprintf(b, "| All sections | %s | %s |\n", quote(general), found(general))
+    }
+    b.WriteString("\n")
+}
+
+// interleave orders per-query results rank by rank, so that with a source
+// limit every query (and so every section) gets some of its top results.
+func interleave(results [][]search.Result) []search.Result {
+    var out []search.Result
+    for rank := 0; ; rank++ {
+        more := false
+        for _, rs := range results {
+            if rank < len(rs) { out = append(out, rs[rank]); more = true }
+        }
+        if !more { break }
+    }
+    return out
+}
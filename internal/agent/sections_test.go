@@ -0,0 +1,98 @@
+package agent
+
+import (
+    "reflect"
+    "sort"
+    "testing"
+    "time"
+
+    "gotcha/internal/search"
+)
+
+func TestParseRecency(t *testing.T) {
+    day := 24 * time.Hour
+    cases := []struct {
+        in   string
+        want time.Duration
+    }{
+        {"past year", 365 * day},
+        {"Past Year", 365 * day},
+        {"last 6 months", 180 * day},
+        {"6-month window", 180 * day},
+        {"2y", 2 * 365 * day},
+        {"3 yrs", 3 * 365 * day},
+        {"12mo", 360 * day},
+        {"past week", 7 * day},
+        {"2w", 14 * day},
+        {"30 days", 30 * day},
+        {"1d", day},
+        {"", 0},
+        {"any", 0},
+        {"any time", 0},
+        {"from 2022 onward", 0},
+        {"new", 0},
+        {"today", 0},
+        {"recent", 0},
+        {"5 minutes", 0},
+    }
+    for _, c := range cases {
+        if got := parseRecency(c.in); got != c.want { t.Errorf("parseRecency(%q) = %v, want %v", c.in, got, c.want) }
+    }
+}
+
+// sectionJournal has three sections: one wanting academic sources, one
+// wanting sources from the past year, one with no queries of its own.
+func sectionJournal() *Journal {
+    now := time.Now()
+    return &Journal{
+        Plan: &Plan{Sections: []Section{
+            {Heading: "Theory", Queries: []string{"battery chemistry"}, SourceTypes: []string{"academic"}},
+            {Heading: "Market", Queries: []string{"battery prices"}, Recency: "past year"},
+            {Heading: "Outlook"},
+        }},
+        Rounds: []SearchRound{{Found: map[string][]string{
+            "https://arxiv.org/abs/1234":      {"battery chemistry"},
+            "https://blog.example.com/cells":  {"battery chemistry"},
+            "https://news.example.com/prices": {"battery prices"},
+            "https://example.org/overview":    {"batteries"},
+        }}},
+        Sources: []search.Document{
+            {URL: "https://arxiv.org/abs/1234", Text: "Lithium iron phosphate cathodes resist thermal runaway."},
+            {URL: "https://blog.example.com/cells", Text: "My favourite cathodes are lithium iron phosphate."},
+            {URL: "https://news.example.com/prices", Text: "Battery pack prices fell to 150 dollars per kWh.", Published: now.AddDate(-3, 0, 0)},
+            {URL: "https://example.org/overview", Text: "Battery pack prices keep falling as cathodes improve.", Published: now.AddDate(0, -1, 0)},
+        },
+    }
+}
+
+func TestAttachSectionSources(t *testing.T) {
+    j := sectionJournal()
+    j.attachSectionSources()
+    want := [][]int{{0}, nil, nil} // the blog is not academic, the news is too old
+    if !reflect.DeepEqual(j.SectionSources, want) { t.Errorf("attached %v, want %v", j.SectionSources, want) }
+}
+
+func TestSectionPassages(t *testing.T) {
+    j := sectionJournal()
+    j.attachSectionSources()
+    sources := func(ps []passage) []int {
+        var out []int
+        for _, p := range ps { out = append(out, p.Source) }
+        sort.Ints(out)
+        return out
+    }
+    cases := []struct {
+        name    string
+        section int
+        query   string
+        want    []int
+    }{
+        {"own sources only", 0, "lithium iron phosphate cathodes", []int{0}},
+        {"own sources say nothing on the query", 0, "pack prices", []int{2, 3}},
+        {"filters matched nothing", 1, "battery pack prices", []int{2, 3}},
+        {"no queries of its own", 2, "cathodes", []int{0, 1, 3}},
+    }
+    for _, c := range cases {
+        if got := sources(j.sectionPassages(c.section, c.query)); !reflect.DeepEqual(got, c.want) { t.Errorf("%s: evidence from sources %v, want %v", c.name, got, c.want) }
+    }
+}
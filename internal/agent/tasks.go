@@ -0,0 +1,372 @@
+package agent
+
+import (
+    "context"
+    "crypto/rand"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "sort"
+    "sync"
+    "time"
+)
+
+// TaskState is the lifecycle state of a background task.
+type TaskState string
+
+const (
+    TaskQueued    TaskState = "queued"
+    TaskRunning   TaskState = "running"
+    TaskPaused    TaskState = "paused"
+    TaskCancelled TaskState = "cancelled"
+    TaskDone      TaskState = "done"
+    TaskFailed    TaskState = "failed"
+)
+
+// Terminal reports whether no further transitions are possible.
+func (s TaskState) Terminal() bool {
+    return s == TaskCancelled || s == TaskDone || s == TaskFailed
+}
+
+var ErrTaskNotFound = errors.New("task not found")
+
+// TaskInfo is a point-in-time snapshot of a task.
+type TaskInfo struct {
+    ID         string
+    SessionID  string
+    Title      string
+    State      TaskState
+    Err        string
+    CreatedAt  time.Time
+    StartedAt  time.Time
+    FinishedAt time.Time
+}
+
+// TaskFunc is the body of a task. It should call t.Checkpoint between units of
+// work so that pause and cancel take effect promptly.
+type TaskFunc func(ctx context.Context, t *Task) error
+
+// maxFinishedTasks bounds how many finished tasks are remembered for List.
+const maxFinishedTasks = 50
+
+// Task is the handle passed to a running TaskFunc.
+type Task struct {
+    mgr     *TaskManager
+    info    TaskInfo // guarded by mgr.mu
+    started bool
+    cancel  context.CancelFunc
+    resume  chan struct{} // non-nil while paused
+    done    chan struct{}
+
+    // Inactive time, when the task is paused or idle; guarded by mgr.mu.
+    inactive     time.Duration // finished inactive spells
+    inactiveFrom time.Time     // start of the current spell
+    inactiveN    int           // overlapping reasons in the current spell
+
+    slotMu  sync.Mutex
+    holding bool // the task holds one of the manager's concurrency slots
+}
+
+func (t *Task) ID() string        { return t.info.ID }
+func (t *Task) SessionID() string { return t.info.SessionID }
+
+// Publish emits e on the manager's bus stamped with the task and session IDs.
+func (t *Task) Publish(ctx context.Context, e Event) {
+    e.SessionID = t.info.SessionID
+    e.TaskID = t.info.ID
+    if e.At.IsZero() { e.At = time.Now() }
+    t.mgr.bus.Publish(ctx, e)
+}
+
+// Checkpoint blocks while the task is paused and returns ctx.Err() once the
+// task has been cancelled. A paused task gives up its concurrency slot and
+// waits for one again when resumed.
+func (t *Task) Checkpoint(ctx context.Context) error {
+    for {
+        t.mgr.mu.Lock()
+        wait := t.resume
+        t.mgr.mu.Unlock()
+        if wait == nil {
+            if err := ctx.Err(); err != nil { return err }
+            return t.acquire(ctx)
+        }
+        t.release()
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        case <-wait:
+        }
+    }
+}
+
+// Idle runs wait, which blocks on something outside the task such as the
+// user's answers, without holding a concurrency slot, so other tasks can run
+// meanwhile. The slot is taken back before Idle returns. The wait counts as
+// inactive time.
+func (t *Task) Idle(ctx context.Context, wait func() error) error {
+    t.release()
+    t.mgr.mu.Lock()
+    t.enterInactiveLocked()
+    t.mgr.mu.Unlock()
+    err := wait()
+    t.mgr.mu.Lock()
+    t.leaveInactiveLocked()
+    t.mgr.mu.Unlock()
+    if err != nil { return err }
+    return t.Checkpoint(ctx)
+}
+
+// Inactive returns how long the task has spent paused or idle.
+func (t *Task) Inactive() time.Duration {
+    t.mgr.mu.Lock()
+    defer t.mgr.mu.Unlock()
+    d := t.inactive
+    if t.inactiveN > 0 { d += time.Since(t.inactiveFrom) }
+    return d
+}
+
+// enterInactiveLocked and leaveInactiveLocked bracket a pause or an idle
+// wait; overlapping ones count once. mgr.mu must be held.
+func (t *Task) enterInactiveLocked() {
+    if t.inactiveN == 0 { t.inactiveFrom = time.Now() }
+    t.inactiveN++
+}
+
+func (t *Task) leaveInactiveLocked() {
+    if t.inactiveN == 0 { return }
+    t.inactiveN--
+    if t.inactiveN == 0 { t.inactive += time.Since(t.inactiveFrom) }
+}
+
+// acquire takes a concurrency slot unless the task already holds one.
+func (t *Task) acquire(ctx context.Context) error {
+    t.slotMu.Lock()
+    defer t.slotMu.Unlock()
+    if t.holding { return nil }
+    select {
+    case t.mgr.slots <- struct{}{}:
+        t.holding = true
+        return nil
+    case <-ctx.Done():
+        return ctx.Err()
+    }
+}
+
+// release gives up the task's concurrency slot if it holds one.
+func (t *Task) release() {
+    t.slotMu.Lock()
+    defer t.slotMu.Unlock()
+    if t.holding {
+        t.holding = false
+        <-t.mgr.slots
+    }
+}
+
+// TaskManager assigns task IDs, bounds concurrency and tracks task state so
+// background research can be listed and controlled from the TUI and CLI.
+type TaskManager struct {
+    bus   EventBus
+    slots chan struct{}
+
+    mu    sync.Mutex
+    seq   int
+    tasks map[string]*Task
+}
+
+// NewTaskManager returns a manager running at most maxConcurrent tasks at once.
+func NewTaskManager(bus EventBus, maxConcurrent int) *TaskManager {
+    if maxConcurrent < 1 { maxConcurrent = 1 }
+    return &TaskManager{bus: bus, slots: make(chan struct{}, maxConcurrent), tasks: map[string]*Task{}}
+}
+
+// newTaskID returns a task ID that sorts by creation time. The random suffix
+// keeps IDs from separate processes (a CLI run next to the TUI) apart, since
+// they double as run IDs naming journal files.
+func newTaskID(seq int) string {
+    var b [3]byte
+    _, _ = rand.Read(b[:])
+    return fmt.Sprintf("task-%s-%d-%s", time.Now().Format("20060102-150405"), seq, hex.EncodeToString(b[:]))
+}
+
+// Submit queues fn and returns the new task ID. If id is empty one is assigned.
+func (m *TaskManager) Submit(ctx context.Context, id, sessionID, title string, fn TaskFunc) string {
+    ctx, cancel := context.WithCancel(ctx)
+    m.mu.Lock()
+    m.seq++
+    if id == "" { id = newTaskID(m.seq) }
+    t := &Task{
+        mgr:    m,
+        info:   TaskInfo{ID: id, SessionID: sessionID, Title: title, State: TaskQueued, CreatedAt: time.Now()},
+        cancel: cancel,
+        done:   make(chan struct{}),
+    }
+    m.tasks[id] = t
+    m.mu.Unlock()
+    m.publishState(t)
+    go m.run(ctx, t, fn)
+    return id
+}
+
+func (m *TaskManager) run(ctx context.Context, t *Task, fn TaskFunc) {
+    defer close(t.done)
+    defer t.cancel()
+    defer t.release()
+    // Wait while paused, then for a slot.
+    err := t.Checkpoint(ctx)
+    if err == nil {
+        m.mu.Lock()
+        t.started = true
+        t.info.StartedAt = time.Now()
+        promoted := t.info.State == TaskQueued
+        if promoted { t.info.State = TaskRunning }
+        m.mu.Unlock()
+        if promoted { m.publishState(t) }
+        err = fn(ctx, t)
+    }
+    m.mu.Lock()
+    t.info.FinishedAt = time.Now()
+    // The outcome decides the state: a run that finished its work before a
+    // cancel took effect is done.
+    switch {
+    case err != nil && ctx.Err() != nil:
+        t.info.State = TaskCancelled
+    case err != nil:
+        t.info.State = TaskFailed
+        t.info.Err = err.Error()
+    default:
+        t.info.State = TaskDone
+    }
+    t.resume = nil
+    m.pruneLocked()
+    m.mu.Unlock()
+    m.publishState(t)
+}
+
+// pruneLocked forgets the oldest finished tasks beyond maxFinishedTasks.
+// m.mu must be held.
+func (m *TaskManager) pruneLocked() {
+    var finished []*Task
+    for _, t := range m.tasks {
+        if t.info.State.Terminal() { finished = append(finished, t) }
+    }
+    if len(finished) <= maxFinishedTasks { return }
+    sort.Slice(finished, func(i, j int) bool { return finished[i].info.FinishedAt.Before(finished[j].info.FinishedAt) })
+    for _, t := range finished[:len(finished)-maxFinishedTasks] { delete(m.tasks, t.info.ID) }
+}
+
+func (m *TaskManager) publishState(t *Task) {
+    m.mu.Lock()
+    info := t.info
+    m.mu.Unlock()
+    m.bus.Publish(context.Background(), Event{
+        SessionID: info.SessionID,
+        TaskID:    info.ID,
+        Phase:     PhaseTask,
+        Type:      string(info.State),
+        Err:       info.Err,
+        At:        time.Now(),
+        Meta:      map[string]any{"title": info.Title},
+    })
+}
+
+// List returns tasks for sessionID (all sessions when empty), oldest first.
+func (m *TaskManager) List(sessionID string) []TaskInfo {
+    m.mu.Lock()
+    out := make([]TaskInfo, 0, len(m.tasks))
+    for _, t := range m.tasks {
+        if sessionID == "" || t.info.SessionID == sessionID { out = append(out, t.info) }
+    }
+    m.mu.Unlock()
+    sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+    return out
+}
+
+// Get returns a snapshot of the task with the given ID.
+func (m *TaskManager) Get(id string) (TaskInfo, bool) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    t, ok := m.tasks[id]
+    if !ok { return TaskInfo{}, false }
+    return t.info, true
+}
+
+// Cancel stops a queued, running or paused task. The task ends as
+// cancelled once it stops, or as done if it finished first.
+func (m *TaskManager) Cancel(id string) error {
+    m.mu.Lock()
+    t, ok := m.tasks[id]
+    if !ok { m.mu.Unlock(); return fmt.Errorf("%w: %s", ErrTaskNotFound, id) }
+    if t.info.State.Terminal() { m.mu.Unlock(); return fmt.Errorf("task %s is already %s", id, t.info.State) }
+    m.mu.Unlock()
+    t.cancel()
+    return nil
+}
+
+// CancelSession cancels every unfinished task of a session and waits until
+// they have stopped or ctx is done, so nothing writes into the session once
+// it is archived or deleted.
+func (m *TaskManager) CancelSession(ctx context.Context, sessionID string) error {
+    m.mu.Lock()
+    var live []*Task
+    for _, t := range m.tasks {
+        if t.info.SessionID == sessionID && !t.info.State.Terminal() { live = append(live, t) }
+    }
+    m.mu.Unlock()
+    for _, t := range live { t.cancel() }
+    for _, t := range live {
+        select {
+        case <-t.done:
+        case <-ctx.Done():
+            return ctx.Err()
+        }
+    }
+    return nil
+}
+
+// Pause suspends a queued or running task at its next checkpoint.
+func (m *TaskManager) Pause(id string) error {
+    m.mu.Lock()
+    t, ok := m.tasks[id]
+    if !ok { m.mu.Unlock(); return fmt.Errorf("%w: %s", ErrTaskNotFound, id) }
+    if t.info.State != TaskQueued && t.info.State != TaskRunning {
+        m.mu.Unlock()
+        return fmt.Errorf("task %s is %s", id, t.info.State)
+    }
+    t.info.State = TaskPaused
+    t.resume = make(chan struct{})
+    t.enterInactiveLocked()
+    m.mu.Unlock()
+    m.publishState(t)
+    return nil
+}
+
+// Resume continues a paused task.
+func (m *TaskManager) Resume(id string) error {
+    m.mu.Lock()
+    t, ok := m.tasks[id]
+    if !ok { m.mu.Unlock(); return fmt.Errorf("%w: %s", ErrTaskNotFound, id) }
+    if t.info.State != TaskPaused { m.mu.Unlock(); return fmt.Errorf("task %s is %s", id, t.info.State) }
+    if t.started { t.info.State = TaskRunning } else { t.info.State = TaskQueued }
+    close(t.resume)
+    t.resume = nil
+    t.leaveInactiveLocked()
+    m.mu.Unlock()
+    m.publishState(t)
+    return nil
+}
+
+// Wait blocks until the task finishes or ctx is done and returns its final snapshot.
+func (m *TaskManager) Wait(ctx context.Context, id string) (TaskInfo, error) {
+    m.mu.Lock()
+    t, ok := m.tasks[id]
+    m.mu.Unlock()
+    if !ok { return TaskInfo{}, fmt.Errorf("%w: %s", ErrTaskNotFound, id) }
+    select {
+    case <-t.done:
+    case <-ctx.Done():
+        return TaskInfo{}, ctx.Err()
+    }
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    return t.info, nil
+}
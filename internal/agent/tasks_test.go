@@ -0,0 +1,173 @@
+package agent
+
+import (
+    "context"
+    "testing"
+    "time"
+)
+
+// waitState polls until task id reaches want.
+func waitState(t *testing.T, m *TaskManager, id string, want TaskState) {
+    t.Helper()
+    deadline := time.Now().Add(2 * time.Second)
+    for time.Now().Before(deadline) {
+        if info, _ := m.Get(id); info.State == want { return }
+        time.Sleep(5 * time.Millisecond)
+    }
+    info, _ := m.Get(id)
+    t.Fatalf("task %s is %s, want %s", id, info.State, want)
+}
+
+// stepper is a task body that does one unit of work per value sent on step
+// and checkpoints between units.
+func stepper(step <-chan struct{}, units int) TaskFunc {
+    return func(ctx context.Context, t *Task) error {
+        for i := 0; i < units; i++ {
+            if err := t.Checkpoint(ctx); err != nil { return err }
+            select {
+            case <-step:
+            case <-ctx.Done():
+                return ctx.Err()
+            }
+        }
+        return t.Checkpoint(ctx)
+    }
+}
+
+func TestTaskPauseResumeDone(t *testing.T) {
+    bus := NewMemoryBus(64)
+    states, cancel := bus.Subscribe(context.Background(), "", WithPhases(PhaseTask))
+    defer cancel()
+    m := NewTaskManager(bus, 1)
+    step := make(chan struct{})
+    id := m.Submit(context.Background(), "", "s1", "steps", stepper(step, 2))
+
+    waitState(t, m, id, TaskRunning)
+    step <- struct{}{}
+    if err := m.Pause(id); err != nil { t.Fatal(err) }
+    if err := m.Resume(id); err != nil { t.Fatal(err) }
+    step <- struct{}{}
+    info, err := m.Wait(context.Background(), id)
+    if err != nil { t.Fatal(err) }
+    if info.State != TaskDone { t.Errorf("final state %s, want done", info.State) }
+
+    var got []string
+    for _, e := range drain(states) { got = append(got, e.Type) }
+    want := []string{"queued", "running", "paused", "running", "done"}
+    if len(got) != len(want) {
+        t.Fatalf("published %v, want %v", got, want)
+    }
+    for i := range want {
+        if got[i] != want[i] { t.Errorf("published %v, want %v", got, want) }
+    }
+}
+
+func TestTaskCancelWhilePaused(t *testing.T) {
+    m := NewTaskManager(NewMemoryBus(64), 1)
+    step := make(chan struct{})
+    id := m.Submit(context.Background(), "", "s1", "steps", stepper(step, 3))
+    waitState(t, m, id, TaskRunning)
+    if err := m.Pause(id); err != nil { t.Fatal(err) }
+    step <- struct{}{} // reach the next checkpoint and block there
+    if err := m.Cancel(id); err != nil { t.Fatal(err) }
+    info, _ := m.Wait(context.Background(), id)
+    if info.State != TaskCancelled { t.Errorf("final state %s, want cancelled", info.State) }
+    if err := m.Cancel(id); err == nil { t.Error("cancelling a finished task succeeded") }
+}
+
+func TestTaskCancelSession(t *testing.T) {
+    m := NewTaskManager(NewMemoryBus(64), 1)
+    step := make(chan struct{})
+    running := m.Submit(context.Background(), "", "s1", "running", stepper(step, 3))
+    waitState(t, m, running, TaskRunning)
+    queued := m.Submit(context.Background(), "", "s1", "queued", stepper(step, 3))
+    other := m.Submit(context.Background(), "", "s2", "other", stepper(step, 3))
+
+    if err := m.CancelSession(context.Background(), "s1"); err != nil { t.Fatal(err) }
+    for _, id := range []string{running, queued} {
+        if info, _ := m.Get(id); info.State != TaskCancelled { t.Errorf("%s is %s after CancelSession, want cancelled", id, info.State) }
+    }
+    if info, _ := m.Get(other); info.State.Terminal() { t.Errorf("task of another session is %s", info.State) }
+    if err := m.Cancel(other); err != nil { t.Fatal(err) }
+}
+
+func TestTaskFinishedBeforeCancelIsDone(t *testing.T) {
+    m := NewTaskManager(NewMemoryBus(64), 1)
+    started, finish := make(chan struct{}), make(chan struct{})
+    id := m.Submit(context.Background(), "", "s1", "report", func(ctx context.Context, t *Task) error {
+        close(started)
+        <-finish // the report is written regardless of the cancel
+        return nil
+    })
+    <-started
+    if err := m.Cancel(id); err != nil { t.Fatal(err) }
+    close(finish)
+    info, _ := m.Wait(context.Background(), id)
+    if info.State != TaskDone { t.Errorf("final state %s, want done", info.State) }
+}
+
+func TestQueuedTaskCancelled(t *testing.T) {
+    m := NewTaskManager(NewMemoryBus(64), 1)
+    step := make(chan struct{})
+    first := m.Submit(context.Background(), "", "s1", "first", stepper(step, 1))
+    waitState(t, m, first, TaskRunning)
+    second := m.Submit(context.Background(), "", "s1", "second", stepper(step, 1))
+    if info, _ := m.Get(second); info.State != TaskQueued { t.Fatalf("second task is %s, want queued", info.State) }
+    if err := m.Cancel(second); err != nil { t.Fatal(err) }
+    info, _ := m.Wait(context.Background(), second)
+    if info.State != TaskCancelled || !info.StartedAt.IsZero() {
+        t.Errorf("second task %s, started %v; want cancelled without starting", info.State, info.StartedAt)
+    }
+    step <- struct{}{}
+    m.Wait(context.Background(), first)
+}
+
+func TestPausedTaskFreesSlot(t *testing.T) {
+    m := NewTaskManager(NewMemoryBus(64), 1)
+    step := make(chan struct{})
+    paused := m.Submit(context.Background(), "", "s1", "paused", stepper(step, 2))
+    waitState(t, m, paused, TaskRunning)
+    if err := m.Pause(paused); err != nil { t.Fatal(err) }
+    step <- struct{}{} // let it reach a checkpoint
+
+    other := m.Submit(context.Background(), "", "s1", "other", func(ctx context.Context, t *Task) error { return nil })
+    ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+    defer cancel()
+    if info, err := m.Wait(ctx, other); err != nil || info.State != TaskDone {
+        t.Fatalf("other task %+v, %v; it should run while the first is paused", info, err)
+    }
+
+    if err := m.Resume(paused); err != nil { t.Fatal(err) }
+    step <- struct{}{}
+    if info, _ := m.Wait(context.Background(), paused); info.State != TaskDone {
+        t.Errorf("resumed task ended %s, want done", info.State)
+    }
+}
+
+func TestIdleTaskFreesSlot(t *testing.T) {
+    m := NewTaskManager(NewMemoryBus(64), 1)
+    answer := make(chan struct{})
+    asking := m.Submit(context.Background(), "", "s1", "asking", func(ctx context.Context, t *Task) error {
+        return t.Idle(ctx, func() error { <-answer; return nil })
+    })
+    waitState(t, m, asking, TaskRunning)
+    other := m.Submit(context.Background(), "", "s1", "other", func(ctx context.Context, t *Task) error { return nil })
+    ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+    defer cancel()
+    if info, err := m.Wait(ctx, other); err != nil || info.State != TaskDone {
+        t.Fatalf("other task %+v, %v; it should run while the first waits for answers", info, err)
+    }
+    close(answer)
+    if info, _ := m.Wait(context.Background(), asking); info.State != TaskDone {
+        t.Errorf("asking task ended %s, want done", info.State)
+    }
+}
+
+func TestFinishedTasksPruned(t *testing.T) {
+    m := NewTaskManager(NewMemoryBus(256), 4)
+    for i := 0; i < maxFinishedTasks+10; i++ {
+        id := m.Submit(context.Background(), "", "s1", "quick", func(ctx context.Context, t *Task) error { return nil })
+        m.Wait(context.Background(), id)
+    }
+    if n := len(m.List("")); n != maxFinishedTasks { t.Errorf("remembered %d tasks, want %d", n, maxFinishedTasks) }
+}
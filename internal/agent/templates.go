@@ -0,0 +1,255 @@
+package agent
+
+import (
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+// Template shapes a report: the planner fills in its sections, the writer
+// follows its guidance, and the final layout uses its options.
+type Template struct {
+    Name        string
+    Description string
+    Guidance    string    // audience and style, given to the planner and writer
+    Sections    []Section // outline the planner starts from, in order
+    // Fixed keeps exactly the template's sections; otherwise the planner may
+    // add topic-specific sections around them.
+    Fixed    bool
+    Words    int  // target length per section; 0 leaves it to the writer
+    Contents bool // list the sections after the title
+    Path     string // file a user-defined template was loaded from
+}
+
+var builtinTemplates = []Template{
+    {
+        Name:        "executive-brief",
+        Description: "One-page brief for decision makers: conclusions first",
+        Guidance:    "Readers are busy executives. Lead with conclusions, use plain language, short paragraphs and bullets, and quantify where the sources allow. No jargon, no background they do not need.",
+        Fixed:       true,
+        Words:       150,
+        Sections: []Section{
+            {Heading: "Bottom Line", Instructions: "State the answer and its confidence in two or three sentences."},
+            {Heading: "Key Findings", Instructions: "Three to five bullets with the evidence behind the bottom line."},
+            {Heading: "Implications", Instructions: "What the findings mean for the reader: opportunities, costs and risks."},
+            {Heading: "Recommended Actions", Instructions: "Concrete next steps, in priority order."},
+        },
+    },
+    {
+        Name:        "literature-review",
+        Description: "Synthesis of published work by theme, with debates and gaps",
+        Guidance:    "Academic register. Synthesize across sources rather than summarizing them one by one, note methods and sample sizes where they matter, and point out where studies disagree. Prefer peer-reviewed and primary sources.",
+        Words:       350,
+        Contents:    true,
+        Sections: []Section{
+            {Heading: "Scope and Method", Instructions: "The question reviewed, what kinds of sources were considered and how."},
+            {Heading: "Background", Instructions: "Key concepts and how the field arrived at the current questions."},
+            {Heading: "Debates and Disagreements", Instructions: "Where findings conflict and the likely reasons."},
+            {Heading: "Gaps and Future Research", Instructions: "What the literature does not yet answer."},
+            {Heading: "Conclusion", Instructions: "What the evidence supports overall and how strongly."},
+        },
+    },
+    {
+        Name:        "decision-memo",
+        Description: "Memo that frames a decision, weighs the options and recommends one",
+        Guidance:    "Write for the person who must decide. Be even-handed about the options before recommending one, and make trade-offs and assumptions explicit.",
+        Fixed:       true,
+        Words:       250,
+        Sections: []Section{
+            {Heading: "Decision Required", Instructions: "The decision, who makes it and by when, in a few sentences."},
+            {Heading: "Background", Instructions: "Only the context needed to understand the options."},
+            {Heading: "Options", Instructions: "Each realistic option with its benefits, costs and risks."},
+            {Heading: "Recommendation", Instructions: "The recommended option and why it beats the others."},
+            {Heading: "Risks and Mitigations", Instructions: "What could go wrong with the recommendation and how to limit it."},
+            {Heading: "Next Steps", Instructions: "Actions, owners and checkpoints if the recommendation is accepted."},
+        },
+    },
+    {
+        Name:        "technical-explainer",
+        Description: "Explains how a technology works, for practitioners",
+        Guidance:    "Readers are engineers new to the topic. Build from first principles to specifics, define terms on first use, and use small examples or code where they make a point clearer.",
+        Words:       350,
+        Contents:    true,
+        Sections: []Section{
+            {Heading: "Overview", Instructions: "What it is, the problem it solves and where it is used."},
+            {Heading: "How It Works", Instructions: "The mechanism step by step, with the key concepts defined."},
+            {Heading: "Trade-offs and Limitations", Instructions: "Costs, failure modes and when not to use it."},
+            {Heading: "Alternatives", Instructions: "Related approaches and how they compare."},
+            {Heading: "Getting Started", Instructions: "Practical first steps, tools and further reading from the sources."},
+        },
+    },
+}
+
+// Templates returns the built-in templates and the user-defined ones in
+// .gotcha/templates, sorted by name. A user template replaces a built-in
+// one of the same name. Files that fail to load are left out, along with
+// any built-in they were meant to replace, and reported in the error; the
+// templates that did load are returned either way.
+func (r *Researcher) Templates() ([]Template, error) {
+    byName, broken := r.loadTemplates()
+    out := make([]Template, 0, len(byName))
+    for _, t := range byName { out = append(out, t) }
+    sort.Slice(out, func(i, k int) bool { return out[i].Name < out[k].Name })
+    names := make([]string, 0, len(broken))
+    for n := range broken { names = append(names, n) }
+    sort.Strings(names)
+    var errs []error
+    for _, n := range names { errs = append(errs, broken[n]) }
+    return out, errors.Join(errs...)
+}
+
+// Template looks up a template by name; "Executive brief" and
+// "executive-brief" name the same one. Only a broken file for this very
+// template makes it fail.
+func (r *Researcher) Template(name string) (Template, error) {
+    byName, broken := r.loadTemplates()
+    key := templateKey(name)
+    if err := broken[key]; err != nil { return Template{}, err }
+    if t, ok := byName[key]; ok { return t, nil }
+    names := make([]string, 0, len(byName))
+    for n := range byName { names = append(names, n) }
+    sort.Strings(names)
+    return Template{}, fmt.Errorf("unknown report template %q (have %s)", name, strings.Join(names, ", "))
+}
+
+// loadTemplates returns the usable templates by name, and the load error of
+// every user template file that failed, keyed by the name of the file.
+func (r *Researcher) loadTemplates() (map[string]Template, map[string]error) {
+    byName := map[string]Template{}
+    for _, t := range builtinTemplates { byName[t.Name] = t }
+    broken := map[string]error{}
+    paths, _ := filepath.Glob(filepath.Join(r.svc.TemplatesDir(), "*.md")) // the pattern is well-formed
+    for _, p := range paths {
+        t, err := loadTemplate(p)
+        if err != nil {
+            key := templateKey(strings.TrimSuffix(filepath.Base(p), filepath.Ext(p)))
+            broken[key] = err
+            delete(byName, key)
+            continue
+        }
+        byName[t.Name] = t
+    }
+    return byName, broken
+}
+
+// runTemplate returns the run's template, or nil when it uses none.
+func (r *Researcher) runTemplate(j *Journal) (*Template, error) {
+    if j.Options.Template == "" { return nil, nil }
+    t, err := r.Template(j.Options.Template)
+    if err != nil { return nil, err }
+    return &t, nil
+}
+
+var reTemplateKey = regexp.MustCompile(`[^a-z0-9]+`)
+
+func templateKey(name string) string {
+    return strings.Trim(reTemplateKey.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+// loadTemplate reads a user-defined template: Markdown whose front matter
+// sets name, description, fixed, words and contents, whose text before the
+// first "## " heading is the guidance, and whose "## " sections are the
+// outline, each with its instructions.
+func loadTemplate(path string) (Template, error) {
+    data, err := os.ReadFile(path)
+    if err != nil { return Template{}, fmt.Errorf("template %s: %w", path, err) }
+    doc := string(data)
+    fm := frontMatter(doc)
+    t := Template{Name: fm["name"], Description: fm["description"], Path: path}
+    if t.Name == "" { t.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)) }
+    t.Name = templateKey(t.Name)
+    t.Fixed, _ = strconv.ParseBool(fm["fixed"])
+    t.Contents, _ = strconv.ParseBool(fm["contents"])
+    t.Words, _ = strconv.Atoi(fm["words"])
+    var guidance []string
+    for _, line := range strings.Split(stripFrontMatter(doc), "\n") {
+        if strings.HasPrefix(line, "## ") {
+            t.Sections = append(t.Sections, Section{Heading: safeHead(line)})
+            continue
+        }
+        if n := len(t.Sections); n > 0 {
+            s := &t.Sections[n-1]
+            s.Instructions = strings.TrimSpace(s.Instructions + "\n" + line)
+        } else if !strings.HasPrefix(line, "# ") {
+            guidance = append(guidance, line)
+        }
+    }
+    t.Guidance = strings.TrimSpace(strings.Join(guidance, "\n"))
+    if len(t.Sections) == 0 { return Template{}, fmt.Errorf("template %s: no \"## \" sections", path) }
+    return t, nil
+}
+
+// plannerBrief tells the planner how the template constrains the outline.
+func (t *Template) plannerBrief() string {
+    var b strings.Builder
+    fmt.Fprintf(&b, "Report template: %s (%s).\n", t.Name, t.Description)
+    if t.Guidance != "" { fmt.Fprintf(&b, "Audience and style: %s\n", t.Guidance) }
+    if t.Fixed {
+        b.WriteString("Use exactly these sections, in this order, with these headings; tailor each one's instructions to the prompt:\n")
+    } else {
+        b.WriteString("Keep these sections in this order and with these headings; tailor their instructions to the prompt, and add topic-specific sections between them where the prompt calls for it:\n")
+    }
+    for _, s := range t.Sections { fmt.Fprintf(&b, "- %s: %s\n", s.Heading, s.Instructions) }
+    return b.String()
+}
+
+// writerBrief is added to every section prompt.
+func (t *Template) writerBrief() string {
+    var b strings.Builder
+    if t.Guidance != "" { fmt.Fprintf(&b, "\nAudience and style: %s\n", t.Guidance) }
+    if t.Words > 0 { fmt.Fprintf(&b, "Length: about %d words.\n", t.Words) }
+    return b.String()
+}
+
+// apply makes a planned outline conform to the template. A fixed template's
+// sections replace the plan's, keeping the planner's instructions for
+// headings it kept; otherwise template sections the planner dropped are put
+// back after the template section before them, ones it moved ahead of an
+// earlier template section are moved back behind it, and kept ones get the
+// template's heading.
+func (t *Template) apply(p Plan) Plan {
+    planned := map[string]Section{}
+    for _, s := range p.Sections { planned[strings.ToLower(safeHead(s.Heading))] = s }
+    if t.Fixed {
+        out := make([]Section, len(t.Sections))
+        for i, s := range t.Sections {
+            out[i] = s
+            ps, ok := planned[strings.ToLower(s.Heading)]
+            if !ok { continue }
+            if strings.TrimSpace(ps.Instructions) != "" { out[i].Instructions = ps.Instructions }
+            out[i].Queries, out[i].SourceTypes, out[i].Recency = ps.Queries, ps.SourceTypes, ps.Recency
+        }
+        p.Sections = out
+        return p
+    }
+    at := 0
+    for _, s := range t.Sections {
+        i := sectionIndex(p.Sections, s.Heading)
+        switch {
+        case i < 0:
+            p.InsertSection(at, s)
+            i = at
+        case i < at:
+            p.MoveSection(i, at-1)
+            i = at - 1
+            fallthrough
+        default:
+            p.Sections[i].Heading = s.Heading
+            if strings.TrimSpace(p.Sections[i].Instructions) == "" { p.Sections[i].Instructions = s.Instructions }
+        }
+        at = i + 1
+    }
+    return p
+}
+
+func sectionIndex(ss []Section, heading string) int {
+    for i, s := range ss {
+        if strings.EqualFold(safeHead(s.Heading), heading) { return i }
+    }
+    return -1
+}
@@ -0,0 +1,135 @@
+package agent
+
+import (
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+func writeTemplate(t *testing.T, dir, name, doc string) string {
+    t.Helper()
+    if err := os.MkdirAll(dir, 0o755); err != nil { t.Fatal(err) }
+    path := filepath.Join(dir, name)
+    if err := os.WriteFile(path, []byte(doc), 0o644); err != nil { t.Fatal(err) }
+    return path
+}
+
+func TestLoadTemplate(t *testing.T) {
+    dir := t.TempDir()
+    path := writeTemplate(t, dir, "ignored.md", `---
+name: Board Update
+description: Quarterly update for the board
+fixed: true
+words: 200
+contents: yes
+---
+
+# Board update
+
+Readers are board members.
+Keep it short.
+
+## Highlights
+What went well.
+
+## Risks
+What could go wrong.
+Include mitigations.
+`)
+    got, err := loadTemplate(path)
+    if err != nil { t.Fatal(err) }
+    want := Template{
+        Name:        "board-update",
+        Description: "Quarterly update for the board",
+        Guidance:    "Readers are board members.\nKeep it short.",
+        Sections: []Section{
+            {Heading: "Highlights", Instructions: "What went well."},
+            {Heading: "Risks", Instructions: "What could go wrong.\nInclude mitigations."},
+        },
+        Fixed: true,
+        Words: 200,
+        Path:  path,
+    }
+    // "yes" is not a boolean strconv understands, so contents stays off.
+    if !reflect.DeepEqual(got, want) { t.Errorf("loadTemplate:\n got %+v\nwant %+v", got, want) }
+
+    bare, err := loadTemplate(writeTemplate(t, dir, "Field Notes.md", "## Notes\n"))
+    if err != nil { t.Fatal(err) }
+    if bare.Name != "field-notes" || bare.Fixed || bare.Words != 0 { t.Errorf("template without front matter: %+v", bare) }
+
+    if _, err := loadTemplate(writeTemplate(t, dir, "empty.md", "# Only a title\n\nNo sections.\n")); err == nil { t.Error("template without sections loaded") }
+    if _, err := loadTemplate(filepath.Join(dir, "missing.md")); err == nil { t.Error("missing template loaded") }
+}
+
+func TestTemplatesSkipBrokenFiles(t *testing.T) {
+    r := testResearcher(t)
+    dir := r.svc.TemplatesDir()
+    writeTemplate(t, dir, "memo.md", "## Summary\n")
+    writeTemplate(t, dir, "broken.md", "no sections here\n")
+    // A broken override hides the built-in it was meant to replace.
+    writeTemplate(t, dir, "executive-brief.md", "also broken\n")
+
+    all, err := r.Templates()
+    if err == nil || !strings.Contains(err.Error(), "broken.md") || !strings.Contains(err.Error(), "executive-brief.md") {
+        t.Errorf("Templates error %v, want one naming both broken files", err)
+    }
+    var names []string
+    for _, tpl := range all { names = append(names, tpl.Name) }
+    want := []string{"decision-memo", "literature-review", "memo", "technical-explainer"}
+    if !reflect.DeepEqual(names, want) { t.Errorf("templates %v, want %v", names, want) }
+
+    if tpl, err := r.Template("Memo"); err != nil || tpl.Name != "memo" { t.Errorf("Template(Memo) = %+v, %v", tpl, err) }
+    if _, err := r.Template("decision memo"); err != nil { t.Errorf("a broken file fails an unrelated template: %v", err) }
+    if _, err := r.Template("broken"); err == nil || !strings.Contains(err.Error(), "no \"## \" sections") { t.Errorf("Template(broken) error %v, want the load error", err) }
+    if _, err := r.Template("executive-brief"); err == nil { t.Error("broken override fell back to the built-in") }
+    if _, err := r.Template("nope"); err == nil || !strings.Contains(err.Error(), "have decision-memo, literature-review") { t.Errorf("Template(nope) error %v", err) }
+}
+
+func headings(p Plan) []string {
+    var out []string
+    for _, s := range p.Sections { out = append(out, s.Heading) }
+    return out
+}
+
+func TestTemplateApply(t *testing.T) {
+    tpl := &Template{Sections: []Section{
+        {Heading: "Overview", Instructions: "template overview"},
+        {Heading: "Details", Instructions: "template details"},
+        {Heading: "Conclusion", Instructions: "template conclusion"},
+    }}
+
+    cases := []struct {
+        name    string
+        planned []string
+        want    []string
+    }{
+        {"kept as planned", []string{"Overview", "History", "Details", "Conclusion"}, []string{"Overview", "History", "Details", "Conclusion"}},
+        {"dropped sections put back", []string{"History", "Details"}, []string{"Overview", "History", "Details", "Conclusion"}},
+        {"headings take the template's case", []string{"overview", "DETAILS", "conclusion"}, []string{"Overview", "Details", "Conclusion"}},
+        {"reordered sections moved back", []string{"Conclusion", "Details", "Overview"}, []string{"Overview", "Details", "Conclusion"}},
+        {"extras stay in place", []string{"Conclusion", "Extra", "Overview", "Details"}, []string{"Extra", "Overview", "Details", "Conclusion"}},
+    }
+    for _, c := range cases {
+        var p Plan
+        for _, h := range c.planned { p.Sections = append(p.Sections, Section{Heading: h}) }
+        if got := headings(tpl.apply(p)); !reflect.DeepEqual(got, c.want) { t.Errorf("%s: %v, want %v", c.name, got, c.want) }
+    }
+
+    p := tpl.apply(Plan{Sections: []Section{{Heading: "Details", Instructions: "planner details"}}})
+    if p.Sections[0].Instructions != "template overview" || p.Sections[1].Instructions != "planner details" {
+        t.Errorf("instructions %+v, want the template's for added sections and the planner's for kept ones", p.Sections)
+    }
+
+    fixed := *tpl
+    fixed.Fixed = true
+    p = fixed.apply(Plan{Sections: []Section{
+        {Heading: "Extra", Instructions: "dropped"},
+        {Heading: "details", Instructions: "planner details", Queries: []string{"q"}, Recency: "past year"},
+        {Heading: "Conclusion"},
+    }})
+    if got, want := headings(p), []string{"Overview", "Details", "Conclusion"}; !reflect.DeepEqual(got, want) { t.Errorf("fixed: %v, want %v", got, want) }
+    if d := p.Sections[1]; d.Instructions != "planner details" || len(d.Queries) != 1 || d.Recency != "past year" { t.Errorf("fixed kept section %+v, want the planner's details", d) }
+    if c := p.Sections[2]; c.Instructions != "template conclusion" { t.Errorf("fixed section without planner instructions %+v", c) }
+}
@@ -0,0 +1,323 @@
+package agent
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "sort"
+    "strings"
+
+    "gotcha/internal/llm"
+    "gotcha/internal/search"
+)
+
+// Verdict is the outcome of checking a claim against the fetched sources.
+type Verdict string
+
+const (
+    VerdictSupported    Verdict = "supported"
+    VerdictContradicted Verdict = "contradicted"
+    VerdictUnsupported  Verdict = "unsupported"
+)
+
+// Claim is one factual statement extracted from a composed section.
+type Claim struct {
+    Text    string  `json:"text"`
+    Quote   string  `json:"quote,omitempty"` // verbatim span of the section the claim was taken from
+    Verdict Verdict `json:"verdict"`
+    Sources []int   `json:"sources,omitempty"` // 1-based source numbers backing the verdict
+    Note    string  `json:"note,omitempty"`
+}
+
+// SectionCheck is the verification result of one section.
+type SectionCheck struct {
+    Done   bool    `json:"done"`
+    Claims []Claim `json:"claims,omitempty"`
+}
+
+const (
+    maxClaimsPerSection = 12
+    claimPassages       = 3 // passages retrieved per claim
+)
+
+// verify extracts the factual claims of every composed section and judges
+// each one against passages from the fetched sources. Results are
+// checkpointed per section; the report flags claims that are not supported.
+func (r *Researcher) verify(ctx context.Context, st *runState) error {
+    j := st.j
+    if r.llm == nil {
+        st.publish(ctx, Event{Phase: PhaseVerify, Type: "done", Meta: map[string]any{"skipped": "LLM not configured"}})
+        return nil
+    }
+    if len(j.Checks) != len(j.Sections) { j.Checks = make([]SectionCheck, len(j.Sections)) }
+    total := len(j.Sections)
+    done := 0
+    for _, c := range j.Checks { if c.Done { done++ } }
+    st.publish(ctx, Event{Phase: PhaseVerify, Type: "started", Progress: Progress{Done: done, Total: total}})
+    for i, text := range j.Sections {
+        if j.Checks[i].Done { continue }
+        if err := st.t.Checkpoint(ctx); err != nil { return err }
+        claims, err := r.checkSection(ctx, st, text)
+        if err != nil {
+            if ctx.Err() != nil { return ctx.Err() }
+            if errors.Is(err, ErrBudgetExhausted) { return err }
+            // A failed check leaves the section unflagged rather than failing the run.
+            st.publish(ctx, Event{Phase: PhaseVerify, Type: "error", Err: err.Error(), Meta: map[string]any{"section": sectionHeading(text)}})
+        }
+        j.Checks[i] = SectionCheck{Done: true, Claims: claims}
+        if err := st.save(); err != nil { return err }
+        done++
+        counts := countVerdicts(claims)
+        st.publish(ctx, Event{Phase: PhaseVerify, Type: "progress", Progress: Progress{Done: done, Total: total}, Meta: map[string]any{
+            "section":      sectionHeading(text),
+            "claims":       len(claims),
+            "supported":    counts[VerdictSupported],
+            "contradicted": counts[VerdictContradicted],
+            "unsupported":  counts[VerdictUnsupported],
+        }})
+    }
+    var all []Claim
+    for _, c := range j.Checks { all = append(all, c.Claims...) }
+    counts := countVerdicts(all)
+    st.publish(ctx, Event{Phase: PhaseVerify, Type: "done", Progress: Progress{Done: total, Total: total}, Meta: map[string]any{
+        "claims":       len(all),
+        "supported":    counts[VerdictSupported],
+        "contradicted": counts[VerdictContradicted],
+        "unsupported":  counts[VerdictUnsupported],
+    }})
+    return nil
+}
+
+// checkSection runs the two verification steps for one section: claim
+// extraction, then a judgement of every claim against its best passages.
+func (r *Researcher) checkSection(ctx context.Context, st *runState, section string) ([]Claim, error) {
+    claims, err := r.extractClaims(ctx, st, section)
+    if err != nil || len(claims) == 0 { return nil, err }
+    docs, weights := st.j.Sources, st.j.sourceWeights()
+    if len(docs) == 0 {
+        for i := range claims {
+            claims[i].Verdict = VerdictUnsupported
+            claims[i].Note = "no sources were retrieved"
+        }
+        return claims, nil
+    }
+
+    var b strings.Builder
+    for i, c := range claims {
+        fmt.Fprintf(&b, "Claim %d: %s\n", i+1, c.Text)
+        if ev := formatPassages(claimEvidence(docs, weights, c)); ev != "" {
+            b.WriteString("Evidence:\n" + ev + "\n\n")
+        } else {
+            b.WriteString("Evidence: (none found)\n\n")
+        }
+    }
+    b.WriteString("Return JSON only.")
+    sys := "You are a fact checker. For each numbered claim, judge it only against the evidence listed under it. Return strict JSON: {\"results\": [{\"claim\": n, \"verdict\": \"supported\"|\"contradicted\"|\"unsupported\", \"sources\": [source numbers that support or contradict it], \"note\": short reason, required when not supported}]}. A claim is supported only when the evidence states it, including any numbers; contradicted when the evidence states otherwise; unsupported when the evidence is silent. No extra text."
+    res, err := st.complete(ctx, llm.Request{System: sys, Prompt: b.String(), MaxTokens: 1200, Temperature: 0})
+    if err != nil { return nil, err }
+    var out struct {
+        Results []struct {
+            Claim   int     `json:"claim"`
+            Verdict Verdict `json:"verdict"`
+            Sources []int   `json:"sources"`
+            Note    string  `json:"note"`
+        } `json:"results"`
+    }
+    if err := json.Unmarshal([]byte(trimFences(res.Text)), &out); err != nil { return nil, fmt.Errorf("unreadable verdicts: %w", err) }
+    for i := range claims { claims[i].Verdict = VerdictUnsupported }
+    for _, v := range out.Results {
+        if v.Claim < 1 || v.Claim > len(claims) { continue }
+        c := &claims[v.Claim-1]
+        switch v.Verdict {
+        case VerdictSupported, VerdictContradicted, VerdictUnsupported:
+            c.Verdict = v.Verdict
+        }
+        c.Sources = nil
+        for _, n := range v.Sources {
+            if n >= 1 && n <= len(docs) { c.Sources = append(c.Sources, n) }
+        }
+        c.Note = strings.TrimSpace(v.Note)
+    }
+    return claims, nil
+}
+
+func (r *Researcher) extractClaims(ctx context.Context, st *runState, section string) ([]Claim, error) {
+    sys := fmt.Sprintf("You extract checkable factual claims (figures, dates, names, causal or comparative statements) from a report section. Skip opinions, recommendations and definitions. Return strict JSON: {\"claims\": [{\"text\": self-contained claim, \"quote\": the shortest verbatim span of the section stating it, \"sources\": [citation numbers like [n] attached to it]}]}. At most %d claims. No extra text.", maxClaimsPerSection)
+    res, err := st.complete(ctx, llm.Request{System: sys, Prompt: section + "\n\nReturn JSON only.", MaxTokens: 900, Temperature: 0})
+    if err != nil { return nil, err }
+    var out struct {
+        Claims []Claim `json:"claims"`
+    }
+    if err := json.Unmarshal([]byte(trimFences(res.Text)), &out); err != nil { return nil, fmt.Errorf("unreadable claims: %w", err) }
+    var claims []Claim
+    for _, c := range out.Claims {
+        c.Text = strings.TrimSpace(c.Text)
+        if c.Text == "" { continue }
+        c.Quote = strings.TrimSpace(c.Quote)
+        c.Verdict = ""
+        claims = append(claims, c)
+        if len(claims) == maxClaimsPerSection { break }
+    }
+    return claims, nil
+}
+
+// claimEvidence returns the passages most relevant to a claim, always
+// including the best passage of each source the section cited for it.
+// Sources are ranked by weights as in selectPassages.
+func claimEvidence(docs []search.Document, weights []float64, c Claim) []passage {
+    var out []passage
+    seen := map[string]bool{}
+    add := func(p passage) {
+        key := fmt.Sprintf("%d:%s", p.Source, p.Text)
+        if seen[key] { return }
+        seen[key] = true
+        out = append(out, p)
+    }
+    for _, n := range c.Sources {
+        if n < 1 || n > len(docs) { continue }
+        for _, p := range selectPassages(docs[n-1:n], nil, c.Text, 1) {
+            p.Source = n - 1
+            add(p)
+        }
+    }
+    for _, p := range selectPassages(docs, weights, c.Text, claimPassages) { add(p) }
+    return out
+}
+
+func countVerdicts(claims []Claim) map[Verdict]int {
+    out := map[Verdict]int{}
+    for _, c := range claims { out[c.Verdict]++ }
+    return out
+}
+
+// flagClaims marks contradicted and unsupported claims inline, right after
+// the sentence that states them.
+func flagClaims(section string, claims []Claim) string {
+    type mark struct {
+        at   int
+        text string
+    }
+    var marks []mark
+    for _, c := range claims {
+        var label string
+        switch c.Verdict {
+        case VerdictContradicted:
+            label = " *[contradicted by sources" + citeList(c.Sources) + "]*"
+        case VerdictUnsupported:
+            label = " *[unverified]*"
+        default:
+            continue
+        }
+        if c.Quote == "" { continue }
+        // A repeated quote is flagged in every sentence that repeats it.
+        for from := 0; ; {
+            i := strings.Index(section[from:], c.Quote)
+            if i < 0 { break }
+            i += from
+            marks = append(marks, mark{at: sentenceEnd(section, i+len(c.Quote)), text: label})
+            from = i + len(c.Quote)
+        }
+    }
+    // Insert from the end so earlier offsets stay valid.
+    sort.SliceStable(marks, func(a, b int) bool { return marks[a].at > marks[b].at })
+    last := -1
+    for _, m := range marks {
+        if m.at == last { continue } // one flag per sentence
+        last = m.at
+        section = section[:m.at] + m.text + section[m.at:]
+    }
+    return section
+}
+
+// sentenceEnd returns the offset just past the sentence containing pos,
+// including any trailing citation markers.
+func sentenceEnd(s string, pos int) int {
+    for i := pos; i < len(s); i++ {
+        switch s[i] {
+        case '\n':
+            return i
+        case '.', '!', '?':
+            end := i + 1
+            if end < len(s) && s[end] != ' ' && s[end] != '\n' && citationEnd(s, end) < 0 { continue }
+            // Markers follow the period as in "… rose. [2][3]" or "… rose.[2]".
+            for {
+                j := end
+                for j < len(s) && (s[j] == ' ' || s[j] == ',') { j++ }
+                k := citationEnd(s, j)
+                if k < 0 { break }
+                end = k
+            }
+            return end
+        }
+    }
+    return len(s)
+}
+
+// citationEnd returns the offset past a citation marker such as "[3]" or
+// "[1, 4]" starting at i, or -1 if there is none.
+func citationEnd(s string, i int) int {
+    if i >= len(s) || s[i] != '[' { return -1 }
+    digits := false
+    for j := i + 1; j < len(s); j++ {
+        switch c := s[j]; {
+        case c >= '0' && c <= '9':
+            digits = true
+        case c == ',' || c == ' ':
+        case c == ']' && digits:
+            return j + 1
+        default:
+            return -1
+        }
+    }
+    return -1
+}
+
+func citeList(sources []int) string {
+    if len(sources) == 0 { return "" }
+    parts := make([]string, len(sources))
+    for i, n := range sources { parts[i] = fmt.Sprintf("[%d]", n) }
+    return " " + strings.Join(parts, ", ")
+}
+
+// sectionHeading returns the first Markdown heading of a composed section.
+func sectionHeading(section string) string {
+    for _, line := range strings.Split(section, "\n") {
+        if strings.HasPrefix(line, "#") { return safeHead(line) }
+    }
+    return ""
+}
+
+// writeVerificationSummary lists every checked claim with its verdict and the
+// sources behind it, so each figure in the report can be traced.
+func writeVerificationSummary(b *strings.Builder, j *Journal, sections []string, checks []SectionCheck) {
+    var rows []string
+    var all []Claim
+    for _, c := range checks {
+        if !c.Done { continue }
+        all = append(all, c.Claims...)
+    }
+    if len(all) == 0 { return }
+    counts := countVerdicts(all)
+    b.WriteString("## Verification Summary\n\n")
+    fmt.Fprintf(b, "%d claims checked against %d sources: %d supported, %d contradicted, %d unsupported.\n\n",
+        len(all), len(j.Sources), counts[VerdictSupported], counts[VerdictContradicted], counts[VerdictUnsupported])
+    b.WriteString("| Section | Claim | Verdict | Evidence |\n|---|---|---|---|\n")
+    for i, c := range checks {
+        heading := sectionHeading(sections[i])
+        for _, cl := range c.Claims {
+            evidence := strings.TrimSpace(citeList(cl.Sources))
+            if cl.Note != "" {
+                if evidence != "" { evidence += " " }
+                evidence += cl.Note
+            }
+            if evidence == "" { evidence = "—" }
+            rows = append(rows, fmt.Sprintf("| %s | %s | %s | %s |", escapeCell(heading), escapeCell(cl.Text), cl.Verdict, escapeCell(evidence)))
+        }
+    }
+    b.WriteString(strings.Join(rows, "\n") + "\n\n")
+}
+
+func escapeCell(s string) string {
+    return strings.NewReplacer("|", "\\|", "\n", " ").Replace(strings.TrimSpace(s))
+}
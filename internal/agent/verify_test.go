@@ -0,0 +1,80 @@
+package agent
+
+import "testing"
+
+func TestSentenceEnd(t *testing.T) {
+    cases := []struct {
+        name string
+        s    string
+        pos  int
+        want string // s up to the returned offset
+    }{
+        {"period", "Sales rose. Then fell.", 5, "Sales rose."},
+        {"end of text", "Sales rose", 5, "Sales rose"},
+        {"line break", "Sales rose\nThen fell.", 5, "Sales rose"},
+        {"marker before period", "Sales rose [1]. Then fell.", 5, "Sales rose [1]."},
+        {"marker after period", "Sales rose. [1] Then fell.", 5, "Sales rose. [1]"},
+        {"attached markers", "Sales rose.[1][2] Then fell.", 5, "Sales rose.[1][2]"},
+        {"grouped marker", "Sales rose. [1, 3], [4] Then fell.", 5, "Sales rose. [1, 3], [4]"},
+        {"decimal", "Sales rose 2.5 times. Then fell.", 5, "Sales rose 2.5 times."},
+        {"bracket that is not a marker", "Sales rose. [see below] Then fell.", 5, "Sales rose."},
+    }
+    for _, c := range cases {
+        if got := c.s[:sentenceEnd(c.s, c.pos)]; got != c.want { t.Errorf("%s: got %q, want %q", c.name, got, c.want) }
+    }
+}
+
+func TestFlagClaims(t *testing.T) {
+    cases := []struct {
+        name    string
+        section string
+        claims  []Claim
+        want    string
+    }{
+        {
+            name:    "supported claims are left alone",
+            section: "Sales rose in 2023. Costs fell.",
+            claims:  []Claim{{Quote: "Sales rose", Verdict: VerdictSupported}},
+            want:    "Sales rose in 2023. Costs fell.",
+        },
+        {
+            name:    "several flags in one section",
+            section: "Sales rose in 2023. Costs fell. Margins held.",
+            claims: []Claim{
+                {Quote: "Sales rose", Verdict: VerdictUnsupported},
+                {Quote: "Margins held", Verdict: VerdictContradicted, Sources: []int{2, 5}},
+            },
+            want: "Sales rose in 2023. *[unverified]* Costs fell. Margins held. *[contradicted by sources [2], [5]]*",
+        },
+        {
+            name:    "one flag per sentence",
+            section: "Sales rose and costs fell. Margins held.",
+            claims: []Claim{
+                {Quote: "Sales rose", Verdict: VerdictUnsupported},
+                {Quote: "costs fell", Verdict: VerdictContradicted},
+            },
+            want: "Sales rose and costs fell. *[unverified]* Margins held.",
+        },
+        {
+            name:    "repeated quote",
+            section: "Sales rose. Costs fell. Again, sales rose.",
+            claims:  []Claim{{Quote: "ales rose", Verdict: VerdictUnsupported}},
+            want:    "Sales rose. *[unverified]* Costs fell. Again, sales rose. *[unverified]*",
+        },
+        {
+            name:    "citation markers after the period",
+            section: "Sales rose. [1][2] Costs fell. [3]",
+            claims:  []Claim{{Quote: "Sales rose", Verdict: VerdictUnsupported}},
+            want:    "Sales rose. [1][2] *[unverified]* Costs fell. [3]",
+        },
+        {
+            name:    "quote not in the section",
+            section: "Sales rose.",
+            claims:  []Claim{{Quote: "Costs fell", Verdict: VerdictUnsupported}, {Verdict: VerdictUnsupported}},
+            want:    "Sales rose.",
+        },
+    }
+    for _, c := range cases {
+        if got := flagClaims(c.section, c.claims); got != c.want { t.Errorf("%s:\n got %q\nwant %q", c.name, got, c.want) }
+    }
+}
@@ -0,0 +1,391 @@
+package agent
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
+    "time"
+
+    "gotcha/internal/diff"
+    "gotcha/internal/llm"
+    "gotcha/internal/platform"
+    "gotcha/internal/schedule"
+)
+
+var (
+    // ErrWatchNotFound is returned for an unknown watch ID.
+    ErrWatchNotFound = errors.New("watch not found")
+    // ErrWatchRunning is returned when a run of the watch is already in
+    // flight, in this process or another one.
+    ErrWatchRunning = errors.New("watch is already running")
+)
+
+// Watch is a saved research prompt that is re-run on demand or on a cron
+// schedule. Every successful run keeps a copy of the report as a numbered
+// version along with a summary of what changed since the previous one.
+type Watch struct {
+    ID        string         `json:"id"`
+    Prompt    string         `json:"prompt"`
+    Schedule  string         `json:"schedule,omitempty"` // cron expression; empty runs on demand only
+    SessionID string         `json:"session_id"`         // session the runs write their report into
+    Options   RunOptions     `json:"options"`
+    CreatedAt time.Time      `json:"created_at"`
+    Versions  []WatchVersion `json:"versions,omitempty"`
+    // LastAttempt is when a run last started, whether or not it succeeded;
+    // Failures counts the runs that failed since the last success.
+    LastAttempt time.Time `json:"last_attempt,omitempty"`
+    Failures    int       `json:"failures,omitempty"`
+}
+
+// WatchVersion is one saved run of a watch.
+type WatchVersion struct {
+    N       int       `json:"n"`
+    RunID    string    `json:"run_id"`
+    Revision int       `json:"revision,omitempty"` // the session's report revision
+    At       time.Time `json:"at"`
+    Report   string    `json:"report"`            // copy of report.md
+    Changes  string    `json:"changes,omitempty"` // what changed since the previous version
+    Summary  string    `json:"summary,omitempty"` // one-line change summary
+}
+
+// LastRun returns when the watch last produced a version.
+func (w Watch) LastRun() time.Time {
+    if len(w.Versions) == 0 { return time.Time{} }
+    return w.Versions[len(w.Versions)-1].At
+}
+
+// Next returns when the watch is next due, or the zero time for on-demand
+// watches. The schedule counts from the later of the last attempt and the
+// last success, and after failures the next try also waits out a backoff, so
+// a watch that keeps failing is not retried on every check.
+func (w Watch) Next() time.Time {
+    if w.Schedule == "" { return time.Time{} }
+    s, err := schedule.Parse(w.Schedule)
+    if err != nil { return time.Time{} }
+    from := w.CreatedAt
+    if last := w.LastRun(); last.After(from) { from = last }
+    if w.LastAttempt.After(from) { from = w.LastAttempt }
+    next := s.Next(from)
+    if w.Failures > 0 {
+        if retry := w.LastAttempt.Add(retryBackoff(w.Failures)); retry.After(next) { next = s.Next(retry.Add(-time.Nanosecond)) }
+    }
+    return next
+}
+
+// retryBackoff is the least time between failed runs of a watch: five
+// minutes after the first failure, doubling up to a day.
+func retryBackoff(failures int) time.Duration {
+    return min(5*time.Minute<<min(failures-1, 9), 24*time.Hour)
+}
+
+// Watcher stores watches and runs them through a Researcher.
+type Watcher struct {
+    r *Researcher
+
+    mu      sync.Mutex      // guards the watch file
+    running map[string]bool // watch IDs with a run in flight in this process
+}
+
+func NewWatcher(r *Researcher) *Watcher { return &Watcher{r: r, running: map[string]bool{}} }
+
+// List returns every watch, ordered by ID.
+func (w *Watcher) List() ([]Watch, error) {
+    w.mu.Lock()
+    defer w.mu.Unlock()
+    return w.load()
+}
+
+// Get returns one watch.
+func (w *Watcher) Get(id string) (Watch, error) {
+    all, err := w.List()
+    if err != nil { return Watch{}, err }
+    for _, wt := range all {
+        if wt.ID == id { return wt, nil }
+    }
+    return Watch{}, fmt.Errorf("%w: %s", ErrWatchNotFound, id)
+}
+
+var watchIDChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Add saves a watch whose runs write into sessionID. An empty id is derived
+// from the prompt; schedule may be empty for on-demand watches.
+func (w *Watcher) Add(id, sessionID, prompt, sched string, opts RunOptions) (Watch, error) {
+    prompt = strings.TrimSpace(prompt)
+    if prompt == "" { return Watch{}, errors.New("watch needs a prompt") }
+    if sched = strings.TrimSpace(sched); sched != "" {
+        if _, err := schedule.Parse(sched); err != nil { return Watch{}, err }
+    }
+    if id == "" { id = fallbackTitle(prompt) }
+    id = strings.Trim(watchIDChars.ReplaceAllString(strings.ToLower(id), "-"), "-")
+    if len(id) > 40 { id = strings.TrimRight(id[:40], "-") }
+    if id == "" { id = "watch" }
+    // Watch runs happen unattended.
+    opts.AutoApprove = true
+
+    w.mu.Lock()
+    defer w.mu.Unlock()
+    all, err := w.load()
+    if err != nil { return Watch{}, err }
+    base := id
+    for n := 2; watchExists(all, id); n++ { id = fmt.Sprintf("%s-%d", base, n) }
+    wt := Watch{ID: id, Prompt: prompt, Schedule: sched, SessionID: sessionID, Options: opts, CreatedAt: time.Now()}
+    all = append(all, wt)
+    return wt, w.save(all)
+}
+
+// Remove deletes a watch and its saved versions.
+func (w *Watcher) Remove(id string) error {
+    w.mu.Lock()
+    defer w.mu.Unlock()
+    all, err := w.load()
+    if err != nil { return err }
+    for i, wt := range all {
+        if wt.ID != id { continue }
+        if err := w.save(append(all[:i], all[i+1:]...)); err != nil { return err }
+        return os.RemoveAll(w.r.svc.WatchDir(id))
+    }
+    return fmt.Errorf("%w: %s", ErrWatchNotFound, id)
+}
+
+// Run researches the watch's prompt now, waits for the run to finish and
+// saves the report as a new version with a summary of what changed.
+func (w *Watcher) Run(ctx context.Context, id string) (WatchVersion, error) {
+    if _, err := w.Get(id); err != nil { return WatchVersion{}, err }
+    w.mu.Lock()
+    if w.running[id] {
+        w.mu.Unlock()
+        return WatchVersion{}, fmt.Errorf("%w: %s", ErrWatchRunning, id)
+    }
+    w.running[id] = true
+    w.mu.Unlock()
+    defer func() {
+        w.mu.Lock()
+        delete(w.running, id)
+        w.mu.Unlock()
+    }()
+    // Another process (the TUI next to a CLI run) may be running it too.
+    release, err := platform.TryLock(filepath.Join(w.r.svc.WatchDir(id), ".lock"))
+    if errors.Is(err, platform.ErrLocked) { return WatchVersion{}, fmt.Errorf("%w: %s", ErrWatchRunning, id) }
+    if err != nil { return WatchVersion{}, err }
+    defer release()
+
+    // Record the attempt before running so that a crash mid-run does not
+    // make the watch due again straight away.
+    var wt Watch
+    if err := w.update(id, func(x *Watch) { x.LastAttempt = time.Now(); wt = *x }); err != nil { return WatchVersion{}, err }
+    v, err := w.run(ctx, wt)
+    if uerr := w.update(id, func(x *Watch) {
+        if err != nil {
+            x.Failures++
+            return
+        }
+        x.Failures = 0
+        x.Versions = append(x.Versions, v)
+    }); err == nil { err = uerr }
+    return v, err
+}
+
+// run does the work of Run for a watch whose lock is held.
+func (w *Watcher) run(ctx context.Context, wt Watch) (WatchVersion, error) {
+    id := wt.ID
+    runID := w.r.Start(wt.SessionID, wt.Prompt, wt.Options)
+    info, err := w.r.tasks.Wait(ctx, runID)
+    if err != nil { return WatchVersion{}, err }
+    if info.State != TaskDone { return WatchVersion{}, fmt.Errorf("watch %s: run %s %s: %s", id, runID, info.State, info.Err) }
+    report, err := os.ReadFile(w.r.svc.ReportPath(wt.SessionID))
+    if err != nil { return WatchVersion{}, err }
+
+    dir := w.r.svc.WatchDir(id)
+    if err := os.MkdirAll(dir, 0o755); err != nil { return WatchVersion{}, fmt.Errorf("mkdir watch: %w", err) }
+    v := WatchVersion{N: len(wt.Versions) + 1, RunID: runID, At: time.Now()}
+    v.Revision, _ = strconv.Atoi(frontMatter(string(report))["revision"])
+    v.Report = filepath.Join(dir, fmt.Sprintf("v%03d.md", v.N))
+    if err := platform.WriteFileAtomic(v.Report, report); err != nil { return WatchVersion{}, err }
+    if len(wt.Versions) > 0 {
+        prev := wt.Versions[len(wt.Versions)-1]
+        old, err := os.ReadFile(prev.Report)
+        if err != nil { return WatchVersion{}, err }
+        changes, summary := w.summarizeChanges(ctx, wt, prev.N, v.N, string(old), string(report))
+        v.Changes, v.Summary = filepath.Join(dir, fmt.Sprintf("v%03d.changes.md", v.N)), summary
+        if err := platform.WriteFileAtomic(v.Changes, []byte(changes)); err != nil { return WatchVersion{}, err }
+    }
+    return v, nil
+}
+
+// update applies fn to the stored watch id.
+func (w *Watcher) update(id string, fn func(*Watch)) error {
+    w.mu.Lock()
+    defer w.mu.Unlock()
+    all, err := w.load()
+    if err != nil { return err }
+    for i := range all {
+        if all[i].ID != id { continue }
+        fn(&all[i])
+        return w.save(all)
+    }
+    return fmt.Errorf("%w: %s", ErrWatchNotFound, id)
+}
+
+// Due returns the scheduled watches whose next run is at or before now.
+func (w *Watcher) Due(now time.Time) ([]Watch, error) {
+    all, err := w.List()
+    if err != nil { return nil, err }
+    var out []Watch
+    for _, wt := range all {
+        if next := wt.Next(); !next.IsZero() && !next.After(now) { out = append(out, wt) }
+    }
+    return out, nil
+}
+
+// Start runs due watches in the background until ctx is done, checking once
+// a minute. report, if set, is called after every scheduled run.
+func (w *Watcher) Start(ctx context.Context, report func(Watch, WatchVersion, error)) {
+    go func() {
+        tick := time.NewTicker(time.Minute)
+        defer tick.Stop()
+        for {
+            due, _ := w.Due(time.Now())
+            for _, wt := range due {
+                w.mu.Lock()
+                busy := w.running[wt.ID]
+                w.mu.Unlock()
+                if busy { continue }
+                go func(wt Watch) {
+                    v, err := w.Run(ctx, wt.ID)
+                    if errors.Is(err, ErrWatchRunning) { return }
+                    if report != nil && ctx.Err() == nil { report(wt, v, err) }
+                }(wt)
+            }
+            select {
+            case <-ctx.Done():
+                return
+            case <-tick.C:
+            }
+        }
+    }()
+}
+
+// summarizeChanges compares two versions of a watch's report section by
+// section and lists new and dropped sources; with an LLM it adds a short
+// narrative of what changed. It returns the Markdown and a one-line summary.
+func (w *Watcher) summarizeChanges(ctx context.Context, wt Watch, from, to int, old, cur string) (string, string) {
+    old, cur = stripFrontMatter(old), stripFrontMatter(cur)
+    var b strings.Builder
+    fmt.Fprintf(&b, "# What changed: v%d → v%d\n\n", from, to)
+    fmt.Fprintf(&b, "Watch `%s`: %s\n\n", wt.ID, wt.Prompt)
+
+    counts := map[string]int{}
+    var rows []string
+    for _, d := range diff.Sections(old, cur) {
+        if d.Heading == "" || d.Heading == "Sources" { continue }
+        counts[d.Status]++
+        if d.Status == diff.SectionUnchanged { continue }
+        rows = append(rows, fmt.Sprintf("| %s | %s | +%d −%d |", escapeCell(d.Heading), d.Status, d.Added, d.Removed))
+    }
+    added, dropped := diffStrings(reportURLs(old), reportURLs(cur))
+    summary := fmt.Sprintf("%d section(s) changed, %d added, %d removed; %d new source(s), %d dropped",
+        counts[diff.SectionChanged], counts[diff.SectionAdded], counts[diff.SectionRemoved], len(added), len(dropped))
+    if len(rows) == 0 && len(added) == 0 && len(dropped) == 0 { summary = "no changes" }
+
+    if narrative := w.narrateChanges(ctx, wt, old, cur); narrative != "" { b.WriteString(narrative + "\n\n") }
+    b.WriteString("## Sections\n\n")
+    if len(rows) == 0 {
+        b.WriteString("No section changed.\n\n")
+    } else {
+        b.WriteString("| Section | Change | Lines |\n|---|---|---|\n")
+        b.WriteString(strings.Join(rows, "\n") + "\n\n")
+    }
+    if len(added) > 0 {
+        b.WriteString("## New sources\n\n")
+        for _, u := range added { b.WriteString("- " + u + "\n") }
+        b.WriteString("\n")
+    }
+    if len(dropped) > 0 {
+        b.WriteString("## Dropped sources\n\n")
+        for _, u := range dropped { b.WriteString("- " + u + "\n") }
+        b.WriteString("\n")
+    }
+    if patch := diff.Unified(fmt.Sprintf("v%03d.md", from), fmt.Sprintf("v%03d.md", to), old, cur, 2); patch != "" {
+        b.WriteString("## Diff\n\n```diff\n" + patch + "```\n")
+    }
+    return b.String(), summary
+}
+
+// maxNarrativeDiff caps the diff shown to the model when narrating changes.
+const maxNarrativeDiff = 12000
+
+// narrateChanges asks the model for a few bullets on what is new. It is
+// best-effort: without an LLM, or on error, it returns "".
+func (w *Watcher) narrateChanges(ctx context.Context, wt Watch, old, cur string) string {
+    if w.r.llm == nil { return "" }
+    patch := diff.Unified("previous", "current", old, cur, 1)
+    if patch == "" { return "" }
+    if len(patch) > maxNarrativeDiff { patch = patch[:maxNarrativeDiff] + "\n[diff truncated]" }
+    sys := "You summarize what changed between two versions of a research report on a tracked topic. Return 3-6 Markdown bullets about substantive new findings, revised figures and dropped claims. Ignore wording changes and renumbered citations. No preamble."
+    res, err := w.r.llm.Complete(ctx, llm.Request{System: sys, Prompt: fmt.Sprintf("Topic: %s\n\nUnified diff:\n%s", wt.Prompt, patch), MaxTokens: 500, Temperature: 0.2}, nil)
+    if err != nil { return "" }
+    return strings.TrimSpace(res.Text)
+}
+
+var (
+    reFrontMatter = regexp.MustCompile(`(?s)\A---\n.*?\n---\n+`)
+    reReportURL   = regexp.MustCompile(`\]\((\S+?)\)`)
+)
+
+func stripFrontMatter(doc string) string { return reFrontMatter.ReplaceAllString(doc, "") }
+
+// reportURLs returns the links of a report's Sources section.
+func reportURLs(doc string) []string {
+    i := strings.Index(doc, "\n## Sources\n")
+    if i < 0 { return nil }
+    var out []string
+    for _, m := range reReportURL.FindAllStringSubmatch(doc[i:], -1) { out = append(out, m[1]) }
+    return out
+}
+
+// diffStrings returns the entries only in b and only in a, sorted.
+func diffStrings(a, b []string) (added, removed []string) {
+    in := func(xs []string) map[string]bool {
+        m := map[string]bool{}
+        for _, x := range xs { m[x] = true }
+        return m
+    }
+    ina, inb := in(a), in(b)
+    for x := range inb { if !ina[x] { added = append(added, x) } }
+    for x := range ina { if !inb[x] { removed = append(removed, x) } }
+    sort.Strings(added)
+    sort.Strings(removed)
+    return added, removed
+}
+
+func watchExists(all []Watch, id string) bool {
+    for _, wt := range all { if wt.ID == id { return true } }
+    return false
+}
+
+func (w *Watcher) load() ([]Watch, error) {
+    data, err := os.ReadFile(w.r.svc.WatchesPath())
+    if err != nil {
+        if os.IsNotExist(err) { return nil, nil }
+        return nil, fmt.Errorf("read watches: %w", err)
+    }
+    var all []Watch
+    if err := json.Unmarshal(data, &all); err != nil { return nil, fmt.Errorf("parse watches: %w", err) }
+    sort.Slice(all, func(i, k int) bool { return all[i].ID < all[k].ID })
+    return all, nil
+}
+
+func (w *Watcher) save(all []Watch) error {
+    data, err := json.MarshalIndent(all, "", "  ")
+    if err != nil { return fmt.Errorf("marshal watches: %w", err) }
+    if err := os.MkdirAll(filepath.Dir(w.r.svc.WatchesPath()), 0o755); err != nil { return err }
+    return platform.WriteFileAtomic(w.r.svc.WatchesPath(), data)
+}
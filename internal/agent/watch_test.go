@@ -0,0 +1,31 @@
+package agent
+
+import (
+    "testing"
+    "time"
+)
+
+func TestWatchNext(t *testing.T) {
+    created := time.Date(2030, 5, 10, 8, 0, 0, 0, time.UTC)
+    at := func(h, m int) time.Time { return time.Date(2030, 5, 10, h, m, 0, 0, time.UTC) }
+    cases := []struct {
+        name string
+        w    Watch
+        want time.Time
+    }{
+        {"on demand", Watch{CreatedAt: created}, time.Time{}},
+        {"never run", Watch{Schedule: "*/10 * * * *", CreatedAt: created}, at(8, 10)},
+        {"after a success", Watch{Schedule: "*/10 * * * *", CreatedAt: created, Versions: []WatchVersion{{At: at(9, 0)}}, LastAttempt: at(9, 0)}, at(9, 10)},
+        // A run in flight or one that crashed counts from when it started.
+        {"after an attempt", Watch{Schedule: "*/10 * * * *", CreatedAt: created, Versions: []WatchVersion{{At: at(9, 0)}}, LastAttempt: at(9, 30)}, at(9, 40)},
+        {"one failure", Watch{Schedule: "* * * * *", CreatedAt: created, LastAttempt: at(9, 0), Failures: 1}, at(9, 5)},
+        {"three failures", Watch{Schedule: "* * * * *", CreatedAt: created, LastAttempt: at(9, 0), Failures: 3}, at(9, 20)},
+        {"backoff on a slot", Watch{Schedule: "*/15 * * * *", CreatedAt: created, LastAttempt: at(9, 0), Failures: 2}, at(9, 15)},
+        {"schedule slower than backoff", Watch{Schedule: "0 * * * *", CreatedAt: created, LastAttempt: at(9, 0), Failures: 1}, at(10, 0)},
+        {"many failures", Watch{Schedule: "* * * * *", CreatedAt: created, LastAttempt: at(9, 0), Failures: 40}, at(9, 0).Add(24 * time.Hour)},
+        {"bad schedule", Watch{Schedule: "every day", CreatedAt: created}, time.Time{}},
+    }
+    for _, c := range cases {
+        if got := c.w.Next(); !got.Equal(c.want) { t.Errorf("%s: next %s, want %s", c.name, got, c.want) }
+    }
+}
@@ -42,5 +42,16 @@ func (s *Service) SaveNote(ctx context.Context, sessionID, text string) error {
 }
 
 func (s *Service) ReportPath(sessionID string) string { return s.paths.SessionReportPath(sessionID) }
+func (s *Service) DraftPath(sessionID string) string { return s.paths.SessionDraftPath(sessionID) }
+func (s *Service) ReportDiffPath(sessionID string) string { return s.paths.SessionReportDiffPath(sessionID) }
+func (s *Service) ComparisonCSVPath(sessionID string) string { return s.paths.SessionComparisonCSVPath(sessionID) }
+func (s *Service) RevisionsDir(sessionID string) string { return s.paths.SessionRevisionsDir(sessionID) }
+func (s *Service) ClarificationsPath(sessionID string) string { return s.paths.SessionClarificationsPath(sessionID) }
+func (s *Service) RunsDir(sessionID string) string { return s.paths.SessionRunsDir(sessionID) }
+func (s *Service) RunJournalPath(sessionID, runID string) string { return s.paths.SessionRunJournalPath(sessionID, runID) }
+func (s *Service) RunLockPath(sessionID, runID string) string { return s.paths.SessionRunLockPath(sessionID, runID) }
 func (s *Service) NotesPath(sessionID string) string { return s.paths.SessionNotesPath(sessionID) }
+func (s *Service) TemplatesDir() string { return s.paths.TemplatesDir() }
+func (s *Service) WatchesPath() string { return s.paths.WatchesPath() }
+func (s *Service) WatchDir(id string) string { return s.paths.WatchDir(id) }
 func (s *Service) DBPath() string { return filepath.Clean(s.paths.DBPath()) }
@@ -0,0 +1,230 @@
+// Package diff computes line diffs between text documents and renders them
+// in unified format.
+package diff
+
+import (
+    "fmt"
+    "strings"
+)
+
+// Kind says how a line changed between the old and new text.
+type Kind int
+
+const (
+    Equal Kind = iota
+    Delete
+    Insert
+)
+
+// Line is one line of an edit script.
+type Line struct {
+    Kind Kind
+    Text string
+}
+
+// Lines returns the edit script turning a into b, line by line, using a
+// longest-common-subsequence alignment.
+func Lines(a, b string) []Line {
+    x, y := split(a), split(b)
+    // Trim the common prefix and suffix; reports usually differ in a few places.
+    pre := 0
+    for pre < len(x) && pre < len(y) && x[pre] == y[pre] { pre++ }
+    suf := 0
+    for suf < len(x)-pre && suf < len(y)-pre && x[len(x)-1-suf] == y[len(y)-1-suf] { suf++ }
+    mx, my := x[pre:len(x)-suf], y[pre:len(y)-suf]
+
+    // lcs[i][j] is the LCS length of mx[i:] and my[j:].
+    lcs := make([][]int, len(mx)+1)
+    for i := range lcs { lcs[i] = make([]int, len(my)+1) }
+    for i := len(mx) - 1; i >= 0; i-- {
+        for j := len(my) - 1; j >= 0; j-- {
+            if mx[i] == my[j] {
+                lcs[i][j] = lcs[i+1][j+1] + 1
+            } else if lcs[i+1][j] >= lcs[i][j+1] {
+                lcs[i][j] = lcs[i+1][j]
+            } else {
+                lcs[i][j] = lcs[i][j+1]
+            }
+        }
+    }
+
+    out := make([]Line, 0, len(x)+len(y))
+    for _, l := range x[:pre] { out = append(out, Line{Equal, l}) }
+    i, j := 0, 0
+    for i < len(mx) && j < len(my) {
+        switch {
+        case mx[i] == my[j]:
+            out = append(out, Line{Equal, mx[i]}); i++; j++
+        case lcs[i+1][j] >= lcs[i][j+1]:
+            out = append(out, Line{Delete, mx[i]}); i++
+        default:
+            out = append(out, Line{Insert, my[j]}); j++
+        }
+    }
+    for ; i < len(mx); i++ { out = append(out, Line{Delete, mx[i]}) }
+    for ; j < len(my); j++ { out = append(out, Line{Insert, my[j]}) }
+    for _, l := range x[len(x)-suf:] { out = append(out, Line{Equal, l}) }
+    return out
+}
+
+// Changed reports whether the script contains any insertion or deletion.
+func Changed(lines []Line) bool {
+    for _, l := range lines {
+        if l.Kind != Equal { return true }
+    }
+    return false
+}
+
+// Stats counts inserted and deleted lines.
+func Stats(lines []Line) (added, removed int) {
+    for _, l := range lines {
+        switch l.Kind {
+        case Insert:
+            added++
+        case Delete:
+            removed++
+        }
+    }
+    return added, removed
+}
+
+// Unified renders the diff of a and b in unified format with the given number
+// of context lines. It returns "" when the texts are identical.
+func Unified(aName, bName, a, b string, context int) string {
+    lines := Lines(a, b)
+    if !Changed(lines) { return "" }
+    var sb strings.Builder
+    fmt.Fprintf(&sb, "--- %s\n+++ %s\n", aName, bName)
+
+    // Walk hunks: runs of changes padded by context lines, merging hunks whose
+    // context would overlap.
+    ai, bi := make([]int, len(lines)+1), make([]int, len(lines)+1)
+    for k, l := range lines {
+        ai[k+1], bi[k+1] = ai[k], bi[k]
+        if l.Kind != Insert { ai[k+1]++ }
+        if l.Kind != Delete { bi[k+1]++ }
+    }
+    for k := 0; k < len(lines); {
+        if lines[k].Kind == Equal { k++; continue }
+        start := max(k-context, 0)
+        end := k
+        for end < len(lines) {
+            if lines[end].Kind != Equal { end++; continue }
+            run := end
+            for run < len(lines) && lines[run].Kind == Equal { run++ }
+            if run == len(lines) || run-end > 2*context { end = min(end+context, len(lines)); break }
+            end = run
+        }
+        fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(ai[start], ai[end]-ai[start]), hunkRange(bi[start], bi[end]-bi[start]))
+        for _, l := range lines[start:end] {
+            switch l.Kind {
+            case Equal:
+                sb.WriteString(" ")
+            case Delete:
+                sb.WriteString("-")
+            case Insert:
+                sb.WriteString("+")
+            }
+            sb.WriteString(l.Text + "\n")
+        }
+        k = end
+    }
+    return sb.String()
+}
+
+func hunkRange(start, n int) string {
+    if n == 0 { return fmt.Sprintf("%d,0", start) }
+    if n == 1 { return fmt.Sprintf("%d", start+1) }
+    return fmt.Sprintf("%d,%d", start+1, n)
+}
+
+func split(s string) []string {
+    if s == "" { return nil }
+    return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// Section statuses reported by Sections.
+const (
+    SectionUnchanged = "unchanged"
+    SectionChanged   = "changed"
+    SectionAdded     = "added"
+    SectionRemoved   = "removed"
+)
+
+// SectionDiff compares one "## " section of two Markdown documents.
+type SectionDiff struct {
+    Heading string
+    Status  string
+    Added   int // lines
+    Removed int
+    Lines   []Line
+}
+
+// Sections diffs two Markdown documents section by section, pairing sections
+// by their "## " heading. Text before the first such heading (front matter
+// and title) is compared as a section with an empty heading. Sections come
+// in the order of b, with removed ones after the section they followed in a.
+func Sections(a, b string) []SectionDiff {
+    as, bs := splitSections(a), splitSections(b)
+    old := map[string]string{}
+    for _, s := range as { old[s.heading] = s.body }
+    seen := map[string]bool{}
+    var out []SectionDiff
+    emit := func(d SectionDiff) {
+        d.Added, d.Removed = Stats(d.Lines)
+        out = append(out, d)
+    }
+    ai := 0
+    for _, s := range bs {
+        // Flush sections of a that are gone, up to this heading's old position.
+        for k := ai; k < len(as); k++ {
+            if as[k].heading != s.heading { continue }
+            for ; ai < k; ai++ {
+                if !containsHeading(bs, as[ai].heading) { emit(SectionDiff{Heading: as[ai].heading, Status: SectionRemoved, Lines: Lines(as[ai].body, "")}) }
+            }
+            ai = k + 1
+            break
+        }
+        seen[s.heading] = true
+        prev, ok := old[s.heading]
+        switch {
+        case !ok:
+            emit(SectionDiff{Heading: s.heading, Status: SectionAdded, Lines: Lines("", s.body)})
+        default:
+            d := SectionDiff{Heading: s.heading, Status: SectionUnchanged, Lines: Lines(prev, s.body)}
+            if Changed(d.Lines) { d.Status = SectionChanged }
+            emit(d)
+        }
+    }
+    for ; ai < len(as); ai++ {
+        if !seen[as[ai].heading] && !containsHeading(bs, as[ai].heading) { emit(SectionDiff{Heading: as[ai].heading, Status: SectionRemoved, Lines: Lines(as[ai].body, "")}) }
+    }
+    return out
+}
+
+type mdSection struct{ heading, body string }
+
+func splitSections(doc string) []mdSection {
+    var out []mdSection
+    cur := mdSection{}
+    var body []string
+    flush := func() {
+        cur.body = strings.Join(body, "\n")
+        if cur.heading != "" || strings.TrimSpace(cur.body) != "" { out = append(out, cur) }
+    }
+    for _, l := range split(doc) {
+        if strings.HasPrefix(l, "## ") {
+            flush()
+            cur, body = mdSection{heading: strings.TrimSpace(strings.TrimPrefix(l, "## "))}, nil
+            continue
+        }
+        body = append(body, l)
+    }
+    flush()
+    return out
+}
+
+func containsHeading(ss []mdSection, h string) bool {
+    for _, s := range ss { if s.heading == h { return true } }
+    return false
+}
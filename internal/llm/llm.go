@@ -1,6 +1,10 @@
 package llm
 
-import "context"
+import (
+    "context"
+
+    "gotcha/internal/platform"
+)
 
 type StreamHandler func(delta string)
 
@@ -36,3 +40,12 @@ type Client interface {
     Name() string
     Complete(ctx context.Context, req Request, onToken StreamHandler) (Response, error)
 }
+
+// FromConfig returns a client for the configured provider, or nil when no
+// provider is usable (callers fall back to offline behaviour).
+func FromConfig(cfg platform.LLMConfig, proxyURL string) Client {
+    if cfg.Provider == "openai" && cfg.APIKey != "" {
+        return NewOpenAI(cfg.APIKey, cfg.BaseURL, cfg.Model, proxyURL)
+    }
+    return nil
+}
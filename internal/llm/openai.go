@@ -9,9 +9,10 @@ import (
     "fmt"
     "io"
     "net/http"
-    "net/url"
     "strings"
     "time"
+
+    "gotcha/internal/platform"
 )
 
 // OpenAIClient is a lightweight client for OpenAI-compatible chat completions.
@@ -55,7 +56,7 @@ func (c *OpenAIClient) Complete(ctx context.Context, req Request, onToken Stream
     if req.ReasoningEffort != "" { rr.Reasoning.Effort = req.ReasoningEffort }
     if req.ReasoningSummary != "" { rr.Reasoning.Summary = req.ReasoningSummary }
     body, _ := json.Marshal(rr)
-    httpClient := &http.Client{Timeout: c.timeout, Transport: transportWithProxy(c.proxyURL)}
+    httpClient := &http.Client{Timeout: c.timeout, Transport: platform.ProxyTransport(c.proxyURL)}
     endpoint := c.baseURL + "/v1/responses"
     httpReq, _ := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
     httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
@@ -147,7 +148,7 @@ func (c *OpenAIClient) Complete(ctx context.Context, req Request, onToken Stream
         // Retry same request without streaming
         rr.Stream = false
         body2, _ := json.Marshal(rr)
-        httpClient2 := &http.Client{Timeout: c.timeout, Transport: transportWithProxy(c.proxyURL)}
+        httpClient2 := &http.Client{Timeout: c.timeout, Transport: platform.ProxyTransport(c.proxyURL)}
         endpoint := c.baseURL + "/v1/responses"
         httpReq2, _ := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body2))
         httpReq2.Header.Set("Authorization", "Bearer "+c.apiKey)
@@ -173,19 +174,6 @@ func (c *OpenAIClient) Complete(ctx context.Context, req Request, onToken Stream
 }
 
 
-func transportWithProxy(proxy string) http.RoundTripper {
-    // Default transport copies http.DefaultTransport settings but allows proxy override.
-    tr := &http.Transport{
-        Proxy: http.ProxyFromEnvironment,
-    }
-    if proxy != "" {
-        if u, err := url.Parse(proxy); err == nil {
-            tr.Proxy = func(_ *http.Request) (*url.URL, error) { return u, nil }
-        }
-    }
-    return tr
-}
-
 // Responses API structures
 type responsesReq struct {
     Model               string      `json:"model"`
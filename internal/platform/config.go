@@ -3,6 +3,8 @@ package platform
 import (
     "os"
     "strconv"
+    "strings"
+    "time"
 )
 
 // LLMConfig captures model provider settings.
@@ -15,6 +17,59 @@ type LLMConfig struct {
     Temperature float64
 }
 
+// SearchConfig selects web search providers.
+type SearchConfig struct {
+    Providers    []string
+    MaxResults   int // sources fetched per research run
+    PerQuery     int // results requested per query
+    TavilyAPIKey string
+    CorpusDir    string // local documents searched alongside (or instead of) the web
+    // Domain reputation lists for scoring sources
+    AllowDomains []string
+    DenyDomains  []string // never fetched
+    BoostDomains []string
+}
+
+// ConcurrencyConfig bounds background work.
+type ConcurrencyConfig struct {
+    Tasks   int // research tasks running at once
+    Search  int
+    Fetch   int
+    Compose int // report sections written at once
+}
+
+// ResearchConfig holds defaults for research runs.
+type ResearchConfig struct {
+    Iterative    bool          // run gap analysis and follow-up searches
+    MaxDepth     int           // search rounds in iterative mode
+    MaxDuration  time.Duration // wall-clock limit for the search loop
+    Verify       bool          // check report claims against fetched sources
+    ReviewRounds int           // critique and revision rounds on the draft
+    AutoApprove  bool          // compose the planner's outline without review
+    Template     string        // report template used when a run names none
+    Clarify      bool          // ask clarifying questions about ambiguous prompts
+}
+
+// BudgetLimits caps what research may consume; zero fields are unlimited.
+type BudgetLimits struct {
+    Tokens   int
+    Cost     float64 // USD
+    Duration time.Duration
+    Requests int // LLM calls, searches and fetches
+}
+
+// BudgetConfig holds per-run and per-session research budgets and the prices
+// used to turn usage into cost.
+type BudgetConfig struct {
+    Run     BudgetLimits
+    Session BudgetLimits
+    WarnAt  []float64 // fractions of a limit that trigger a warning event
+    // Prices in USD
+    InputPerMTok  float64
+    OutputPerMTok float64
+    PerSearch     float64
+}
+
 // Config holds runtime configuration.
 type Config struct {
     AppName string
@@ -22,6 +77,10 @@ type Config struct {
     ShowSources bool
     Paths Paths
     LLM  LLMConfig
+    Search SearchConfig
+    Research ResearchConfig
+    Concurrency ConcurrencyConfig
+    Budget BudgetConfig
     ProxyURL string
 }
 
@@ -43,6 +102,50 @@ func LoadConfig() Config {
             MaxTokens:   intEnvOr("LLM_MAX_TOKENS", 1500),
             Temperature: floatEnvOr("LLM_TEMPERATURE", 0.2),
         },
+        Search: SearchConfig{
+            Providers:    listEnvOr("GOTCHA_SEARCH_PROVIDERS", []string{"tavily"}),
+            MaxResults:   intEnvOr("GOTCHA_SEARCH_MAX_RESULTS", 30),
+            PerQuery:     intEnvOr("GOTCHA_SEARCH_PER_QUERY", 5),
+            TavilyAPIKey: os.Getenv("TAVILY_API_KEY"),
+            CorpusDir:    os.Getenv("GOTCHA_CORPUS_DIR"),
+            AllowDomains: listEnvOr("GOTCHA_SOURCES_ALLOW", nil),
+            DenyDomains:  listEnvOr("GOTCHA_SOURCES_DENY", nil),
+            BoostDomains: listEnvOr("GOTCHA_SOURCES_BOOST", nil),
+        },
+        Research: ResearchConfig{
+            Iterative:    boolEnvOr("GOTCHA_RESEARCH_ITERATIVE", false),
+            MaxDepth:     intEnvOr("GOTCHA_RESEARCH_MAX_DEPTH", 3),
+            MaxDuration:  durationEnvOr("GOTCHA_RESEARCH_MAX_DURATION", 10*time.Minute),
+            Verify:       boolEnvOr("GOTCHA_RESEARCH_VERIFY", true),
+            ReviewRounds: intEnvOr("GOTCHA_RESEARCH_REVIEW_ROUNDS", 0),
+            AutoApprove:  boolEnvOr("GOTCHA_RESEARCH_AUTO_APPROVE", false),
+            Template:     envOr("GOTCHA_RESEARCH_TEMPLATE", ""),
+            Clarify:      boolEnvOr("GOTCHA_RESEARCH_CLARIFY", true),
+        },
+        Concurrency: ConcurrencyConfig{
+            Tasks:   intEnvOr("GOTCHA_CONCURRENCY_TASKS", 2),
+            Search:  intEnvOr("GOTCHA_CONCURRENCY_SEARCH", 4),
+            Fetch:   intEnvOr("GOTCHA_CONCURRENCY_FETCH", 6),
+            Compose: intEnvOr("GOTCHA_CONCURRENCY_COMPOSE", 3),
+        },
+        Budget: BudgetConfig{
+            Run: BudgetLimits{
+                Tokens:   intEnvOr("GOTCHA_BUDGET_RUN_TOKENS", 0),
+                Cost:     floatEnvOr("GOTCHA_BUDGET_RUN_COST", 0),
+                Duration: durationEnvOr("GOTCHA_BUDGET_RUN_DURATION", 0),
+                Requests: intEnvOr("GOTCHA_BUDGET_RUN_REQUESTS", 0),
+            },
+            Session: BudgetLimits{
+                Tokens:   intEnvOr("GOTCHA_BUDGET_SESSION_TOKENS", 0),
+                Cost:     floatEnvOr("GOTCHA_BUDGET_SESSION_COST", 0),
+                Duration: durationEnvOr("GOTCHA_BUDGET_SESSION_DURATION", 0),
+                Requests: intEnvOr("GOTCHA_BUDGET_SESSION_REQUESTS", 0),
+            },
+            WarnAt:        floatListEnvOr("GOTCHA_BUDGET_WARN_AT", []float64{0.5, 0.8}),
+            InputPerMTok:  floatEnvOr("GOTCHA_PRICE_INPUT_PER_MTOK", 0.25),
+            OutputPerMTok: floatEnvOr("GOTCHA_PRICE_OUTPUT_PER_MTOK", 2.0),
+            PerSearch:     floatEnvOr("GOTCHA_PRICE_PER_SEARCH", 0.008),
+        },
         ProxyURL: firstNonEmpty(
             os.Getenv("PROXY_URL"),
             os.Getenv("HTTPS_PROXY"),
@@ -74,6 +177,42 @@ func floatEnvOr(key string, def float64) float64 {
     return def
 }
 
+func boolEnvOr(key string, def bool) bool {
+    if v := os.Getenv(key); v != "" {
+        if x, err := strconv.ParseBool(v); err == nil { return x }
+    }
+    return def
+}
+
+func durationEnvOr(key string, def time.Duration) time.Duration {
+    if v := os.Getenv(key); v != "" {
+        if x, err := time.ParseDuration(v); err == nil { return x }
+    }
+    return def
+}
+
+// listEnvOr reads a comma-separated list; an explicitly empty value yields none.
+func listEnvOr(key string, def []string) []string {
+    v, ok := os.LookupEnv(key)
+    if !ok { return def }
+    var out []string
+    for _, s := range strings.Split(v, ",") {
+        if s = strings.TrimSpace(s); s != "" { out = append(out, s) }
+    }
+    return out
+}
+
+// floatListEnvOr reads a comma-separated list of numbers, skipping bad entries.
+func floatListEnvOr(key string, def []float64) []float64 {
+    v, ok := os.LookupEnv(key)
+    if !ok { return def }
+    var out []float64
+    for _, s := range strings.Split(v, ",") {
+        if x, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil { out = append(out, x) }
+    }
+    return out
+}
+
 func firstNonEmpty(vals ...string) string {
     for _, v := range vals { if v != "" { return v } }
     return ""
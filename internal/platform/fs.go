@@ -3,6 +3,8 @@ package platform
 import (
     "fmt"
     "os"
+    "path/filepath"
+    "runtime"
 )
 
 func AppendFile(path string, data []byte) error {
@@ -13,15 +15,35 @@ func AppendFile(path string, data []byte) error {
     return nil
 }
 
-// WriteFileAtomic writes a file atomically by writing to temp file and renaming.
+// WriteFileAtomic writes a file atomically: the data goes to a temp file
+// next to path, which is synced to disk and then renamed over path, and the
+// directory is synced so the rename survives a crash. Readers see either the
+// old content or the new, never a truncated file. A crash can leave the temp
+// file (named "<base>.*.tmp") behind.
 func WriteFileAtomic(path string, data []byte) error {
-    dir := os.DirFS(".")
-    _ = dir // avoid unused in some envs; we use os functions below
-    tmp := path + ".tmp"
-    f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
-    if err != nil { return fmt.Errorf("open tmp %s: %w", tmp, err) }
-    if _, err := f.Write(data); err != nil { _ = f.Close(); return fmt.Errorf("write tmp %s: %w", tmp, err) }
-    if err := f.Close(); err != nil { return fmt.Errorf("close tmp %s: %w", tmp, err) }
-    if err := os.Rename(tmp, path); err != nil { return fmt.Errorf("rename %s -> %s: %w", tmp, path, err) }
+    dir := filepath.Dir(path)
+    f, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+    if err != nil { return fmt.Errorf("create tmp for %s: %w", path, err) }
+    tmp := f.Name()
+    fail := func(step string, err error) error {
+        _ = f.Close()
+        _ = os.Remove(tmp)
+        return fmt.Errorf("%s tmp %s: %w", step, tmp, err)
+    }
+    if err := f.Chmod(0o644); err != nil && runtime.GOOS != "windows" { return fail("chmod", err) }
+    if _, err := f.Write(data); err != nil { return fail("write", err) }
+    if err := f.Sync(); err != nil { return fail("sync", err) }
+    if err := f.Close(); err != nil { _ = os.Remove(tmp); return fmt.Errorf("close tmp %s: %w", tmp, err) }
+    if err := os.Rename(tmp, path); err != nil { _ = os.Remove(tmp); return fmt.Errorf("rename %s -> %s: %w", tmp, path, err) }
+    syncDir(dir)
     return nil
 }
+
+// syncDir flushes a directory entry change to disk. Not every platform can
+// sync a directory (Windows cannot), so failures are ignored.
+func syncDir(dir string) {
+    d, err := os.Open(dir)
+    if err != nil { return }
+    _ = d.Sync()
+    _ = d.Close()
+}
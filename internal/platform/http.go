@@ -0,0 +1,19 @@
+package platform
+
+import (
+    "net/http"
+    "net/url"
+)
+
+// ProxyTransport copies http.DefaultTransport settings but allows a proxy override.
+func ProxyTransport(proxy string) http.RoundTripper {
+    tr := &http.Transport{
+        Proxy: http.ProxyFromEnvironment,
+    }
+    if proxy != "" {
+        if u, err := url.Parse(proxy); err == nil {
+            tr.Proxy = func(_ *http.Request) (*url.URL, error) { return u, nil }
+        }
+    }
+    return tr
+}
@@ -0,0 +1,42 @@
+package platform
+
+import (
+    "errors"
+    "fmt"
+    "os"
+    "path/filepath"
+    "time"
+)
+
+// ErrLocked is returned when another process holds a file lock.
+var ErrLocked = errors.New("locked")
+
+// TryLock takes an exclusive advisory lock on the file at path, creating it
+// if needed, without waiting. The lock is held until release is called or
+// the process exits. Separate opens conflict, so two callers in the same
+// process exclude each other as well.
+func TryLock(path string) (release func(), err error) {
+    if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil { return nil, fmt.Errorf("mkdir %s: %w", filepath.Dir(path), err) }
+    f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+    if err != nil { return nil, fmt.Errorf("open lock file: %w", err) }
+    if err := tryLock(f); err != nil {
+        f.Close()
+        return nil, err
+    }
+    return func() {
+        _ = unlock(f)
+        f.Close()
+    }, nil
+}
+
+// Lock is TryLock retried with backoff for up to timeout. On timeout the
+// error wraps ErrLocked.
+func Lock(path string, timeout time.Duration) (release func(), err error) {
+    deadline := time.Now().Add(timeout)
+    for wait := time.Millisecond; ; wait = min(wait*2, 50*time.Millisecond) {
+        release, err := TryLock(path)
+        if !errors.Is(err, ErrLocked) { return release, err }
+        if time.Now().After(deadline) { return nil, fmt.Errorf("%s: %w", path, ErrLocked) }
+        time.Sleep(wait)
+    }
+}
@@ -0,0 +1,11 @@
+//go:build !unix && !windows
+
+package platform
+
+import "os"
+
+// Without file locks callers rely on their exclusive creates (session
+// directories, revision files) and in-process guards.
+func tryLock(f *os.File) error { return nil }
+
+func unlock(f *os.File) error { return nil }
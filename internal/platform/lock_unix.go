@@ -0,0 +1,17 @@
+//go:build unix
+
+package platform
+
+import (
+    "errors"
+    "os"
+    "syscall"
+)
+
+func tryLock(f *os.File) error {
+    err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+    if errors.Is(err, syscall.EWOULDBLOCK) { return ErrLocked }
+    return err
+}
+
+func unlock(f *os.File) error { return syscall.Flock(int(f.Fd()), syscall.LOCK_UN) }
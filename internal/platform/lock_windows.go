@@ -0,0 +1,21 @@
+//go:build windows
+
+package platform
+
+import (
+    "errors"
+    "os"
+
+    "golang.org/x/sys/windows"
+)
+
+func tryLock(f *os.File) error {
+    ol := new(windows.Overlapped)
+    err := windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, ol)
+    if errors.Is(err, windows.ERROR_LOCK_VIOLATION) { return ErrLocked }
+    return err
+}
+
+func unlock(f *os.File) error {
+    return windows.UnlockFileEx(windows.Handle(f.Fd()), 0, 1, 0, new(windows.Overlapped))
+}
@@ -24,6 +24,18 @@ func (p Paths) SessionsDir() string { return filepath.Join(p.Base, "sessions") }
 func (p Paths) SessionDir(id string) string { return filepath.Join(p.SessionsDir(), id) }
 func (p Paths) SessionNotesPath(id string) string { return filepath.Join(p.SessionDir(id), "notes.md") }
 func (p Paths) SessionReportPath(id string) string { return filepath.Join(p.SessionDir(id), "report.md") }
+func (p Paths) SessionDraftPath(id string) string { return filepath.Join(p.SessionDir(id), "report.draft.md") }
+func (p Paths) SessionReportDiffPath(id string) string { return filepath.Join(p.SessionDir(id), "report.diff") }
+func (p Paths) SessionComparisonCSVPath(id string) string { return filepath.Join(p.SessionDir(id), "comparison.csv") }
+func (p Paths) SessionRevisionsDir(id string) string { return filepath.Join(p.SessionDir(id), "revisions") }
+func (p Paths) SessionClarificationsPath(id string) string { return filepath.Join(p.SessionDir(id), "clarifications.json") }
+func (p Paths) SessionEventsPath(id string) string { return filepath.Join(p.SessionDir(id), "events.jsonl") }
+func (p Paths) SessionRunsDir(id string) string { return filepath.Join(p.SessionDir(id), "runs") }
+func (p Paths) SessionRunJournalPath(id, runID string) string { return filepath.Join(p.SessionRunsDir(id), runID+".json") }
+func (p Paths) SessionRunLockPath(id, runID string) string { return filepath.Join(p.SessionRunsDir(id), runID+".lock") }
+func (p Paths) TemplatesDir() string { return filepath.Join(p.Base, "templates") }
+func (p Paths) WatchesPath() string { return filepath.Join(p.Base, "watches.json") }
+func (p Paths) WatchDir(id string) string { return filepath.Join(p.Base, "watches", id) }
 func (p Paths) DBPath() string { return filepath.Join(p.Base, "gotcha.sqlite") }
 
 func (p Paths) EnsureSession(id string) (string, error) {
@@ -0,0 +1,151 @@
+// Package schedule parses cron expressions.
+package schedule
+
+import (
+    "fmt"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// Schedule is a parsed five-field cron expression (minute, hour, day of
+// month, month, day of week) evaluated in the location of the time given to
+// Next.
+type Schedule struct {
+    expr             string
+    minute, hour     uint64 // bit sets
+    dom, month, dow  uint64
+    domStar, dowStar bool
+}
+
+var macros = map[string]string{
+    "@hourly":   "0 * * * *",
+    "@daily":    "0 0 * * *",
+    "@midnight": "0 0 * * *",
+    "@weekly":   "0 0 * * 0",
+    "@monthly":  "0 0 1 * *",
+    "@yearly":   "0 0 1 1 *",
+    "@annually": "0 0 1 1 *",
+}
+
+var (
+    monthNames = map[string]int{"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6, "jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12}
+    dowNames   = map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}
+)
+
+// Parse reads a cron expression such as "0 9 * * mon-fri", "*/30 * * * *"
+// or one of the macros @hourly, @daily, @weekly, @monthly and @yearly.
+func Parse(expr string) (Schedule, error) {
+    expr = strings.TrimSpace(expr)
+    spec := expr
+    if m, ok := macros[strings.ToLower(spec)]; ok { spec = m }
+    f := strings.Fields(spec)
+    if len(f) != 5 { return Schedule{}, fmt.Errorf("cron %q: want 5 fields (minute hour day month weekday)", expr) }
+    s := Schedule{expr: expr, domStar: f[2] == "*" || f[2] == "?", dowStar: f[4] == "*" || f[4] == "?"}
+    var err error
+    if s.minute, err = parseField(f[0], 0, 59, nil); err != nil { return Schedule{}, fmt.Errorf("cron %q: minute: %w", expr, err) }
+    if s.hour, err = parseField(f[1], 0, 23, nil); err != nil { return Schedule{}, fmt.Errorf("cron %q: hour: %w", expr, err) }
+    if s.dom, err = parseField(f[2], 1, 31, nil); err != nil { return Schedule{}, fmt.Errorf("cron %q: day of month: %w", expr, err) }
+    if s.month, err = parseField(f[3], 1, 12, monthNames); err != nil { return Schedule{}, fmt.Errorf("cron %q: month: %w", expr, err) }
+    if s.dow, err = parseField(f[4], 0, 7, dowNames); err != nil { return Schedule{}, fmt.Errorf("cron %q: day of week: %w", expr, err) }
+    if s.dow&(1<<7) != 0 { s.dow |= 1 } // 7 is Sunday too
+    return s, nil
+}
+
+// String returns the expression as written.
+func (s Schedule) String() string { return s.expr }
+
+// Next returns the first time after t that matches the schedule, or the zero
+// time if none does within five years. Matching follows the wall clock of
+// t's location: a time skipped by a daylight saving jump runs at the first
+// minute after the jump, and a time repeated when clocks go back runs once.
+func (s Schedule) Next(t time.Time) time.Time {
+    // Walk wall-clock times in UTC, where every day has 24 hours, and map
+    // each match back to t's location.
+    loc := t.Location()
+    w := wallClock(t).Truncate(time.Minute).Add(time.Minute)
+    limit := w.AddDate(5, 0, 0)
+    for w.Before(limit) {
+        if s.month&(1<<uint(w.Month())) == 0 {
+            w = time.Date(w.Year(), w.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+            continue
+        }
+        if !s.dayMatches(w) {
+            w = time.Date(w.Year(), w.Month(), w.Day()+1, 0, 0, 0, 0, time.UTC)
+            continue
+        }
+        if s.hour&(1<<uint(w.Hour())) == 0 {
+            w = w.Truncate(time.Hour).Add(time.Hour)
+            continue
+        }
+        if s.minute&(1<<uint(w.Minute())) == 0 {
+            w = w.Add(time.Minute)
+            continue
+        }
+        c := time.Date(w.Year(), w.Month(), w.Day(), w.Hour(), w.Minute(), 0, 0, loc)
+        // In a gap Go picks a time before the jump; move past it.
+        for wallClock(c).Before(w) { c = c.Add(time.Minute) }
+        if c.After(t) { return c }
+        // A repeated wall time resolves to its first occurrence; when t is
+        // in the repeat, the second one may still be ahead.
+        _, first := c.Zone()
+        _, cur := t.Zone()
+        if alt := c.Add(time.Duration(first-cur) * time.Second); alt.After(t) && wallClock(alt).Equal(w) { return alt }
+        w = w.Add(time.Minute)
+    }
+    return time.Time{}
+}
+
+// wallClock returns t's local date and time as the same reading in UTC.
+func wallClock(t time.Time) time.Time {
+    return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+// dayMatches follows cron: when both day fields are restricted, either may match.
+func (s Schedule) dayMatches(t time.Time) bool {
+    dom := s.dom&(1<<uint(t.Day())) != 0
+    dow := s.dow&(1<<uint(t.Weekday())) != 0
+    switch {
+    case s.domStar && s.dowStar:
+        return true
+    case s.domStar:
+        return dow
+    case s.dowStar:
+        return dom
+    }
+    return dom || dow
+}
+
+func parseField(field string, lo, hi int, names map[string]int) (uint64, error) {
+    var bits uint64
+    for _, part := range strings.Split(field, ",") {
+        rng, step := part, 1
+        if i := strings.IndexByte(part, '/'); i >= 0 {
+            n, err := strconv.Atoi(part[i+1:])
+            if err != nil || n < 1 { return 0, fmt.Errorf("bad step in %q", part) }
+            rng, step = part[:i], n
+        }
+        from, to := lo, hi
+        if rng != "*" && rng != "?" {
+            a, b, isRange := strings.Cut(rng, "-")
+            var err error
+            if from, err = parseValue(a, names); err != nil { return 0, err }
+            to = from
+            if isRange {
+                if to, err = parseValue(b, names); err != nil { return 0, err }
+            } else if step > 1 {
+                to = hi
+            }
+        }
+        if from < lo || to > hi || from > to { return 0, fmt.Errorf("%q out of range %d-%d", part, lo, hi) }
+        for v := from; v <= to; v += step { bits |= 1 << uint(v) }
+    }
+    return bits, nil
+}
+
+func parseValue(s string, names map[string]int) (int, error) {
+    if v, ok := names[strings.ToLower(s)]; ok { return v, nil }
+    v, err := strconv.Atoi(s)
+    if err != nil { return 0, fmt.Errorf("bad value %q", s) }
+    return v, nil
+}
@@ -0,0 +1,106 @@
+package schedule
+
+import (
+    "testing"
+    "time"
+)
+
+func TestParse(t *testing.T) {
+    valid := []string{
+        "* * * * *",
+        "0 9 * * mon-fri",
+        "*/30 * * * *",
+        "5-50/15 0,12 1 jan,JUL ?",
+        "0 0 * * 7",
+        "@daily",
+        " @Weekly ",
+    }
+    for _, expr := range valid {
+        if _, err := Parse(expr); err != nil { t.Errorf("Parse(%q): %v", expr, err) }
+    }
+    invalid := []string{
+        "",
+        "* * * *",
+        "* * * * * *",
+        "60 * * * *",
+        "* 24 * * *",
+        "* * 0 * *",
+        "* * * 13 *",
+        "* * * * 8",
+        "*/0 * * * *",
+        "10-5 * * * *",
+        "a * * * *",
+        "* * * foo *",
+        "@fortnightly",
+    }
+    for _, expr := range invalid {
+        if _, err := Parse(expr); err == nil { t.Errorf("Parse(%q) succeeded", expr) }
+    }
+    if s, _ := Parse(" @daily "); s.String() != "@daily" { t.Errorf("String() = %q, want the expression as written", s.String()) }
+}
+
+func TestNext(t *testing.T) {
+    at := func(s string) time.Time {
+        v, err := time.ParseInLocation("2006-01-02 15:04", s, time.UTC)
+        if err != nil { t.Fatal(err) }
+        return v
+    }
+    cases := []struct {
+        expr, from, want string
+    }{
+        {"* * * * *", "2030-05-10 08:15", "2030-05-10 08:16"},
+        {"30 9 * * *", "2030-05-10 08:15", "2030-05-10 09:30"},
+        {"30 9 * * *", "2030-05-10 09:30", "2030-05-11 09:30"},
+        {"*/20 * * * *", "2030-05-10 08:41", "2030-05-10 09:00"},
+        {"5-50/15 * * * *", "2030-05-10 08:36", "2030-05-10 08:50"},
+        {"5-50/15 * * * *", "2030-05-10 08:51", "2030-05-10 09:05"},
+        {"0 0,12 * * *", "2030-05-10 08:00", "2030-05-10 12:00"},
+        {"0 9 * * mon-fri", "2030-05-10 10:00", "2030-05-13 09:00"}, // Friday to Monday
+        {"0 0 * * 7", "2030-05-10 10:00", "2030-05-12 00:00"},       // 7 is Sunday
+        {"0 0 13 * fri", "2030-05-10 10:00", "2030-05-13 00:00"},    // either day field matches
+        {"0 0 13 * fri", "2030-05-13 10:00", "2030-05-17 00:00"},
+        {"@monthly", "2030-01-31 23:59", "2030-02-01 00:00"},
+        {"0 0 31 * *", "2030-04-15 00:00", "2030-05-31 00:00"}, // April has no 31st
+        {"0 0 29 2 *", "2030-03-01 00:00", "2032-02-29 00:00"}, // next leap day
+        {"@yearly", "2030-12-31 23:59", "2031-01-01 00:00"},
+        {"59 23 31 dec *", "2030-12-31 23:59", "2031-12-31 23:59"},
+    }
+    for _, c := range cases {
+        s, err := Parse(c.expr)
+        if err != nil { t.Fatalf("Parse(%q): %v", c.expr, err) }
+        if got := s.Next(at(c.from)); !got.Equal(at(c.want)) { t.Errorf("%q after %s = %s, want %s", c.expr, c.from, got.Format("2006-01-02 15:04"), c.want) }
+    }
+
+    s, _ := Parse("0 0 30 2 *")
+    if got := s.Next(at("2030-01-01 00:00")); !got.IsZero() { t.Errorf("impossible schedule next = %s, want zero", got) }
+    if got := s.Next(at("2030-01-01 00:00").Add(30 * time.Second)); !got.IsZero() { t.Errorf("impossible schedule next = %s, want zero", got) }
+}
+
+func TestNextDaylightSaving(t *testing.T) {
+    ny, err := time.LoadLocation("America/New_York")
+    if err != nil { t.Skipf("no time zone data: %v", err) }
+    at := func(s string) time.Time {
+        v, err := time.ParseInLocation("2006-01-02 15:04 MST", s, ny)
+        if err != nil { t.Fatal(err) }
+        return v
+    }
+    cases := []struct {
+        name, expr, from, want string
+    }{
+        // Clocks jump from 02:00 EST to 03:00 EDT on 2030-03-10.
+        {"skipped time runs after the jump", "30 2 * * *", "2030-03-10 00:00 EST", "2030-03-10 03:00 EDT"},
+        {"skipped time next day", "30 2 * * *", "2030-03-10 03:00 EDT", "2030-03-11 02:30 EDT"},
+        {"hour after the jump", "0 3 * * *", "2030-03-10 01:30 EST", "2030-03-10 03:00 EDT"},
+        {"hourly across the jump", "0 * * * *", "2030-03-10 01:30 EST", "2030-03-10 03:00 EDT"},
+        // Clocks go back from 02:00 EDT to 01:00 EST on 2030-11-03.
+        {"repeated time runs once", "30 1 * * *", "2030-11-03 00:00 EDT", "2030-11-03 01:30 EDT"},
+        {"repeated time not rerun", "30 1 * * *", "2030-11-03 01:30 EDT", "2030-11-04 01:30 EST"},
+        {"in the repeat", "*/15 * * * *", "2030-11-03 01:40 EST", "2030-11-03 01:45 EST"},
+        {"daily keeps wall time", "0 9 * * *", "2030-11-02 09:00 EDT", "2030-11-03 09:00 EST"},
+    }
+    for _, c := range cases {
+        s, err := Parse(c.expr)
+        if err != nil { t.Fatalf("Parse(%q): %v", c.expr, err) }
+        if got := s.Next(at(c.from)); !got.Equal(at(c.want)) { t.Errorf("%s: %q after %s = %s, want %s", c.name, c.expr, c.from, got.Format("2006-01-02 15:04 MST"), c.want) }
+    }
+}
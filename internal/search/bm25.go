@@ -0,0 +1,97 @@
+package search
+
+import (
+    "math"
+    "sort"
+    "strings"
+    "unicode"
+)
+
+// BM25 parameters.
+const (
+    bm25K1 = 1.2
+    bm25B  = 0.75
+)
+
+// BM25 is an in-memory Okapi BM25 index over short texts such as passages.
+// It is not safe for concurrent use.
+type BM25 struct {
+    tf     []map[string]int
+    length []int
+    df     map[string]int
+    total  int // sum of lengths
+}
+
+// Hit is a scored index entry.
+type Hit struct {
+    ID    int // order of Add
+    Score float64
+}
+
+func NewBM25() *BM25 { return &BM25{df: map[string]int{}} }
+
+// Add indexes text and returns its ID.
+func (x *BM25) Add(text string) int {
+    tf := map[string]int{}
+    n := 0
+    for _, t := range Tokenize(text) { tf[t]++; n++ }
+    for t := range tf { x.df[t]++ }
+    x.tf = append(x.tf, tf)
+    x.length = append(x.length, n)
+    x.total += n
+    return len(x.tf) - 1
+}
+
+// Len returns the number of indexed texts.
+func (x *BM25) Len() int { return len(x.tf) }
+
+// Search returns up to max entries matching query, best first.
+func (x *BM25) Search(query string, max int) []Hit {
+    if len(x.tf) == 0 { return nil }
+    q := map[string]bool{}
+    for _, t := range Tokenize(query) { q[t] = true }
+    avg := float64(x.total) / float64(len(x.tf))
+    if avg == 0 { avg = 1 }
+    var hits []Hit
+    for id, tf := range x.tf {
+        score := 0.0
+        for t := range q {
+            if tf[t] == 0 { continue }
+            score += BM25Weight(tf[t], x.df[t], len(x.tf), x.length[id], avg)
+        }
+        if score > 0 { hits = append(hits, Hit{ID: id, Score: score}) }
+    }
+    sort.SliceStable(hits, func(a, b int) bool { return hits[a].Score > hits[b].Score })
+    if max > 0 && len(hits) > max { hits = hits[:max] }
+    return hits
+}
+
+// BM25Weight scores one query term in one document: tf is the term's count
+// in the document, df the number of documents containing it out of n, and
+// length the document's length in tokens against the average avg.
+func BM25Weight(tf, df, n, length int, avg float64) float64 {
+    f, d := float64(tf), float64(df)
+    idf := math.Log(1 + (float64(n)-d+0.5)/(d+0.5))
+    return idf * f * (bm25K1 + 1) / (f + bm25K1*(1-bm25B+bm25B*float64(length)/avg))
+}
+
+var stopwords = map[string]bool{
+    "the": true, "and": true, "for": true, "with": true, "that": true, "this": true, "are": true, "was": true,
+    "from": true, "what": true, "which": true, "how": true, "why": true, "who": true, "when": true, "into": true,
+    "its": true, "has": true, "have": true, "had": true, "not": true, "but": true, "all": true, "any": true,
+    "can": true, "will": true, "you": true, "your": true, "our": true, "of": true, "to": true, "in": true,
+    "on": true, "is": true, "it": true, "as": true, "at": true, "by": true, "or": true, "an": true, "be": true,
+}
+
+// Tokenize lower-cases s and splits it into words, dropping one-letter words
+// and common stopwords. Plural "s" endings are stripped so "laptops" matches
+// "laptop".
+func Tokenize(s string) []string {
+    var out []string
+    for _, w := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+        if len(w) < 2 || stopwords[w] { continue }
+        if len(w) > 3 && strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss") { w = w[:len(w)-1] }
+        out = append(out, w)
+    }
+    return out
+}
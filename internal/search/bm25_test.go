@@ -0,0 +1,53 @@
+package search
+
+import (
+    "reflect"
+    "testing"
+)
+
+func TestBM25Ranking(t *testing.T) {
+    docs := []string{
+        "Solar panels convert sunlight into electricity.",
+        "Wind turbines and solar panels both produce renewable power. Solar output peaks at noon; solar farms need land.",
+        "The history of the steam engine.",
+        "Battery storage smooths the output of wind farms.",
+    }
+    x := NewBM25()
+    for i, d := range docs {
+        if id := x.Add(d); id != i { t.Fatalf("Add returned ID %d, want %d", id, i) }
+    }
+    cases := []struct {
+        name  string
+        query string
+        max   int
+        want  []int // IDs, best first
+    }{
+        {"more occurrences rank higher", "solar", 0, []int{1, 0}},
+        {"rare terms outweigh common ones", "wind storage", 0, []int{3, 1}},
+        {"plurals match singulars", "turbine", 0, []int{1}},
+        {"max limits the hits", "solar", 1, []int{1}},
+        {"stopwords alone match nothing", "the and of", 0, nil},
+        {"no match", "nuclear", 0, nil},
+    }
+    for _, c := range cases {
+        var got []int
+        for _, h := range x.Search(c.query, c.max) { got = append(got, h.ID) }
+        if !reflect.DeepEqual(got, c.want) { t.Errorf("%s: %q ranked %v, want %v", c.name, c.query, got, c.want) }
+    }
+    if hits := NewBM25().Search("solar", 0); hits != nil { t.Errorf("empty index returned %v", hits) }
+}
+
+func TestTokenize(t *testing.T) {
+    cases := []struct {
+        in   string
+        want []string
+    }{
+        {"The Laptops and the GPUs", []string{"laptop", "gpu"}},
+        {"glass class bus", []string{"glass", "class", "bus"}},
+        {"x-ray, 5G and a b", []string{"ray", "5g"}},
+        {"", nil},
+    }
+    for _, c := range cases {
+        if got := Tokenize(c.in); !reflect.DeepEqual(got, c.want) { t.Errorf("Tokenize(%q) = %q, want %q", c.in, got, c.want) }
+    }
+}
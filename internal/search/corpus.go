@@ -0,0 +1,206 @@
+package search
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "io/fs"
+    "os"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+)
+
+const (
+    corpusPassageChars = 900      // passage length when chunking files
+    maxCorpusFile      = 32 << 20 // larger files are skipped
+)
+
+// corpusExts lists the file types a Corpus indexes.
+var corpusExts = map[string]bool{".md": true, ".markdown": true, ".txt": true, ".text": true, ".html": true, ".htm": true, ".pdf": true}
+
+// Corpus is a Provider over a local directory of Markdown, text, HTML and
+// PDF files. Files are split into passages and ranked with BM25; results
+// carry the file's full text so the research pipeline treats them like
+// fetched web pages, with file:// URLs. Nothing is sent over the network.
+//
+// The index is rebuilt lazily when files are added, changed or removed.
+type Corpus struct {
+    root string
+
+    mu      sync.Mutex
+    files   map[string]*corpusFile // by absolute path
+    index   *BM25
+    owners  []passageRef // passage ID -> file and text
+    scanned time.Time
+}
+
+type corpusFile struct {
+    path    string
+    title   string
+    text    string
+    modTime time.Time
+    size    int64
+}
+
+type passageRef struct {
+    file *corpusFile
+    text string
+}
+
+// rescanAfter is how long a scan of the directory is trusted.
+const rescanAfter = 10 * time.Second
+
+// NewCorpus returns a provider over dir, which must exist.
+func NewCorpus(dir string) (*Corpus, error) {
+    abs, err := filepath.Abs(dir)
+    if err != nil { return nil, err }
+    info, err := os.Stat(abs)
+    if err != nil { return nil, fmt.Errorf("corpus: %w", err) }
+    if !info.IsDir() { return nil, fmt.Errorf("corpus: %s is not a directory", dir) }
+    return &Corpus{root: abs, files: map[string]*corpusFile{}}, nil
+}
+
+// CorpusName is the provider name corpus results carry.
+const CorpusName = "corpus"
+
+func (c *Corpus) Name() string { return CorpusName }
+
+// Root returns the indexed directory.
+func (c *Corpus) Root() string { return c.root }
+
+// Stats reports how many files and passages are indexed, scanning first if
+// the directory has not been read yet.
+func (c *Corpus) Stats(ctx context.Context) (files, passages int, err error) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    if err := c.refresh(ctx); err != nil { return 0, 0, err }
+    return len(c.files), c.index.Len(), nil
+}
+
+// Search ranks passages against query and returns the files of the best
+// ones, each with its best passage as the snippet.
+func (c *Corpus) Search(ctx context.Context, query string, max int) ([]Result, error) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    if err := c.refresh(ctx); err != nil { return nil, err }
+    if max < 1 { max = 5 }
+    var out []Result
+    seen := map[*corpusFile]bool{}
+    for _, h := range c.index.Search(query, 0) {
+        ref := c.owners[h.ID]
+        if seen[ref.file] { continue }
+        seen[ref.file] = true
+        out = append(out, Result{
+            Title:     ref.file.title,
+            URL:       fileURL(ref.file.path),
+            Snippet:   ref.text,
+            Content:   ref.file.text,
+            Published: ref.file.modTime,
+            Provider:  c.Name(),
+        })
+        if len(out) == max { break }
+    }
+    return out, nil
+}
+
+// refresh rereads changed files and rebuilds the index when anything changed.
+// Callers hold c.mu.
+func (c *Corpus) refresh(ctx context.Context) error {
+    if c.index != nil && time.Since(c.scanned) < rescanAfter { return nil }
+    changed := c.index == nil
+    present := map[string]bool{}
+    err := filepath.WalkDir(c.root, func(path string, d fs.DirEntry, err error) error {
+        if err != nil { return nil } // unreadable entries are skipped
+        if ctx.Err() != nil { return ctx.Err() }
+        if d.IsDir() {
+            if path != c.root && strings.HasPrefix(d.Name(), ".") { return filepath.SkipDir }
+            return nil
+        }
+        if !corpusExts[strings.ToLower(filepath.Ext(path))] { return nil }
+        info, err := d.Info()
+        if err != nil || info.Size() > maxCorpusFile { return nil }
+        present[path] = true
+        if f, ok := c.files[path]; ok && f.modTime.Equal(info.ModTime()) && f.size == info.Size() { return nil }
+        f, err := readCorpusFile(path)
+        if err != nil { return nil }
+        f.modTime, f.size = info.ModTime(), info.Size()
+        c.files[path] = f
+        changed = true
+        return nil
+    })
+    if err != nil && !errors.Is(err, filepath.SkipDir) { return err }
+    for path := range c.files {
+        if !present[path] { delete(c.files, path); changed = true }
+    }
+    c.scanned = time.Now()
+    if changed { c.rebuild() }
+    return nil
+}
+
+func (c *Corpus) rebuild() {
+    paths := make([]string, 0, len(c.files))
+    for p := range c.files { paths = append(paths, p) }
+    sort.Strings(paths)
+    c.index, c.owners = NewBM25(), nil
+    for _, p := range paths {
+        f := c.files[p]
+        for _, chunk := range ChunkText(f.text, corpusPassageChars) {
+            // Index the title with each passage so it counts toward every part of the file.
+            c.index.Add(f.title + "\n" + chunk)
+            c.owners = append(c.owners, passageRef{file: f, text: chunk})
+        }
+    }
+}
+
+var reMarkdownTitle = regexp.MustCompile(`(?m)^#\s+(.+)$`)
+
+func readCorpusFile(path string) (*corpusFile, error) {
+    data, err := os.ReadFile(path)
+    if err != nil { return nil, err }
+    f := &corpusFile{path: path}
+    switch strings.ToLower(filepath.Ext(path)) {
+    case ".html", ".htm":
+        f.title, f.text = ExtractHTML(string(data))
+    case ".pdf":
+        f.title, f.text = ExtractPDF(data)
+    default:
+        f.text = strings.TrimSpace(string(data))
+        if m := reMarkdownTitle.FindStringSubmatch(f.text); m != nil { f.title = strings.TrimSpace(m[1]) }
+    }
+    if strings.TrimSpace(f.text) == "" { return nil, fmt.Errorf("corpus: no text in %s", path) }
+    if f.title == "" { f.title = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)) }
+    return f, nil
+}
+
+func fileURL(path string) string { return "file://" + filepath.ToSlash(path) }
+
+// ChunkText groups paragraphs into pieces of roughly size characters.
+func ChunkText(text string, size int) []string {
+    var out []string
+    var cur strings.Builder
+    flush := func() {
+        if s := strings.TrimSpace(cur.String()); s != "" { out = append(out, s) }
+        cur.Reset()
+    }
+    for _, para := range strings.Split(text, "\n\n") {
+        para = strings.TrimSpace(para)
+        if para == "" { continue }
+        if cur.Len() > 0 && cur.Len()+len(para) > size { flush() }
+        for len(para) > size*2 {
+            // split overlong paragraphs at a space near the limit
+            cut := strings.LastIndexByte(para[:size], ' ')
+            if cut <= 0 { cut = size }
+            cur.WriteString(para[:cut])
+            flush()
+            para = strings.TrimSpace(para[cut:])
+        }
+        if cur.Len() > 0 { cur.WriteString("\n\n") }
+        cur.WriteString(para)
+    }
+    flush()
+    return out
+}
@@ -0,0 +1,100 @@
+package search
+
+import (
+    "context"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func writeCorpus(t *testing.T, files map[string]string) string {
+    t.Helper()
+    dir := t.TempDir()
+    for name, body := range files {
+        path := filepath.Join(dir, name)
+        if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil { t.Fatal(err) }
+        if err := os.WriteFile(path, []byte(body), 0o644); err != nil { t.Fatal(err) }
+    }
+    return dir
+}
+
+func TestCorpusIndexesFileTypes(t *testing.T) {
+    dir := writeCorpus(t, map[string]string{
+        "notes/heat.md":   "# Heat pumps\n\nHeat pumps move heat with a refrigerant cycle.",
+        "boilers.txt":     "Gas boilers burn methane to heat water.",
+        "insulation.html": "<html><head><title>Insulation guide</title><script>var heat = 1;</script></head><body><p>Loft insulation keeps heat in.</p></body></html>",
+        "report.pdf":      string(testPDF(t, "Solar guide", "Solar thermal panels heat water.")),
+        "skip.csv":        "heat,pump\n1,2",
+        ".hidden/heat.md": "Heat in a hidden directory.",
+        "empty.md":        "   \n",
+    })
+    c, err := NewCorpus(dir)
+    if err != nil { t.Fatal(err) }
+    files, passages, err := c.Stats(context.Background())
+    if err != nil { t.Fatal(err) }
+    if files != 4 || passages != 4 { t.Errorf("indexed %d files and %d passages, want 4 and 4", files, passages) }
+
+    cases := []struct {
+        query     string
+        wantTitle string
+        wantFile  string
+    }{
+        {"refrigerant cycle", "Heat pumps", "notes/heat.md"},
+        {"methane", "boilers", "boilers.txt"},
+        {"loft insulation", "Insulation guide", "insulation.html"},
+        {"solar thermal", "Solar guide", "report.pdf"},
+    }
+    for _, tc := range cases {
+        res, err := c.Search(context.Background(), tc.query, 5)
+        if err != nil { t.Fatal(err) }
+        if len(res) == 0 {
+            t.Errorf("%q found nothing", tc.query)
+            continue
+        }
+        got := res[0]
+        if got.Title != tc.wantTitle || got.URL != fileURL(filepath.Join(dir, tc.wantFile)) || got.Provider != CorpusName {
+            t.Errorf("%q: top result %q %s from %s, want %q %s", tc.query, got.Title, got.URL, got.Provider, tc.wantTitle, tc.wantFile)
+        }
+        if got.Content == "" || got.Snippet == "" { t.Errorf("%q: result carries no text", tc.query) }
+    }
+    if res, _ := c.Search(context.Background(), "script variable", 5); len(res) != 0 { t.Errorf("HTML scripts were indexed: %v", res) }
+    res, _ := c.Search(context.Background(), "heat", 2)
+    if len(res) != 2 { t.Errorf("max 2 returned %d results", len(res)) }
+}
+
+func TestCorpusSkipsUnreadableFiles(t *testing.T) {
+    dir := writeCorpus(t, map[string]string{
+        "good.md":    "Tidal power uses the rise and fall of the sea.",
+        "broken.pdf": "%PDF-1.4 not really a pdf",
+    })
+    // A dangling link and, unless running as root, a file without read permission.
+    if err := os.Symlink(filepath.Join(dir, "missing.md"), filepath.Join(dir, "link.md")); err != nil { t.Fatal(err) }
+    locked := filepath.Join(dir, "locked.txt")
+    if err := os.WriteFile(locked, []byte("Tidal barrages are locked away."), 0o000); err != nil { t.Fatal(err) }
+    if os.Geteuid() == 0 { os.Remove(locked) }
+
+    c, err := NewCorpus(dir)
+    if err != nil { t.Fatal(err) }
+    res, err := c.Search(context.Background(), "tidal", 5)
+    if err != nil { t.Fatalf("an unreadable file failed the search: %v", err) }
+    if len(res) != 1 || !strings.HasSuffix(res[0].URL, "/good.md") { t.Errorf("got %v, want only good.md", res) }
+}
+
+func TestCorpusPicksUpChanges(t *testing.T) {
+    dir := writeCorpus(t, map[string]string{"a.md": "Geothermal wells tap underground heat."})
+    c, err := NewCorpus(dir)
+    if err != nil { t.Fatal(err) }
+    if res, _ := c.Search(context.Background(), "geothermal", 5); len(res) != 1 { t.Fatalf("got %d results, want 1", len(res)) }
+    if err := os.Remove(filepath.Join(dir, "a.md")); err != nil { t.Fatal(err) }
+    if err := os.WriteFile(filepath.Join(dir, "b.md"), []byte("Hydro dams store water."), 0o644); err != nil { t.Fatal(err) }
+    c.scanned = c.scanned.Add(-rescanAfter) // let the next search rescan
+    if res, _ := c.Search(context.Background(), "geothermal", 5); len(res) != 0 { t.Errorf("removed file still found: %v", res) }
+    if res, _ := c.Search(context.Background(), "hydro", 5); len(res) != 1 { t.Errorf("added file not found: %v", res) }
+}
+
+func TestNewCorpusNeedsDirectory(t *testing.T) {
+    dir := writeCorpus(t, map[string]string{"a.md": "text"})
+    if _, err := NewCorpus(filepath.Join(dir, "a.md")); err == nil { t.Error("a file was accepted as corpus") }
+    if _, err := NewCorpus(filepath.Join(dir, "missing")); err == nil { t.Error("a missing directory was accepted as corpus") }
+}
@@ -0,0 +1,107 @@
+package search
+
+import (
+    "context"
+    "fmt"
+    "html"
+    "io"
+    "net/http"
+    "regexp"
+    "strings"
+    "time"
+
+    "gotcha/internal/platform"
+)
+
+// maxFetchBytes caps how much of a page is read.
+const maxFetchBytes = 2 << 20
+
+// HTTPFetcher downloads pages and extracts readable text without external deps.
+type HTTPFetcher struct {
+    client *http.Client
+}
+
+func NewHTTPFetcher(proxyURL string) *HTTPFetcher {
+    return &HTTPFetcher{client: &http.Client{Timeout: 20 * time.Second, Transport: platform.ProxyTransport(proxyURL)}}
+}
+
+func (f *HTTPFetcher) Fetch(ctx context.Context, url string) (Document, error) {
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+    if err != nil { return Document{}, err }
+    req.Header.Set("User-Agent", "gotcha-research/1.0")
+    req.Header.Set("Accept", "text/html,text/plain;q=0.9,*/*;q=0.5")
+    resp, err := f.client.Do(req)
+    if err != nil { return Document{}, err }
+    defer resp.Body.Close()
+    if resp.StatusCode < 200 || resp.StatusCode >= 300 { return Document{}, fmt.Errorf("fetch %s: http %d", url, resp.StatusCode) }
+    body, err := io.ReadAll(io.LimitReader(resp.Body, maxFetchBytes))
+    if err != nil { return Document{}, fmt.Errorf("fetch %s: %w", url, err) }
+
+    doc := Document{URL: url, FetchedAt: time.Now()}
+    if ct := resp.Header.Get("Content-Type"); strings.Contains(ct, "html") || ct == "" {
+        doc.Title, doc.Text = ExtractHTML(string(body))
+        doc.Published = ExtractPublished(string(body))
+    } else {
+        doc.Text = strings.TrimSpace(string(body))
+    }
+    if doc.Title == "" { doc.Title = url }
+    return doc, nil
+}
+
+var (
+    reDropBlocks = regexp.MustCompile(`(?is)<(script|style|noscript|svg|nav|footer|header|form)\b.*?</(script|style|noscript|svg|nav|footer|header|form)>`)
+    reComments   = regexp.MustCompile(`(?s)<!--.*?-->`)
+    reTitle      = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+    reBlockTags  = regexp.MustCompile(`(?i)</?(p|div|br|li|ul|ol|h[1-6]|tr|table|section|article|blockquote|pre)\b[^>]*>`)
+    reTags       = regexp.MustCompile(`(?s)<[^>]+>`)
+    reSpaces     = regexp.MustCompile(`[ \t\r\f\v]+`)
+    reBlankLines = regexp.MustCompile(`\n\s*\n+`)
+    rePublished  = []*regexp.Regexp{
+        regexp.MustCompile(`(?is)<meta[^>]+(?:property|name|itemprop)=["'](?:article:published_time|og:published_time|datePublished|date|dc\.date|pubdate|publish_date)["'][^>]+content=["']([^"']+)["']`),
+        regexp.MustCompile(`(?is)<meta[^>]+content=["']([^"']+)["'][^>]+(?:property|name|itemprop)=["'](?:article:published_time|og:published_time|datePublished|date|dc\.date|pubdate|publish_date)["']`),
+        regexp.MustCompile(`"datePublished"\s*:\s*"([^"]+)"`),
+        regexp.MustCompile(`(?is)<time[^>]+datetime=["']([^"']+)["']`),
+    }
+)
+
+// ExtractHTML returns the page title and its visible text with paragraph
+// breaks preserved.
+func ExtractHTML(page string) (title, text string) {
+    if m := reTitle.FindStringSubmatch(page); m != nil {
+        title = strings.TrimSpace(html.UnescapeString(reSpaces.ReplaceAllString(m[1], " ")))
+    }
+    s := reComments.ReplaceAllString(page, "")
+    s = reDropBlocks.ReplaceAllString(s, " ")
+    s = reTitle.ReplaceAllString(s, " ")
+    s = reBlockTags.ReplaceAllString(s, "\n\n")
+    s = reTags.ReplaceAllString(s, " ")
+    s = html.UnescapeString(s)
+    s = reSpaces.ReplaceAllString(s, " ")
+    lines := strings.Split(s, "\n")
+    for i := range lines { lines[i] = strings.TrimSpace(lines[i]) }
+    s = strings.Join(lines, "\n")
+    s = reBlankLines.ReplaceAllString(s, "\n\n")
+    return title, strings.TrimSpace(s)
+}
+
+// ExtractPublished returns the publication date declared in a page's
+// metadata, structured data or first <time> element, if any.
+func ExtractPublished(page string) time.Time {
+    for _, re := range rePublished {
+        if m := re.FindStringSubmatch(page); m != nil {
+            if t := ParseDate(m[1]); !t.IsZero() { return t }
+        }
+    }
+    return time.Time{}
+}
+
+// ParseDate accepts the date formats commonly found in search APIs and page
+// metadata; it returns the zero time when none match.
+func ParseDate(s string) time.Time {
+    s = strings.TrimSpace(s)
+    if s == "" { return time.Time{} }
+    for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02", time.RFC1123, time.RFC1123Z, "Mon, 02 Jan 2006 15:04:05 MST", "January 2, 2006", "Jan 2, 2006"} {
+        if t, err := time.Parse(layout, s); err == nil { return t }
+    }
+    return time.Time{}
+}
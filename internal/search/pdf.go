@@ -0,0 +1,198 @@
+package search
+
+import (
+    "bytes"
+    "compress/zlib"
+    "io"
+    "regexp"
+    "strconv"
+    "strings"
+    "unicode/utf16"
+)
+
+// maxPDFStream caps the inflated size of one PDF stream.
+const maxPDFStream = 8 << 20
+
+var (
+    rePDFStream = regexp.MustCompile(`(?s)<<(.*?)>>\s*stream\r?\n`)
+    rePDFTitle  = regexp.MustCompile(`/Title\s*\(((?:\\.|[^\\)])*)\)`)
+)
+
+// ExtractPDF returns the title and text of a PDF. It understands the common
+// case of Flate-compressed content streams with literal or hex strings and
+// is best-effort otherwise: scanned pages and exotic font encodings yield
+// little or no text.
+func ExtractPDF(data []byte) (title, text string) {
+    if m := rePDFTitle.FindSubmatch(data); m != nil { title = strings.TrimSpace(decodePDFString(unescapePDF(m[1]))) }
+    var b strings.Builder
+    for _, loc := range rePDFStream.FindAllSubmatchIndex(data, -1) {
+        dict := string(data[loc[2]:loc[3]])
+        if strings.Contains(dict, "/Image") || strings.Contains(dict, "/FontFile") || strings.Contains(dict, "/Length1") || strings.Contains(dict, "/XRef") || strings.Contains(dict, "/ObjStm") { continue }
+        start := loc[1]
+        end := bytes.Index(data[start:], []byte("endstream"))
+        if end < 0 { continue }
+        raw := data[start : start+end]
+        if strings.Contains(dict, "/FlateDecode") {
+            zr, err := zlib.NewReader(bytes.NewReader(raw))
+            if err != nil { continue }
+            raw, err = io.ReadAll(io.LimitReader(zr, maxPDFStream))
+            zr.Close()
+            if err != nil && len(raw) == 0 { continue }
+        } else if strings.Contains(dict, "/Filter") {
+            continue // other filters are not supported
+        }
+        if t := pdfContentText(raw); strings.TrimSpace(t) != "" {
+            b.WriteString(t)
+            b.WriteString("\n\n")
+        }
+    }
+    text = reSpaces.ReplaceAllString(b.String(), " ")
+    lines := strings.Split(text, "\n")
+    for i := range lines { lines[i] = strings.TrimSpace(lines[i]) }
+    return title, strings.TrimSpace(reBlankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+// pdfContentText runs the text operators of a content stream.
+func pdfContentText(content []byte) string {
+    var (
+        b        strings.Builder
+        operands []string // decoded strings since the last operator
+        inText   bool
+    )
+    i := 0
+    for i < len(content) {
+        c := content[i]
+        switch {
+        case c == '(':
+            s, n := readPDFLiteral(content[i:])
+            operands = append(operands, decodePDFString(s))
+            i += n
+        case c == '<' && i+1 < len(content) && content[i+1] != '<':
+            end := bytes.IndexByte(content[i:], '>')
+            if end < 0 { return b.String() }
+            operands = append(operands, decodePDFString(hexPDF(content[i+1:i+end])))
+            i += end + 1
+        case c == '[' || c == ']':
+            i++
+        case c == '%':
+            for i < len(content) && content[i] != '\n' && content[i] != '\r' { i++ }
+        case c == '-' || c == '.' || (c >= '0' && c <= '9'):
+            j := i + 1
+            for j < len(content) && (content[j] == '.' || (content[j] >= '0' && content[j] <= '9')) { j++ }
+            // Large negative kerning inside TJ arrays separates words.
+            if f, err := strconv.ParseFloat(string(content[i:j]), 64); err == nil && f < -180 && len(operands) > 0 { operands = append(operands, " ") }
+            i = j
+        case isPDFOpChar(c):
+            j := i + 1
+            for j < len(content) && isPDFOpChar(content[j]) { j++ }
+            op := string(content[i:j])
+            i = j
+            switch op {
+            case "BT":
+                inText = true
+            case "ET":
+                inText = false
+                b.WriteString("\n")
+            case "Tj", "TJ":
+                if inText { b.WriteString(strings.Join(operands, "")) }
+            case "'", "\"":
+                if inText { b.WriteString("\n" + strings.Join(operands, "")) }
+            case "T*", "Td", "TD":
+                if inText { b.WriteString("\n") }
+            }
+            operands = operands[:0]
+        default:
+            i++
+        }
+    }
+    return b.String()
+}
+
+func isPDFOpChar(c byte) bool {
+    return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '*' || c == '\'' || c == '"'
+}
+
+// readPDFLiteral reads a (...) string starting at s[0], returning its
+// unescaped bytes and the number of input bytes consumed.
+func readPDFLiteral(s []byte) ([]byte, int) {
+    depth := 0
+    for i := 0; i < len(s); i++ {
+        switch s[i] {
+        case '\\':
+            i++
+        case '(':
+            depth++
+        case ')':
+            depth--
+            if depth == 0 { return unescapePDF(s[1:i]), i + 1 }
+        }
+    }
+    return unescapePDF(s[1:]), len(s)
+}
+
+func unescapePDF(s []byte) []byte {
+    out := make([]byte, 0, len(s))
+    for i := 0; i < len(s); i++ {
+        if s[i] != '\\' || i+1 >= len(s) { out = append(out, s[i]); continue }
+        i++
+        switch c := s[i]; c {
+        case 'n':
+            out = append(out, '\n')
+        case 'r':
+            out = append(out, '\r')
+        case 't':
+            out = append(out, '\t')
+        case 'b', 'f':
+        case '\r', '\n':
+            // line continuation
+        default:
+            if c >= '0' && c <= '7' {
+                j := i
+                for j < len(s) && j < i+3 && s[j] >= '0' && s[j] <= '7' { j++ }
+                v, _ := strconv.ParseUint(string(s[i:j]), 8, 8)
+                out = append(out, byte(v))
+                i = j - 1
+            } else {
+                out = append(out, c)
+            }
+        }
+    }
+    return out
+}
+
+func hexPDF(s []byte) []byte {
+    var digits []byte
+    for _, c := range s {
+        if (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F') { digits = append(digits, c) }
+    }
+    if len(digits)%2 == 1 { digits = append(digits, '0') }
+    out := make([]byte, len(digits)/2)
+    for i := range out {
+        v, _ := strconv.ParseUint(string(digits[2*i:2*i+2]), 16, 8)
+        out[i] = byte(v)
+    }
+    return out
+}
+
+// decodePDFString reads UTF-16 strings (with a byte order mark or, as
+// two-byte CID strings usually are, high bytes of zero) and treats anything
+// else as Latin-1.
+func decodePDFString(s []byte) string {
+    utf16be := len(s) >= 2 && s[0] == 0xFE && s[1] == 0xFF
+    if utf16be {
+        s = s[2:]
+    } else if len(s) >= 2 && len(s)%2 == 0 {
+        utf16be = true
+        for i := 0; i < len(s); i += 2 {
+            if s[i] != 0 { utf16be = false; break }
+        }
+    }
+    if utf16be && len(s)%2 == 0 {
+        u := make([]uint16, len(s)/2)
+        for i := range u { u[i] = uint16(s[2*i])<<8 | uint16(s[2*i+1]) }
+        return string(utf16.Decode(u))
+    }
+    r := make([]rune, len(s))
+    for i, c := range s { r[i] = rune(c) }
+    return string(r)
+}
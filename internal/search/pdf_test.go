@@ -0,0 +1,51 @@
+package search
+
+import (
+    "bytes"
+    "compress/zlib"
+    "fmt"
+    "testing"
+)
+
+// testPDF builds a minimal PDF with title and one Flate-compressed page
+// showing text.
+func testPDF(t *testing.T, title, text string) []byte {
+    t.Helper()
+    content := fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)
+    var z bytes.Buffer
+    w := zlib.NewWriter(&z)
+    w.Write([]byte(content))
+    if err := w.Close(); err != nil { t.Fatal(err) }
+    var b bytes.Buffer
+    b.WriteString("%PDF-1.4\n1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj\n")
+    fmt.Fprintf(&b, "4 0 obj << /Length %d /Filter /FlateDecode >>\nstream\n", z.Len())
+    b.Write(z.Bytes())
+    b.WriteString("\nendstream\nendobj\n")
+    fmt.Fprintf(&b, "5 0 obj << /Title (%s) /Producer (test) >> endobj\ntrailer << /Root 1 0 R /Info 5 0 R >>\n%%%%EOF\n", title)
+    return b.Bytes()
+}
+
+func TestExtractPDF(t *testing.T) {
+    plain := func(dict, content string) []byte {
+        return []byte(fmt.Sprintf("%%PDF-1.4\n1 0 obj << %s /Length %d >>\nstream\n%s\nendstream\nendobj\n", dict, len(content), content))
+    }
+    cases := []struct {
+        name      string
+        pdf       []byte
+        wantTitle string
+        wantText  string
+    }{
+        {"flate stream", testPDF(t, "Wind report", "Offshore wind grew."), "Wind report", "Offshore wind grew."},
+        {"uncompressed stream", plain("", "BT (Hello) Tj ET"), "", "Hello"},
+        {"escaped parentheses", plain("", `BT (a \(b\) c) Tj ET`), "", "a (b) c"},
+        {"hex string", plain("", "BT <48656C6C6F> Tj ET"), "", "Hello"},
+        {"TJ array", plain("", "BT [(Hel) -20 (lo)] TJ ET"), "", "Hello"},
+        {"unsupported filter", plain("/Filter /DCTDecode", "BT (Hidden) Tj ET"), "", ""},
+        {"image stream", plain("/Subtype /Image", "BT (Hidden) Tj ET"), "", ""},
+        {"not a pdf", []byte("just text"), "", ""},
+    }
+    for _, c := range cases {
+        title, text := ExtractPDF(c.pdf)
+        if title != c.wantTitle || text != c.wantText { t.Errorf("%s: got %q %q, want %q %q", c.name, title, text, c.wantTitle, c.wantText) }
+    }
+}
@@ -0,0 +1,58 @@
+package search
+
+import (
+    "context"
+    "time"
+
+    "gotcha/internal/platform"
+)
+
+// Result is one hit returned by a search provider.
+type Result struct {
+    Title     string    `json:"title"`
+    URL       string    `json:"url"`
+    Snippet   string    `json:"snippet,omitempty"`
+    Content   string    `json:"content,omitempty"` // full text when the provider returns it
+    Published time.Time `json:"published,omitempty"`
+    Provider  string    `json:"provider"`
+}
+
+// Provider answers search queries.
+type Provider interface {
+    Name() string
+    Search(ctx context.Context, query string, max int) ([]Result, error)
+}
+
+// Document is the extracted text of a fetched source.
+type Document struct {
+    URL       string    `json:"url"`
+    Title     string    `json:"title"`
+    Text      string    `json:"text"`
+    // Alternates lists other URLs serving the same content (mirrors, syndicated copies).
+    Alternates []string `json:"alternates,omitempty"`
+    Published time.Time `json:"published,omitempty"`
+    FetchedAt time.Time `json:"fetched_at"`
+}
+
+// Fetcher retrieves and extracts a source document.
+type Fetcher interface {
+    Fetch(ctx context.Context, url string) (Document, error)
+}
+
+// ProvidersFromConfig builds the configured providers, skipping any that lack
+// credentials. An empty result means research runs without web search. A
+// corpus directory adds the local corpus provider; list no web providers to
+// keep research entirely local.
+func ProvidersFromConfig(cfg platform.SearchConfig, proxyURL string) []Provider {
+    var out []Provider
+    for _, name := range cfg.Providers {
+        switch name {
+        case "tavily":
+            if cfg.TavilyAPIKey != "" { out = append(out, NewTavily(cfg.TavilyAPIKey, proxyURL)) }
+        }
+    }
+    if cfg.CorpusDir != "" {
+        if c, err := NewCorpus(cfg.CorpusDir); err == nil { out = append(out, c) }
+    }
+    return out
+}
@@ -0,0 +1,71 @@
+package search
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "time"
+
+    "gotcha/internal/platform"
+)
+
+// Tavily is a Provider backed by the Tavily search API.
+type Tavily struct {
+    apiKey   string
+    endpoint string
+    client   *http.Client
+}
+
+func NewTavily(apiKey, proxyURL string) *Tavily {
+    return &Tavily{
+        apiKey:   apiKey,
+        endpoint: "https://api.tavily.com/search",
+        client:   &http.Client{Timeout: 30 * time.Second, Transport: platform.ProxyTransport(proxyURL)},
+    }
+}
+
+func (t *Tavily) Name() string { return "tavily" }
+
+type tavilyReq struct {
+    Query       string `json:"query"`
+    MaxResults  int    `json:"max_results,omitempty"`
+    SearchDepth string `json:"search_depth,omitempty"`
+}
+
+type tavilyResp struct {
+    Results []struct {
+        Title         string `json:"title"`
+        URL           string `json:"url"`
+        Content       string `json:"content"`
+        RawContent    string `json:"raw_content"`
+        PublishedDate string `json:"published_date"`
+    } `json:"results"`
+}
+
+func (t *Tavily) Search(ctx context.Context, query string, max int) ([]Result, error) {
+    if t.apiKey == "" { return nil, errors.New("tavily: missing API key") }
+    body, _ := json.Marshal(tavilyReq{Query: query, MaxResults: max, SearchDepth: "basic"})
+    req, _ := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(body))
+    req.Header.Set("Authorization", "Bearer "+t.apiKey)
+    req.Header.Set("Content-Type", "application/json")
+    resp, err := t.client.Do(req)
+    if err != nil { return nil, err }
+    defer resp.Body.Close()
+    if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+        b, _ := io.ReadAll(io.LimitReader(resp.Body, 8192))
+        return nil, fmt.Errorf("tavily: http %d: %s", resp.StatusCode, string(b))
+    }
+    var tr tavilyResp
+    if err := json.NewDecoder(resp.Body).Decode(&tr); err != nil { return nil, fmt.Errorf("tavily: decode: %w", err) }
+    out := make([]Result, 0, len(tr.Results))
+    for _, r := range tr.Results {
+        res := Result{Title: r.Title, URL: r.URL, Snippet: r.Content, Content: r.RawContent, Provider: t.Name()}
+        res.Published = ParseDate(r.PublishedDate)
+        out = append(out, res)
+    }
+    return out, nil
+}
@@ -0,0 +1,439 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gotcha/internal/platform"
+	"gotcha/internal/search"
+)
+
+// Full-text search. Every session keeps an inverted index of its own
+// documents in sessions/<id>/index.json: one document per message, note and
+// report section, plus one for the title and description. Saves leave the
+// index alone; Search first catches up on every file written since it was
+// last indexed, re-reading only those files and re-tokenizing only the
+// documents in them whose text changed, and then ranks the documents of all
+// sessions with BM25.
+
+// indexVersion is bumped when the documents or tokenization change, so
+// older indexes are rebuilt.
+const indexVersion = 1
+
+// Kinds of indexed documents.
+const (
+	KindTitle   = "title"
+	KindMessage = "message"
+	KindNote    = "note"
+	KindReport  = "report"
+)
+
+// snippetWords is the length of a hit's snippet.
+const snippetWords = 30
+
+// SearchHit is a document matching a search.
+type SearchHit struct {
+	SessionID string
+	Title     string // the session's title
+	Archived  bool
+	Kind      string
+	Source    string // file in the session directory the document comes from
+	Index     int    // message index in the conversation, note number or report section
+	Role      string // message author
+	Heading   string // report section heading
+	Snippet   string // words around the first match
+	Score     float64
+}
+
+// Location describes where in its session a hit is, e.g. "message 4
+// (assistant)" or "report: Findings".
+func (h SearchHit) Location() string {
+	switch h.Kind {
+	case KindMessage:
+		return fmt.Sprintf("message %d (%s)", h.Index+1, h.Role)
+	case KindNote:
+		if h.Source == "notes.md" {
+			return fmt.Sprintf("note %d in notes.md", h.Index+1)
+		}
+		return fmt.Sprintf("note %d", h.Index)
+	case KindReport:
+		if h.Heading == "" {
+			return "report"
+		}
+		return "report: " + h.Heading
+	}
+	return "title"
+}
+
+// sessionIndex is the inverted index of one session. Documents are keyed
+// "<source>#<index>".
+type sessionIndex struct {
+	Version  int                       `json:"version"`
+	Stamps   map[string]fileStamp      `json:"stamps"` // each source file as it was when indexed
+	Docs     map[string]indexDoc       `json:"docs"`
+	Postings map[string]map[string]int `json:"postings"` // term -> document key -> frequency
+}
+
+type indexDoc struct {
+	Kind  string   `json:"kind"`
+	Hash  uint64   `json:"hash"`
+	Len   int      `json:"len"`
+	Terms []string `json:"terms"` // distinct terms, to drop the postings when the text changes
+}
+
+type fileStamp struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+func (s fileStamp) same(o fileStamp) bool { return s.Size == o.Size && s.ModTime.Equal(o.ModTime) }
+
+// document is a piece of a session file as indexed.
+type document struct {
+	Kind, Source, Role, Heading, Text string
+	Index                             int
+}
+
+func (d document) key() string { return d.Source + "#" + strconv.Itoa(d.Index) }
+
+func (d document) hash() uint64 {
+	h := fnv.New64a()
+	for _, s := range []string{d.Kind, d.Role, d.Heading, d.Text} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return h.Sum64()
+}
+
+// Search ranks the documents of every session, archived ones included,
+// against query with BM25 and returns up to limit hits, best first.
+func (m *Manager) Search(query string, limit int) ([]SearchHit, error) {
+	terms := uniqueTerms(search.Tokenize(query))
+	if len(terms) == 0 {
+		return nil, errors.New("no searchable words in the query")
+	}
+	sessions, err := m.AllSessions()
+	if err != nil {
+		return nil, err
+	}
+
+	// Collection statistics across all sessions
+	indexes := make([]*sessionIndex, len(sessions))
+	docs, total := 0, 0
+	df := map[string]int{}
+	for i, s := range sessions {
+		// A failed save only means the index is caught up again next time.
+		indexes[i], _ = m.indexSession(s.ID)
+		docs += len(indexes[i].Docs)
+		for _, d := range indexes[i].Docs {
+			total += d.Len
+		}
+		for _, t := range terms {
+			df[t] += len(indexes[i].Postings[t])
+		}
+	}
+	if docs == 0 {
+		return nil, nil
+	}
+	avg := max(float64(total)/float64(docs), 1)
+
+	type scored struct {
+		session int
+		key     string
+		score   float64
+	}
+	var found []scored
+	for i, idx := range indexes {
+		scores := map[string]float64{}
+		for _, t := range terms {
+			for key, tf := range idx.Postings[t] {
+				scores[key] += search.BM25Weight(tf, df[t], docs, idx.Docs[key].Len, avg)
+			}
+		}
+		for key, score := range scores {
+			found = append(found, scored{i, key, score})
+		}
+	}
+	sort.Slice(found, func(a, b int) bool {
+		if found[a].score != found[b].score {
+			return found[a].score > found[b].score
+		}
+		if found[a].session != found[b].session {
+			return found[a].session < found[b].session
+		}
+		return found[a].key < found[b].key
+	})
+	if limit > 0 && len(found) > limit {
+		found = found[:limit]
+	}
+
+	// Snippets come from the documents themselves, read once per source.
+	read := map[string]map[string]document{}
+	hits := make([]SearchHit, 0, len(found))
+	for _, f := range found {
+		s := sessions[f.session]
+		source := f.key[:strings.LastIndex(f.key, "#")]
+		if read[s.ID+"/"+source] == nil {
+			read[s.ID+"/"+source] = map[string]document{}
+			for _, d := range m.sourceDocuments(s.ID, source) {
+				read[s.ID+"/"+source][d.key()] = d
+			}
+		}
+		d, ok := read[s.ID+"/"+source][f.key]
+		if !ok {
+			continue // changed since it was indexed a moment ago
+		}
+		hits = append(hits, SearchHit{
+			SessionID: s.ID, Title: s.Title, Archived: s.Archived,
+			Kind: d.Kind, Source: d.Source, Index: d.Index, Role: d.Role, Heading: d.Heading,
+			Snippet: snippet(d.Text, terms), Score: f.score,
+		})
+	}
+	return hits, nil
+}
+
+// indexSession brings a session's index up to date and returns it. Only
+// source files whose size or time changed are read again. The index is
+// returned even when saving it fails.
+func (m *Manager) indexSession(sessionID string) (*sessionIndex, error) {
+	path := m.indexPath(sessionID)
+	idx := &sessionIndex{}
+	if data, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, idx)
+	}
+	if idx.Version != indexVersion || idx.Docs == nil || idx.Postings == nil || idx.Stamps == nil {
+		// Missing, damaged or outdated: start over.
+		idx = &sessionIndex{Version: indexVersion, Stamps: map[string]fileStamp{}, Docs: map[string]indexDoc{}, Postings: map[string]map[string]int{}}
+	}
+
+	stamps := m.indexSources(sessionID)
+	changed := false
+	for source, stamp := range stamps {
+		if old, ok := idx.Stamps[source]; ok && old.same(stamp) {
+			continue
+		}
+		idx.replace(source, m.sourceDocuments(sessionID, source))
+		idx.Stamps[source] = stamp
+		changed = true
+	}
+	for source := range idx.Stamps {
+		if _, ok := stamps[source]; !ok {
+			idx.replace(source, nil)
+			delete(idx.Stamps, source)
+			changed = true
+		}
+	}
+	if !changed {
+		return idx, nil
+	}
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return idx, err
+	}
+	return idx, platform.WriteFileAtomic(path, data)
+}
+
+// replace indexes the documents now in source, leaving unchanged ones as
+// they are and dropping those no longer there.
+func (x *sessionIndex) replace(source string, docs []document) {
+	seen := map[string]bool{}
+	for _, d := range docs {
+		key, hash := d.key(), d.hash()
+		seen[key] = true
+		if old, ok := x.Docs[key]; ok && old.Hash == hash {
+			continue
+		}
+		x.remove(key)
+		x.add(key, hash, d)
+	}
+	prefix := source + "#"
+	for key := range x.Docs {
+		if strings.HasPrefix(key, prefix) && !seen[key] {
+			x.remove(key)
+		}
+	}
+}
+
+func (x *sessionIndex) add(key string, hash uint64, d document) {
+	tokens := search.Tokenize(d.Heading + " " + d.Text)
+	if len(tokens) == 0 {
+		return
+	}
+	tf := map[string]int{}
+	for _, t := range tokens {
+		tf[t]++
+	}
+	doc := indexDoc{Kind: d.Kind, Hash: hash, Len: len(tokens)}
+	for t, n := range tf {
+		if x.Postings[t] == nil {
+			x.Postings[t] = map[string]int{}
+		}
+		x.Postings[t][key] = n
+		doc.Terms = append(doc.Terms, t)
+	}
+	sort.Strings(doc.Terms)
+	x.Docs[key] = doc
+}
+
+func (x *sessionIndex) remove(key string) {
+	doc, ok := x.Docs[key]
+	if !ok {
+		return
+	}
+	for _, t := range doc.Terms {
+		delete(x.Postings[t], key)
+		if len(x.Postings[t]) == 0 {
+			delete(x.Postings, t)
+		}
+	}
+	delete(x.Docs, key)
+}
+
+// indexSources returns the size and time of each indexed file of a session:
+// its context (title and conversation), notes and report.
+func (m *Manager) indexSources(sessionID string) map[string]fileStamp {
+	dir := filepath.Join(m.basePath, "sessions", sessionID)
+	names := []string{"context.json", "notes.md", "report.md"}
+	if notes, err := filepath.Glob(filepath.Join(dir, "note_*.md")); err == nil {
+		for _, n := range notes {
+			names = append(names, filepath.Base(n))
+		}
+	}
+	stamps := map[string]fileStamp{}
+	for _, name := range names {
+		if fi, err := os.Stat(filepath.Join(dir, name)); err == nil && fi.Mode().IsRegular() {
+			stamps[name] = fileStamp{Size: fi.Size(), ModTime: fi.ModTime()}
+		}
+	}
+	return stamps
+}
+
+// sourceDocuments splits a session file into documents: the title and each
+// user or assistant message of context.json, each note of notes.md, a
+// note_N.md file whole, and each section of report.md.
+func (m *Manager) sourceDocuments(sessionID, source string) []document {
+	data, err := os.ReadFile(filepath.Join(m.basePath, "sessions", sessionID, source))
+	if err != nil {
+		return nil
+	}
+	text := string(data)
+	var docs []document
+	switch {
+	case source == "context.json":
+		var context Context
+		if json.Unmarshal(data, &context) != nil {
+			return nil
+		}
+		if title := strings.TrimSpace(context.Title + "\n" + context.Description); title != "" {
+			docs = append(docs, document{Kind: KindTitle, Source: source, Index: -1, Text: title})
+		}
+		for i, msg := range context.Conversations {
+			if msg.Role == "user" || msg.Role == "assistant" {
+				docs = append(docs, document{Kind: KindMessage, Source: source, Index: i, Role: msg.Role, Text: msg.Text})
+			}
+		}
+	case source == "notes.md":
+		// One "- [time] text" entry per note, continuing until the next.
+		var cur []string
+		flush := func() {
+			if len(cur) > 0 {
+				docs = append(docs, document{Kind: KindNote, Source: source, Index: len(docs), Text: strings.Join(cur, "\n")})
+				cur = nil
+			}
+		}
+		for _, line := range strings.Split(text, "\n") {
+			if strings.HasPrefix(line, "- [") {
+				flush()
+				if i := strings.Index(line, "] "); i > 0 {
+					line = line[i+2:]
+				}
+			}
+			if strings.TrimSpace(line) != "" {
+				cur = append(cur, line)
+			}
+		}
+		flush()
+	case strings.HasPrefix(source, "note_"):
+		n, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(source, "note_"), ".md"))
+		// Drop the "# Note N / Created" header above the rule.
+		if i := strings.Index(text, "\n---\n"); i >= 0 {
+			text = text[i+5:]
+		}
+		docs = append(docs, document{Kind: KindNote, Source: source, Index: n, Text: strings.TrimSpace(text)})
+	case source == "report.md":
+		if strings.HasPrefix(text, "---\n") {
+			if i := strings.Index(text[4:], "\n---\n"); i >= 0 {
+				text = text[4+i+5:]
+			}
+		}
+		heading, body := "", []string{}
+		flush := func() {
+			if heading != "" || strings.TrimSpace(strings.Join(body, "")) != "" {
+				docs = append(docs, document{Kind: KindReport, Source: source, Index: len(docs), Heading: heading, Text: strings.TrimSpace(strings.Join(body, "\n"))})
+			}
+		}
+		for _, line := range strings.Split(text, "\n") {
+			if strings.HasPrefix(line, "#") {
+				flush()
+				heading, body = strings.TrimSpace(strings.TrimLeft(line, "#")), nil
+				continue
+			}
+			body = append(body, line)
+		}
+		flush()
+	}
+	return docs
+}
+
+// snippet returns the words of text around the first match of terms.
+func snippet(text string, terms []string) string {
+	want := map[string]bool{}
+	for _, t := range terms {
+		want[t] = true
+	}
+	words := strings.Fields(text)
+	at := 0
+find:
+	for i, w := range words {
+		for _, t := range search.Tokenize(w) {
+			if want[t] {
+				at = i
+				break find
+			}
+		}
+	}
+	start := max(0, at-snippetWords/4)
+	end := min(len(words), start+snippetWords)
+	s := strings.Join(words[start:end], " ")
+	if start > 0 {
+		s = "…" + s
+	}
+	if end < len(words) {
+		s += "…"
+	}
+	return s
+}
+
+func uniqueTerms(terms []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, t := range terms {
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func (m *Manager) indexPath(sessionID string) string {
+	return filepath.Join(m.basePath, "sessions", sessionID, "index.json")
+}
@@ -0,0 +1,275 @@
+package session
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultGCMinAge is how long a session or temp file must have been left
+// untouched before GC removes it, so it never pulls the rug from under a
+// session that another gotcha process has just opened.
+const DefaultGCMinAge = time.Hour
+
+// deletedSuffix marks a session directory being deleted; GC finishes the
+// job if the removal was interrupted.
+const deletedSuffix = ".deleted"
+
+// disposableFiles are the files an otherwise empty session may have.
+var disposableFiles = map[string]bool{
+	"context.json": true, "context.json.bak": true, "events.jsonl": true, "index.json": true, "notes.md": true, "transcript.md": true,
+}
+
+// GCOptions controls GC.
+type GCOptions struct {
+	MinAge time.Duration // skip anything modified more recently; 0 means DefaultGCMinAge
+	DryRun bool          // report what would be removed without removing it
+}
+
+// GCReport lists what GC removed, or would remove in a dry run.
+type GCReport struct {
+	Sessions []string // empty sessions
+	Files    []string // stale temp files and leftovers of interrupted deletes
+	Bytes    int64    // space freed
+}
+
+// AllSessions returns every session, archived ones included, most recent
+// first.
+func (m *Manager) AllSessions() ([]SessionInfo, error) {
+	metadata, err := m.loadMetadata()
+	if err != nil {
+		return nil, err
+	}
+	sessions := append([]SessionInfo(nil), metadata.Sessions...)
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].UpdatedAt.After(sessions[j].UpdatedAt)
+	})
+	return sessions, nil
+}
+
+// OnRemove registers fn to run before Archive or Delete takes a session
+// away, so the caller can stop work still writing into it, such as running
+// research. An error from fn stops the removal. Call it before the manager
+// is shared.
+func (m *Manager) OnRemove(fn func(sessionID string) error) {
+	m.beforeRemove = fn
+}
+
+// Archive hides a session from the default listing and the -continue
+// choice without deleting anything.
+func (m *Manager) Archive(sessionID string) error {
+	if m.beforeRemove != nil {
+		if err := m.beforeRemove(sessionID); err != nil {
+			return err
+		}
+	}
+	_, err := m.updateSession(sessionID, func(s *SessionInfo) bool {
+		changed := !s.Archived
+		s.Archived = true
+		return changed
+	})
+	return err
+}
+
+// Unarchive returns an archived session to the default listing.
+func (m *Manager) Unarchive(sessionID string) error {
+	_, err := m.updateSession(sessionID, func(s *SessionInfo) bool {
+		changed := s.Archived
+		s.Archived = false
+		return changed
+	})
+	return err
+}
+
+// Delete removes a session: its metadata entry and its directory with the
+// conversation, notes, reports and run history. The directory is renamed
+// out of the way first, so an interrupted delete never leaves a half-removed
+// session behind; GC clears the remains.
+func (m *Manager) Delete(sessionID string) error {
+	if sessionID == "" || sessionID == "." || sessionID == ".." || strings.ContainsAny(sessionID, `/\`) {
+		return fmt.Errorf("invalid session ID %q", sessionID)
+	}
+	if m.beforeRemove != nil {
+		if err := m.beforeRemove(sessionID); err != nil {
+			return err
+		}
+	}
+	return m.withLock(func() error {
+		metadata, problem, err := m.readMetadata()
+		if err != nil {
+			return err
+		}
+		if problem != "" {
+			m.noteRebuild(problem, metadata)
+		}
+		kept := metadata.Sessions[:0]
+		found := false
+		for _, s := range metadata.Sessions {
+			if s.ID == sessionID {
+				found = true
+				continue
+			}
+			kept = append(kept, s)
+		}
+		dir := filepath.Join(m.basePath, "sessions", sessionID)
+		if _, err := os.Stat(dir); err == nil {
+			found = true
+		}
+		if !found {
+			return fmt.Errorf("session %s not found", sessionID)
+		}
+
+		trash := filepath.Join(m.basePath, "sessions", fmt.Sprintf(".%s.%d%s", sessionID, time.Now().UnixNano(), deletedSuffix))
+		if err := os.Rename(dir, trash); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to delete session directory: %w", err)
+		}
+		metadata.Sessions = kept
+		if metadata.LastSessionID == sessionID {
+			metadata.LastSessionID = ""
+		}
+		metadata.UpdatedAt = time.Now()
+		if err := m.saveMetadata(metadata); err != nil {
+			return err
+		}
+		if err := os.RemoveAll(trash); err != nil {
+			return fmt.Errorf("failed to delete session directory: %w", err)
+		}
+		return nil
+	})
+}
+
+// GC removes empty sessions, ones with no conversation, notes, reports or
+// runs, along with temp files left by interrupted writes and the remains of
+// interrupted deletes. Anything touched within MinAge is left alone.
+func (m *Manager) GC(opts GCOptions) (GCReport, error) {
+	if opts.MinAge <= 0 {
+		opts.MinAge = DefaultGCMinAge
+	}
+	cutoff := time.Now().Add(-opts.MinAge)
+	var report GCReport
+
+	err := m.withLock(func() error {
+		metadata, problem, err := m.readMetadata()
+		if err != nil {
+			return err
+		}
+		if problem != "" {
+			m.noteRebuild(problem, metadata)
+		}
+
+		// Empty sessions
+		kept := metadata.Sessions[:0]
+		for _, s := range metadata.Sessions {
+			dir := filepath.Join(m.basePath, "sessions", s.ID)
+			size, empty := m.emptySession(s, cutoff)
+			if !empty {
+				kept = append(kept, s)
+				continue
+			}
+			report.Sessions = append(report.Sessions, s.ID)
+			report.Bytes += size
+			if !opts.DryRun {
+				if err := os.RemoveAll(dir); err != nil {
+					return fmt.Errorf("failed to remove %s: %w", s.ID, err)
+				}
+			}
+		}
+		if !opts.DryRun && len(report.Sessions) > 0 {
+			metadata.Sessions = kept
+			for _, id := range report.Sessions {
+				if metadata.LastSessionID == id {
+					metadata.LastSessionID = ""
+				}
+			}
+			metadata.UpdatedAt = time.Now()
+			if err := m.saveMetadata(metadata); err != nil {
+				return err
+			}
+		}
+
+		// Stale temp files and interrupted deletes
+		return filepath.WalkDir(m.basePath, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			stale := strings.HasSuffix(d.Name(), ".tmp") || (d.IsDir() && strings.HasSuffix(d.Name(), deletedSuffix))
+			if !stale {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil || info.ModTime().After(cutoff) {
+				return nil
+			}
+			size := info.Size()
+			if d.IsDir() {
+				size = dirSize(path)
+			}
+			rel, _ := filepath.Rel(m.basePath, path)
+			report.Files = append(report.Files, rel)
+			report.Bytes += size
+			if !opts.DryRun {
+				if err := os.RemoveAll(path); err != nil {
+					return fmt.Errorf("failed to remove %s: %w", rel, err)
+				}
+			}
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		})
+	})
+	return report, err
+}
+
+// emptySession reports whether a session holds nothing worth keeping and
+// was last touched before cutoff, with the size of its directory.
+func (m *Manager) emptySession(s SessionInfo, cutoff time.Time) (int64, bool) {
+	if s.CreatedAt.After(cutoff) || s.UpdatedAt.After(cutoff) {
+		return 0, false
+	}
+	dir := filepath.Join(m.basePath, "sessions", s.ID)
+	if info, err := os.Stat(dir); err == nil && info.ModTime().After(cutoff) {
+		return 0, false
+	}
+	context, err := readJSON[Context](m, m.contextPath(s.ID))
+	if err != nil && !os.IsNotExist(err) {
+		return 0, false
+	}
+	if len(context.Conversations) > 0 || context.NoteCount > 0 || context.UpdatedAt.After(cutoff) {
+		return 0, false
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return 0, false
+	}
+	var size int64
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || e.IsDir() || !disposableFiles[e.Name()] || info.ModTime().After(cutoff) {
+			return 0, false
+		}
+		// Notes and transcripts count only when they have content.
+		if (e.Name() == "notes.md" || e.Name() == "transcript.md") && info.Size() > 0 {
+			return 0, false
+		}
+		size += info.Size()
+	}
+	return size, true
+}
+
+func dirSize(dir string) int64 {
+	var size int64
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
@@ -0,0 +1,32 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"gotcha/internal/platform"
+)
+
+// lockTimeout bounds how long a process waits for another one to release
+// the session store.
+const lockTimeout = 10 * time.Second
+
+// withLock runs fn holding an exclusive advisory lock on the session store,
+// so that gotcha processes sharing a .gotcha directory do not interleave
+// their read-modify-write cycles on metadata.json. The lock is released
+// when fn returns or the process exits. Without file locks (neither unix
+// nor windows), ID allocation still cannot collide because each session
+// directory is claimed with an exclusive mkdir.
+func (m *Manager) withLock(fn func() error) error {
+	release, err := platform.Lock(filepath.Join(m.basePath, ".lock"), lockTimeout)
+	if errors.Is(err, platform.ErrLocked) {
+		return fmt.Errorf("session store %s is locked by another process", m.basePath)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock session store: %w", err)
+	}
+	defer release()
+	return fn()
+}
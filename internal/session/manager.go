@@ -1,13 +1,13 @@
 package session
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
-	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -20,15 +20,22 @@ type Metadata struct {
 
 // SessionInfo holds basic session information
 type SessionInfo struct {
-	ID        string    `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Title     string    `json:"title,omitempty"`
+	ID          string    `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Title       string    `json:"title,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Renamed     bool      `json:"renamed,omitempty"`  // title set by the user, not generated
+	Archived    bool      `json:"archived,omitempty"` // hidden from the default listing
 }
 
 // Context holds the complete conversation state for persistence
 type Context struct {
 	SessionID     string     `json:"session_id"`
+	Title         string     `json:"title,omitempty"`       // copy of the metadata entry's
+	Description   string     `json:"description,omitempty"` // copy of the metadata entry's
+	Renamed       bool       `json:"renamed,omitempty"`     // copy of the metadata entry's
+	Archived      bool       `json:"archived,omitempty"`    // copy of the metadata entry's
 	Conversations []ChatMsg  `json:"conversations"`
 	CreatedAt     time.Time  `json:"created_at"`
 	UpdatedAt     time.Time  `json:"updated_at"`
@@ -45,6 +52,11 @@ type ChatMsg struct {
 // Manager handles session creation, loading, and persistence
 type Manager struct {
 	basePath string
+
+	mu      sync.Mutex
+	repairs []Repair // problems fixed while loading, until Repairs is called
+
+	beforeRemove func(sessionID string) error // see OnRemove
 }
 
 // NewManager creates a new session manager
@@ -54,122 +66,137 @@ func NewManager() *Manager {
 	return &Manager{basePath: basePath}
 }
 
-// CreateNewSession creates a new session with sequential ID
+// CreateNewSession creates a new session with sequential ID. It is safe to
+// call from concurrent processes sharing the same .gotcha directory.
 func (m *Manager) CreateNewSession() (string, error) {
 	if err := m.ensureDirectories(); err != nil {
 		return "", err
 	}
 
-	metadata, err := m.loadMetadata()
-	if err != nil {
-		return "", err
-	}
-
-	// Generate next session ID
-	sessionID := m.generateNextSessionID(metadata.Sessions)
+	var sessionID string
+	err := m.withLock(func() error {
+		metadata, problem, err := m.readMetadata()
+		if err != nil {
+			return err
+		}
+		if problem != "" {
+			m.noteRebuild(problem, metadata)
+		}
 
-	// Create session directory
-	sessionDir := filepath.Join(m.basePath, "sessions", sessionID)
-	if err := os.MkdirAll(sessionDir, 0o755); err != nil {
-		return "", fmt.Errorf("failed to create session directory: %w", err)
-	}
+		// Generate next session ID and claim its directory
+		sessionID, err = m.allocateSessionID(metadata.Sessions)
+		if err != nil {
+			return err
+		}
 
-	// Create initial context
-	context := Context{
-		SessionID:     sessionID,
-		Conversations: []ChatMsg{},
-		CreatedAt:     time.Now(),
-		UpdatedAt:     time.Now(),
-		NoteCount:     0,
-	}
+		// Create initial context
+		now := time.Now()
+		context := Context{
+			SessionID:     sessionID,
+			Conversations: []ChatMsg{},
+			CreatedAt:     now,
+			UpdatedAt:     now,
+			NoteCount:     0,
+		}
 
-	if err := m.saveContext(sessionID, context); err != nil {
-		return "", err
-	}
+		if err := m.saveContext(sessionID, context); err != nil {
+			return err
+		}
 
-	// Update metadata
-	sessionInfo := SessionInfo{
-		ID:        sessionID,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-	}
+		// Update metadata
+		sessionInfo := SessionInfo{
+			ID:        sessionID,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
 
-	metadata.Sessions = append(metadata.Sessions, sessionInfo)
-	metadata.LastSessionID = sessionID
-	metadata.UpdatedAt = time.Now()
+		metadata.Sessions = append(metadata.Sessions, sessionInfo)
+		metadata.LastSessionID = sessionID
+		metadata.UpdatedAt = now
 
-	if err := m.saveMetadata(metadata); err != nil {
+		return m.saveMetadata(metadata)
+	})
+	if err != nil {
 		return "", err
 	}
 
 	return sessionID, nil
 }
 
-// LoadSession loads an existing session context
+// LoadSession loads an existing session context. A damaged context.json is
+// replaced by its last good backup, or by an empty conversation when there
+// is none; either way the repair is reported by Repairs.
 func (m *Manager) LoadSession(sessionID string) (Context, error) {
-	contextPath := filepath.Join(m.basePath, "sessions", sessionID, "context.json")
-
-	data, err := os.ReadFile(contextPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			// Create new context for existing session directory
-			return Context{
-				SessionID:     sessionID,
-				Conversations: []ChatMsg{},
-				CreatedAt:     time.Now(),
-				UpdatedAt:     time.Now(),
-				NoteCount:     0,
-			}, nil
-		}
+	contextPath := m.contextPath(sessionID)
+
+	context, err := readJSON[Context](m, contextPath)
+	switch {
+	case err == nil:
+		return context, nil
+	case os.IsNotExist(err):
+		// Create new context for existing session directory
+	case errors.Is(err, errCorrupt):
+		m.noteRepair(contextPath, "no usable backup", "started an empty conversation")
+	default:
 		return Context{}, fmt.Errorf("failed to read context: %w", err)
 	}
-
-	var context Context
-	if err := json.Unmarshal(data, &context); err != nil {
-		return Context{}, fmt.Errorf("failed to parse context: %w", err)
-	}
-
-	return context, nil
+	return Context{
+		SessionID:     sessionID,
+		Conversations: []ChatMsg{},
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+		NoteCount:     0,
+	}, nil
 }
 
-// SaveSession saves the current session context
+// SaveSession saves the current session context. Its title, description
+// and flags are taken from the metadata, where Rename, Describe and Archive
+// keep them. The search index catches up on the next Search.
 func (m *Manager) SaveSession(sessionID string, context Context) error {
 	context.UpdatedAt = time.Now()
+	if info, err := m.GetSession(sessionID); err == nil {
+		context.Title, context.Description = info.Title, info.Description
+		context.Renamed, context.Archived = info.Renamed, info.Archived
+	}
 	return m.saveContext(sessionID, context)
 }
 
-// GetLastSession returns the most recent session ID
+// GetLastSession returns the most recent session ID, skipping archived
+// sessions
 func (m *Manager) GetLastSession() (string, error) {
 	metadata, err := m.loadMetadata()
 	if err != nil {
 		return "", err
 	}
 
-	if metadata.LastSessionID == "" && len(metadata.Sessions) > 0 {
-		// Fallback to most recent session
-		sort.Slice(metadata.Sessions, func(i, j int) bool {
-			return metadata.Sessions[i].UpdatedAt.After(metadata.Sessions[j].UpdatedAt)
-		})
-		return metadata.Sessions[0].ID, nil
+	for _, s := range metadata.Sessions {
+		if s.ID == metadata.LastSessionID && !s.Archived {
+			return s.ID, nil
+		}
 	}
 
-	return metadata.LastSessionID, nil
+	// Fallback to most recent session
+	sessions, err := m.ListSessions()
+	if err != nil || len(sessions) == 0 {
+		return "", err
+	}
+	return sessions[0].ID, nil
 }
 
-// ListSessions returns all available sessions
+// ListSessions returns the sessions that are not archived, most recent
+// first; AllSessions includes archived ones.
 func (m *Manager) ListSessions() ([]SessionInfo, error) {
-	metadata, err := m.loadMetadata()
+	all, err := m.AllSessions()
 	if err != nil {
 		return nil, err
 	}
 
-	// Sort by most recent first
-	sessions := make([]SessionInfo, len(metadata.Sessions))
-	copy(sessions, metadata.Sessions)
-
-	sort.Slice(sessions, func(i, j int) bool {
-		return sessions[i].UpdatedAt.After(sessions[j].UpdatedAt)
-	})
+	sessions := make([]SessionInfo, 0, len(all))
+	for _, s := range all {
+		if !s.Archived {
+			sessions = append(sessions, s)
+		}
+	}
 
 	return sessions, nil
 }
@@ -181,43 +208,69 @@ func (m *Manager) ensureDirectories() error {
 	return os.MkdirAll(sessionsDir, 0o755)
 }
 
+// loadMetadata reads the session index, repairing it first if it is
+// damaged or missing while sessions exist.
 func (m *Manager) loadMetadata() (Metadata, error) {
-	metadataPath := filepath.Join(m.basePath, "metadata.json")
+	metadata, problem, err := m.readMetadata()
+	if err != nil || problem == "" {
+		return metadata, err
+	}
+	// Save the rebuilt index, unless another process got there first.
+	err = m.withLock(func() error {
+		current, again, err := m.readMetadata()
+		if err != nil || again == "" {
+			metadata = current
+			return err
+		}
+		m.noteRebuild(problem, metadata)
+		return m.saveMetadata(metadata)
+	})
+	return metadata, err
+}
 
-	// Return empty metadata if file doesn't exist
-	if _, err := os.Stat(metadataPath); os.IsNotExist(err) {
-		return Metadata{
-			Sessions:  []SessionInfo{},
-			UpdatedAt: time.Now(),
-		}, nil
+// readMetadata reads metadata.json, falling back to its backup and then to
+// rebuilding it from the session directories. A rebuild is not saved; its
+// reason is returned as problem.
+func (m *Manager) readMetadata() (metadata Metadata, problem string, err error) {
+	metadata, err = readJSON[Metadata](m, m.metadataPath())
+	switch {
+	case err == nil:
+		if metadata.Sessions == nil {
+			metadata.Sessions = []SessionInfo{}
+		}
+		return metadata, "", nil
+	case os.IsNotExist(err):
+		problem = "missing"
+	case errors.Is(err, errCorrupt):
+		problem = "damaged with no usable backup"
+	default:
+		return Metadata{}, "", fmt.Errorf("failed to read metadata: %w", err)
 	}
 
-	data, err := os.ReadFile(metadataPath)
+	metadata, err = m.rebuildMetadata()
 	if err != nil {
-		return Metadata{}, fmt.Errorf("failed to read metadata: %w", err)
+		return Metadata{}, "", err
 	}
-
-	var metadata Metadata
-	if err := json.Unmarshal(data, &metadata); err != nil {
-		return Metadata{}, fmt.Errorf("failed to parse metadata: %w", err)
+	if problem == "missing" && len(metadata.Sessions) == 0 {
+		// A new store, nothing to repair.
+		return metadata, "", nil
 	}
+	return metadata, problem, nil
+}
 
-	return metadata, nil
+func (m *Manager) noteRebuild(problem string, metadata Metadata) {
+	m.noteRepair(m.metadataPath(), problem, fmt.Sprintf("rebuilt from %d session directories", len(metadata.Sessions)))
 }
 
 func (m *Manager) saveMetadata(metadata Metadata) error {
-	metadataPath := filepath.Join(m.basePath, "metadata.json")
-
-	data, err := json.MarshalIndent(metadata, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal metadata: %w", err)
+	if err := writeJSON(m.metadataPath(), metadata); err != nil {
+		return fmt.Errorf("failed to save metadata: %w", err)
 	}
-
-	return os.WriteFile(metadataPath, data, 0o644)
+	return nil
 }
 
 func (m *Manager) saveContext(sessionID string, context Context) error {
-	contextPath := filepath.Join(m.basePath, "sessions", sessionID, "context.json")
+	contextPath := m.contextPath(sessionID)
 
 	// Ensure session directory exists
 	sessionDir := filepath.Dir(contextPath)
@@ -225,15 +278,43 @@ func (m *Manager) saveContext(sessionID string, context Context) error {
 		return fmt.Errorf("failed to create session directory: %w", err)
 	}
 
-	data, err := json.MarshalIndent(context, "", "  ")
+	if err := writeJSON(contextPath, context); err != nil {
+		return fmt.Errorf("failed to save context: %w", err)
+	}
+	return nil
+}
+
+// allocateSessionID picks the next sequential ID and claims it by creating
+// its directory. Directories without a metadata entry count as taken, and
+// an ID whose directory already exists is skipped, so two creators can
+// never be handed the same session.
+func (m *Manager) allocateSessionID(sessions []SessionInfo) (string, error) {
+	sessionsDir := filepath.Join(m.basePath, "sessions")
+	entries, err := os.ReadDir(sessionsDir)
 	if err != nil {
-		return fmt.Errorf("failed to marshal context: %w", err)
+		return "", fmt.Errorf("failed to list sessions: %w", err)
+	}
+	known := append([]SessionInfo(nil), sessions...)
+	for _, e := range entries {
+		if e.IsDir() {
+			known = append(known, SessionInfo{ID: e.Name()})
+		}
 	}
 
-	return os.WriteFile(contextPath, data, 0o644)
+	for n := maxSessionNumber(known) + 1; ; n++ {
+		sessionID := fmt.Sprintf("session-%d", n)
+		err := os.Mkdir(filepath.Join(sessionsDir, sessionID), 0o755)
+		if err == nil {
+			return sessionID, nil
+		}
+		if !os.IsExist(err) {
+			return "", fmt.Errorf("failed to create session directory: %w", err)
+		}
+	}
 }
 
-func (m *Manager) generateNextSessionID(sessions []SessionInfo) string {
+// maxSessionNumber returns the highest N among IDs of the form session-N.
+func maxSessionNumber(sessions []SessionInfo) int {
 	maxNum := 0
 
 	for _, session := range sessions {
@@ -245,7 +326,7 @@ func (m *Manager) generateNextSessionID(sessions []SessionInfo) string {
 		}
 	}
 
-	return fmt.Sprintf("session-%d", maxNum+1)
+	return maxNum
 }
 
 // SaveTranscript generates or appends to the session's transcript.md file
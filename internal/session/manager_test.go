@@ -0,0 +1,406 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// creatorEnv makes the test binary act as a separate gotcha process that
+// creates sessions in the given store.
+const creatorEnv = "GOTCHA_TEST_SESSION_CREATOR"
+
+func TestMain(m *testing.M) {
+	if base := os.Getenv(creatorEnv); base != "" {
+		mgr := &Manager{basePath: base}
+		for i := 0; i < 5; i++ {
+			id, err := mgr.CreateNewSession()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			fmt.Println(id)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestCreateNewSessionConcurrent(t *testing.T) {
+	base := t.TempDir()
+	const goroutines, processes, perProcess = 20, 8, 5
+
+	var (
+		mu  sync.Mutex
+		ids []string
+		wg  sync.WaitGroup
+	)
+	record := func(id string) {
+		mu.Lock()
+		ids = append(ids, id)
+		mu.Unlock()
+	}
+
+	// Creators in this process, each with its own manager.
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			id, err := (&Manager{basePath: base}).CreateNewSession()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			record(id)
+		}()
+	}
+
+	// Creators in other processes.
+	for i := 0; i < processes; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cmd := exec.Command(os.Args[0], "-test.run=^$")
+			cmd.Env = append(os.Environ(), creatorEnv+"="+base)
+			out, err := cmd.Output()
+			if err != nil {
+				t.Errorf("creator process: %v", err)
+				return
+			}
+			for _, id := range strings.Fields(string(out)) {
+				record(id)
+			}
+		}()
+	}
+	wg.Wait()
+
+	want := goroutines + processes*perProcess
+	if len(ids) != want {
+		t.Fatalf("created %d sessions, want %d", len(ids), want)
+	}
+	seen := map[string]bool{}
+	for _, id := range ids {
+		if seen[id] {
+			t.Errorf("session ID %s handed out twice", id)
+		}
+		seen[id] = true
+	}
+
+	// Every session made it into the metadata, numbered 1..want.
+	sessions, err := (&Manager{basePath: base}).ListSessions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != want {
+		t.Fatalf("metadata lists %d sessions, want %d", len(sessions), want)
+	}
+	for i := 1; i <= want; i++ {
+		id := fmt.Sprintf("session-%d", i)
+		if !seen[id] {
+			t.Errorf("missing %s", id)
+		}
+		if _, err := os.Stat(fmt.Sprintf("%s/sessions/%s/context.json", base, id)); err != nil {
+			t.Errorf("%s: %v", id, err)
+		}
+	}
+}
+
+func TestCreateNewSessionSkipsUnlistedDirectories(t *testing.T) {
+	base := t.TempDir()
+	// A directory left behind without a metadata entry must not be reused.
+	if err := os.MkdirAll(base+"/sessions/session-1", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	id, err := (&Manager{basePath: base}).CreateNewSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "session-2" {
+		t.Errorf("got %s, want session-2", id)
+	}
+}
+
+func TestLoadRecoversDamagedFiles(t *testing.T) {
+	base := t.TempDir()
+	m := &Manager{basePath: base}
+	id, err := m.CreateNewSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, _ := m.LoadSession(id)
+	ctx.Conversations = append(ctx.Conversations, ChatMsg{Role: "user", Text: "first"})
+	if err := m.SaveSession(id, ctx); err != nil {
+		t.Fatal(err)
+	}
+	ctx.Conversations = append(ctx.Conversations, ChatMsg{Role: "user", Text: "second"})
+	if err := m.SaveSession(id, ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// A crash mid-write truncated context.json: the backup has one message.
+	contextPath := m.contextPath(id)
+	if err := os.WriteFile(contextPath, []byte(`{"session_id": "ses`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := m.LoadSession(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Conversations) != 1 || got.Conversations[0].Text != "first" {
+		t.Errorf("recovered %v, want the backup's one message", got.Conversations)
+	}
+	if _, err := os.Stat(contextPath + ".corrupt"); err != nil {
+		t.Errorf("damaged copy not kept: %v", err)
+	}
+
+	// metadata.json and its backup are both gone: rebuild from the sessions.
+	os.Remove(m.metadataPath())
+	os.Remove(m.metadataPath() + ".bak")
+	if _, err := m.CreateNewSession(); err != nil {
+		t.Fatal(err)
+	}
+	sessions, err := m.ListSessions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 2 {
+		t.Errorf("rebuilt metadata lists %d sessions, want 2", len(sessions))
+	}
+
+	repairs := m.Repairs()
+	if len(repairs) != 2 {
+		t.Fatalf("got repairs %v, want the context restore and the metadata rebuild", repairs)
+	}
+	if !strings.Contains(repairs[0].Action, "backup") || !strings.Contains(repairs[1].Action, "rebuilt") {
+		t.Errorf("unexpected repairs %v", repairs)
+	}
+	if len(m.Repairs()) != 0 {
+		t.Error("Repairs did not clear the list")
+	}
+}
+
+func TestArchiveDeleteAndGC(t *testing.T) {
+	base := t.TempDir()
+	m := &Manager{basePath: base}
+	var ids []string
+	for i := 0; i < 3; i++ {
+		id, err := m.CreateNewSession()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	kept, archived, empty := ids[0], ids[1], ids[2]
+	ctx, _ := m.LoadSession(kept)
+	ctx.Conversations = append(ctx.Conversations, ChatMsg{Role: "user", Text: "hello"})
+	if err := m.SaveSession(kept, ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Archive(archived); err != nil {
+		t.Fatal(err)
+	}
+	listed, _ := m.ListSessions()
+	all, _ := m.AllSessions()
+	if len(listed) != 2 || len(all) != 3 {
+		t.Errorf("listed %d of %d sessions, want 2 of 3", len(listed), len(all))
+	}
+	if last, _ := m.GetLastSession(); last == archived {
+		t.Error("GetLastSession returned an archived session")
+	}
+
+	if err := m.Delete(archived); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(base, "sessions", archived)); !os.IsNotExist(err) {
+		t.Errorf("deleted session directory still there: %v", err)
+	}
+	if err := m.Delete(archived); err == nil {
+		t.Error("deleting a missing session succeeded")
+	}
+
+	// A stale temp file from an interrupted write.
+	tmp := filepath.Join(base, "metadata.json.123.tmp")
+	if err := os.WriteFile(tmp, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	report, err := m.GC(GCOptions{MinAge: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Sessions) != 1 || report.Sessions[0] != empty {
+		t.Errorf("GC removed sessions %v, want only %s", report.Sessions, empty)
+	}
+	if len(report.Files) != 1 {
+		t.Errorf("GC removed files %v, want the temp file", report.Files)
+	}
+	all, _ = m.AllSessions()
+	if len(all) != 1 || all[0].ID != kept {
+		t.Errorf("after GC sessions are %v, want only %s", all, kept)
+	}
+}
+
+func TestDeleteRejectsInvalidIDs(t *testing.T) {
+	base := t.TempDir()
+	m := &Manager{basePath: base}
+	id, err := m.CreateNewSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []string{"", ".", "..", "../" + id, id + "/..", `..\` + id, "sessions/" + id} {
+		if err := m.Delete(bad); err == nil {
+			t.Errorf("Delete(%q) succeeded", bad)
+		}
+	}
+	for _, dir := range []string{base, filepath.Join(base, "sessions"), filepath.Join(base, "sessions", id)} {
+		if _, err := os.Stat(dir); err != nil {
+			t.Errorf("%s is gone: %v", dir, err)
+		}
+	}
+}
+
+func TestOnRemoveRunsBeforeArchiveAndDelete(t *testing.T) {
+	m := &Manager{basePath: t.TempDir()}
+	id, err := m.CreateNewSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var removed []string
+	m.OnRemove(func(sessionID string) error {
+		removed = append(removed, sessionID)
+		if len(removed) == 1 {
+			return errors.New("busy")
+		}
+		return nil
+	})
+	if err := m.Archive(id); err == nil {
+		t.Error("Archive went ahead although OnRemove failed")
+	}
+	if info, _ := m.GetSession(id); info.Archived {
+		t.Error("session archived although OnRemove failed")
+	}
+	if err := m.Archive(id); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Delete(id); err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 3 {
+		t.Errorf("OnRemove ran for %v, want three calls", removed)
+	}
+}
+
+func TestArchiveSurvivesMetadataRebuild(t *testing.T) {
+	base := t.TempDir()
+	m := &Manager{basePath: base}
+	kept, err := m.CreateNewSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	archived, err := m.CreateNewSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Rename(archived, "Old notes"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Archive(archived); err != nil {
+		t.Fatal(err)
+	}
+	// A later save must not drop the flags from context.json.
+	ctx, _ := m.LoadSession(archived)
+	if err := m.SaveSession(archived, ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// metadata.json and its backup are both unreadable: rebuild from the sessions.
+	if err := os.WriteFile(m.metadataPath(), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(m.metadataPath() + ".bak")
+	listed, err := m.ListSessions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 1 || listed[0].ID != kept {
+		t.Errorf("listed %v after the rebuild, want only %s", listed, kept)
+	}
+	all, _ := m.AllSessions()
+	var got *SessionInfo
+	for i := range all {
+		if all[i].ID == archived {
+			got = &all[i]
+		}
+	}
+	if got == nil {
+		t.Fatalf("archived session missing from %v", all)
+	}
+	if !got.Archived || !got.Renamed || got.Title != "Old notes" {
+		t.Errorf("rebuilt entry %+v, want archived and renamed to %q", *got, "Old notes")
+	}
+}
+
+func TestSearchIndexesIncrementally(t *testing.T) {
+	base := t.TempDir()
+	m := &Manager{basePath: base}
+	vectors, _ := m.CreateNewSession()
+	other, _ := m.CreateNewSession()
+
+	ctx, _ := m.LoadSession(vectors)
+	ctx.Conversations = []ChatMsg{
+		{Role: "user", Text: "Compare vector databases for semantic search"},
+		{Role: "assistant", Text: "Milvus, Qdrant and pgvector are the usual vector database choices."},
+	}
+	if err := m.SaveSession(vectors, ctx); err != nil {
+		t.Fatal(err)
+	}
+	ctx, _ = m.LoadSession(other)
+	ctx.Conversations = []ChatMsg{{Role: "user", Text: "What is the best espresso grinder?"}}
+	if err := m.SaveSession(other, ctx); err != nil {
+		t.Fatal(err)
+	}
+	// Saves leave indexing to the next search.
+	if _, err := os.Stat(m.indexPath(vectors)); !os.IsNotExist(err) {
+		t.Errorf("SaveSession wrote the index: %v", err)
+	}
+
+	hits, err := m.Search("vector databases", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 2 || hits[0].SessionID != vectors || hits[0].Kind != KindMessage {
+		t.Fatalf("got hits %+v, want the two vector database messages", hits)
+	}
+
+	// A report written outside a save is picked up by the next search.
+	report := "# Grinders\n\n## Burr sets\n\nConical burrs suit espresso.\n"
+	if err := os.WriteFile(filepath.Join(base, "sessions", other, "report.md"), []byte(report), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	hits, _ = m.Search("conical burrs", 10)
+	if len(hits) != 1 || hits[0].SessionID != other || hits[0].Kind != KindReport || hits[0].Heading != "Burr sets" {
+		t.Fatalf("got hits %+v, want the report's burr section", hits)
+	}
+
+	// Editing a message replaces its postings.
+	ctx, _ = m.LoadSession(vectors)
+	ctx.Conversations[1].Text = "Try a managed service."
+	if err := m.SaveSession(vectors, ctx); err != nil {
+		t.Fatal(err)
+	}
+	hits, _ = m.Search("qdrant", 10)
+	if len(hits) != 0 {
+		t.Errorf("stale postings matched: %+v", hits)
+	}
+	hits, _ = m.Search("managed", 10)
+	if len(hits) != 1 || hits[0].Index != 1 || hits[0].Role != "assistant" {
+		t.Errorf("got hits %+v, want message 1", hits)
+	}
+}
@@ -0,0 +1,149 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gotcha/internal/platform"
+)
+
+// errCorrupt marks a JSON file that could not be parsed and had no usable
+// backup.
+var errCorrupt = errors.New("corrupt")
+
+// Repair records a problem found while loading the session store and what
+// was done about it.
+type Repair struct {
+	Path    string
+	Problem string
+	Action  string
+}
+
+func (r Repair) String() string {
+	return fmt.Sprintf("%s: %s; %s", r.Path, r.Problem, r.Action)
+}
+
+// Repairs returns the repairs made since the last call.
+func (m *Manager) Repairs() []Repair {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := m.repairs
+	m.repairs = nil
+	return out
+}
+
+func (m *Manager) noteRepair(path, problem, action string) {
+	if rel, err := filepath.Rel(m.basePath, path); err == nil {
+		path = filepath.Join(filepath.Base(m.basePath), rel)
+	}
+	r := Repair{Path: path, Problem: problem, Action: action}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, seen := range m.repairs {
+		if seen == r {
+			return
+		}
+	}
+	m.repairs = append(m.repairs, r)
+}
+
+// writeJSON saves v to path atomically. The version being replaced is kept
+// as path.bak first, as long as it is itself valid, so the backup is always
+// the last good copy.
+func writeJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if old, err := os.ReadFile(path); err == nil && json.Valid(old) {
+		if err := platform.WriteFileAtomic(path+".bak", old); err != nil {
+			return fmt.Errorf("failed to back up %s: %w", filepath.Base(path), err)
+		}
+	}
+	return platform.WriteFileAtomic(path, data)
+}
+
+// readJSON loads a T from path. A file that cannot be parsed (truncated by a
+// crash, say) is moved aside to path.corrupt and replaced by its backup when
+// the backup parses; otherwise readJSON returns an error wrapping
+// errCorrupt. A missing file yields an os.IsNotExist error.
+func readJSON[T any](m *Manager, path string) (T, error) {
+	var v T
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return v, err
+	}
+	perr := json.Unmarshal(data, &v)
+	if perr == nil {
+		return v, nil
+	}
+
+	problem := "unreadable JSON (" + perr.Error() + ")"
+	if len(data) == 0 {
+		problem = "empty file"
+	}
+	aside := path + ".corrupt"
+	var zero T
+	if err := os.Rename(path, aside); err != nil {
+		return zero, fmt.Errorf("%w: %s: %v", errCorrupt, path, perr)
+	}
+	backup, err := os.ReadFile(path + ".bak")
+	var restored T
+	if err == nil && json.Unmarshal(backup, &restored) == nil {
+		if err := platform.WriteFileAtomic(path, backup); err != nil {
+			return zero, err
+		}
+		m.noteRepair(path, problem, "restored the last good backup (damaged copy kept as "+filepath.Base(aside)+")")
+		return restored, nil
+	}
+	m.noteRepair(path, problem, "damaged copy kept as "+filepath.Base(aside))
+	return zero, fmt.Errorf("%w: %s: %v", errCorrupt, path, perr)
+}
+
+// rebuildMetadata reconstructs the session index from the session
+// directories, for when metadata.json is lost or damaged beyond its backup.
+// Titles, descriptions and times come from each context.json, or the times
+// from the directory when a session has none.
+func (m *Manager) rebuildMetadata() (Metadata, error) {
+	metadata := Metadata{Sessions: []SessionInfo{}, UpdatedAt: time.Now()}
+	entries, err := os.ReadDir(filepath.Join(m.basePath, "sessions"))
+	if err != nil && !os.IsNotExist(err) {
+		return Metadata{}, fmt.Errorf("failed to scan sessions: %w", err)
+	}
+	for _, e := range entries {
+		if !e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		info := SessionInfo{ID: e.Name()}
+		if context, err := readJSON[Context](m, m.contextPath(e.Name())); err == nil {
+			info.CreatedAt, info.UpdatedAt = context.CreatedAt, context.UpdatedAt
+			info.Title, info.Description = context.Title, context.Description
+			info.Renamed, info.Archived = context.Renamed, context.Archived
+		} else if fi, err := e.Info(); err == nil {
+			info.CreatedAt, info.UpdatedAt = fi.ModTime(), fi.ModTime()
+		}
+		metadata.Sessions = append(metadata.Sessions, info)
+	}
+	sort.Slice(metadata.Sessions, func(i, j int) bool {
+		return metadata.Sessions[i].CreatedAt.Before(metadata.Sessions[j].CreatedAt)
+	})
+	var latest time.Time
+	for _, s := range metadata.Sessions {
+		if s.UpdatedAt.After(latest) {
+			latest, metadata.LastSessionID = s.UpdatedAt, s.ID
+		}
+	}
+	return metadata, nil
+}
+
+func (m *Manager) metadataPath() string { return filepath.Join(m.basePath, "metadata.json") }
+
+func (m *Manager) contextPath(sessionID string) string {
+	return filepath.Join(m.basePath, "sessions", sessionID, "context.json")
+}
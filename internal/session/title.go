@@ -0,0 +1,264 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"gotcha/internal/llm"
+)
+
+// Title limits.
+const (
+	maxTitleWords       = 8
+	maxTitleLen         = 60
+	maxDescriptionLen   = 160
+	minSubstantiveWords = 3
+)
+
+// Rename sets a session's title. A renamed session is never retitled
+// automatically.
+func (m *Manager) Rename(sessionID, title string) error {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return errors.New("title cannot be empty")
+	}
+	_, err := m.updateSession(sessionID, func(s *SessionInfo) bool {
+		s.Title, s.Renamed = title, true
+		return true
+	})
+	return err
+}
+
+// Describe sets a session's description; an empty one clears it.
+func (m *Manager) Describe(sessionID, description string) error {
+	_, err := m.updateSession(sessionID, func(s *SessionInfo) bool {
+		s.Description = strings.TrimSpace(description)
+		return true
+	})
+	return err
+}
+
+// SetAutoTitle titles a session that has no title yet, and describes it if
+// it has no description. It reports whether the title was applied.
+func (m *Manager) SetAutoTitle(sessionID, title, description string) (bool, error) {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return false, nil
+	}
+	return m.updateSession(sessionID, func(s *SessionInfo) bool {
+		if s.Title != "" || s.Renamed {
+			return false
+		}
+		s.Title = title
+		if s.Description == "" {
+			s.Description = strings.TrimSpace(description)
+		}
+		return true
+	})
+}
+
+// GetSession returns a session's metadata entry.
+func (m *Manager) GetSession(sessionID string) (SessionInfo, error) {
+	metadata, err := m.loadMetadata()
+	if err != nil {
+		return SessionInfo{}, err
+	}
+	for _, s := range metadata.Sessions {
+		if s.ID == sessionID {
+			return s, nil
+		}
+	}
+	return SessionInfo{}, fmt.Errorf("session %s not found", sessionID)
+}
+
+// updateSession applies fn to a session's metadata entry under the store
+// lock and saves it when fn reports a change. The title, description and
+// flags are copied into the session's context.json so a rebuilt index keeps
+// them.
+func (m *Manager) updateSession(sessionID string, fn func(*SessionInfo) bool) (bool, error) {
+	changed := false
+	err := m.withLock(func() error {
+		metadata, problem, err := m.readMetadata()
+		if err != nil {
+			return err
+		}
+		if problem != "" {
+			m.noteRebuild(problem, metadata)
+		}
+		i := -1
+		for k := range metadata.Sessions {
+			if metadata.Sessions[k].ID == sessionID {
+				i = k
+			}
+		}
+		if i < 0 {
+			return fmt.Errorf("session %s not found", sessionID)
+		}
+		info := &metadata.Sessions[i]
+		if changed = fn(info); !changed {
+			return nil
+		}
+		info.UpdatedAt = time.Now()
+		metadata.UpdatedAt = info.UpdatedAt
+		if err := m.saveMetadata(metadata); err != nil {
+			return err
+		}
+		context, err := readJSON[Context](m, m.contextPath(sessionID))
+		if err != nil {
+			// Nothing to sync into yet; SaveSession copies it later.
+			return nil
+		}
+		context.Title, context.Description = info.Title, info.Description
+		context.Renamed, context.Archived = info.Renamed, info.Archived
+		return m.saveContext(sessionID, context)
+	})
+	return changed, err
+}
+
+// FirstExchange returns the first substantive user message, one of at least
+// a few words that is not a slash command, and the reply that followed it.
+// ok is false until there is both.
+func FirstExchange(msgs []ChatMsg) (exchange []ChatMsg, ok bool) {
+	for i, msg := range msgs {
+		text := strings.TrimSpace(msg.Text)
+		if msg.Role != "user" || strings.HasPrefix(text, "/") || len(strings.Fields(text)) < minSubstantiveWords {
+			continue
+		}
+		for _, reply := range msgs[i+1:] {
+			if reply.Role == "assistant" && strings.TrimSpace(reply.Text) != "" {
+				return []ChatMsg{msg, reply}, true
+			}
+		}
+		return nil, false
+	}
+	return nil, false
+}
+
+// SuggestTitle proposes a title and a one-sentence description for a session
+// from an exchange: the first substantive message and its reply, or just a
+// research prompt. The model is asked when client is set; without one, or
+// when it fails, a title is derived from the user's words.
+func SuggestTitle(ctx context.Context, client llm.Client, exchange []ChatMsg) (title, description string) {
+	title, description = HeuristicTitle(exchange)
+	if client == nil || len(exchange) == 0 {
+		return title, description
+	}
+	var b strings.Builder
+	for _, msg := range exchange {
+		fmt.Fprintf(&b, "%s: %s\n\n", msg.Role, truncateRunes(strings.TrimSpace(msg.Text), 1500))
+	}
+	sys := "You name research sessions. Return strict JSON: {\"title\": \"3-7 word title naming the topic, no quotes or trailing punctuation\", \"description\": \"one sentence on what the user wants to find out\"}. No extra text."
+	res, err := client.Complete(ctx, llm.Request{System: sys, Prompt: b.String() + "Return JSON only.", MaxTokens: 120, Temperature: 0.2}, nil)
+	if err != nil {
+		return title, description
+	}
+	raw := strings.TrimSpace(res.Text)
+	raw = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(raw, "```json"), "```"), "```")
+	var out struct {
+		Title       string `json:"title"`
+		Description string `json:"description"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(raw)), &out); err != nil || strings.TrimSpace(out.Title) == "" {
+		return title, description
+	}
+	title = truncateRunes(strings.Trim(strings.TrimSpace(out.Title), "\"'.!?"), maxTitleLen)
+	if d := strings.TrimSpace(out.Description); d != "" {
+		description = truncateRunes(d, maxDescriptionLen)
+	}
+	return title, description
+}
+
+// titleFillers are conversational openings dropped from heuristic titles.
+var titleFillers = []string{
+	"can you", "could you", "would you", "will you", "please", "help me", "i want to know", "i'd like to know",
+	"i would like to know", "i want to", "i need to", "i'd like to", "tell me about", "tell me", "explain",
+	"research", "find out", "look into", "what is", "what are", "how do i", "how do you", "how does", "how do",
+	"how to", "why is", "why are", "why do", "is there", "are there",
+}
+
+// HeuristicTitle derives a title from the first user message of an exchange:
+// its first sentence with conversational openings dropped, cut to a few
+// words. The description is the first sentence of the reply, or of the
+// message when there is no reply.
+func HeuristicTitle(exchange []ChatMsg) (title, description string) {
+	var question, reply string
+	for _, msg := range exchange {
+		switch {
+		case msg.Role == "user" && question == "":
+			question = strings.TrimSpace(msg.Text)
+		case msg.Role == "assistant" && reply == "":
+			reply = strings.TrimSpace(msg.Text)
+		}
+	}
+	first := firstSentence(question)
+	for trimmed := true; trimmed; {
+		trimmed = false
+		for _, f := range titleFillers {
+			// Compare in place: lowercasing can change the byte length of
+			// non-ASCII text, so offsets into a lowered copy are unsafe.
+			if len(first) > len(f) && first[len(f)] == ' ' && strings.EqualFold(first[:len(f)], f) {
+				first = strings.TrimSpace(first[len(f):])
+				trimmed = true
+			}
+		}
+	}
+	words := strings.Fields(strings.Trim(first, "\"'.!?,:; "))
+	if len(words) > maxTitleWords {
+		words = words[:maxTitleWords]
+	}
+	title = truncateRunes(strings.Join(words, " "), maxTitleLen)
+	if r, size := utf8.DecodeRuneInString(title); size > 0 {
+		title = string(unicode.ToUpper(r)) + title[size:]
+	}
+
+	description = firstSentence(stripMarkdown(reply))
+	if description == "" {
+		description = firstSentence(question)
+	}
+	return title, truncateRunes(description, maxDescriptionLen)
+}
+
+// firstSentence returns the text up to the first sentence end or line break.
+func firstSentence(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexAny(s, "\n"); i >= 0 {
+		s = s[:i]
+	}
+	cut := len(s)
+	for _, end := range []string{". ", "? ", "! "} {
+		if i := strings.Index(s, end); i >= 0 && i < cut {
+			cut = i + 1
+		}
+	}
+	return strings.TrimSpace(s[:cut])
+}
+
+// stripMarkdown drops headings, blank lines and leading list and quote
+// markers, so a reply's first sentence is prose.
+func stripMarkdown(s string) string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), ">*-+ "))
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	r := []rune(s)
+	return strings.TrimSpace(string(r[:n-1])) + "…"
+}
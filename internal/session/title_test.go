@@ -0,0 +1,125 @@
+package session
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestHeuristicTitle(t *testing.T) {
+	cases := []struct {
+		name, question, reply string
+		title, description    string
+	}{
+		{
+			name:        "filler chain",
+			question:    "Can you please tell me about the history of the Roman Empire? I need it for class.",
+			reply:       "## Overview\n\nThe Roman Empire lasted from 27 BC to AD 476. It spanned three continents.",
+			title:       "The history of the Roman Empire",
+			description: "The Roman Empire lasted from 27 BC to AD 476.",
+		},
+		{
+			name:        "fillers in any case",
+			question:    "COULD YOU explain how do vaccines train the immune system",
+			title:       "Vaccines train the immune system",
+			description: "COULD YOU explain how do vaccines train the immune system",
+		},
+		{
+			name:        "long question cut to a few words",
+			question:    "What are the main differences between the economic policies of the last five governments in the region",
+			title:       "The main differences between the economic policies of",
+			description: "What are the main differences between the economic policies of the last five governments in the region",
+		},
+		{
+			name:        "short message",
+			question:    "hi",
+			reply:       "Hello! How can I help?",
+			title:       "Hi",
+			description: "Hello!",
+		},
+		{
+			name:        "only a filler",
+			question:    "Please",
+			title:       "Please",
+			description: "Please",
+		},
+		{
+			name:        "non-ASCII",
+			question:    "über die deutsche Energiewende und ihre Kosten",
+			reply:       "Die Energiewende ist der Umstieg auf erneuerbare Energien.",
+			title:       "Über die deutsche Energiewende und ihre Kosten",
+			description: "Die Energiewende ist der Umstieg auf erneuerbare Energien.",
+		},
+		{
+			name:        "filler before non-ASCII text",
+			question:    "Tell me about İstanbul's ferries",
+			title:       "İstanbul's ferries",
+			description: "Tell me about İstanbul's ferries",
+		},
+		{
+			name:        "no user message",
+			reply:       "Hello.",
+			title:       "",
+			description: "Hello.",
+		},
+	}
+	for _, c := range cases {
+		var exchange []ChatMsg
+		if c.question != "" {
+			exchange = append(exchange, ChatMsg{Role: "user", Text: c.question})
+		}
+		if c.reply != "" {
+			exchange = append(exchange, ChatMsg{Role: "assistant", Text: c.reply})
+		}
+		title, description := HeuristicTitle(exchange)
+		if title != c.title || description != c.description {
+			t.Errorf("%s: got (%q, %q), want (%q, %q)", c.name, title, description, c.title, c.description)
+		}
+	}
+}
+
+func TestHeuristicTitleTruncatesRunes(t *testing.T) {
+	question := strings.Repeat("東京の地下鉄", 40)
+	title, description := HeuristicTitle([]ChatMsg{{Role: "user", Text: question}})
+	if n := utf8.RuneCountInString(title); n != maxTitleLen || !utf8.ValidString(title) || !strings.HasSuffix(title, "…") {
+		t.Errorf("title %q has %d runes, want %d valid runes ending in an ellipsis", title, n, maxTitleLen)
+	}
+	if n := utf8.RuneCountInString(description); n != maxDescriptionLen || !utf8.ValidString(description) {
+		t.Errorf("description has %d runes, want %d", n, maxDescriptionLen)
+	}
+}
+
+func TestFirstExchange(t *testing.T) {
+	question := ChatMsg{Role: "user", Text: "How do heat pumps work in cold climates?"}
+	reply := ChatMsg{Role: "assistant", Text: "They move heat rather than make it."}
+	cases := []struct {
+		name string
+		msgs []ChatMsg
+		ok   bool
+	}{
+		{"question and reply", []ChatMsg{question, reply}, true},
+		{"slash commands and short messages skipped", []ChatMsg{
+			{Role: "user", Text: "/research heat pumps in cold climates"},
+			{Role: "assistant", Text: "Research task started."},
+			{Role: "user", Text: "hi there"},
+			{Role: "assistant", Text: "Hello!"},
+			question,
+			{Role: "assistant", Text: "  "},
+			reply,
+		}, true},
+		{"no reply yet", []ChatMsg{question}, false},
+		{"later messages do not stand in for an unanswered one", []ChatMsg{question, {Role: "user", Text: "Also what about air conditioners?"}}, false},
+		{"only commands", []ChatMsg{{Role: "user", Text: "/notes"}, {Role: "assistant", Text: "No notes."}}, false},
+		{"empty", nil, false},
+	}
+	for _, c := range cases {
+		got, ok := FirstExchange(c.msgs)
+		if ok != c.ok {
+			t.Errorf("%s: ok = %v, want %v", c.name, ok, c.ok)
+			continue
+		}
+		if ok && (len(got) != 2 || got[0] != question || got[1] != reply) {
+			t.Errorf("%s: exchange %+v, want the question and its reply", c.name, got)
+		}
+	}
+}
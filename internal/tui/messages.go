@@ -1,5 +1,10 @@
 package tui
 
+import (
+	"gotcha/internal/agent"
+	"gotcha/internal/session"
+)
+
 // NewTaskMsg is emitted when a new research task has been created from input.
 type NewTaskMsg struct{ Title string }
 
@@ -14,3 +19,68 @@ type SaveSessionMsg struct{}
 
 // Transcript save message
 type SaveTranscriptMsg struct{}
+
+// TaskCommandMsg asks the root model to act on a research task.
+// Action is one of list|cancel|pause|resume; TaskID may be empty to target
+// the most recent matching task in the current session.
+type TaskCommandMsg struct{ Action, TaskID string }
+
+// ResearchCommandMsg asks the root model to start a research run on Prompt
+// in the current session; Compare selects comparison mode.
+type ResearchCommandMsg struct {
+	Prompt  string
+	Compare bool
+}
+
+// WeightsCommandMsg reweights the criteria of the session's latest
+// comparison; Args holds "criterion=n" pairs.
+type WeightsCommandMsg struct{ Args string }
+
+// CorpusCommandMsg points research at a local document directory; an empty
+// Dir shows the current corpus and "off" stops using it.
+type CorpusCommandMsg struct{ Dir string }
+
+// WatchCommandMsg lists, adds, runs or removes watches; Args is the text
+// after /watch.
+type WatchCommandMsg struct{ Args string }
+
+// WatchRunMsg reports a finished watch run, scheduled or on demand.
+type WatchRunMsg struct {
+	Watch     agent.Watch
+	Version   agent.WatchVersion
+	Err       error
+	Scheduled bool // run by the scheduler rather than /watch run
+}
+
+// RevisionsCommandMsg opens the browser of the session's report revisions.
+type RevisionsCommandMsg struct{}
+
+// TemplateCommandMsg picks the report template for later research runs; an
+// empty Name lists the templates and "off" stops using one.
+type TemplateCommandMsg struct{ Name string }
+
+// RenameCommandMsg titles the session; Args is the text after /rename, with
+// an optional description after a "|".
+type RenameCommandMsg struct{ Args string }
+
+// SessionTitleMsg carries a generated title and description for the session.
+type SessionTitleMsg struct {
+	Title       string
+	Description string
+}
+
+// SearchCommandMsg searches every session for Query.
+type SearchCommandMsg struct{ Query string }
+
+// SearchResultsMsg carries the matches of a search.
+type SearchResultsMsg struct {
+	Query string
+	Hits  []session.SearchHit
+	Err   error
+}
+
+// OpenReportMsg opens the session's report in the viewer pane.
+type OpenReportMsg struct{}
+
+// OutlineCommandMsg reopens the review of an outline awaiting approval.
+type OutlineCommandMsg struct{}
@@ -2,7 +2,10 @@ package tui
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/viewport"
@@ -20,27 +23,42 @@ import (
 type RootModel struct {
 	ctx context.Context
 
-	cfg platform.Config
-	bus agent.EventBus
-	app *app.Service
+	cfg        platform.Config
+	bus        agent.EventBus
+	eventLog   *agent.EventLog
+	app        *app.Service
+	tasks      *agent.TaskManager
+	researcher *agent.Researcher
+	watcher    *agent.Watcher
+	watchDone  chan WatchRunMsg // scheduled watch runs that finished
+	template   string           // report template for new research runs
 
 	// Session management
 	sessionID      string
 	sessionManager *session.Manager
 	sessionContext session.Context
+	llm            llm.Client
+	titled         bool // the session has a title, or one is being generated
 
 	focus int // 0=input,1=notes
 
-	welcome WelcomePane
-	input   InputPane
-	notes   NotesPane
-	status  StatusPane
+	welcome  WelcomePane
+	input    InputPane
+	notes    NotesPane
+	status   StatusPane
+	progress ProgressPane
+	viewer   ReportViewer
+	outline  OutlineEditor
+	clarify  ClarifyForm
+	history  RevisionBrowser
+	found    SearchResults
 
 	vp            viewport.Model
 	mouseEnabled  bool
 	selecting     bool
 	selectAt      time.Time
 	width, height int
+	events        <-chan agent.Event
 	cancelSub     func()
 }
 
@@ -54,54 +72,119 @@ func NewRootModel(ctx context.Context, cfg platform.Config) RootModel {
 }
 
 func NewRootModelWithSession(ctx context.Context, cfg platform.Config, sessionID string, sessionManager *session.Manager, sessionContext session.Context) RootModel {
-	bus := agent.NewMemoryBus(64)
+	eventLog := agent.NewEventLog(cfg.Paths)
+	bus := eventLog.Wrap(agent.NewMemoryBus(64))
 	// Storage
 	db, _ := storage.Open(cfg.Paths.DBPath())
 	_ = storage.Migrate(db)
 	service := app.NewService(db, cfg.Paths)
 
-	// Ensure session exists in app service
-	_, _ = service.CreateOrOpenSession(ctx, sessionID, "Session", "")
-
 	// LLM client
 	llmClient := llmClientFrom(cfg)
 
+	// Background research
+	tasks := agent.NewTaskManager(bus, cfg.Concurrency.Tasks)
+	// Stop a session's research before it is archived or deleted.
+	sessionManager.OnRemove(func(id string) error {
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		return tasks.CancelSession(ctx, id)
+	})
+
 	rm := RootModel{
 		ctx:            ctx,
 		cfg:            cfg,
 		bus:            bus,
+		eventLog:       eventLog,
 		app:            service,
-		sessionID:      sessionID,
+		tasks:          tasks,
+		researcher:     agent.NewResearcher(bus, llmClient, service, tasks, agent.NewResearchConfig(cfg)),
 		sessionManager: sessionManager,
-		sessionContext: sessionContext,
 		welcome:        NewWelcomePane(mustCwd()),
-		input:          NewInputPaneWithSessionAndLLM(bus, sessionID, llmClient),
-		notes:          NewNotesPaneWithSession(bus, service, sessionID),
-		status:         NewStatusPane(),
+		viewer:         NewReportViewer(),
+		outline:        NewOutlineEditor(),
+		clarify:        NewClarifyForm(),
+		found:          NewSearchResults(),
+	}
+
+	rm.history = NewRevisionBrowser(rm.researcher)
+	rm.llm = llmClient
+	rm.template = cfg.Research.Template
+	rm.openSession(sessionID, sessionContext)
+
+	// Re-run scheduled watches while the TUI is open
+	rm.watcher = agent.NewWatcher(rm.researcher)
+	rm.watchDone = make(chan WatchRunMsg, 8)
+	rm.watcher.Start(ctx, func(w agent.Watch, v agent.WatchVersion, err error) {
+		select {
+		case rm.watchDone <- WatchRunMsg{Watch: w, Version: v, Err: err, Scheduled: true}:
+		default:
+		}
+	})
+
+	rm.vp = viewport.New(0, 0)
+	rm.mouseEnabled = true
+	return rm
+}
+
+// openSession shows a session: its conversation, note count and task
+// status, with notices about repairs and interrupted runs, and subscribes
+// to its events.
+func (m *RootModel) openSession(sessionID string, sessionContext session.Context) {
+	// Ensure session exists in app service
+	_, _ = m.app.CreateOrOpenSession(m.ctx, sessionID, "Session", "")
+
+	m.sessionID, m.sessionContext = sessionID, sessionContext
+	m.input = NewInputPaneWithSessionAndLLM(m.bus, sessionID, m.llm)
+	m.notes = NewNotesPaneWithSession(m.bus, m.app, sessionID)
+	m.status = NewStatusPane()
+	m.progress = NewProgressPane()
+	m.titled = false
+	if info, err := m.sessionManager.GetSession(sessionID); err != nil || info.Title != "" {
+		m.titled = true
 	}
 
 	// Restore conversation context if exists
 	if len(sessionContext.Conversations) > 0 {
-		rm.input.RestoreConversation(sessionContext.Conversations)
+		m.input.RestoreConversation(sessionContext.Conversations)
 	}
 
 	// Set note counter
-	rm.notes.SetNoteCount(sessionContext.NoteCount)
+	m.notes.SetNoteCount(sessionContext.NoteCount)
+
+	// Rebuild task status from the session's event log
+	if events, err := m.eventLog.Read(sessionID); err == nil {
+		for _, e := range events {
+			e.Replayed = true
+			m.status, _ = m.status.Update(EventMsg{E: e})
+		}
+	}
+
+	// Report session files recovered from a backup or rebuilt
+	for _, r := range m.sessionManager.Repairs() {
+		m.input.AppendNotice("Repaired " + r.String())
+	}
+
+	// Offer to pick up research runs interrupted by a crash or failure
+	if runs, _ := m.researcher.IncompleteRuns(sessionID); len(runs) > 0 {
+		for _, j := range runs {
+			done, total := j.Progress()
+			m.input.AppendNotice(fmt.Sprintf("Research run %s (%q) stopped at %d/%d sections. Type /resume %s to continue from its checkpoint.",
+				j.RunID, j.Title(), done, total, j.RunID))
+		}
+	}
 
 	// load prompt.md if present
 	if b, err := os.ReadFile("prompt.md"); err == nil {
-		rm.input.SetSystemPrompt(string(b))
+		m.input.SetSystemPrompt(string(b))
 	}
-	rm.vp = viewport.New(0, 0)
-	rm.mouseEnabled = true
-	return rm
+	// One subscription per session; progress bursts are coalesced so a busy
+	// run cannot starve key handling.
+	m.events, m.cancelSub = m.bus.Subscribe(m.ctx, sessionID, agent.WithOverflow(agent.CoalesceProgress))
 }
 
 func llmClientFrom(cfg platform.Config) llm.Client {
-	if cfg.LLM.Provider == "openai" && cfg.LLM.APIKey != "" {
-		return llm.NewOpenAI(cfg.LLM.APIKey, cfg.LLM.BaseURL, cfg.LLM.Model, cfg.ProxyURL)
-	}
-	return nil
+	return llm.FromConfig(cfg.LLM, cfg.ProxyURL)
 }
 
 type EventMsg struct{ E agent.Event }
@@ -110,10 +193,49 @@ func (m RootModel) Init() tea.Cmd {
 	subCmd := (&m).subscribeCmd()
 	// Start with mouse enabled for page-level scrolling
 	enableMouse := func() tea.Msg { return tea.EnableMouseCellMotion() }
-	return tea.Batch(m.input.Init(), m.notes.Init(), subCmd, enableMouse)
+	return tea.Batch(m.input.Init(), m.notes.Init(), subCmd, m.waitWatchCmd(), enableMouse)
 }
 
 func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	// The outline editor, report viewer, revision browser and search results,
+	// while open, own keyboard and mouse input, as do clarifying questions.
+	if m.clarify.IsOpen() {
+		if _, ok := msg.(tea.KeyMsg); ok {
+			var cmd tea.Cmd
+			m.clarify, cmd = m.clarify.Update(msg)
+			return m, cmd
+		}
+	}
+	if m.outline.IsOpen() {
+		if _, ok := msg.(tea.KeyMsg); ok {
+			var cmd tea.Cmd
+			m.outline, cmd = m.outline.Update(msg)
+			return m, cmd
+		}
+	}
+	if m.viewer.IsOpen() {
+		switch msg.(type) {
+		case tea.KeyMsg, tea.MouseMsg:
+			var cmd tea.Cmd
+			m.viewer, cmd = m.viewer.Update(msg)
+			return m, cmd
+		}
+	}
+	if m.history.IsOpen() {
+		switch msg.(type) {
+		case tea.KeyMsg, tea.MouseMsg:
+			var cmd tea.Cmd
+			m.history, cmd = m.history.Update(msg)
+			return m, cmd
+		}
+	}
+	if m.found.IsOpen() {
+		if _, ok := msg.(tea.KeyMsg); ok {
+			var cmd tea.Cmd
+			m.found, cmd = m.found.Update(msg)
+			return m, cmd
+		}
+	}
 	// Let viewport process messages first (mouse wheel scrolling, etc.)
 	var cmds []tea.Cmd
 	var cmd tea.Cmd
@@ -161,12 +283,197 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.vp.Height < 1 {
 			m.vp.Height = 1
 		}
+		m.viewer.SetSize(m.width, m.height)
+		m.history.SetSize(m.width, m.height)
+		m.found.SetSize(m.width, m.height)
+		m.outline.SetWidth(m.width)
+		m.clarify.SetWidth(m.width)
 		m.updateViewportContent(wasBottom)
 	case EventMsg:
-		// We no longer show phase counts; ignore agent events for statusline.
+		if msg.E.SessionID != m.sessionID {
+			// Read from the subscription of a session switched away from
+			return m, nil
+		}
+		m.status, _ = m.status.Update(msg)
+		if e := msg.E; e.Phase == agent.PhaseOutline && e.Type == "approval" && !e.Replayed && !m.outline.IsOpen() {
+			if plan, ok := m.researcher.PendingOutline(e.TaskID); ok {
+				m.outline.Open(e.TaskID, plan)
+			}
+		}
+		if e := msg.E; e.Phase == agent.PhaseClarify && e.Type == "questions" && !e.Replayed && !m.clarify.IsOpen() {
+			if qs, ok := m.researcher.PendingQuestions(e.TaskID); ok {
+				m.clarify.Open(e.TaskID, qs)
+			}
+		}
+		// Progress events leave the conversation alone, so only a finished
+		// run's notice is worth a save; replayed events never finish one.
+		save := false
+		if final := m.progress.Observe(msg.E); final != "" {
+			save = true
+			if m.outline.IsOpen() && m.outline.TaskID() == msg.E.TaskID {
+				m.outline.Close()
+			}
+			if m.clarify.IsOpen() && m.clarify.TaskID() == msg.E.TaskID {
+				m.clarify.Close()
+			}
+			m.input.AppendNotice(m.researchFinished(msg.E.TaskID, final))
+			m.recalcLayout()
+			wasBottom = true
+		}
+		m.updateViewportContent(wasBottom)
+		if !save {
+			return m, (&m).subscribeCmd()
+		}
 		return m, tea.Batch((&m).subscribeCmd(), m.saveSessionCmd())
+	case ResearchCommandMsg:
+		m.input.AppendNotice(m.startResearch(msg.Prompt, msg.Compare))
+		m.recalcLayout()
+		m.updateViewportContent(true)
+		if !m.titled && len(strings.Fields(msg.Prompt)) > 0 {
+			// A research prompt is substantive enough to title the session.
+			m.titled = true
+			return m, m.titleCmd([]session.ChatMsg{{Role: "user", Text: msg.Prompt}})
+		}
+		return m, nil
+	case RenameCommandMsg:
+		m.input.AppendNotice(m.rename(msg.Args))
+		m.recalcLayout()
+		m.updateViewportContent(true)
+		return m, nil
+	case SessionTitleMsg:
+		if ok, err := m.sessionManager.SetAutoTitle(m.sessionID, msg.Title, msg.Description); err == nil && ok {
+			m.input.AppendNotice(fmt.Sprintf("Titled this session %q. /rename to change it.", msg.Title))
+			m.recalcLayout()
+			m.updateViewportContent(true)
+		}
+		return m, nil
+	case CorpusCommandMsg:
+		m.input.AppendNotice(m.setCorpus(msg.Dir))
+		m.recalcLayout()
+		m.updateViewportContent(true)
+		return m, nil
+	case WatchCommandMsg:
+		notice, cmd := m.watchCommand(msg.Args)
+		m.input.AppendNotice(notice)
+		m.recalcLayout()
+		m.updateViewportContent(true)
+		return m, cmd
+	case WatchRunMsg:
+		m.input.AppendNotice(watchRunNotice(msg))
+		m.recalcLayout()
+		m.updateViewportContent(true)
+		if msg.Scheduled {
+			return m, m.waitWatchCmd()
+		}
+		return m, nil
+	case TemplateCommandMsg:
+		m.input.AppendNotice(m.setTemplate(msg.Name))
+		m.recalcLayout()
+		m.updateViewportContent(true)
+		return m, nil
+	case WeightsCommandMsg:
+		m.input.AppendNotice(m.reweight(msg.Args))
+		m.recalcLayout()
+		m.updateViewportContent(true)
+		return m, nil
+	case OutlineCommandMsg:
+		if !m.openPendingOutline() {
+			m.input.AppendNotice("No research run is waiting for answers or outline approval.")
+			m.recalcLayout()
+			m.updateViewportContent(true)
+		}
+		return m, nil
+	case ClarifyAnswersMsg:
+		answered := 0
+		for _, a := range msg.Answers {
+			if strings.TrimSpace(a) != "" {
+				answered++
+			}
+		}
+		if err := m.researcher.AnswerQuestions(msg.TaskID, msg.Answers); err != nil {
+			m.input.AppendNotice(fmt.Sprintf("Could not pass the answers on: %v", err))
+		} else {
+			m.input.AppendNotice(fmt.Sprintf("Thanks — %d of %d questions answered; planning %s with them. A re-run of the same prompt in this session reuses them.", answered, len(msg.Answers), msg.TaskID))
+		}
+		m.recalcLayout()
+		m.updateViewportContent(true)
+		return m, nil
+	case OutlineDecisionMsg:
+		m.input.AppendNotice(m.decideOutline(msg))
+		m.recalcLayout()
+		m.updateViewportContent(true)
+		return m, nil
+	case SearchCommandMsg:
+		if strings.TrimSpace(msg.Query) == "" {
+			m.input.AppendNotice("Usage: /search <terms> searches every session's conversation, notes and report.")
+			m.recalcLayout()
+			m.updateViewportContent(true)
+			return m, nil
+		}
+		manager := m.sessionManager
+		return m, func() tea.Msg {
+			hits, err := manager.Search(msg.Query, maxSearchHits)
+			return SearchResultsMsg{Query: msg.Query, Hits: hits, Err: err}
+		}
+	case SearchResultsMsg:
+		switch {
+		case msg.Err != nil:
+			m.input.AppendNotice(fmt.Sprintf("Search failed: %v", msg.Err))
+		case len(msg.Hits) == 0:
+			m.input.AppendNotice(fmt.Sprintf("No matches for %q in any session.", msg.Query))
+		default:
+			m.found.Open(msg.Query, msg.Hits)
+			return m, nil
+		}
+		m.recalcLayout()
+		m.updateViewportContent(true)
+		return m, nil
+	case SearchJumpMsg:
+		notice, cmd := m.jump(msg.Hit)
+		m.input.AppendNotice(notice)
+		m.recalcLayout()
+		m.updateViewportContent(true)
+		m.scrollToMarked()
+		return m, cmd
+	case NoteSaveResultMsg:
+		// Keep the note count and get the note into the search index
+		cmds = append(cmds, m.saveSessionCmd())
+	case OpenReportMsg:
+		if err := m.viewer.Open(m.app.ReportPath(m.sessionID)); err != nil {
+			m.input.AppendNotice("No report in this session yet. Use /research <prompt> to write one.")
+			m.recalcLayout()
+			m.updateViewportContent(true)
+		}
+		return m, nil
+	case RevisionsCommandMsg:
+		if err := m.history.Open(m.sessionID); err != nil {
+			m.input.AppendNotice("No report revisions in this session yet. Every report /research writes is kept as one.")
+			m.recalcLayout()
+			m.updateViewportContent(true)
+		}
+		return m, nil
+	case RevisionRestoreMsg:
+		if rev, err := m.researcher.RestoreRevision(m.sessionID, msg.N); err != nil {
+			m.input.AppendNotice(fmt.Sprintf("Could not restore revision %d: %v", msg.N, err))
+		} else {
+			m.input.AppendNotice(fmt.Sprintf("Restored revision %d as the report (saved as revision %d). /report to view it.", msg.N, rev.N))
+		}
+		m.recalcLayout()
+		m.updateViewportContent(true)
+		return m, nil
+	case TaskCommandMsg:
+		m.input.AppendNotice(m.runTaskCommand(msg))
+		m.recalcLayout()
+		m.updateViewportContent(true)
+		return m, m.saveSessionCmd()
 	case SessionSaveMsg:
-		// Session has been saved successfully - no action needed
+		// Title the session once it has a substantive exchange
+		if !m.titled {
+			if exchange, ok := session.FirstExchange(m.sessionConversations()); ok {
+				m.titled = true
+				return m, m.titleCmd(exchange)
+			}
+		}
 		return m, nil
 	case ChatDoneMsg:
 		// Let InputPane handle the message first to stop blinking
@@ -207,17 +514,30 @@ func (m RootModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 
-func (m RootModel) View() string { return m.vp.View() }
+func (m RootModel) View() string {
+	if m.clarify.IsOpen() {
+		return m.clarify.View()
+	}
+	if m.outline.IsOpen() {
+		return m.outline.View()
+	}
+	if m.viewer.IsOpen() {
+		return m.viewer.View()
+	}
+	if m.history.IsOpen() {
+		return m.history.View()
+	}
+	if m.found.IsOpen() {
+		return m.found.View()
+	}
+	return m.vp.View()
+}
 
 // Helpers
 func now() time.Time { return time.Now() }
 
 func (m *RootModel) subscribeCmd() tea.Cmd {
-	ch, cancel := m.bus.Subscribe(m.ctx, "")
-	// store cancel once
-	if m.cancelSub == nil {
-		m.cancelSub = cancel
-	}
+	ch := m.events
 	return func() tea.Msg {
 		e, ok := <-ch
 		if !ok {
@@ -227,6 +547,325 @@ func (m *RootModel) subscribeCmd() tea.Cmd {
 	}
 }
 
+// startResearch starts a research run for the current session and returns
+// the text to show.
+func (m *RootModel) startResearch(prompt string, compare bool) string {
+	prompt = strings.TrimSpace(prompt)
+	opts := m.researcher.DefaultOptions()
+	if compare {
+		if prompt == "" {
+			return "Usage: /compare <option> vs <option> [for <use case>]"
+		}
+		opts.Mode = agent.ModeCompare
+	}
+	if prompt == "" {
+		return "Usage: /research <prompt>"
+	}
+	opts.Template = m.template
+	id := m.researcher.Start(m.sessionID, prompt, opts)
+	title := prompt
+	if info, ok := m.tasks.Get(id); ok {
+		title = info.Title
+	}
+	m.progress.Track(id, title)
+	return fmt.Sprintf("Research task %s started. The report opens here when it is done; /tasks, /pause and /cancel control it.", id)
+}
+
+// setTemplate picks the report template for later research runs in this
+// session; no name lists the templates and "off" stops using one.
+func (m *RootModel) setTemplate(name string) string {
+	switch name {
+	case "":
+		all, err := m.researcher.Templates()
+		var b strings.Builder
+		b.WriteString("Report templates (/template <name> to use one, /template off for none):")
+		for _, t := range all {
+			marker := "  "
+			if t.Name == m.template {
+				marker = "› "
+			}
+			fmt.Fprintf(&b, "\n%s%s: %s", marker, t.Name, t.Description)
+		}
+		if err != nil {
+			fmt.Fprintf(&b, "\nSkipped templates that failed to load:\n%v", err)
+		}
+		return b.String()
+	case "off", "none":
+		m.template = ""
+		return "Research runs no longer use a report template."
+	}
+	t, err := m.researcher.Template(name)
+	if err != nil {
+		return err.Error()
+	}
+	m.template = t.Name
+	return fmt.Sprintf("Research runs now use the %s template: %s.", t.Name, t.Description)
+}
+
+// setCorpus switches the local document corpus used by later research runs.
+func (m *RootModel) setCorpus(dir string) string {
+	switch dir {
+	case "":
+		if c, ok := m.researcher.Corpus(); ok {
+			return fmt.Sprintf("Research searches the local corpus in %s. /corpus off stops it.", c.Root())
+		}
+		return "Usage: /corpus <dir> to search local Markdown, text, HTML and PDF files; /corpus off to stop."
+	case "off":
+		m.researcher.SetCorpus(context.Background(), "")
+		return "Research no longer searches a local corpus."
+	}
+	files, passages, err := m.researcher.SetCorpus(context.Background(), dir)
+	if err != nil {
+		return "Cannot use corpus: " + err.Error()
+	}
+	return fmt.Sprintf("Indexed %d files (%d passages) in %s. Research runs now search them.", files, passages, dir)
+}
+
+// waitWatchCmd delivers the next scheduled watch run that finishes.
+func (m RootModel) waitWatchCmd() tea.Cmd {
+	ch := m.watchDone
+	return func() tea.Msg { return <-ch }
+}
+
+// watchCommand handles /watch: no arguments lists the watches, "add [cron |]
+// <prompt>" saves one that writes into this session, "run <id>" runs one now
+// and "rm <id>" deletes one.
+func (m *RootModel) watchCommand(args string) (string, tea.Cmd) {
+	verb, rest, _ := strings.Cut(strings.TrimSpace(args), " ")
+	rest = strings.TrimSpace(rest)
+	switch verb {
+	case "", "list":
+		all, err := m.watcher.List()
+		if err != nil {
+			return "Cannot list watches: " + err.Error(), nil
+		}
+		if len(all) == 0 {
+			return "No watches. /watch add [cron |] <prompt> saves one.", nil
+		}
+		var b strings.Builder
+		b.WriteString("Watches:")
+		for _, w := range all {
+			sched := "on demand"
+			if next := w.Next(); !next.IsZero() {
+				sched = fmt.Sprintf("%s, next %s", w.Schedule, next.Format("Jan 2 15:04"))
+			}
+			fmt.Fprintf(&b, "\n  %s (%s, %d versions): %s", w.ID, sched, len(w.Versions), w.Prompt)
+			if k := len(w.Versions); k > 0 && w.Versions[k-1].Summary != "" {
+				fmt.Fprintf(&b, "\n    last change: %s", w.Versions[k-1].Summary)
+			}
+			if w.Failures > 0 {
+				fmt.Fprintf(&b, "\n    %d failed run(s) since the last success; retrying with backoff", w.Failures)
+			}
+		}
+		return b.String(), nil
+	case "add":
+		sched, prompt := "", rest
+		if before, after, ok := strings.Cut(rest, "|"); ok {
+			sched, prompt = strings.TrimSpace(before), strings.TrimSpace(after)
+		}
+		opts := m.researcher.DefaultOptions()
+		opts.Template = m.template
+		w, err := m.watcher.Add("", m.sessionID, prompt, sched, opts)
+		if err != nil {
+			return "Cannot add watch: " + err.Error(), nil
+		}
+		if next := w.Next(); !next.IsZero() {
+			return fmt.Sprintf("Watch %s saved; next run %s. /watch run %s runs it now.", w.ID, next.Format("Mon Jan 2 15:04"), w.ID), nil
+		}
+		return fmt.Sprintf("Watch %s saved. /watch run %s runs it.", w.ID, w.ID), nil
+	case "run":
+		w, err := m.watcher.Get(rest)
+		if err != nil {
+			return err.Error(), nil
+		}
+		watcher, ctx := m.watcher, m.ctx
+		return fmt.Sprintf("Running watch %s.", w.ID), func() tea.Msg {
+			v, err := watcher.Run(ctx, w.ID)
+			return WatchRunMsg{Watch: w, Version: v, Err: err}
+		}
+	case "rm", "remove":
+		if err := m.watcher.Remove(rest); err != nil {
+			return err.Error(), nil
+		}
+		return fmt.Sprintf("Watch %s removed.", rest), nil
+	}
+	return "Usage: /watch, /watch add [cron |] <prompt>, /watch run <id>, /watch rm <id>", nil
+}
+
+func watchRunNotice(msg WatchRunMsg) string {
+	if msg.Err != nil {
+		return fmt.Sprintf("Watch %s failed: %v", msg.Watch.ID, msg.Err)
+	}
+	v := msg.Version
+	if v.Changes == "" {
+		return fmt.Sprintf("Watch %s saved its first version to %s.", msg.Watch.ID, v.Report)
+	}
+	return fmt.Sprintf("Watch %s v%d: %s. What changed: %s", msg.Watch.ID, v.N, v.Summary, v.Changes)
+}
+
+// reweight applies criterion weights to the session's latest comparison and
+// reports the new weighted scores.
+func (m *RootModel) reweight(args string) string {
+	j, ok := m.researcher.LatestComparison(m.sessionID)
+	if !ok {
+		return "No comparison in this session. Use /compare <option> vs <option> to start one."
+	}
+	weights, err := agent.ParseWeights(args)
+	if err != nil {
+		return "Usage: /weights criterion=n [criterion=n ...]: " + err.Error()
+	}
+	if j, err = m.researcher.Reweight(m.sessionID, j.RunID, weights); err != nil {
+		return "Cannot reweight: " + err.Error()
+	}
+	var b strings.Builder
+	b.WriteString("Weights:")
+	for _, c := range j.Comparison.Criteria {
+		fmt.Fprintf(&b, " %s=%g", c.Name, c.Weight)
+	}
+	var scores []string
+	totals := j.Comparison.Totals()
+	for i, o := range j.Comparison.Options {
+		if totals[i] >= 0 {
+			scores = append(scores, fmt.Sprintf("%s %.2f", o, totals[i]))
+		}
+	}
+	b.WriteString("\nWeighted scores: " + strings.Join(scores, ", "))
+	if j.Status == agent.RunComplete {
+		b.WriteString("\nReport and comparison.csv updated; /report to view.")
+	}
+	return b.String()
+}
+
+// openPendingOutline opens the clarifying questions or the outline editor of
+// the oldest run of the session waiting for the user.
+func (m *RootModel) openPendingOutline() bool {
+	for _, id := range m.researcher.PendingClarifications(m.sessionID) {
+		if qs, ok := m.researcher.PendingQuestions(id); ok {
+			m.clarify.Open(id, qs)
+			return true
+		}
+	}
+	for _, id := range m.researcher.PendingOutlines(m.sessionID) {
+		if plan, ok := m.researcher.PendingOutline(id); ok {
+			m.outline.Open(id, plan)
+			return true
+		}
+	}
+	return false
+}
+
+// decideOutline applies the user's outline decision and returns the text to show.
+func (m *RootModel) decideOutline(d OutlineDecisionMsg) string {
+	if !d.Approve {
+		if err := m.tasks.Cancel(d.TaskID); err != nil {
+			return fmt.Sprintf("Could not cancel: %v", err)
+		}
+		return fmt.Sprintf("Research task %s cancelled at outline review.", d.TaskID)
+	}
+	if err := m.researcher.ApproveOutline(d.TaskID, d.Plan); err != nil {
+		return fmt.Sprintf("Could not approve outline: %v", err)
+	}
+	return fmt.Sprintf("Outline approved (%d sections); composing %q.", len(d.Plan.Sections), d.Plan.Title)
+}
+
+// researchFinished opens the report of a successful run and returns the text
+// to show for the run's outcome.
+func (m *RootModel) researchFinished(taskID string, state agent.TaskState) string {
+	switch state {
+	case agent.TaskDone:
+		path := m.app.ReportPath(m.sessionID)
+		if err := m.viewer.Open(path); err != nil {
+			return fmt.Sprintf("Research task %s finished but its report could not be opened: %v", taskID, err)
+		}
+		return fmt.Sprintf("Research task %s finished. Report written to %s (/report to view it again).", taskID, path)
+	case agent.TaskFailed:
+		msg := fmt.Sprintf("Research task %s failed.", taskID)
+		if info, ok := m.tasks.Get(taskID); ok && info.Err != "" {
+			msg = fmt.Sprintf("Research task %s failed: %s", taskID, info.Err)
+		}
+		return msg + fmt.Sprintf(" Type /resume %s to retry from its checkpoint.", taskID)
+	default:
+		return fmt.Sprintf("Research task %s %s.", taskID, state)
+	}
+}
+
+// runTaskCommand applies a task control command and returns the text to show.
+func (m *RootModel) runTaskCommand(msg TaskCommandMsg) string {
+	tasks := m.tasks.List(m.sessionID)
+	if msg.Action == "list" {
+		if len(tasks) == 0 {
+			return "No research tasks in this session."
+		}
+		var b strings.Builder
+		for _, t := range tasks {
+			fmt.Fprintf(&b, "%s  [%s]  %s", t.ID, t.State, t.Title)
+			if t.Err != "" {
+				fmt.Fprintf(&b, " (%s)", t.Err)
+			}
+			b.WriteString("\n")
+		}
+		return b.String()
+	}
+
+	id := msg.TaskID
+	if id == "" {
+		// Default to the newest task the action applies to
+		for i := len(tasks) - 1; i >= 0 && id == ""; i-- {
+			switch st := tasks[i].State; msg.Action {
+			case "pause":
+				if st == agent.TaskQueued || st == agent.TaskRunning {
+					id = tasks[i].ID
+				}
+			case "resume":
+				if st == agent.TaskPaused {
+					id = tasks[i].ID
+				}
+			case "cancel":
+				if !st.Terminal() {
+					id = tasks[i].ID
+				}
+			}
+		}
+		if id == "" && msg.Action == "resume" {
+			// Fall back to the newest run left incomplete by a crash or failure
+			if runs, _ := m.researcher.IncompleteRuns(m.sessionID); len(runs) > 0 {
+				id = runs[len(runs)-1].RunID
+			}
+		}
+		if id == "" {
+			return fmt.Sprintf("No task to %s.", msg.Action)
+		}
+	}
+
+	if msg.Action == "resume" {
+		if info, ok := m.tasks.Get(id); !ok || info.State.Terminal() {
+			if _, err := m.researcher.ResumeRun(m.sessionID, id); err != nil {
+				return fmt.Sprintf("Could not resume: %v", err)
+			}
+			title := id
+			if info, ok := m.tasks.Get(id); ok {
+				title = info.Title
+			}
+			m.progress.Track(id, title)
+			return fmt.Sprintf("Run %s resumed from its last checkpoint.", id)
+		}
+	}
+
+	var err error
+	switch msg.Action {
+	case "pause":
+		err = m.tasks.Pause(id)
+	case "resume":
+		err = m.tasks.Resume(id)
+	case "cancel":
+		err = m.tasks.Cancel(id)
+	}
+	if err != nil {
+		return fmt.Sprintf("Could not %s: %v", msg.Action, err)
+	}
+	return fmt.Sprintf("Task %s: %s requested.", id, msg.Action)
+}
+
 func mustCwd() string {
 	if wd, err := os.Getwd(); err == nil {
 		return wd
@@ -268,6 +907,9 @@ func (m *RootModel) updateViewportContent(stickBottom bool) {
 	}
 	welcome := m.welcome.View()
 	status := m.status.View()
+	if progress := m.progress.View(); progress != "" {
+		status = lipgloss.JoinVertical(lipgloss.Left, status, progress)
+	}
 	transcript := m.input.TranscriptViewWithWidth(paneW)
 	indicator := m.input.IndicatorView()
 	inputV := ResearchBorder.Render(m.input.View())
@@ -313,16 +955,25 @@ func (m RootModel) autoReenableMouseCmd(d time.Duration) tea.Cmd {
 	return tea.Tick(d, func(time.Time) tea.Msg { return autoMouseMsg{} })
 }
 
+// sessionConversations converts the transcript for persistence. Notices are
+// command output for this run of the TUI only and are not saved.
+func (m *RootModel) sessionConversations() []session.ChatMsg {
+	conv := m.input.GetConversation()
+	conversations := make([]session.ChatMsg, 0, len(conv))
+	for _, conv := range conv {
+		if conv.Role == "notice" {
+			continue
+		}
+		conversations = append(conversations, session.ChatMsg{Role: conv.Role, Text: conv.Text})
+	}
+	return conversations
+}
+
 // saveSessionCmd periodically saves the session context
 func (m *RootModel) saveSessionCmd() tea.Cmd {
 	return func() tea.Msg {
 		// Update session context with current conversation and note count
-		conversations := make([]session.ChatMsg, len(m.input.GetConversation()))
-		for i, conv := range m.input.GetConversation() {
-			conversations[i] = session.ChatMsg{Role: conv.Role, Text: conv.Text}
-		}
-
-		m.sessionContext.Conversations = conversations
+		m.sessionContext.Conversations = m.sessionConversations()
 		m.sessionContext.NoteCount = m.notes.GetNoteCount()
 
 		// Save session context
@@ -334,16 +985,141 @@ func (m *RootModel) saveSessionCmd() tea.Cmd {
 	}
 }
 
+// jump shows a search hit, opening its session first when it is not the
+// current one, and returns the notice to show with the command that reads
+// the new session's events.
+func (m *RootModel) jump(hit session.SearchHit) (string, tea.Cmd) {
+	var cmd tea.Cmd
+	if hit.SessionID != m.sessionID {
+		if err := m.switchSession(hit.SessionID); err != nil {
+			return fmt.Sprintf("Cannot open %s: %v", hit.SessionID, err), nil
+		}
+		cmd = m.subscribeCmd()
+	}
+	where := m.sessionID
+	if hit.Title != "" {
+		where = fmt.Sprintf("%s (%q)", m.sessionID, hit.Title)
+	}
+	switch hit.Kind {
+	case session.KindMessage:
+		if !m.input.MarkMessage(hit.Index) {
+			return fmt.Sprintf("In %s; message %d is no longer in the conversation.", where, hit.Index+1), cmd
+		}
+		return fmt.Sprintf("In %s; the matching message is marked with ›.", where), cmd
+	case session.KindReport:
+		if err := m.viewer.OpenAt(m.app.ReportPath(m.sessionID), hit.Heading); err != nil {
+			return fmt.Sprintf("In %s; its report is gone.", where), cmd
+		}
+		return fmt.Sprintf("In %s; showing the report section that matched.", where), cmd
+	case session.KindNote:
+		return fmt.Sprintf("In %s; %s: %s", where, hit.Location(), hit.Snippet), cmd
+	}
+	return fmt.Sprintf("In %s.", where), cmd
+}
+
+// switchSession saves the current session and opens sessionID in its place.
+// Research runs and chat replies belong to the session they started in, so
+// switching waits until the current session has none in progress.
+func (m *RootModel) switchSession(sessionID string) error {
+	if m.input.streaming {
+		return errors.New("a reply is still streaming here")
+	}
+	for _, t := range m.tasks.List(m.sessionID) {
+		if !t.State.Terminal() {
+			return fmt.Errorf("research task %s is still %s here; wait for it or /cancel it first", t.ID, t.State)
+		}
+	}
+	sessionContext, err := m.sessionManager.LoadSession(sessionID)
+	if err != nil {
+		return err
+	}
+	m.sessionContext.Conversations = m.sessionConversations()
+	m.sessionContext.NoteCount = m.notes.GetNoteCount()
+	if err := m.sessionManager.SaveSession(m.sessionID, m.sessionContext); err != nil {
+		return fmt.Errorf("saving %s: %w", m.sessionID, err)
+	}
+	m.cancelSub()
+	m.outline.Close()
+	m.clarify.Close()
+	m.viewer.Close()
+	m.history.Close()
+	m.openSession(sessionID, sessionContext)
+	return nil
+}
+
+// scrollToMarked scrolls the page so a message marked by a search jump is
+// at the top.
+func (m *RootModel) scrollToMarked() {
+	if m.input.marked < 0 {
+		return
+	}
+	paneW := m.width - 2
+	if paneW < 1 {
+		paneW = m.width - 1
+	}
+	status := m.status.View()
+	if progress := m.progress.View(); progress != "" {
+		status = lipgloss.JoinVertical(lipgloss.Left, status, progress)
+	}
+	m.vp.SetYOffset(lipgloss.Height(m.welcome.View()) + lipgloss.Height(status) + m.input.MarkedLine(paneW))
+}
+
+// titleCmd generates a title and description for the session from an
+// exchange, with the model when one is configured.
+func (m *RootModel) titleCmd(exchange []session.ChatMsg) tea.Cmd {
+	ctx, client := m.ctx, m.llm
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+		title, description := session.SuggestTitle(ctx, client, exchange)
+		return SessionTitleMsg{Title: title, Description: description}
+	}
+}
+
+// rename handles /rename <title> [| <description>].
+func (m *RootModel) rename(args string) string {
+	title, description, described := strings.Cut(args, "|")
+	title = strings.TrimSpace(title)
+	if title == "" && !described {
+		info, err := m.sessionManager.GetSession(m.sessionID)
+		if err != nil {
+			return "Cannot read the session: " + err.Error()
+		}
+		out := "Usage: /rename <title> [| <description>]"
+		if info.Title != "" {
+			out = fmt.Sprintf("This session is %q.", info.Title)
+			if info.Description != "" {
+				out += " " + info.Description
+			}
+			out += "\nUse /rename <title> [| <description>] to change it."
+		}
+		return out
+	}
+	if title != "" {
+		if err := m.sessionManager.Rename(m.sessionID, title); err != nil {
+			return "Cannot rename the session: " + err.Error()
+		}
+		m.titled = true
+	}
+	if described {
+		if err := m.sessionManager.Describe(m.sessionID, description); err != nil {
+			return "Cannot describe the session: " + err.Error()
+		}
+	}
+	switch {
+	case title == "" && strings.TrimSpace(description) == "":
+		return "Cleared the session description."
+	case title == "":
+		return "Described the session: " + strings.TrimSpace(description)
+	}
+	return fmt.Sprintf("Renamed the session to %q.", title)
+}
+
 // saveTranscriptCmd saves the conversation to a transcript.md file
 func (m *RootModel) saveTranscriptCmd() tea.Cmd {
 	return func() tea.Msg {
 		// Update session context with current conversation
-		conversations := make([]session.ChatMsg, len(m.input.GetConversation()))
-		for i, conv := range m.input.GetConversation() {
-			conversations[i] = session.ChatMsg{Role: conv.Role, Text: conv.Text}
-		}
-
-		m.sessionContext.Conversations = conversations
+		m.sessionContext.Conversations = m.sessionConversations()
 		m.sessionContext.NoteCount = m.notes.GetNoteCount()
 
 		// Save transcript
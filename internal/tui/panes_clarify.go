@@ -0,0 +1,115 @@
+package tui
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// ClarifyForm asks the clarifying questions a research run is waiting on,
+// one at a time. While open it takes all key input.
+type ClarifyForm struct {
+	open      bool
+	taskID    string
+	questions []string
+	answers   []string
+	cur       int
+	input     textinput.Model
+	width     int
+}
+
+// ClarifyAnswersMsg carries the answers to a run's clarifying questions;
+// empty answers mean no preference.
+type ClarifyAnswersMsg struct {
+	TaskID  string
+	Answers []string
+}
+
+func NewClarifyForm() ClarifyForm {
+	in := textinput.New()
+	in.Prompt = "> "
+	in.CharLimit = 0
+	return ClarifyForm{input: in}
+}
+
+func (f ClarifyForm) IsOpen() bool    { return f.open }
+func (f ClarifyForm) TaskID() string  { return f.taskID }
+func (f *ClarifyForm) SetWidth(w int) { f.width = w; f.input.Width = max(w-4, 10) }
+func (f *ClarifyForm) Close()         { f.open = false; f.input.Blur() }
+
+// Open starts asking questions for taskID.
+func (f *ClarifyForm) Open(taskID string, questions []string) {
+	f.open, f.taskID, f.questions = true, taskID, questions
+	f.answers, f.cur = make([]string, len(questions)), 0
+	f.input.SetValue("")
+	f.input.Focus()
+}
+
+func (f ClarifyForm) Update(msg tea.Msg) (ClarifyForm, tea.Cmd) {
+	km, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return f, nil
+	}
+	switch km.String() {
+	case "enter", "tab":
+		f.answers[f.cur] = f.input.Value()
+		if f.cur == len(f.questions)-1 && km.String() == "enter" {
+			return f.submit()
+		}
+		f.move(min(f.cur+1, len(f.questions)-1))
+		return f, nil
+	case "shift+tab", "up":
+		f.answers[f.cur] = f.input.Value()
+		f.move(max(f.cur-1, 0))
+		return f, nil
+	case "down":
+		f.answers[f.cur] = f.input.Value()
+		f.move(min(f.cur+1, len(f.questions)-1))
+		return f, nil
+	case "ctrl+s":
+		f.answers[f.cur] = f.input.Value()
+		return f.submit()
+	case "esc":
+		// Skip: plan without the answers not yet given.
+		return f.submit()
+	}
+	var cmd tea.Cmd
+	f.input, cmd = f.input.Update(km)
+	return f, cmd
+}
+
+func (f *ClarifyForm) move(to int) {
+	f.cur = to
+	f.input.SetValue(f.answers[to])
+	f.input.CursorEnd()
+}
+
+func (f ClarifyForm) submit() (ClarifyForm, tea.Cmd) {
+	f.Close()
+	d := ClarifyAnswersMsg{TaskID: f.taskID, Answers: append([]string(nil), f.answers...)}
+	return f, func() tea.Msg { return d }
+}
+
+func (f ClarifyForm) View() string {
+	wrap := lipgloss.NewStyle().Width(max(f.width-6, 20))
+	lines := []string{
+		PrimaryBold.Render("Clarify the research prompt") + Gray.Render("  "+f.taskID),
+		Gray.Render("Answers shape the outline; leave one empty for no preference."),
+		"",
+	}
+	for i, q := range f.questions {
+		label := fmt.Sprintf("%d. %s", i+1, q)
+		if i == f.cur {
+			lines = append(lines, CommandIndicator.Render("› ")+wrap.Inherit(CommandIndicator.Bold(true)).Render(label), "  "+f.input.View())
+			continue
+		}
+		lines = append(lines, "  "+wrap.Inherit(Text).Render(label))
+		if f.answers[i] != "" {
+			lines = append(lines, "    "+wrap.Inherit(Gray).Render(f.answers[i]))
+		}
+	}
+	lines = append(lines, "", Gray.Render("enter next / submit on the last · tab/shift+tab move · ctrl+s submit · esc skip the rest"))
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
@@ -27,7 +27,8 @@ type InputPane struct {
 	errText   string
 
 	// inline transcript above the textarea
-	convo []chatMsg
+	convo  []chatMsg
+	marked int // convo index of a message found by /search, -1 for none
 	// streaming state
 	streamCh     chan string
 	streamErrCh  chan error
@@ -81,7 +82,7 @@ func NewInputPane(bus agent.EventBus) InputPane { // deprecated constructor kept
 	ta.FocusedStyle = f
 	ta.BlurredStyle = b
 	ta.Focus()
-	return InputPane{ta: ta, bus: bus}
+	return InputPane{ta: ta, bus: bus, marked: -1}
 }
 
 func NewInputPaneWithSession(bus agent.EventBus, sessionID string) InputPane {
@@ -283,15 +284,8 @@ func (p InputPane) Update(msg tea.Msg) (InputPane, tea.Cmd) {
 			// Check for slash commands immediately
 			if strings.HasPrefix(query, "/") {
 				p.ta.SetValue("") // Clear input
-
-				if query == "/model" {
-					return p, p.handleModelCommand()
-				}
-				if query == "/save" {
-					return p, p.handleSaveCommand()
-				}
-				if query == "/quit" {
-					return p, tea.Quit
+				if cmd, ok := p.handleSlashCommand(query); ok {
+					return p, cmd
 				}
 			}
 
@@ -463,8 +457,13 @@ type ModelOption struct {
 }
 
 func (p *InputPane) appendUser(t string) {
+	p.marked = -1
 	p.convo = append(p.convo, chatMsg{Role: "user", Text: strings.TrimSpace(t)})
 }
+// AppendNotice adds a short status line (command output) to the transcript.
+func (p *InputPane) AppendNotice(t string) {
+	p.convo = append(p.convo, chatMsg{Role: "notice", Text: strings.TrimRight(t, "\n")})
+}
 func (p *InputPane) appendAssistant(t string) {
 	p.convo = append(p.convo, chatMsg{Role: "assistant", Text: t})
 	p.assistantIdx = len(p.convo) - 1
@@ -483,6 +482,81 @@ func (p *InputPane) getCommands() []Command {
 			Description: "Save current session",
 			Handler:     nil, // handled in handleCommandKeys
 		},
+		{
+			Name:        "/rename",
+			Description: "Title the session; add | <description> to describe it",
+			Handler:     nil, // handled in handleCommandKeys
+		},
+		{
+			Name:        "/search",
+			Description: "Search every session's conversation, notes and report",
+			Handler:     nil, // handled in handleCommandKeys
+		},
+		{
+			Name:        "/research",
+			Description: "Research a topic and write the session report",
+			Handler:     nil, // handled in handleCommandKeys
+		},
+		{
+			Name:        "/compare",
+			Description: "Compare options in a weighted decision matrix",
+			Handler:     nil, // handled in handleCommandKeys
+		},
+		{
+			Name:        "/weights",
+			Description: "Reweight comparison criteria, e.g. cost=2",
+			Handler:     nil, // handled in handleCommandKeys
+		},
+		{
+			Name:        "/template",
+			Description: "Pick a report template for research runs (off for none)",
+			Handler:     nil, // handled in handleCommandKeys
+		},
+		{
+			Name:        "/corpus",
+			Description: "Search a local directory of documents (off to stop)",
+			Handler:     nil, // handled in handleCommandKeys
+		},
+		{
+			Name:        "/watch",
+			Description: "List watches; add [cron |] <prompt>, run <id> or rm <id>",
+			Handler:     nil, // handled in handleCommandKeys
+		},
+		{
+			Name:        "/outline",
+			Description: "Answer clarifying questions or review an outline awaiting approval",
+			Handler:     nil, // handled in handleCommandKeys
+		},
+		{
+			Name:        "/report",
+			Description: "View the session's report.md",
+			Handler:     nil, // handled in handleCommandKeys
+		},
+		{
+			Name:        "/revisions",
+			Description: "Browse, compare and restore earlier versions of the report",
+			Handler:     nil, // handled in handleCommandKeys
+		},
+		{
+			Name:        "/tasks",
+			Description: "List research tasks in this session",
+			Handler:     nil, // handled in handleCommandKeys
+		},
+		{
+			Name:        "/pause",
+			Description: "Pause a research task (latest if no ID)",
+			Handler:     nil, // handled in handleCommandKeys
+		},
+		{
+			Name:        "/resume",
+			Description: "Resume a paused task or an interrupted run",
+			Handler:     nil, // handled in handleCommandKeys
+		},
+		{
+			Name:        "/cancel",
+			Description: "Cancel a research task",
+			Handler:     nil, // handled in handleCommandKeys
+		},
 		{
 			Name:        "/quit",
 			Description: "Exit the program",
@@ -491,6 +565,58 @@ func (p *InputPane) getCommands() []Command {
 	}
 }
 
+// handleSlashCommand runs a typed command line such as "/pause task-1".
+// It reports false when the line is not a known command.
+func (p *InputPane) handleSlashCommand(line string) (tea.Cmd, bool) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil, false
+	}
+	arg := strings.TrimSpace(strings.TrimPrefix(line, fields[0]))
+	switch fields[0] {
+	case "/model":
+		return p.handleModelCommand(), true
+	case "/save":
+		return p.handleSaveCommand(), true
+	case "/quit":
+		return tea.Quit, true
+	case "/rename":
+		return func() tea.Msg { return RenameCommandMsg{Args: arg} }, true
+	case "/search":
+		return func() tea.Msg { return SearchCommandMsg{Query: arg} }, true
+	case "/research":
+		return func() tea.Msg { return ResearchCommandMsg{Prompt: arg} }, true
+	case "/compare":
+		return func() tea.Msg { return ResearchCommandMsg{Prompt: arg, Compare: true} }, true
+	case "/corpus":
+		return func() tea.Msg { return CorpusCommandMsg{Dir: arg} }, true
+	case "/template":
+		return func() tea.Msg { return TemplateCommandMsg{Name: arg} }, true
+	case "/weights":
+		return func() tea.Msg { return WeightsCommandMsg{Args: arg} }, true
+	case "/watch":
+		return func() tea.Msg { return WatchCommandMsg{Args: arg} }, true
+	case "/outline":
+		return func() tea.Msg { return OutlineCommandMsg{} }, true
+	case "/report":
+		return func() tea.Msg { return OpenReportMsg{} }, true
+	case "/revisions":
+		return func() tea.Msg { return RevisionsCommandMsg{} }, true
+	case "/tasks":
+		return p.handleTaskCommand("list", ""), true
+	case "/pause", "/resume", "/cancel":
+		return p.handleTaskCommand(strings.TrimPrefix(fields[0], "/"), arg), true
+	}
+	return nil, false
+}
+
+// Handle /tasks, /pause, /resume and /cancel
+func (p *InputPane) handleTaskCommand(action, taskID string) tea.Cmd {
+	return func() tea.Msg {
+		return TaskCommandMsg{Action: action, TaskID: taskID}
+	}
+}
+
 // Initialize model options
 func (p *InputPane) getModelOptions() []ModelOption {
 	return []ModelOption{
@@ -572,22 +698,20 @@ func (p *InputPane) handleCommandKeys(msg tea.KeyMsg) (*InputPane, tea.Cmd) {
 			}
 			return p, nil
 		} else if p.showCommands {
+			// A command typed with arguments runs as written
+			if line := p.ta.Value(); len(strings.Fields(line)) > 1 {
+				p.showCommands = false
+				p.ta.SetValue("")
+				cmd, _ := p.handleSlashCommand(line)
+				return p, cmd
+			}
 			// Execute command
 			commands := p.getFilteredCommands()
 			if p.selectedCmd < len(commands) {
-				cmd := commands[p.selectedCmd]
-				switch cmd.Name {
-				case "/model":
-					p.showCommands = false
-					p.ta.SetValue("")
-					return p, p.handleModelCommand()
-				case "/save":
-					p.showCommands = false
-					p.ta.SetValue("")
-					return p, p.handleSaveCommand()
-				case "/quit":
-					return p, tea.Quit
-				}
+				p.showCommands = false
+				p.ta.SetValue("")
+				cmd, _ := p.handleSlashCommand(commands[p.selectedCmd].Name)
+				return p, cmd
 			}
 			return p, nil
 		}
@@ -715,10 +839,13 @@ func (p InputPane) TranscriptViewWithWidth(width int) string {
 		return ""
 	}
 	rows := make([]string, 0, len(p.convo)*2)
-	for _, m := range p.convo {
+	for i, m := range p.convo {
 		switch m.Role {
 		case "user":
 			prefix := Gray.Render("> ")
+			if i == p.marked {
+				prefix = CommandIndicator.Render("› ")
+			}
 			contentW := width - lipgloss.Width(prefix)
 			if contentW < 4 {
 				contentW = width
@@ -731,6 +858,9 @@ func (p InputPane) TranscriptViewWithWidth(width int) string {
 		case "assistant":
 			dot := "⏺"
 			prefix := White.Render(dot + " ")
+			if i == p.marked {
+				prefix = CommandIndicator.Render("› ")
+			}
 			contentW := width - lipgloss.Width(prefix)
 			if contentW < 4 {
 				contentW = width
@@ -754,6 +884,17 @@ func (p InputPane) TranscriptViewWithWidth(width int) string {
 				lipgloss.NewStyle().Width(contentW).Render(label+body),
 			)
 			rows = append(rows, row)
+		case "notice":
+			prefix := CommandIndicator.Render("⏺ ")
+			contentW := width - lipgloss.Width(prefix)
+			if contentW < 4 {
+				contentW = width
+			}
+			row := lipgloss.JoinHorizontal(lipgloss.Top,
+				prefix,
+				lipgloss.NewStyle().Width(contentW).Render(Gray.Render(m.Text)),
+			)
+			rows = append(rows, row)
 		case "reason":
 			// Thinking header with static dot
 			dot := "⏺"
@@ -956,12 +1097,41 @@ func repeatSpaces(n int) []rune {
 
 // RestoreConversation restores a previous conversation from session data
 func (p *InputPane) RestoreConversation(conversations []session.ChatMsg) {
-	p.convo = make([]chatMsg, len(conversations))
+	p.convo, p.marked = make([]chatMsg, len(conversations)), -1
 	for i, conv := range conversations {
 		p.convo[i] = chatMsg{Role: conv.Role, Text: conv.Text}
 	}
 }
 
+// MarkMessage marks the message at index i of the saved conversation, which
+// leaves out notices, and reports whether there is one.
+func (p *InputPane) MarkMessage(i int) bool {
+	p.marked = -1
+	n := 0
+	for k, m := range p.convo {
+		if m.Role == "notice" {
+			continue
+		}
+		if n == i {
+			p.marked = k
+			return true
+		}
+		n++
+	}
+	return false
+}
+
+// MarkedLine returns the line of the transcript, rendered at width, where
+// the marked message starts.
+func (p InputPane) MarkedLine(width int) int {
+	if p.marked <= 0 {
+		return 0
+	}
+	before := p
+	before.convo = p.convo[:p.marked]
+	return lipgloss.Height(before.TranscriptViewWithWidth(width)) + 1 // blank row between messages
+}
+
 // GetConversation returns the current conversation for session saving
 func (p *InputPane) GetConversation() []chatMsg {
 	return p.convo
@@ -0,0 +1,219 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"gotcha/internal/agent"
+)
+
+// OutlineEditor lets the user review a research run's proposed outline
+// before it is composed: edit the title, headings and instructions, reorder,
+// add or delete sections, then approve or cancel the run. While open it takes
+// all key input.
+type OutlineEditor struct {
+	open   bool
+	taskID string
+	plan   agent.Plan
+	sel    int
+	mode   string // "" | title | heading | instructions | add
+	input  textinput.Model
+	errMsg string
+	width  int
+}
+
+// OutlineDecisionMsg carries the user's decision on an outline.
+type OutlineDecisionMsg struct {
+	TaskID  string
+	Plan    agent.Plan
+	Approve bool // false cancels the run
+}
+
+func NewOutlineEditor() OutlineEditor {
+	in := textinput.New()
+	in.Prompt = "> "
+	in.CharLimit = 0
+	return OutlineEditor{input: in}
+}
+
+func (o OutlineEditor) IsOpen() bool    { return o.open }
+func (o OutlineEditor) TaskID() string  { return o.taskID }
+func (o *OutlineEditor) SetWidth(w int) { o.width = w; o.input.Width = max(w-4, 10) }
+func (o *OutlineEditor) Close()         { o.open = false; o.mode = "" }
+
+// Open starts reviewing plan for taskID.
+func (o *OutlineEditor) Open(taskID string, plan agent.Plan) {
+	o.open, o.taskID, o.plan = true, taskID, plan
+	o.sel, o.mode, o.errMsg = 0, "", ""
+}
+
+func (o OutlineEditor) Update(msg tea.Msg) (OutlineEditor, tea.Cmd) {
+	km, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return o, nil
+	}
+	if o.mode != "" {
+		return o.updateEditing(km)
+	}
+	o.errMsg = ""
+	n := len(o.plan.Sections)
+	switch km.String() {
+	case "up", "k":
+		if o.sel > 0 {
+			o.sel--
+		}
+	case "down", "j":
+		if o.sel < n-1 {
+			o.sel++
+		}
+	case "shift+up", "K":
+		if o.sel > 0 {
+			o.plan.MoveSection(o.sel, o.sel-1)
+			o.sel--
+		}
+	case "shift+down", "J":
+		if o.sel < n-1 {
+			o.plan.MoveSection(o.sel, o.sel+1)
+			o.sel++
+		}
+	case "enter", "e":
+		if n > 0 {
+			o.edit("heading", o.plan.Sections[o.sel].Heading)
+		}
+	case "i":
+		if n > 0 {
+			o.edit("instructions", o.plan.Sections[o.sel].Instructions)
+		}
+	case "s":
+		if n > 0 {
+			o.edit("queries", strings.Join(o.plan.Sections[o.sel].Queries, "; "))
+		}
+	case "t":
+		o.edit("title", o.plan.Title)
+	case "a":
+		o.edit("add", "")
+	case "d", "delete":
+		if n > 0 {
+			o.plan.DeleteSection(o.sel)
+			if o.sel >= len(o.plan.Sections) && o.sel > 0 {
+				o.sel--
+			}
+		}
+	case "y":
+		if err := o.plan.Validate(); err != nil {
+			o.errMsg = err.Error()
+			return o, nil
+		}
+		o.open = false
+		d := OutlineDecisionMsg{TaskID: o.taskID, Plan: o.plan.Clone(), Approve: true}
+		return o, func() tea.Msg { return d }
+	case "x":
+		o.open = false
+		d := OutlineDecisionMsg{TaskID: o.taskID}
+		return o, func() tea.Msg { return d }
+	case "esc", "q":
+		// The run keeps waiting; /outline reopens the review.
+		o.open = false
+	}
+	return o, nil
+}
+
+func (o *OutlineEditor) edit(mode, value string) {
+	o.mode = mode
+	o.input.SetValue(value)
+	o.input.CursorEnd()
+	o.input.Focus()
+}
+
+func (o OutlineEditor) updateEditing(km tea.KeyMsg) (OutlineEditor, tea.Cmd) {
+	switch km.String() {
+	case "esc":
+		o.mode = ""
+		o.input.Blur()
+		return o, nil
+	case "enter":
+		v := strings.TrimSpace(o.input.Value())
+		switch o.mode {
+		case "title":
+			o.plan.Title = v
+		case "heading":
+			o.plan.Sections[o.sel].Heading = v
+		case "instructions":
+			o.plan.Sections[o.sel].Instructions = v
+		case "queries":
+			o.plan.Sections[o.sel].Queries = strings.Split(v, ";")
+		case "add":
+			if v != "" {
+				at := o.sel + 1
+				if len(o.plan.Sections) == 0 {
+					at = 0
+				}
+				o.plan.InsertSection(at, agent.Section{Heading: v})
+				o.sel = at
+			}
+		}
+		o.mode = ""
+		o.input.Blur()
+		return o, nil
+	}
+	var cmd tea.Cmd
+	o.input, cmd = o.input.Update(km)
+	return o, cmd
+}
+
+func (o OutlineEditor) View() string {
+	wrap := lipgloss.NewStyle().Width(max(o.width-6, 20))
+	lines := []string{
+		PrimaryBold.Render("Review outline") + Gray.Render("  "+o.taskID),
+		"",
+		Strong.Render(o.plan.Title),
+		"",
+	}
+	for i, s := range o.plan.Sections {
+		marker := "  "
+		heading := Text.Render(fmt.Sprintf("%d. %s", i+1, s.Heading))
+		if i == o.sel {
+			marker = CommandIndicator.Render("› ")
+			heading = CommandIndicator.Bold(true).Render(fmt.Sprintf("%d. %s", i+1, s.Heading))
+		}
+		lines = append(lines, marker+heading)
+		if s.Instructions != "" {
+			lines = append(lines, "     "+wrap.Inherit(Gray).Render(s.Instructions))
+		}
+		var search []string
+		if len(s.Queries) > 0 {
+			search = append(search, "search: "+strings.Join(s.Queries, "; "))
+		}
+		if len(s.SourceTypes) > 0 {
+			search = append(search, "prefers "+strings.Join(s.SourceTypes, ", "))
+		}
+		if s.Recency != "" {
+			search = append(search, s.Recency)
+		}
+		if len(search) > 0 {
+			lines = append(lines, "     "+wrap.Inherit(Accent).Render(strings.Join(search, " · ")))
+		}
+	}
+	if len(o.plan.Queries) > 0 {
+		lines = append(lines, "", Gray.Render("  search for all sections: "+strings.Join(o.plan.Queries, "; ")))
+	}
+	if len(o.plan.Sections) == 0 {
+		lines = append(lines, Gray.Render("  (no sections — press a to add one)"))
+	}
+	lines = append(lines, "")
+	if o.mode != "" {
+		label := map[string]string{"title": "Title", "heading": "Heading", "instructions": "Instructions", "queries": "Search queries, separated by ;", "add": "New section heading"}[o.mode]
+		lines = append(lines, Text.Render(label+" (enter to save, esc to cancel)"), o.input.View())
+	} else {
+		lines = append(lines, Gray.Render("↑/↓ select · shift+↑/↓ move · e heading · i instructions · s queries · t title · a add · d delete"))
+		lines = append(lines, Gray.Render("y approve and compose · x cancel run · esc review later (/outline)"))
+	}
+	if o.errMsg != "" {
+		lines = append(lines, lipgloss.NewStyle().Foreground(lipgloss.Color("#E06C75")).Render(o.errMsg))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
@@ -0,0 +1,158 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"gotcha/internal/agent"
+)
+
+// ProgressPane shows the live phase progress of research runs started or
+// resumed in this TUI. A run disappears once its task finishes.
+type ProgressPane struct {
+	runs []runProgress
+}
+
+type runProgress struct {
+	taskID string
+	title  string
+	state  agent.TaskState
+	phases []phaseProgress // in order of first appearance
+	budget string
+}
+
+type phaseProgress struct {
+	phase       agent.Phase
+	state       string // started|progress|done|error
+	done, total int
+	detail      string
+}
+
+func NewProgressPane() ProgressPane { return ProgressPane{} }
+
+// Track starts following a task's events.
+func (p *ProgressPane) Track(taskID, title string) {
+	for _, r := range p.runs {
+		if r.taskID == taskID {
+			return
+		}
+	}
+	p.runs = append(p.runs, runProgress{taskID: taskID, title: title, state: agent.TaskQueued})
+}
+
+// Observe applies a live event. It returns the final state when the event
+// ends a tracked run, and "" otherwise.
+func (p *ProgressPane) Observe(e agent.Event) agent.TaskState {
+	if e.Replayed {
+		return ""
+	}
+	i := p.index(e.TaskID)
+	if i < 0 {
+		return ""
+	}
+	r := &p.runs[i]
+	if b, ok := e.Meta["budget"].(map[string]any); ok {
+		r.budget = agent.FormatBudget(b)
+	}
+	switch e.Phase {
+	case agent.PhaseTask:
+		r.state = agent.TaskState(e.Type)
+		if r.state.Terminal() {
+			p.runs = append(p.runs[:i], p.runs[i+1:]...)
+			return agent.TaskState(e.Type)
+		}
+		return ""
+	case agent.PhaseSection:
+		// Section attempts roll up into the compose line.
+		return ""
+	}
+	ph := r.phase(e.Phase)
+	if e.Type != "progress" || ph.state != "error" {
+		ph.state = e.Type
+	}
+	if e.Progress.Total > 0 {
+		ph.done, ph.total = e.Progress.Done, e.Progress.Total
+	}
+	if e.Err != "" {
+		ph.detail = e.Err
+	} else if e.Phase == agent.PhaseBudget && e.Type == "warning" {
+		ph.detail = fmt.Sprintf("%v %v at %.0f%% of %v", e.Meta["scope"], e.Meta["limit"], metaFloat(e.Meta["used"])*100, e.Meta["of"])
+	}
+	return ""
+}
+
+func (p *ProgressPane) index(taskID string) int {
+	for i := range p.runs {
+		if p.runs[i].taskID == taskID {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r *runProgress) phase(ph agent.Phase) *phaseProgress {
+	for i := range r.phases {
+		if r.phases[i].phase == ph {
+			return &r.phases[i]
+		}
+	}
+	r.phases = append(r.phases, phaseProgress{phase: ph})
+	return &r.phases[len(r.phases)-1]
+}
+
+func metaFloat(v any) float64 {
+	f, _ := v.(float64)
+	return f
+}
+
+func (p ProgressPane) View() string {
+	if len(p.runs) == 0 {
+		return ""
+	}
+	var lines []string
+	for _, r := range p.runs {
+		head := CommandIndicator.Render("⏺ ") + Strong.Render("Researching: "+r.title)
+		state := string(r.state)
+		if r.budget != "" {
+			state += " · " + r.budget
+		}
+		lines = append(lines, head+Gray.Render("  ["+state+"]"))
+		var steps []string
+		var details []string
+		for _, ph := range r.phases {
+			if ph.phase == agent.PhaseBudget {
+				details = append(details, "budget: "+ph.detail)
+				continue
+			}
+			var mark string
+			switch ph.state {
+			case "approval":
+				steps = append(steps, "? outline awaiting approval (/outline)")
+				continue
+			case "done", "approved":
+				mark = "✓ "
+			case "error":
+				mark = "✗ "
+			default:
+				mark = "… "
+			}
+			step := mark + string(ph.phase)
+			if ph.total > 0 && ph.state != "done" {
+				step += fmt.Sprintf(" %d/%d", ph.done, ph.total)
+			}
+			steps = append(steps, step)
+			if ph.state == "error" && ph.detail != "" {
+				details = append(details, string(ph.phase)+": "+ph.detail)
+			}
+		}
+		if len(steps) > 0 {
+			lines = append(lines, Text.Render("  "+strings.Join(steps, "  ")))
+		}
+		for _, d := range details {
+			lines = append(lines, Gray.Render("  "+d))
+		}
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
@@ -0,0 +1,124 @@
+package tui
+
+import (
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// ReportViewer shows a Markdown report full screen. While open it takes all
+// key input; esc or q closes it.
+type ReportViewer struct {
+	open   bool
+	path   string
+	raw    string
+	vp     viewport.Model
+	width  int
+	height int
+}
+
+func NewReportViewer() ReportViewer { return ReportViewer{vp: viewport.New(0, 0)} }
+
+func (v ReportViewer) IsOpen() bool { return v.open }
+
+// Open loads the report at path and shows it from the top.
+func (v *ReportViewer) Open(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	v.open, v.path, v.raw = true, path, string(b)
+	v.layout()
+	v.vp.GotoTop()
+	return nil
+}
+
+// OpenAt loads the report at path and scrolls to the section under heading,
+// or shows it from the top when there is no such section.
+func (v *ReportViewer) OpenAt(path, heading string) error {
+	if err := v.Open(path); err != nil {
+		return err
+	}
+	lines := strings.Split(v.raw, "\n")
+	for i, line := range lines {
+		if heading != "" && strings.HasPrefix(line, "#") && strings.TrimSpace(strings.TrimLeft(line, "#")) == heading {
+			if i > 0 {
+				v.vp.SetYOffset(lipgloss.Height(renderMarkdown(strings.Join(lines[:i], "\n"), v.vp.Width)))
+			}
+			break
+		}
+	}
+	return nil
+}
+
+func (v *ReportViewer) Close() { v.open = false }
+
+func (v *ReportViewer) SetSize(w, h int) {
+	v.width, v.height = w, h
+	v.layout()
+}
+
+func (v *ReportViewer) layout() {
+	const chrome = 2 // header and footer lines
+	v.vp.Width = max(v.width, 1)
+	v.vp.Height = max(v.height-chrome, 1)
+	v.vp.SetContent(renderMarkdown(v.raw, v.vp.Width))
+}
+
+func (v ReportViewer) Update(msg tea.Msg) (ReportViewer, tea.Cmd) {
+	if km, ok := msg.(tea.KeyMsg); ok {
+		switch km.String() {
+		case "esc", "q":
+			v.open = false
+			return v, nil
+		case "g", "home":
+			v.vp.GotoTop()
+			return v, nil
+		case "G", "end":
+			v.vp.GotoBottom()
+			return v, nil
+		}
+	}
+	var cmd tea.Cmd
+	v.vp, cmd = v.vp.Update(msg)
+	return v, cmd
+}
+
+func (v ReportViewer) View() string {
+	header := PrimaryBold.Render("Report") + Gray.Render("  "+v.path)
+	footer := Gray.Render("↑/↓ scroll · pgup/pgdn page · g/G top/bottom · esc close")
+	return lipgloss.JoinVertical(lipgloss.Left, header, v.vp.View(), footer)
+}
+
+// renderMarkdown applies light terminal styling to Markdown: headings stand
+// out, quotes and front matter are dimmed and long lines wrap to width.
+func renderMarkdown(md string, width int) string {
+	wrap := lipgloss.NewStyle().Width(width)
+	var out []string
+	inFront := false
+	for i, line := range strings.Split(md, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case i == 0 && trimmed == "---":
+			inFront = true
+			out = append(out, Gray.Render(line))
+		case inFront:
+			if trimmed == "---" {
+				inFront = false
+			}
+			out = append(out, Gray.Render(line))
+		case strings.HasPrefix(trimmed, "# "):
+			out = append(out, wrap.Inherit(PrimaryBold).Render(strings.TrimPrefix(trimmed, "# ")))
+		case strings.HasPrefix(trimmed, "#"):
+			out = append(out, wrap.Inherit(Strong).Render(strings.TrimLeft(trimmed, "# ")))
+		case strings.HasPrefix(trimmed, ">"):
+			out = append(out, wrap.Inherit(Gray).Render(line))
+		default:
+			out = append(out, wrap.Inherit(Text).Render(line))
+		}
+	}
+	return strings.Join(out, "\n")
+}
@@ -0,0 +1,275 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"gotcha/internal/agent"
+	"gotcha/internal/diff"
+)
+
+// RevisionBrowser lists the revisions of the session's report. A revision
+// can be read, compared side by side with another one section by section,
+// or restored. While open it takes all key input.
+type RevisionBrowser struct {
+	open      bool
+	r         *agent.Researcher
+	sessionID string
+	revs      []agent.Revision
+	sel       int
+	mark      int    // revision marked for comparison, 0 for none
+	mode      string // "" | view | diff | restore
+	title     string // header of the view or diff
+	vp        viewport.Model
+	errMsg    string
+	width     int
+	height    int
+}
+
+// RevisionRestoreMsg asks to make revision N the session's report again.
+type RevisionRestoreMsg struct{ N int }
+
+func NewRevisionBrowser(r *agent.Researcher) RevisionBrowser {
+	return RevisionBrowser{r: r, vp: viewport.New(0, 0)}
+}
+
+func (b RevisionBrowser) IsOpen() bool { return b.open }
+func (b *RevisionBrowser) Close()      { b.open = false }
+
+func (b *RevisionBrowser) SetSize(w, h int) {
+	b.width, b.height = w, h
+	const chrome = 2 // header and footer lines
+	b.vp.Width = max(w, 1)
+	b.vp.Height = max(h-chrome, 1)
+}
+
+// Open lists the session's revisions with the latest selected.
+func (b *RevisionBrowser) Open(sessionID string) error {
+	revs, err := b.r.Revisions(sessionID)
+	if err != nil {
+		return err
+	}
+	if len(revs) == 0 {
+		return fmt.Errorf("no report revisions in %s", sessionID)
+	}
+	b.open, b.sessionID, b.revs = true, sessionID, revs
+	b.sel, b.mark, b.mode, b.errMsg = len(revs)-1, 0, "", ""
+	return nil
+}
+
+func (b RevisionBrowser) Update(msg tea.Msg) (RevisionBrowser, tea.Cmd) {
+	km, ok := msg.(tea.KeyMsg)
+	if !ok {
+		if b.mode == "view" || b.mode == "diff" {
+			var cmd tea.Cmd
+			b.vp, cmd = b.vp.Update(msg)
+			return b, cmd
+		}
+		return b, nil
+	}
+	switch b.mode {
+	case "view", "diff":
+		switch km.String() {
+		case "esc", "q":
+			b.mode = ""
+		case "g", "home":
+			b.vp.GotoTop()
+		case "G", "end":
+			b.vp.GotoBottom()
+		default:
+			var cmd tea.Cmd
+			b.vp, cmd = b.vp.Update(msg)
+			return b, cmd
+		}
+		return b, nil
+	case "restore":
+		b.mode = ""
+		if km.String() == "y" {
+			b.open = false
+			n := b.revs[b.sel].N
+			return b, func() tea.Msg { return RevisionRestoreMsg{N: n} }
+		}
+		return b, nil
+	}
+	b.errMsg = ""
+	cur := b.revs[b.sel]
+	switch km.String() {
+	case "up", "k":
+		if b.sel > 0 {
+			b.sel--
+		}
+	case "down", "j":
+		if b.sel < len(b.revs)-1 {
+			b.sel++
+		}
+	case " ", "m":
+		if b.mark == cur.N {
+			b.mark = 0
+		} else {
+			b.mark = cur.N
+		}
+	case "enter", "v":
+		_, doc, err := b.r.Revision(b.sessionID, cur.N)
+		if err != nil {
+			b.errMsg = err.Error()
+			return b, nil
+		}
+		b.show("view", fmt.Sprintf("Revision %d", cur.N), renderMarkdown(doc, b.vp.Width))
+	case "d":
+		// Compare with the marked revision, or with the one before.
+		from := b.mark
+		if from == 0 || from == cur.N {
+			if b.sel == 0 {
+				b.errMsg = "Mark another revision with space to compare with the first one."
+				return b, nil
+			}
+			from = b.revs[b.sel-1].N
+		}
+		a, z := min(from, cur.N), max(from, cur.N)
+		diffs, err := b.r.DiffRevisions(b.sessionID, a, z)
+		if err != nil {
+			b.errMsg = err.Error()
+			return b, nil
+		}
+		b.show("diff", fmt.Sprintf("Revision %d → %d", a, z), renderSideBySide(diffs, b.vp.Width))
+	case "r":
+		if b.sel == len(b.revs)-1 {
+			b.errMsg = "This is already the current report."
+			return b, nil
+		}
+		b.mode = "restore"
+	case "esc", "q":
+		b.open = false
+	}
+	return b, nil
+}
+
+func (b *RevisionBrowser) show(mode, title, content string) {
+	b.mode, b.title = mode, title
+	b.vp.SetContent(content)
+	b.vp.GotoTop()
+}
+
+func (b RevisionBrowser) View() string {
+	if b.mode == "view" || b.mode == "diff" {
+		header := PrimaryBold.Render(b.title) + Gray.Render("  "+b.sessionID)
+		footer := Gray.Render("↑/↓ scroll · pgup/pgdn page · g/G top/bottom · esc back")
+		return lipgloss.JoinVertical(lipgloss.Left, header, b.vp.View(), footer)
+	}
+	lines := []string{PrimaryBold.Render("Report revisions") + Gray.Render("  "+b.sessionID), ""}
+	for i, rev := range b.revs {
+		marker := "  "
+		if b.mark == rev.N {
+			marker = Accent.Render("• ")
+		}
+		label := fmt.Sprintf("r%d  %s  %d sources  $%.4f  %s", rev.N, rev.At.Local().Format("2006-01-02 15:04"), rev.Sources, rev.Cost, rev.Title)
+		if rev.RestoredFrom > 0 {
+			label += fmt.Sprintf("  (restored r%d)", rev.RestoredFrom)
+		}
+		if i == len(b.revs)-1 {
+			label += "  [current]"
+		}
+		if i == b.sel {
+			lines = append(lines, CommandIndicator.Render("› ")+CommandIndicator.Bold(true).Render(label))
+		} else {
+			lines = append(lines, marker+Text.Render(label))
+		}
+	}
+	lines = append(lines, "")
+	if rev := b.revs[b.sel]; rev.Prompt != "" {
+		info := "Prompt: " + rev.Prompt
+		if rev.Model != "" {
+			info += "  ·  model " + rev.Model
+		}
+		lines = append(lines, lipgloss.NewStyle().Width(max(b.width-2, 20)).Inherit(Gray).Render(info), "")
+	}
+	if b.mode == "restore" {
+		lines = append(lines, Text.Render(fmt.Sprintf("Restore revision %d as the current report? y to confirm, any other key to cancel.", b.revs[b.sel].N)))
+	} else {
+		lines = append(lines, Gray.Render("↑/↓ select · enter view · space mark · d diff with marked (or previous) · r restore · esc close"))
+	}
+	if b.errMsg != "" {
+		lines = append(lines, lipgloss.NewStyle().Foreground(lipgloss.Color("#E06C75")).Render(b.errMsg))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
+
+// renderSideBySide shows changed sections with the old text on the left and
+// the new on the right. Unchanged sections are only counted, and long runs of
+// unchanged lines inside a section are folded.
+func renderSideBySide(diffs []diff.SectionDiff, width int) string {
+	col := max((width-3)/2, 10)
+	cell := lipgloss.NewStyle().Width(col)
+	del := cell.Foreground(lipgloss.Color("#E06C75"))
+	ins := cell.Foreground(lipgloss.Color("#98C379"))
+	sep := Gray.Render(" │ ")
+	row := func(l, r string, ls, rs lipgloss.Style) string {
+		return lipgloss.JoinHorizontal(lipgloss.Top, ls.Render(l), sep, rs.Render(r))
+	}
+
+	var out []string
+	unchanged := 0
+	for _, d := range diffs {
+		if d.Status == diff.SectionUnchanged {
+			unchanged++
+			continue
+		}
+		heading := d.Heading
+		if heading == "" {
+			heading = "(title)"
+		}
+		out = append(out, Strong.Render(fmt.Sprintf("## %s", heading))+Gray.Render(fmt.Sprintf("  %s +%d −%d", d.Status, d.Added, d.Removed)))
+		lines := d.Lines
+		for i := 0; i < len(lines); {
+			if lines[i].Kind == diff.Equal {
+				k := i
+				for k < len(lines) && lines[k].Kind == diff.Equal {
+					k++
+				}
+				if k-i > 4 {
+					out = append(out, row(lines[i].Text, lines[i].Text, cell.Inherit(Gray), cell.Inherit(Gray)))
+					out = append(out, Gray.Render(fmt.Sprintf("  ⋯ %d unchanged lines", k-i-2)))
+					out = append(out, row(lines[k-1].Text, lines[k-1].Text, cell.Inherit(Gray), cell.Inherit(Gray)))
+				} else {
+					for ; i < k; i++ {
+						out = append(out, row(lines[i].Text, lines[i].Text, cell.Inherit(Gray), cell.Inherit(Gray)))
+					}
+				}
+				i = k
+				continue
+			}
+			// Pair a run of deletions with the insertions that follow it.
+			var olds, news []string
+			for i < len(lines) && lines[i].Kind == diff.Delete {
+				olds = append(olds, lines[i].Text)
+				i++
+			}
+			for i < len(lines) && lines[i].Kind == diff.Insert {
+				news = append(news, lines[i].Text)
+				i++
+			}
+			for k := 0; k < max(len(olds), len(news)); k++ {
+				var l, r string
+				if k < len(olds) {
+					l = olds[k]
+				}
+				if k < len(news) {
+					r = news[k]
+				}
+				out = append(out, row(l, r, del, ins))
+			}
+		}
+		out = append(out, "")
+	}
+	if len(out) == 0 {
+		out = append(out, Gray.Render("The two revisions are identical."))
+	}
+	if unchanged > 0 {
+		out = append(out, Gray.Render(fmt.Sprintf("%d unchanged section(s) not shown.", unchanged)))
+	}
+	return strings.Join(out, "\n")
+}
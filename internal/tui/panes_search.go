@@ -0,0 +1,90 @@
+package tui
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"gotcha/internal/session"
+)
+
+// maxSearchHits caps the matches a /search lists.
+const maxSearchHits = 30
+
+// SearchResults lists the matches of a /search across all sessions. Enter
+// jumps to the selected one. While open it takes all key input.
+type SearchResults struct {
+	open   bool
+	query  string
+	hits   []session.SearchHit
+	sel    int
+	width  int
+	height int
+}
+
+// SearchJumpMsg asks to show a search hit: its session, and in it the
+// message, report section or note that matched.
+type SearchJumpMsg struct{ Hit session.SearchHit }
+
+func NewSearchResults() SearchResults { return SearchResults{} }
+
+func (r SearchResults) IsOpen() bool      { return r.open }
+func (r *SearchResults) Close()           { r.open = false }
+func (r *SearchResults) SetSize(w, h int) { r.width, r.height = w, h }
+
+// Open lists hits for query with the best one selected.
+func (r *SearchResults) Open(query string, hits []session.SearchHit) {
+	r.open, r.query, r.hits, r.sel = true, query, hits, 0
+}
+
+func (r SearchResults) Update(msg tea.Msg) (SearchResults, tea.Cmd) {
+	km, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return r, nil
+	}
+	switch km.String() {
+	case "up", "k":
+		if r.sel > 0 {
+			r.sel--
+		}
+	case "down", "j":
+		if r.sel < len(r.hits)-1 {
+			r.sel++
+		}
+	case "enter":
+		r.open = false
+		hit := r.hits[r.sel]
+		return r, func() tea.Msg { return SearchJumpMsg{Hit: hit} }
+	case "esc", "q":
+		r.open = false
+	}
+	return r, nil
+}
+
+func (r SearchResults) View() string {
+	wrap := lipgloss.NewStyle().Width(max(r.width-6, 20))
+	lines := []string{PrimaryBold.Render("Search") + Gray.Render(fmt.Sprintf("  %q · %d matches", r.query, len(r.hits))), ""}
+	// Keep the selection in view: each hit takes three lines.
+	perPage := max((r.height-5)/3, 1)
+	first := max(0, min(r.sel-perPage/2, len(r.hits)-perPage))
+	for i := first; i < len(r.hits) && i < first+perPage; i++ {
+		h := r.hits[i]
+		title := h.Title
+		if title == "" {
+			title = "(untitled)"
+		}
+		if h.Archived {
+			title += " [archived]"
+		}
+		label := fmt.Sprintf("%s  %s  %s", h.SessionID, h.Location(), title)
+		if i == r.sel {
+			lines = append(lines, CommandIndicator.Render("› ")+CommandIndicator.Bold(true).Render(label))
+		} else {
+			lines = append(lines, "  "+Text.Render(label))
+		}
+		lines = append(lines, "    "+wrap.Inherit(Gray).MaxHeight(1).Render(h.Snippet), "")
+	}
+	lines = append(lines, Gray.Render("↑/↓ select · enter jump to it · esc close"))
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
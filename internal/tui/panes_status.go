@@ -3,10 +3,23 @@ package tui
 import (
     tea "github.com/charmbracelet/bubbletea"
     "github.com/charmbracelet/lipgloss"
+
+    "gotcha/internal/agent"
 )
 
 // StatusPane renders only the current research task titles on one subtle line.
-type StatusPane struct { tasks []string }
+type StatusPane struct {
+    tasks []string
+    // research tasks tracked from agent task events, newest first
+    research []taskStatus
+}
+
+type taskStatus struct {
+    id, title string
+    state     agent.TaskState
+    replayed  bool   // last seen in the event log rather than live
+    budget    string // latest budget consumption, formatted
+}
 
 func NewStatusPane() StatusPane { return StatusPane{tasks: []string{}} }
 
@@ -16,15 +29,49 @@ func (p StatusPane) Update(msg tea.Msg) (StatusPane, tea.Cmd) {
     switch m := msg.(type) {
     case NewTaskMsg:
         if m.Title != "" { p.tasks = append([]string{m.Title}, p.tasks...) }
+    case EventMsg:
+        if m.E.Phase == agent.PhaseTask { p.trackTask(m.E) } else { p.trackBudget(m.E) }
     }
     return p, nil
 }
 
+func (p *StatusPane) trackTask(e agent.Event) {
+    title, _ := e.Meta["title"].(string)
+    for i := range p.research {
+        if p.research[i].id == e.TaskID {
+            p.research[i].state = agent.TaskState(e.Type)
+            p.research[i].replayed = e.Replayed
+            if title != "" { p.research[i].title = title }
+            return
+        }
+    }
+    p.research = append([]taskStatus{{id: e.TaskID, title: title, state: agent.TaskState(e.Type), replayed: e.Replayed}}, p.research...)
+}
+
+// trackBudget records the budget consumption carried by a run's phase events.
+func (p *StatusPane) trackBudget(e agent.Event) {
+    b, ok := e.Meta["budget"].(map[string]any)
+    if !ok { return }
+    for i := range p.research {
+        if p.research[i].id == e.TaskID { p.research[i].budget = agent.FormatBudget(b); return }
+    }
+}
+
 func (p StatusPane) View() string {
-    if len(p.tasks) == 0 { return "" }
+    var items []string
+    for _, t := range p.research {
+        if t.state.Terminal() && t.state != agent.TaskFailed { continue }
+        state := string(t.state)
+        // A task still active when the log ends died with a previous process
+        if t.replayed && !t.state.Terminal() { state = "interrupted" }
+        if t.budget != "" { state += " · " + t.budget }
+        items = append(items, t.title+" ["+state+"]")
+    }
+    items = append(items, p.tasks...)
+    if len(items) == 0 { return "" }
     max := 3
-    if len(p.tasks) < max { max = len(p.tasks) }
-    line := p.tasks[0]
-    for i := 1; i < max; i++ { line += " • " + p.tasks[i] }
+    if len(items) < max { max = len(items) }
+    line := items[0]
+    for i := 1; i < max; i++ { line += " • " + items[i] }
     return lipgloss.NewStyle().Foreground(Gray.GetForeground()).Render(line)
 }
@@ -12,19 +12,41 @@ import (
 )
 
 type SessionSelector struct {
+	manager         *session.Manager
 	sessions        []session.SessionInfo
 	cursor          int
 	selectedSession string
 	cancelled       bool
+	showArchived    bool   // list archived sessions instead of active ones
+	confirmDelete   bool   // waiting for y to delete the session under the cursor
+	status          string // result of the last archive or delete
 }
 
-func NewSessionSelector(sessions []session.SessionInfo) SessionSelector {
+func NewSessionSelector(manager *session.Manager, sessions []session.SessionInfo) SessionSelector {
 	return SessionSelector{
+		manager:  manager,
 		sessions: sessions,
 		cursor:   0,
 	}
 }
 
+// reload lists the active or the archived sessions again, keeping the
+// cursor in range.
+func (m *SessionSelector) reload() {
+	all, err := m.manager.AllSessions()
+	if err != nil {
+		m.status = "Cannot list sessions: " + err.Error()
+		return
+	}
+	m.sessions = m.sessions[:0]
+	for _, s := range all {
+		if s.Archived == m.showArchived {
+			m.sessions = append(m.sessions, s)
+		}
+	}
+	m.cursor = max(0, min(m.cursor, len(m.sessions)-1))
+}
+
 func (m SessionSelector) Init() tea.Cmd {
 	return nil
 }
@@ -32,7 +54,45 @@ func (m SessionSelector) Init() tea.Cmd {
 func (m SessionSelector) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m.confirmDelete {
+			m.confirmDelete = false
+			if msg.String() == "y" && m.cursor < len(m.sessions) {
+				id := m.sessions[m.cursor].ID
+				if err := m.manager.Delete(id); err != nil {
+					m.status = "Cannot delete " + id + ": " + err.Error()
+				} else {
+					m.status = "Deleted " + id + "."
+				}
+				m.reload()
+			} else {
+				m.status = ""
+			}
+			return m, nil
+		}
+		m.status = ""
 		switch msg.String() {
+		case "d", "delete":
+			if len(m.sessions) > 0 {
+				m.confirmDelete = true
+			}
+		case "a":
+			if len(m.sessions) > 0 {
+				id := m.sessions[m.cursor].ID
+				var err error
+				if m.showArchived {
+					err, m.status = m.manager.Unarchive(id), "Restored "+id+" to the session list."
+				} else {
+					err, m.status = m.manager.Archive(id), "Archived "+id+"; press v to see archived sessions."
+				}
+				if err != nil {
+					m.status = "Cannot archive " + id + ": " + err.Error()
+				}
+				m.reload()
+			}
+		case "v":
+			m.showArchived = !m.showArchived
+			m.cursor = 0
+			m.reload()
 		case "q":
 			m.cancelled = true
 			return m, tea.Quit
@@ -68,7 +128,11 @@ func (m SessionSelector) View() string {
 		Bold(true).
 		Padding(1, 0)
 
-	b.WriteString(titleStyle.Render("📚 Gotcha Session Manager"))
+	heading := "📚 Gotcha Session Manager"
+	if m.showArchived {
+		heading += " — archived sessions"
+	}
+	b.WriteString(titleStyle.Render(heading))
 	b.WriteString("\n\n")
 
 	// Instructions
@@ -76,7 +140,10 @@ func (m SessionSelector) View() string {
 		Foreground(lipgloss.Color("#999999")).
 		Italic(true)
 
-	instructions := "Use ↑/↓ to navigate • Enter to select • 'n' for new session • 'q' to quit"
+	instructions := "Use ↑/↓ to navigate • Enter to select • 'n' for new session • 'a' archive • 'd' delete • 'v' archived • 'q' to quit"
+	if m.showArchived {
+		instructions = "Use ↑/↓ to navigate • Enter to select • 'a' unarchive • 'd' delete • 'v' back to sessions • 'q' to quit"
+	}
 	b.WriteString(instructionStyle.Render(instructions))
 	b.WriteString("\n\n")
 
@@ -85,9 +152,18 @@ func (m SessionSelector) View() string {
 			Foreground(lipgloss.Color("#999999")).
 			Italic(true)
 
-		b.WriteString(noSessionsStyle.Render("No existing sessions found."))
-		b.WriteString("\n\n")
-		b.WriteString("Press 'n' to create a new session or 'q' to quit.")
+		if m.showArchived {
+			b.WriteString(noSessionsStyle.Render("No archived sessions."))
+			b.WriteString("\n\n")
+			b.WriteString("Press 'v' to go back to the session list or 'q' to quit.")
+		} else {
+			b.WriteString(noSessionsStyle.Render("No existing sessions found."))
+			b.WriteString("\n\n")
+			b.WriteString("Press 'n' to create a new session, 'v' for archived sessions or 'q' to quit.")
+		}
+		if m.status != "" {
+			b.WriteString("\n\n" + m.status)
+		}
 		return b.String()
 	}
 
@@ -116,7 +192,7 @@ func (m SessionSelector) View() string {
 			title = "Untitled Session"
 		}
 
-		sessionInfo := fmt.Sprintf("%s  •  %s  •  %s", sess.ID, timeStr, ageStr)
+		sessionInfo := fmt.Sprintf("%s  •  %s  •  %s  •  %s", title, sess.ID, timeStr, ageStr)
 
 		if i == m.cursor {
 			// Selected session
@@ -136,6 +212,14 @@ func (m SessionSelector) View() string {
 			sessionLine = normalStyle.Render("  " + sessionInfo)
 		}
 
+		if sess.Description != "" {
+			descriptionStyle := lipgloss.NewStyle().
+				Foreground(lipgloss.Color("#999999")).
+				Padding(0, 1)
+
+			sessionLine += "\n" + descriptionStyle.Render("    "+sess.Description)
+		}
+
 		b.WriteString(sessionLine)
 		b.WriteString("\n")
 	}
@@ -147,6 +231,13 @@ func (m SessionSelector) View() string {
 		Italic(true)
 
 	footer := "💡 Tip: Press 'n' to create a new session"
+	switch {
+	case m.confirmDelete:
+		footer = fmt.Sprintf("Delete %s and everything in it (conversation, notes, reports)? y to confirm, any other key to cancel.", m.sessions[m.cursor].ID)
+		footerStyle = footerStyle.Foreground(lipgloss.Color("#E06C75")).Italic(false)
+	case m.status != "":
+		footer = m.status
+	}
 	b.WriteString(footerStyle.Render(footer))
 
 	return b.String()